@@ -38,6 +38,7 @@ const (
 	KeySubscriptionNotificationSent = "sub_reminder"
 	KeyNewsbox                      = "newsbox"
 	KeyInviteCode                   = "invite"
+	KeyTeamInvite                   = "team_invite"
 
 	SessionKeyDefault = "default"
 
@@ -46,7 +47,9 @@ const (
 
 	ErrUnauthorized        = "401 unauthorized"
 	ErrBadRequest          = "400 bad request"
+	ErrForbidden           = "403 forbidden"
 	ErrNotFound            = "404 not found"
+	ErrTooManyRequests     = "429 too many requests"
 	ErrInternalServerError = "500 internal server error"
 )
 
@@ -65,6 +68,12 @@ const (
 	MailProviderSmtp = "smtp"
 )
 
+const (
+	Enforce2faNone   = "none"
+	Enforce2faAdmins = "admins"
+	Enforce2faAll    = "all"
+)
+
 var emailProviders = []string{
 	MailProviderSmtp,
 }
@@ -87,10 +96,12 @@ type appConfig struct {
 	AggregationTime           string                       `yaml:"aggregation_time" default:"0 15 2 * * *" env:"WAKAPI_AGGREGATION_TIME"`
 	ReportTimeWeekly          string                       `yaml:"report_time_weekly" default:"0 0 18 * * 5" env:"WAKAPI_REPORT_TIME_WEEKLY"`
 	DataCleanupTime           string                       `yaml:"data_cleanup_time" default:"0 0 6 * * 0" env:"WAKAPI_DATA_CLEANUP_TIME"`
+	GoalNotificationTime      string                       `yaml:"goal_notification_time" default:"0 0 8 * * *" env:"WAKAPI_GOAL_NOTIFICATION_TIME"`
 	ImportEnabled             bool                         `yaml:"import_enabled" default:"true" env:"WAKAPI_IMPORT_ENABLED"`
 	ImportBackoffMin          int                          `yaml:"import_backoff_min" default:"5" env:"WAKAPI_IMPORT_BACKOFF_MIN"`
 	ImportMaxRate             int                          `yaml:"import_max_rate" default:"24" env:"WAKAPI_IMPORT_MAX_RATE"` // at max one successful import every x hours
 	ImportBatchSize           int                          `yaml:"import_batch_size" default:"50" env:"WAKAPI_IMPORT_BATCH_SIZE"`
+	ImportMaxFileSizeMb       int                          `yaml:"import_max_file_size_mb" default:"64" env:"WAKAPI_IMPORT_MAX_FILE_SIZE_MB"`
 	InactiveDays              int                          `yaml:"inactive_days" default:"7" env:"WAKAPI_INACTIVE_DAYS"`
 	HeartbeatMaxAge           string                       `yaml:"heartbeat_max_age" default:"168h" env:"WAKAPI_HEARTBEAT_MAX_AGE"`
 	CountCacheTTLMin          int                          `yaml:"count_cache_ttl_min" default:"30" env:"WAKAPI_COUNT_CACHE_TTL_MIN"`
@@ -123,11 +134,30 @@ type securityConfig struct {
 	SignupMaxRate              string                     `yaml:"signup_max_rate" default:"5/1h" env:"WAKAPI_SIGNUP_MAX_RATE"`
 	LoginMaxRate               string                     `yaml:"login_max_rate" default:"10/1m" env:"WAKAPI_LOGIN_MAX_RATE"`
 	PasswordResetMaxRate       string                     `yaml:"password_reset_max_rate" default:"5/1h" env:"WAKAPI_PASSWORD_RESET_MAX_RATE"`
+	HeartbeatMaxRate           string                     `yaml:"heartbeat_max_rate" default:"300/1m" env:"WAKAPI_HEARTBEAT_MAX_RATE"`        // per api key, '0/1s' to disable
+	HeartbeatIpMaxRate         string                     `yaml:"heartbeat_ip_max_rate" default:"1200/1m" env:"WAKAPI_HEARTBEAT_IP_MAX_RATE"` // per ip address, '0/1s' to disable
+	SummaryMaxRate             string                     `yaml:"summary_max_rate" default:"120/1m" env:"WAKAPI_SUMMARY_MAX_RATE"`            // per api key, '0/1s' to disable
+	SummaryIpMaxRate           string                     `yaml:"summary_ip_max_rate" default:"600/1m" env:"WAKAPI_SUMMARY_IP_MAX_RATE"`      // per ip address, '0/1s' to disable
+	Enforce2fa                 string                     `yaml:"enforce_2fa" default:"none" env:"WAKAPI_ENFORCE_2FA"`                        // one of 'none', 'admins' or 'all'
+	AllowedPrivateHosts        string                     `yaml:"allowed_private_hosts" default:"" env:"WAKAPI_ALLOWED_PRIVATE_HOSTS"`        // comma-separated list of host names, ips or cidrs in the local network that users may relay heartbeats or send notifications to
+	Oidc                       oidcConfig                 `yaml:"oidc"`
 	SecureCookie               *securecookie.SecureCookie `yaml:"-"`
 	SessionKey                 []byte                     `yaml:"-"`
 	trustReverseProxyIpsParsed []net.IPNet
 }
 
+type oidcConfig struct {
+	Enabled       bool   `yaml:"enabled" default:"false" env:"WAKAPI_OIDC_ENABLED"`
+	Name          string `yaml:"name" default:"SSO" env:"WAKAPI_OIDC_NAME"` // display name of the provider, shown on the login page
+	Issuer        string `yaml:"issuer" env:"WAKAPI_OIDC_ISSUER"`
+	ClientId      string `yaml:"client_id" env:"WAKAPI_OIDC_CLIENT_ID"`
+	ClientSecret  string `yaml:"client_secret" env:"WAKAPI_OIDC_CLIENT_SECRET"`
+	Scopes        string `yaml:"scopes" default:"openid profile email" env:"WAKAPI_OIDC_SCOPES"`
+	UsernameClaim string `yaml:"username_claim" default:"preferred_username" env:"WAKAPI_OIDC_USERNAME_CLAIM"`
+	AutoProvision bool   `yaml:"auto_provision" default:"false" env:"WAKAPI_OIDC_AUTO_PROVISION"` // create new users on first login, given that signups are allowed
+	LinkByEmail   bool   `yaml:"link_by_email" default:"false" env:"WAKAPI_OIDC_LINK_BY_EMAIL"`   // link to existing users with the same (verified) e-mail address on first login
+}
+
 type dbConfig struct {
 	Host                    string `env:"WAKAPI_DB_HOST"`
 	Socket                  string `env:"WAKAPI_DB_SOCKET"`
@@ -294,6 +324,10 @@ func (c *appConfig) GetWeeklyReportCron() string {
 	return utils.CronPadToSecondly(c.ReportTimeWeekly)
 }
 
+func (c *appConfig) GetGoalNotificationCron() string {
+	return utils.CronPadToSecondly(c.GoalNotificationTime)
+}
+
 func (c *appConfig) GetLeaderboardGenerationTimeCron() []string {
 	crons := []string{}
 
@@ -369,6 +403,24 @@ func (c *securityConfig) TrustReverseProxyIPs() []net.IPNet {
 	return c.trustReverseProxyIpsParsed
 }
 
+// GetAllowedPrivateHosts returns the hosts in the local network that outgoing requests on behalf of users may go to, despite them not being public
+func (c *securityConfig) GetAllowedPrivateHosts() *utils.HostAllowList {
+	return utils.ParseHostAllowList(c.AllowedPrivateHosts)
+}
+
+func (c *oidcConfig) GetScopes() []string {
+	scopes := strings.Fields(strings.ReplaceAll(c.Scopes, ",", " "))
+	if !slice.Contain(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return scopes
+}
+
+// Requires2fa returns whether the given kind of user must set up two-factor authentication before being able to log in to the web interface
+func (c *securityConfig) Requires2fa(isAdmin bool) bool {
+	return c.Enforce2fa == Enforce2faAll || (c.Enforce2fa == Enforce2faAdmins && isAdmin)
+}
+
 func (c *securityConfig) GetSignupMaxRate() (int, time.Duration) {
 	return c.parseRate(c.SignupMaxRate)
 }
@@ -381,6 +433,22 @@ func (c *securityConfig) GetPasswordResetMaxRate() (int, time.Duration) {
 	return c.parseRate(c.PasswordResetMaxRate)
 }
 
+func (c *securityConfig) GetHeartbeatMaxRate() (int, time.Duration) {
+	return c.parseRate(c.HeartbeatMaxRate)
+}
+
+func (c *securityConfig) GetHeartbeatIpMaxRate() (int, time.Duration) {
+	return c.parseRate(c.HeartbeatIpMaxRate)
+}
+
+func (c *securityConfig) GetSummaryMaxRate() (int, time.Duration) {
+	return c.parseRate(c.SummaryMaxRate)
+}
+
+func (c *securityConfig) GetSummaryIpMaxRate() (int, time.Duration) {
+	return c.parseRate(c.SummaryIpMaxRate)
+}
+
 func (c *securityConfig) parseRate(rate string) (int, time.Duration) {
 	pattern := regexp.MustCompile("(\\d+)/(\\d+)([smh])")
 	matches := pattern.FindStringSubmatch(rate)
@@ -560,6 +628,12 @@ func Load(configFlag string, version string) *Config {
 	if config.Security.TrustedHeaderAuth && len(config.Security.trustReverseProxyIpsParsed) == 0 {
 		config.Security.TrustedHeaderAuth = false
 	}
+	if !slice.Contain([]string{Enforce2faNone, Enforce2faAdmins, Enforce2faAll}, config.Security.Enforce2fa) {
+		Log().Fatal("invalid value for enforce_2fa, must be one of 'none', 'admins' or 'all'", "value", config.Security.Enforce2fa)
+	}
+	if config.Security.Oidc.Enabled && (config.Security.Oidc.Issuer == "" || config.Security.Oidc.ClientId == "") {
+		Log().Fatal("oidc requires both issuer and client_id to be set")
+	}
 	if d, err := time.Parse(config.App.DateFormat, config.App.DateFormat); err != nil || !d.Equal(time.Date(2006, time.January, 2, 0, 0, 0, 0, d.Location())) {
 		Log().Fatal("invalid date format", "format", config.App.DateFormat)
 	}
@@ -575,6 +649,9 @@ func Load(configFlag string, version string) *Config {
 	if _, err := cronParser.Parse(config.App.GetAggregationTimeCron()); err != nil {
 		Log().Fatal("invalid cron expression for aggregation_time")
 	}
+	if _, err := cronParser.Parse(config.App.GetGoalNotificationCron()); err != nil {
+		Log().Fatal("invalid cron expression for goal_notification_time")
+	}
 	for _, c := range config.App.GetLeaderboardGenerationTimeCron() {
 		if _, err := cronParser.Parse(c); err != nil {
 			Log().Fatal("invalid cron expression for leaderboard_generation_time")
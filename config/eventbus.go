@@ -11,9 +11,11 @@ const (
 	TopicUser                    = "user.*"
 	TopicHeartbeat               = "heartbeat.*"
 	TopicProjectLabel            = "project_label.*"
+	TopicSummary                 = "summary.*"
 	EventUserUpdate              = "user.update"
 	EventUserDelete              = "user.delete"
 	EventHeartbeatCreate         = "heartbeat.create"
+	EventSummaryCreate           = "summary.create"
 	EventProjectLabelCreate      = "project_label.create"
 	EventProjectLabelDelete      = "project_label.delete"
 	EventWakatimeFailure         = "wakatime.failure"
@@ -21,6 +23,7 @@ const (
 	FieldPayload                 = "payload"
 	FieldUser                    = "user"
 	FieldUserId                  = "user.id"
+	FieldRelayTarget             = "relay_target"
 )
 
 var eventHub *hub.Hub
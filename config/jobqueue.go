@@ -17,6 +17,9 @@ const (
 	QueueMails        = "wakapi.mail"
 	QueueImports      = "wakapi.imports"
 	QueueHousekeeping = "wakapi.housekeeping"
+	QueueWebhooks     = "wakapi.webhooks"
+	QueueRelay        = "wakapi.relay"
+	QueueNotification = "wakapi.notification"
 )
 
 type JobQueueMetrics struct {
@@ -36,6 +39,9 @@ func StartJobs() {
 	InitQueue(QueueMails, 1)
 	InitQueue(QueueImports, 1)
 	InitQueue(QueueHousekeeping, utils.HalfCPUs())
+	InitQueue(QueueWebhooks, utils.HalfCPUs())
+	InitQueue(QueueRelay, utils.HalfCPUs())
+	InitQueue(QueueNotification, 1)
 }
 
 func InitQueue(name string, workers int) error {
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"sync"
+	"time"
+
+	"github.com/muety/wakapi/utils"
+)
+
+var rateLimiters map[rateLimitKey]*utils.RateLimiter
+var rateLimitersLock sync.Mutex
+
+const (
+	RateLimitHeartbeats = "heartbeats"
+	RateLimitSummaries  = "summaries"
+)
+
+const (
+	RateLimitByApiKey = "api_key"
+	RateLimitByIp     = "ip"
+)
+
+type rateLimitKey struct {
+	scope string
+	by    string
+}
+
+type RateLimitMetrics struct {
+	Scope    string
+	By       string
+	Rejected int64
+}
+
+func init() {
+	rateLimiters = make(map[rateLimitKey]*utils.RateLimiter)
+}
+
+// GetRateLimiter returns the limiter shared by all endpoints of the given scope for the given kind of key, or nil, if rate limiting is disabled for it
+func GetRateLimiter(scope, by string) *utils.RateLimiter {
+	rateLimitersLock.Lock()
+	defer rateLimitersLock.Unlock()
+
+	key := rateLimitKey{scope: scope, by: by}
+	if limiter, ok := rateLimiters[key]; ok {
+		return limiter
+	}
+
+	var rate string
+	var parse func() (int, time.Duration)
+	security := &Get().Security
+	switch key {
+	case rateLimitKey{RateLimitHeartbeats, RateLimitByApiKey}:
+		rate, parse = security.HeartbeatMaxRate, security.GetHeartbeatMaxRate
+	case rateLimitKey{RateLimitHeartbeats, RateLimitByIp}:
+		rate, parse = security.HeartbeatIpMaxRate, security.GetHeartbeatIpMaxRate
+	case rateLimitKey{RateLimitSummaries, RateLimitByApiKey}:
+		rate, parse = security.SummaryMaxRate, security.GetSummaryMaxRate
+	case rateLimitKey{RateLimitSummaries, RateLimitByIp}:
+		rate, parse = security.SummaryIpMaxRate, security.GetSummaryIpMaxRate
+	}
+
+	var limiter *utils.RateLimiter
+	if rate != "" { // empty in tests
+		if limit, window := parse(); limit > 0 {
+			limiter = utils.NewRateLimiter(limit, window)
+		}
+	}
+	rateLimiters[key] = limiter
+	return limiter
+}
+
+func GetRateLimitMetrics() []*RateLimitMetrics {
+	rateLimitersLock.Lock()
+	defer rateLimitersLock.Unlock()
+
+	metrics := make([]*RateLimitMetrics, 0, len(rateLimiters))
+	for key, limiter := range rateLimiters {
+		if limiter == nil {
+			continue
+		}
+		metrics = append(metrics, &RateLimitMetrics{
+			Scope:    key.scope,
+			By:       key.by,
+			Rejected: limiter.Rejected(),
+		})
+	}
+	return metrics
+}
@@ -1,14 +1,22 @@
 package config
 
 const (
-	IndexTemplate         = "index.tpl.html"
-	LoginTemplate         = "login.tpl.html"
-	ImprintTemplate       = "imprint.tpl.html"
-	SignupTemplate        = "signup.tpl.html"
-	SetPasswordTemplate   = "set-password.tpl.html"
-	ResetPasswordTemplate = "reset-password.tpl.html"
-	SettingsTemplate      = "settings.tpl.html"
-	SummaryTemplate       = "summary.tpl.html"
-	LeaderboardTemplate   = "leaderboard.tpl.html"
-	ProjectsTemplate      = "projects.tpl.html"
+	IndexTemplate            = "index.tpl.html"
+	LoginTemplate            = "login.tpl.html"
+	TotpLoginTemplate        = "login-2fa.tpl.html"
+	ImprintTemplate          = "imprint.tpl.html"
+	SignupTemplate           = "signup.tpl.html"
+	SetPasswordTemplate      = "set-password.tpl.html"
+	ResetPasswordTemplate    = "reset-password.tpl.html"
+	SettingsTemplate         = "settings.tpl.html"
+	SummaryTemplate          = "summary.tpl.html"
+	LeaderboardTemplate      = "leaderboard.tpl.html"
+	ProjectsTemplate         = "projects.tpl.html"
+	ProjectTemplate          = "project.tpl.html"
+	TimelineTemplate         = "timeline.tpl.html"
+	TimesheetTemplate        = "timesheet.tpl.html"
+	TimesheetInvoiceTemplate = "timesheet-invoice.tpl.html"
+	TeamsTemplate            = "teams.tpl.html"
+	TeamTemplate             = "team.tpl.html"
+	AdminTemplate            = "admin.tpl.html"
 )
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/migrations"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/muety/wakapi/services/mail"
+	"github.com/muety/wakapi/utils"
+)
+
+const ctlUsage = `Usage: wakapi [-config <path>] ctl <command> [<args>]
+
+Commands:
+  users list
+  users create -name <username> -password <password> [-email <email>] [-admin]
+  users reset-password -name <username> [-password <password>]
+  users restore -name <username> -file <path> [-keep-ids]
+  summaries regenerate [-user <username>] [-from <yyyy-mm-dd>] [-to <yyyy-mm-dd>]
+  durations regenerate [-user <username>]
+  heartbeats dedupe [-user <username>]
+  migrate
+
+Commands that accept -user apply to all users if omitted.
+Durations, unlike summaries, are always regenerated in full, i.e. from a user's first heartbeat up until now.
+Heartbeat ids from an account archive can only be kept on an instance without any heartbeats (stop the server first).
+`
+
+type ctlCommand func(args []string) error
+
+// runCtl performs a single maintenance task directly against the configured database, without starting the web server, and returns the process' exit code
+func runCtl(args []string) int {
+	command, args := resolveCtlCommand(args)
+	if command == nil {
+		fmt.Fprint(os.Stderr, ctlUsage)
+		return 2
+	}
+
+	sqlDb := initDatabase()
+	defer sqlDb.Close()
+
+	if !config.SkipMigrations {
+		migrations.Run(db, config)
+	}
+
+	initCtlServices()
+
+	if err := command(args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// initCtlServices only sets up the services needed by ctl commands. Others, e.g. for webhooks, notifications or relays, would subscribe to events and queue work that gets lost once the process exits.
+func initCtlServices() {
+	aliasRepository = repositories.NewAliasRepository(db)
+	heartbeatRepository = repositories.NewHeartbeatRepository(db)
+	userRepository = repositories.NewUserRepository(db)
+	languageMappingRepository = repositories.NewLanguageMappingRepository(db)
+	projectLabelRepository = repositories.NewProjectLabelRepository(db)
+	summaryRepository = repositories.NewSummaryRepository(db)
+	keyValueRepository = repositories.NewKeyValueRepository(db)
+	durationRepository = repositories.NewDurationRepository(db)
+	apiTokenRepository = repositories.NewApiTokenRepository(db)
+
+	mailService = mail.NewMailService()
+	aliasService = services.NewAliasService(aliasRepository)
+	keyValueService = services.NewKeyValueService(keyValueRepository)
+	apiTokenService = services.NewApiTokenService(apiTokenRepository)
+	userService = services.NewUserService(keyValueService, mailService, apiTokenService, userRepository)
+	languageMappingService = services.NewLanguageMappingService(languageMappingRepository)
+	projectLabelService = services.NewProjectLabelService(projectLabelRepository)
+	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService)
+	durationService = services.NewDurationService(durationRepository, heartbeatService, userService, languageMappingService)
+	summaryService = services.NewSummaryService(summaryRepository, heartbeatService, durationService, aliasService, projectLabelService)
+	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService, durationService)
+	exportService = services.NewExportService(userService, heartbeatService, aliasService, projectLabelService, languageMappingService)
+}
+
+func resolveCtlCommand(args []string) (ctlCommand, []string) {
+	if len(args) == 1 && args[0] == "migrate" {
+		return ctlMigrate, nil
+	}
+	if len(args) < 2 {
+		return nil, nil
+	}
+
+	switch args[0] + " " + args[1] {
+	case "users list":
+		return ctlListUsers, args[2:]
+	case "users create":
+		return ctlCreateUser, args[2:]
+	case "users reset-password":
+		return ctlResetPassword, args[2:]
+	case "users restore":
+		return ctlRestoreUser, args[2:]
+	case "summaries regenerate":
+		return ctlRegenerateSummaries, args[2:]
+	case "durations regenerate":
+		return ctlRegenerateDurations, args[2:]
+	case "heartbeats dedupe":
+		return ctlDedupeHeartbeats, args[2:]
+	}
+	return nil, nil
+}
+
+func ctlMigrate(args []string) error {
+	if config.SkipMigrations { // otherwise, they already ran before
+		migrations.Run(db, config)
+	}
+	fmt.Println("migrations completed")
+	return nil
+}
+
+func ctlListUsers(args []string) error {
+	users, err := userService.GetAll()
+	if err != nil {
+		return err
+	}
+	counts, err := heartbeatService.CountByUsers(users)
+	if err != nil {
+		return err
+	}
+	countsByUser := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		countsByUser[c.User] = c.Count
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tADMIN\tDISABLED\tHEARTBEATS\tCREATED\tLAST LOGIN")
+	for _, u := range users {
+		lastLogin := "-"
+		if u.LastLoggedInAt.Valid() {
+			lastLogin = u.LastLoggedInAt.T().Format(conf.SimpleDateTimeFormat)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%d\t%s\t%s\n", u.ID, u.Email, u.IsAdmin, u.IsDisabled, countsByUser[u.ID], u.CreatedAt.T().Format(conf.SimpleDateTimeFormat), lastLogin)
+	}
+	return w.Flush()
+}
+
+func ctlCreateUser(args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+	name := fs.String("name", "", "username")
+	password := fs.String("password", "", "password")
+	email := fs.String("email", "", "e-mail address")
+	admin := fs.Bool("admin", false, "grant admin privileges")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !models.ValidateUsername(*name) || !models.ValidatePassword(*password) || (*email != "" && !models.ValidateEmail(*email)) {
+		return fmt.Errorf("invalid username, password or e-mail")
+	}
+
+	user, created, err := userService.CreateOrGet(&models.Signup{
+		Username: *name,
+		Email:    *email,
+		Password: *password,
+		Location: time.Local.String(),
+	}, *admin)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return fmt.Errorf("user '%s' already exists", user.ID)
+	}
+
+	fmt.Printf("created user '%s', api key: %s\n", user.ID, user.ApiKey)
+	return nil
+}
+
+func ctlResetPassword(args []string) error {
+	fs := flag.NewFlagSet("users reset-password", flag.ContinueOnError)
+	name := fs.String("name", "", "username")
+	password := fs.String("password", "", "new password, randomly generated if omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := userService.GetUserById(*name)
+	if err != nil {
+		return fmt.Errorf("user '%s' not found", *name)
+	}
+
+	newPassword := *password
+	if newPassword == "" {
+		newPassword = utils.RandomUrlToken(12)
+	}
+	if !models.ValidatePassword(newPassword) {
+		return fmt.Errorf("invalid password")
+	}
+
+	hash, err := utils.HashPassword(newPassword, config.Security.PasswordSalt)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	user.ResetToken = ""
+	if _, err := userService.Update(user); err != nil {
+		return err
+	}
+
+	if *password == "" {
+		fmt.Printf("password of user '%s' was reset to: %s\n", user.ID, newPassword)
+	} else {
+		fmt.Printf("password of user '%s' was reset\n", user.ID)
+	}
+	return nil
+}
+
+func ctlRestoreUser(args []string) error {
+	fs := flag.NewFlagSet("users restore", flag.ContinueOnError)
+	name := fs.String("name", "", "username of an existing user to restore the archive for")
+	file := fs.String("file", "", "path to an account archive (zip or ndjson)")
+	keepIds := fs.Bool("keep-ids", false, "retain the archive's heartbeat ids, only possible on an instance without any heartbeats")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" || *file == "" {
+		return errors.New("-name and -file are required")
+	}
+
+	user, err := userService.GetUserById(*name)
+	if err != nil {
+		return fmt.Errorf("user '%s' not found", *name)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	if *keepIds {
+		if config.Db.IsMssql() {
+			return errors.New("heartbeat ids can't be kept on mssql")
+		}
+		// ids are unique across all users, so they could only collide with other heartbeats otherwise
+		count, err := heartbeatService.Count(false)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("heartbeat ids can only be kept on an instance without any heartbeats, found %d", count)
+		}
+	}
+
+	importer, err := imports.NewWakapiArchiveImporter(data, *keepIds)
+	if err != nil {
+		return fmt.Errorf("invalid archive: %v", err)
+	}
+	if err := exportService.Restore(user, importer.Archive()); err != nil {
+		return fmt.Errorf("failed to restore settings: %v", err)
+	}
+
+	stream, err := importer.ImportAll(user)
+	if err != nil {
+		return fmt.Errorf("invalid archive: %v", err)
+	}
+
+	countBefore, err := heartbeatRepository.CountByUser(user) // bypasses the cached count
+	if err != nil {
+		return err
+	}
+
+	batch := make([]*models.Heartbeat, 0, config.App.ImportBatchSize)
+	for hb := range stream {
+		batch = append(batch, hb)
+		if len(batch) == config.App.ImportBatchSize {
+			if err := heartbeatService.InsertBatch(batch); err != nil {
+				return fmt.Errorf("failed to insert heartbeats: %v", err)
+			}
+			batch = make([]*models.Heartbeat, 0, config.App.ImportBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		if err := heartbeatService.InsertBatch(batch); err != nil {
+			return fmt.Errorf("failed to insert heartbeats: %v", err)
+		}
+	}
+
+	if *keepIds {
+		if err := heartbeatService.SyncIdSequence(); err != nil {
+			return fmt.Errorf("failed to sync heartbeat id sequence: %v", err)
+		}
+	}
+
+	countAfter, err := heartbeatRepository.CountByUser(user)
+	if err != nil {
+		return err
+	}
+	if countAfter > 0 && !user.HasData {
+		user.HasData = true
+		if _, err := userService.Update(user); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("restored %d heartbeats for user '%s', run 'summaries regenerate -user %s' to aggregate them\n", countAfter-countBefore, user.ID, user.ID)
+	return nil
+}
+
+func ctlRegenerateSummaries(args []string) error {
+	fs := flag.NewFlagSet("summaries regenerate", flag.ContinueOnError)
+	userId := fs.String("user", "", "username, all users if omitted")
+	fromStr := fs.String("from", "", "first day to regenerate, defaults to the user's first heartbeat")
+	toStr := fs.String("to", "", "last day to regenerate, defaults to yesterday")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	users, err := ctlResolveUsers(*userId)
+	if err != nil {
+		return err
+	}
+
+	firstHeartbeats, err := heartbeatService.GetFirstByUsers()
+	if err != nil {
+		return err
+	}
+	firstHeartbeatLookup := make(map[string]models.CustomTime, len(firstHeartbeats))
+	for _, e := range firstHeartbeats {
+		firstHeartbeatLookup[e.User] = e.Time
+	}
+
+	to := time.Now()
+	if *toStr != "" {
+		if to, err = time.ParseInLocation(conf.SimpleDateFormat, *toStr, time.Local); err != nil {
+			return fmt.Errorf("invalid date '%s'", *toStr)
+		}
+	}
+
+	for _, user := range users {
+		first := firstHeartbeatLookup[user.ID]
+		from := first.T()
+		if *fromStr != "" {
+			if from, err = time.ParseInLocation(conf.SimpleDateFormat, *fromStr, time.Local); err != nil {
+				return fmt.Errorf("invalid date '%s'", *fromStr)
+			}
+		} else if !first.Valid() || from.IsZero() {
+			fmt.Printf("skipping user '%s', because they have no heartbeats\n", user.ID)
+			continue
+		}
+
+		count, err := aggregationService.RegenerateSummariesWithin(user, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to regenerate summaries for user '%s': %v", user.ID, err)
+		}
+		fmt.Printf("regenerated %d summaries for user '%s'\n", count, user.ID)
+	}
+	return nil
+}
+
+func ctlRegenerateDurations(args []string) error {
+	fs := flag.NewFlagSet("durations regenerate", flag.ContinueOnError)
+	userId := fs.String("user", "", "username, all users if omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	users, err := ctlResolveUsers(*userId)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		durationService.Regenerate(user, true) // always in full, there is no way to only replace durations within a certain range
+		fmt.Printf("regenerated durations for user '%s'\n", user.ID)
+	}
+	return nil
+}
+
+func ctlDedupeHeartbeats(args []string) error {
+	fs := flag.NewFlagSet("heartbeats dedupe", flag.ContinueOnError)
+	userId := fs.String("user", "", "username, all users if omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	users, err := ctlResolveUsers(*userId)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, user := range users {
+		count, err := heartbeatRepository.DeleteDuplicates(user)
+		if err != nil {
+			return fmt.Errorf("failed to delete duplicate heartbeats of user '%s': %v", user.ID, err)
+		}
+		if count > 0 {
+			fmt.Printf("deleted %d duplicate heartbeats of user '%s'\n", count, user.ID)
+		}
+		total += count
+	}
+	fmt.Printf("deleted %d duplicate heartbeats in total\n", total)
+	return nil
+}
+
+func ctlResolveUsers(userId string) ([]*models.User, error) {
+	if strings.TrimSpace(userId) == "" {
+		return userService.GetAll()
+	}
+	user, err := userService.GetUserById(userId)
+	if err != nil {
+		return nil, fmt.Errorf("user '%s' not found", userId)
+	}
+	return []*models.User{user}, nil
+}
@@ -0,0 +1,98 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// ParseTimesheetParams reads a timesheet's range from either an interval or from and to, where a plain date as to is inclusive.
+// Rates are given as comma-separated list of label:rate pairs, e.g. "work:80,oss:0".
+func ParseTimesheetParams(r *http.Request) (*models.TimesheetParams, error) {
+	user := extractUser(r)
+	params := r.URL.Query()
+
+	var err error
+	var from, to time.Time
+
+	if params.Get("from") != "" || params.Get("to") != "" {
+		if from, err = ParseDateTimeTZ(params.Get("from"), user.TZ()); err != nil {
+			return nil, errors.New("missing or invalid 'from' parameter")
+		}
+		if to, err = time.ParseInLocation(config.SimpleDateFormat, params.Get("to"), user.TZ()); err == nil {
+			to = to.AddDate(0, 0, 1)
+		} else if to, err = ParseDateTimeTZ(params.Get("to"), user.TZ()); err != nil {
+			return nil, errors.New("missing or invalid 'to' parameter")
+		}
+	} else {
+		interval := params.Get("interval")
+		if interval == "" {
+			interval = (*models.IntervalLastMonth)[0]
+		}
+		if err, from, to = ResolveIntervalRawTZ(interval, user.TZ()); err != nil {
+			return nil, errors.New("invalid 'interval' parameter")
+		}
+	}
+
+	timesheetParams := &models.TimesheetParams{
+		From:         from,
+		To:           to,
+		Projects:     []string{},
+		Label:        strings.TrimSpace(params.Get("label")),
+		GroupBy:      params.Get("group_by"),
+		RoundingMode: params.Get("rounding_mode"),
+		Rates:        map[string]float64{},
+		Currency:     strings.TrimSpace(params.Get("currency")),
+	}
+
+	if timesheetParams.GroupBy == "" {
+		timesheetParams.GroupBy = models.TimesheetGroupDay
+	}
+	if timesheetParams.RoundingMode == "" {
+		timesheetParams.RoundingMode = models.TimesheetRoundingUp
+	}
+
+	for _, p := range strings.Split(params.Get("project"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			timesheetParams.Projects = append(timesheetParams.Projects, p)
+		}
+	}
+
+	if rounding := params.Get("rounding"); rounding != "" {
+		minutes, err := strconv.Atoi(rounding)
+		if err != nil {
+			return nil, errors.New("invalid 'rounding' parameter")
+		}
+		timesheetParams.Rounding = time.Duration(minutes) * time.Minute
+	}
+
+	if rate := params.Get("rate"); rate != "" {
+		if timesheetParams.DefaultRate, err = strconv.ParseFloat(rate, 64); err != nil {
+			return nil, errors.New("invalid 'rate' parameter")
+		}
+	}
+
+	for _, pair := range strings.Split(params.Get("rates"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		label, rate, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errors.New("invalid 'rates' parameter")
+		}
+		if timesheetParams.Rates[strings.TrimSpace(label)], err = strconv.ParseFloat(strings.TrimSpace(rate), 64); err != nil {
+			return nil, errors.New("invalid 'rates' parameter")
+		}
+	}
+
+	if !timesheetParams.IsValid() {
+		return nil, errors.New("invalid timesheet parameters")
+	}
+
+	return timesheetParams, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"embed"
 	"flag"
 	"io/fs"
@@ -58,17 +59,27 @@ var (
 )
 
 var (
-	aliasRepository           repositories.IAliasRepository
-	heartbeatRepository       repositories.IHeartbeatRepository
-	userRepository            repositories.IUserRepository
-	languageMappingRepository repositories.ILanguageMappingRepository
-	projectLabelRepository    repositories.IProjectLabelRepository
-	summaryRepository         repositories.ISummaryRepository
-	leaderboardRepository     *repositories.LeaderboardRepository
-	keyValueRepository        repositories.IKeyValueRepository
-	diagnosticsRepository     repositories.IDiagnosticsRepository
-	metricsRepository         *repositories.MetricsRepository
-	durationRepository        *repositories.DurationRepository
+	aliasRepository               repositories.IAliasRepository
+	heartbeatRepository           repositories.IHeartbeatRepository
+	userRepository                repositories.IUserRepository
+	languageMappingRepository     repositories.ILanguageMappingRepository
+	heartbeatRuleRepository       repositories.IHeartbeatRuleRepository
+	projectLabelRepository        repositories.IProjectLabelRepository
+	archivedProjectRepository     repositories.IArchivedProjectRepository
+	summaryRepository             repositories.ISummaryRepository
+	leaderboardRepository         *repositories.LeaderboardRepository
+	keyValueRepository            repositories.IKeyValueRepository
+	diagnosticsRepository         repositories.IDiagnosticsRepository
+	metricsRepository             *repositories.MetricsRepository
+	durationRepository            *repositories.DurationRepository
+	teamRepository                repositories.ITeamRepository
+	apiTokenRepository            repositories.IApiTokenRepository
+	webhookRepository             repositories.IWebhookRepository
+	relayRepository               repositories.IRelayRepository
+	goalRepository                repositories.IGoalRepository
+	oidcIdentityRepository        repositories.IOidcIdentityRepository
+	reportSubscriptionRepository  repositories.IReportSubscriptionRepository
+	notificationChannelRepository repositories.INotificationChannelRepository
 )
 
 var (
@@ -76,7 +87,9 @@ var (
 	heartbeatService       services.IHeartbeatService
 	userService            services.IUserService
 	languageMappingService services.ILanguageMappingService
+	heartbeatRuleService   services.IHeartbeatRuleService
 	projectLabelService    services.IProjectLabelService
+	projectService         services.IProjectService
 	durationService        services.IDurationService
 	summaryService         services.ISummaryService
 	leaderboardService     services.ILeaderboardService
@@ -84,10 +97,22 @@ var (
 	mailService            services.IMailService
 	keyValueService        services.IKeyValueService
 	reportService          services.IReportService
+	notificationService    services.INotificationService
 	activityService        services.IActivityService
+	badgeService           services.IBadgeService
+	timesheetService       services.ITimesheetService
 	diagnosticsService     services.IDiagnosticsService
 	housekeepingService    services.IHousekeepingService
 	miscService            services.IMiscService
+	teamService            services.ITeamService
+	apiTokenService        services.IApiTokenService
+	exportService          services.IExportService
+	webhookService         services.IWebhookService
+	relayService           services.IRelayService
+	goalService            services.IGoalService
+	oidcService            services.IOidcService
+	totpService            services.ITotpService
+	liveService            services.ILiveService
 )
 
 // TODO: Refactor entire project to be structured after business domains
@@ -127,33 +152,11 @@ func main() {
 
 	slog.Info("Wakapi", "version", version)
 
-	// Set up GORM
-	gormLogger := logger.New(
-		log.New(os.Stdout, "", log.LstdFlags),
-		logger.Config{
-			SlowThreshold: time.Minute,
-			Colorful:      false,
-			LogLevel:      logger.Silent,
-		},
-	)
-
-	// Connect to database
-	var err error
-	slog.Info("starting with database", "dialect", config.Db.Dialect)
-	db, err = gorm.Open(config.Db.GetDialector(), &gorm.Config{Logger: gormLogger}, conf.GetWakapiDBOpts(&config.Db))
-	if err != nil {
-		conf.Log().Fatal("could not connect to database", "error", err)
+	if flag.Arg(0) == "ctl" {
+		os.Exit(runCtl(flag.Args()[1:]))
 	}
 
-	if config.IsDev() {
-		db = db.Debug()
-	}
-	sqlDb, err := db.DB()
-	if err != nil {
-		conf.Log().Fatal("could not connect to database", "error", err)
-	}
-	sqlDb.SetMaxIdleConns(int(config.Db.MaxConn))
-	sqlDb.SetMaxOpenConns(int(config.Db.MaxConn))
+	sqlDb := initDatabase()
 	defer sqlDb.Close()
 
 	// Migrate database schema
@@ -161,39 +164,7 @@ func main() {
 		migrations.Run(db, config)
 	}
 
-	// Repositories
-	aliasRepository = repositories.NewAliasRepository(db)
-	heartbeatRepository = repositories.NewHeartbeatRepository(db)
-	userRepository = repositories.NewUserRepository(db)
-	languageMappingRepository = repositories.NewLanguageMappingRepository(db)
-	projectLabelRepository = repositories.NewProjectLabelRepository(db)
-	summaryRepository = repositories.NewSummaryRepository(db)
-	leaderboardRepository = repositories.NewLeaderboardRepository(db)
-	keyValueRepository = repositories.NewKeyValueRepository(db)
-	diagnosticsRepository = repositories.NewDiagnosticsRepository(db)
-	metricsRepository = repositories.NewMetricsRepository(db)
-	durationRepository = repositories.NewDurationRepository(db)
-
-	// Services
-	mailService = mail.NewMailService()
-	aliasService = services.NewAliasService(aliasRepository)
-	keyValueService = services.NewKeyValueService(keyValueRepository)
-	userService = services.NewUserService(keyValueService, mailService, userRepository)
-	languageMappingService = services.NewLanguageMappingService(languageMappingRepository)
-	projectLabelService = services.NewProjectLabelService(projectLabelRepository)
-	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService)
-	durationService = services.NewDurationService(durationRepository, heartbeatService, userService, languageMappingService)
-	summaryService = services.NewSummaryService(summaryRepository, heartbeatService, durationService, aliasService, projectLabelService)
-	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService, durationService)
-	reportService = services.NewReportService(summaryService, userService, mailService)
-	activityService = services.NewActivityService(summaryService)
-	diagnosticsService = services.NewDiagnosticsService(diagnosticsRepository)
-	housekeepingService = services.NewHousekeepingService(userService, heartbeatService, summaryService)
-	miscService = services.NewMiscService(userService, heartbeatService, summaryService, keyValueService, mailService)
-
-	if config.App.LeaderboardEnabled {
-		leaderboardService = services.NewLeaderboardService(leaderboardRepository, summaryService, userService)
-	}
+	initServices()
 
 	// Schedule background tasks
 	go conf.StartJobs()
@@ -201,42 +172,57 @@ func main() {
 	go reportService.Schedule()
 	go housekeepingService.Schedule()
 	go miscService.Schedule()
+	go webhookService.Schedule()
+	go relayService.Schedule()
+	go goalService.Schedule()
 
 	if config.App.LeaderboardEnabled {
 		go leaderboardService.Schedule()
+		go teamService.Schedule()
 	}
 
 	routes.Init()
 
 	// API Handlers
 	healthApiHandler := api.NewHealthApiHandler(db)
-	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService)
+	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService, relayService, heartbeatRuleService)
 	summaryApiHandler := api.NewSummaryApiHandler(userService, summaryService)
 	metricsHandler := api.NewMetricsHandler(userService, summaryService, heartbeatService, leaderboardService, keyValueService, metricsRepository)
 	diagnosticsHandler := api.NewDiagnosticsApiHandler(userService, diagnosticsService)
 	avatarHandler := api.NewAvatarHandler()
 	activityHandler := api.NewActivityApiHandler(userService, activityService)
-	badgeHandler := api.NewBadgeHandler(userService, summaryService)
+	badgeHandler := api.NewBadgeHandler(userService, summaryService, goalService, badgeService, leaderboardService)
 	captchaHandler := api.NewCaptchaHandler()
+	exportHandler := api.NewExportApiHandler(userService, exportService)
+	goalsHandler := api.NewGoalsApiHandler(userService, goalService)
+	timesheetApiHandler := api.NewTimesheetApiHandler(userService, timesheetService)
+	liveApiHandler := api.NewLiveApiHandler(userService, teamService, liveService)
 
 	// Compat Handlers
 	wakatimeV1StatusBarHandler := wtV1Routes.NewStatusBarHandler(userService, summaryService)
 	wakatimeV1AllHandler := wtV1Routes.NewAllTimeHandler(userService, summaryService)
 	wakatimeV1SummariesHandler := wtV1Routes.NewSummariesHandler(userService, summaryService)
+	wakatimeV1DurationsHandler := wtV1Routes.NewDurationsHandler(userService, durationService, summaryService, aliasService)
 	wakatimeV1StatsHandler := wtV1Routes.NewStatsHandler(userService, summaryService)
 	wakatimeV1UsersHandler := wtV1Routes.NewUsersHandler(userService, heartbeatService)
 	wakatimeV1ProjectsHandler := wtV1Routes.NewProjectsHandler(userService, heartbeatService)
 	wakatimeV1HeartbeatsHandler := wtV1Routes.NewHeartbeatHandler(userService, heartbeatService)
 	wakatimeV1LeadersHandler := wtV1Routes.NewLeadersHandler(userService, leaderboardService)
-	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService)
+	wakatimeV1GoalsHandler := wtV1Routes.NewGoalsHandler(userService, goalService)
+	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService, goalService, badgeService, leaderboardService)
 
 	// MVC Handlers
-	summaryHandler := routes.NewSummaryHandler(summaryService, userService, keyValueService)
-	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService)
+	summaryHandler := routes.NewSummaryHandler(summaryService, userService, keyValueService, goalService)
+	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService, apiTokenService, exportService, webhookService, relayService, goalService, oidcService, totpService, reportService, notificationService, heartbeatRuleService)
 	subscriptionHandler := routes.NewSubscriptionHandler(userService, mailService, keyValueService)
-	projectsHandler := routes.NewProjectsHandler(userService, heartbeatService)
+	projectsHandler := routes.NewProjectsHandler(userService, heartbeatService, summaryService, projectService)
+	timelineHandler := routes.NewTimelineHandler(userService, durationService, aliasService)
+	timesheetHandler := routes.NewTimesheetHandler(userService, timesheetService, projectLabelService)
+	teamsHandler := routes.NewTeamsHandler(userService, teamService, leaderboardService)
+	adminHandler := routes.NewAdminHandler(userService, heartbeatService, durationService, summaryService, aggregationService)
 	homeHandler := routes.NewHomeHandler(userService, keyValueService)
-	loginHandler := routes.NewLoginHandler(userService, mailService, keyValueService)
+	loginHandler := routes.NewLoginHandler(userService, mailService, keyValueService, totpService)
+	oidcHandler := routes.NewOidcHandler(userService, oidcService, totpService)
 	imprintHandler := routes.NewImprintHandler(keyValueService)
 	leaderboardHandler := condition.TernaryOperator[bool, routes.Handler](config.App.LeaderboardEnabled, routes.NewLeaderboardHandler(userService, leaderboardService), routes.NewNoopHandler())
 
@@ -275,10 +261,15 @@ func main() {
 	// Route registrations
 	homeHandler.RegisterRoutes(rootRouter)
 	loginHandler.RegisterRoutes(rootRouter)
+	oidcHandler.RegisterRoutes(rootRouter)
 	imprintHandler.RegisterRoutes(rootRouter)
 	summaryHandler.RegisterRoutes(rootRouter)
 	leaderboardHandler.RegisterRoutes(rootRouter)
 	projectsHandler.RegisterRoutes(rootRouter)
+	timelineHandler.RegisterRoutes(rootRouter)
+	timesheetHandler.RegisterRoutes(rootRouter)
+	teamsHandler.RegisterRoutes(rootRouter)
+	adminHandler.RegisterRoutes(rootRouter)
 	settingsHandler.RegisterRoutes(rootRouter)
 	subscriptionHandler.RegisterRoutes(rootRouter)
 	relayHandler.RegisterRoutes(rootRouter)
@@ -295,13 +286,19 @@ func main() {
 	wakatimeV1StatusBarHandler.RegisterRoutes(apiRouter)
 	wakatimeV1AllHandler.RegisterRoutes(apiRouter)
 	wakatimeV1SummariesHandler.RegisterRoutes(apiRouter)
+	wakatimeV1DurationsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1StatsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1UsersHandler.RegisterRoutes(apiRouter)
 	wakatimeV1ProjectsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1HeartbeatsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1LeadersHandler.RegisterRoutes(apiRouter)
+	wakatimeV1GoalsHandler.RegisterRoutes(apiRouter)
 	shieldV1BadgeHandler.RegisterRoutes(apiRouter)
 	captchaHandler.RegisterRoutes(apiRouter)
+	exportHandler.RegisterRoutes(apiRouter)
+	goalsHandler.RegisterRoutes(apiRouter)
+	timesheetApiHandler.RegisterRoutes(apiRouter)
+	liveApiHandler.RegisterRoutes(apiRouter)
 
 	// Static Routes
 	// https://github.com/golang/go/issues/43431
@@ -440,3 +437,97 @@ func listen(handler http.Handler) {
 
 	<-make(chan interface{}, 1)
 }
+
+// initDatabase connects to the configured database and returns the underlying connection pool, which is to be closed by the caller
+func initDatabase() *sql.DB {
+	// Set up GORM
+	gormLogger := logger.New(
+		log.New(os.Stdout, "", log.LstdFlags),
+		logger.Config{
+			SlowThreshold: time.Minute,
+			Colorful:      false,
+			LogLevel:      logger.Silent,
+		},
+	)
+
+	// Connect to database
+	var err error
+	slog.Info("starting with database", "dialect", config.Db.Dialect)
+	db, err = gorm.Open(config.Db.GetDialector(), &gorm.Config{Logger: gormLogger}, conf.GetWakapiDBOpts(&config.Db))
+	if err != nil {
+		conf.Log().Fatal("could not connect to database", "error", err)
+	}
+
+	if config.IsDev() {
+		db = db.Debug()
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		conf.Log().Fatal("could not connect to database", "error", err)
+	}
+	sqlDb.SetMaxIdleConns(int(config.Db.MaxConn))
+	sqlDb.SetMaxOpenConns(int(config.Db.MaxConn))
+	return sqlDb
+}
+
+// initServices instantiates all repositories and services, but doesn't schedule any of their background tasks, yet
+func initServices() {
+	// Repositories
+	aliasRepository = repositories.NewAliasRepository(db)
+	heartbeatRepository = repositories.NewHeartbeatRepository(db)
+	userRepository = repositories.NewUserRepository(db)
+	languageMappingRepository = repositories.NewLanguageMappingRepository(db)
+	heartbeatRuleRepository = repositories.NewHeartbeatRuleRepository(db)
+	projectLabelRepository = repositories.NewProjectLabelRepository(db)
+	archivedProjectRepository = repositories.NewArchivedProjectRepository(db)
+	summaryRepository = repositories.NewSummaryRepository(db)
+	leaderboardRepository = repositories.NewLeaderboardRepository(db)
+	keyValueRepository = repositories.NewKeyValueRepository(db)
+	diagnosticsRepository = repositories.NewDiagnosticsRepository(db)
+	metricsRepository = repositories.NewMetricsRepository(db)
+	durationRepository = repositories.NewDurationRepository(db)
+	teamRepository = repositories.NewTeamRepository(db)
+	apiTokenRepository = repositories.NewApiTokenRepository(db)
+	webhookRepository = repositories.NewWebhookRepository(db)
+	relayRepository = repositories.NewRelayRepository(db)
+	goalRepository = repositories.NewGoalRepository(db)
+	oidcIdentityRepository = repositories.NewOidcIdentityRepository(db)
+	reportSubscriptionRepository = repositories.NewReportSubscriptionRepository(db)
+	notificationChannelRepository = repositories.NewNotificationChannelRepository(db)
+
+	// Services
+	mailService = mail.NewMailService()
+	aliasService = services.NewAliasService(aliasRepository)
+	keyValueService = services.NewKeyValueService(keyValueRepository)
+	apiTokenService = services.NewApiTokenService(apiTokenRepository)
+	userService = services.NewUserService(keyValueService, mailService, apiTokenService, userRepository)
+	languageMappingService = services.NewLanguageMappingService(languageMappingRepository)
+	heartbeatRuleService = services.NewHeartbeatRuleService(heartbeatRuleRepository)
+	projectLabelService = services.NewProjectLabelService(projectLabelRepository)
+	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService)
+	durationService = services.NewDurationService(durationRepository, heartbeatService, userService, languageMappingService)
+	summaryService = services.NewSummaryService(summaryRepository, heartbeatService, durationService, aliasService, projectLabelService)
+	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService, durationService)
+	projectService = services.NewProjectService(archivedProjectRepository, heartbeatService, durationService, aggregationService, projectLabelService)
+	notificationService = services.NewNotificationService(notificationChannelRepository, mailService)
+	reportService = services.NewReportService(reportSubscriptionRepository, summaryService, userService, notificationService)
+	activityService = services.NewActivityService(summaryService, durationService)
+	badgeService = services.NewBadgeService(summaryService)
+	timesheetService = services.NewTimesheetService(summaryService, projectLabelService)
+	diagnosticsService = services.NewDiagnosticsService(diagnosticsRepository)
+	housekeepingService = services.NewHousekeepingService(userService, heartbeatService, summaryService)
+	miscService = services.NewMiscService(userService, heartbeatService, summaryService, keyValueService, notificationService)
+	exportService = services.NewExportService(userService, heartbeatService, aliasService, projectLabelService, languageMappingService)
+	webhookService = services.NewWebhookService(webhookRepository)
+	relayService = services.NewRelayService(relayRepository)
+	goalService = services.NewGoalService(goalRepository, summaryService, mailService)
+	oidcService = services.NewOidcService(oidcIdentityRepository, userService)
+	totpService = services.NewTotpService(userService)
+	liveService = services.NewLiveService(heartbeatService, summaryService)
+
+	if config.App.LeaderboardEnabled {
+		leaderboardService = services.NewLeaderboardService(leaderboardRepository, summaryService, userService, projectService)
+	}
+
+	teamService = services.NewTeamService(teamRepository, summaryService, keyValueService, leaderboardService)
+}
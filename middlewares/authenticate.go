@@ -21,7 +21,8 @@ const (
 )
 
 var (
-	errEmptyKey = fmt.Errorf("the api_key is empty")
+	errEmptyKey     = fmt.Errorf("the api_key is empty")
+	errUserDisabled = fmt.Errorf("the user is disabled")
 )
 
 type AuthenticateMiddleware struct {
@@ -29,8 +30,9 @@ type AuthenticateMiddleware struct {
 	userSrvc             services.IUserService
 	optionalForPaths     []string
 	optionalForMethods   []string
-	redirectTarget       string // optional
-	redirectErrorMessage string // optional
+	redirectTarget       string   // optional
+	redirectErrorMessage string   // optional
+	acceptedScopes       []string // optional, scoped api tokens are rejected, unless they have any of these scopes
 }
 
 func NewAuthenticateMiddleware(userService services.IUserService) *AuthenticateMiddleware {
@@ -62,6 +64,11 @@ func (m *AuthenticateMiddleware) WithRedirectErrorMessage(message string) *Authe
 	return m
 }
 
+func (m *AuthenticateMiddleware) WithAcceptedScopes(scopes ...string) *AuthenticateMiddleware {
+	m.acceptedScopes = scopes
+	return m
+}
+
 func (m *AuthenticateMiddleware) Handler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		m.ServeHTTP(w, r, h.ServeHTTP)
@@ -70,6 +77,7 @@ func (m *AuthenticateMiddleware) Handler(h http.Handler) http.Handler {
 
 func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	var user *models.User
+	var apiToken *models.ApiToken
 
 	user, err := m.tryGetUserByCookie(r)
 	if err != nil {
@@ -78,9 +86,18 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		user, err = m.tryGetUserByApiKeyQuery(r)
 	}
+	if err != nil {
+		user, apiToken, err = m.tryGetUserByApiTokenHeader(r)
+	}
+	if err != nil {
+		user, apiToken, err = m.tryGetUserByApiTokenQuery(r)
+	}
 	if err != nil && m.config.Security.TrustedHeaderAuth {
 		user, err = m.tryGetUserByTrustedHeader(r)
 	}
+	if err == nil && user != nil && user.IsDisabled {
+		err = errUserDisabled
+	}
 
 	if err != nil || user == nil {
 		if m.isOptional(r) {
@@ -103,7 +120,19 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// scoped api tokens only grant access to routes that explicitly accept one of its scopes
+	if apiToken != nil && !m.isPermitted(apiToken) {
+		if m.isOptional(r) {
+			next(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(conf.ErrForbidden))
+		return
+	}
+
 	SetPrincipal(r, user)
+	SetApiToken(r, apiToken)
 	next(w, r)
 }
 
@@ -121,6 +150,12 @@ func (m *AuthenticateMiddleware) isOptional(r *http.Request) bool {
 	return false
 }
 
+func (m *AuthenticateMiddleware) isPermitted(apiToken *models.ApiToken) bool {
+	return slice.ContainBy[string](m.acceptedScopes, func(scope string) bool {
+		return apiToken.HasScope(scope)
+	})
+}
+
 func (m *AuthenticateMiddleware) tryGetUserByApiKeyHeader(r *http.Request) (*models.User, error) {
 	key, err := utils.ExtractBearerAuth(r)
 	if err != nil {
@@ -150,6 +185,22 @@ func (m *AuthenticateMiddleware) tryGetUserByApiKeyQuery(r *http.Request) (*mode
 	return user, nil
 }
 
+func (m *AuthenticateMiddleware) tryGetUserByApiTokenHeader(r *http.Request) (*models.User, *models.ApiToken, error) {
+	key, err := utils.ExtractBearerAuth(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.userSrvc.GetUserByToken(strings.TrimSpace(key))
+}
+
+func (m *AuthenticateMiddleware) tryGetUserByApiTokenQuery(r *http.Request) (*models.User, *models.ApiToken, error) {
+	key := strings.TrimSpace(r.URL.Query().Get(queryApiKey))
+	if key == "" {
+		return nil, nil, errEmptyKey
+	}
+	return m.userSrvc.GetUserByToken(key)
+}
+
 func (m *AuthenticateMiddleware) tryGetUserByTrustedHeader(r *http.Request) (*models.User, error) {
 	if !m.config.Security.TrustedHeaderAuth {
 		return nil, errors.New("trusted header auth disabled")
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/muety/wakapi/config"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -101,6 +102,43 @@ func TestAuthenticateMiddleware_tryGetUserByApiKeyQuery_Invalid(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestAuthenticateMiddleware_tryGetUserByApiTokenHeader_Success(t *testing.T) {
+	testPlainToken := "a61f2a4c-6e32-4d3e-9a1e-6c1e63c0e7d1"
+	testHeader := base64.StdEncoding.EncodeToString([]byte(testPlainToken))
+	testUser := &models.User{ID: "user01"}
+	testApiToken := &models.ApiToken{UserID: testUser.ID, Scopes: models.ApiTokenScopeHeartbeatsWrite}
+
+	mockRequest := &http.Request{
+		Header: http.Header{
+			"Authorization": []string{fmt.Sprintf("Basic %s", testHeader)},
+		},
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByToken", testPlainToken).Return(testUser, testApiToken, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, resultToken, err := sut.tryGetUserByApiTokenHeader(mockRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+	assert.Equal(t, testApiToken, resultToken)
+}
+
+func TestAuthenticateMiddleware_isPermitted(t *testing.T) {
+	userServiceMock := new(mocks.UserServiceMock)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+	assert.False(t, sut.isPermitted(&models.ApiToken{Scopes: models.ApiTokenScopeHeartbeatsWrite}))
+
+	sut = NewAuthenticateMiddleware(userServiceMock).WithAcceptedScopes(models.ApiTokenScopeSummariesRead, models.ApiTokenScopeHeartbeatsWrite)
+	assert.True(t, sut.isPermitted(&models.ApiToken{Scopes: models.ApiTokenScopeHeartbeatsWrite}))
+	assert.True(t, sut.isPermitted(&models.ApiToken{Scopes: fmt.Sprintf("%s,%s", models.ApiTokenScopeMetricsRead, models.ApiTokenScopeSummariesRead)}))
+	assert.False(t, sut.isPermitted(&models.ApiToken{Scopes: models.ApiTokenScopeMetricsRead}))
+	assert.False(t, sut.isPermitted(&models.ApiToken{Scopes: ""}))
+}
+
 func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_Disabled(t *testing.T) {
 	cfg := config.Empty()
 	cfg.Security.TrustedHeaderAuth = false
@@ -185,4 +223,29 @@ func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_Success(t *testing.T)
 	}
 }
 
+func TestAuthenticateMiddleware_ServeHTTP_Disabled(t *testing.T) {
+	config.Set(config.Empty())
+
+	testApiKey := "z5uig69cn9ut93n"
+	testToken := base64.StdEncoding.EncodeToString([]byte(testApiKey))
+	testUser := &models.User{ID: "user01", ApiKey: testApiKey, IsDisabled: true}
+
+	mockRequest := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	mockRequest.Header.Set("Authorization", fmt.Sprintf("Basic %s", testToken))
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", testApiKey).Return(testUser, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	var called bool
+	rec := httptest.NewRecorder()
+	sut.ServeHTTP(rec, mockRequest, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
 // TODO: somehow test cookie auth function
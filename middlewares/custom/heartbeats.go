@@ -0,0 +1,89 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+
+	"github.com/muety/wakapi/models"
+)
+
+const keyAcceptedHeartbeats = "accepted_heartbeats"
+
+// acceptedHeartbeats holds the heartbeats, which the heartbeat handler has accepted and stored, along with their positions in the request body.
+// Relay middlewares only forward these, after the handler has processed the request.
+type acceptedHeartbeats struct {
+	heartbeats []*models.Heartbeat
+	indices    []int
+}
+
+// SetAcceptedHeartbeats lets the heartbeat handler tell relay middlewares which heartbeats (at which positions of the request body) to forward
+func SetAcceptedHeartbeats(r *http.Request, heartbeats []*models.Heartbeat, indices []int) {
+	if c := r.Context().Value(keyAcceptedHeartbeats); c != nil {
+		c.(*acceptedHeartbeats).heartbeats = heartbeats
+		c.(*acceptedHeartbeats).indices = indices
+	}
+}
+
+// withAcceptedHeartbeats adds a container for accepted heartbeats to the request context, unless another relay middleware already did so
+func withAcceptedHeartbeats(r *http.Request) *http.Request {
+	if c := r.Context().Value(keyAcceptedHeartbeats); c != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), keyAcceptedHeartbeats, &acceptedHeartbeats{}))
+}
+
+// getAcceptedHeartbeats returns the heartbeats accepted by the handler along with their raw json representation at the same indices.
+// Heartbeats dropped by the user's heartbeat rules (see models.HeartbeatRule) aren't among them and entities and projects rewritten by the rules are carried over to the raw data, for no redacted information to leave the instance.
+func getAcceptedHeartbeats(r *http.Request, rawHeartbeats []interface{}) ([]*models.Heartbeat, []interface{}) {
+	c, ok := r.Context().Value(keyAcceptedHeartbeats).(*acceptedHeartbeats)
+	if !ok {
+		return nil, nil
+	}
+
+	heartbeats := make([]*models.Heartbeat, 0, len(c.heartbeats))
+	raw := make([]interface{}, 0, len(c.heartbeats))
+	for i, hb := range c.heartbeats {
+		if idx := c.indices[i]; idx < len(rawHeartbeats) {
+			heartbeats = append(heartbeats, hb)
+			raw = append(raw, rewriteRawHeartbeat(rawHeartbeats[idx], hb))
+		}
+	}
+	return heartbeats, raw
+}
+
+func rewriteRawHeartbeat(rawHeartbeat interface{}, heartbeat *models.Heartbeat) interface{} {
+	data, ok := rawHeartbeat.(map[string]interface{})
+	if !ok {
+		return rawHeartbeat
+	}
+
+	rewritten := maps.Clone(data)
+	rewritten["entity"] = heartbeat.Entity
+	if heartbeat.Project != "" { // placeholders cleared for browsing heartbeats are left for upstream to resolve
+		rewritten["project"] = heartbeat.Project
+	}
+	return rewritten
+}
+
+// readRawHeartbeats returns the heartbeats' raw json representation, which, unlike the serialization of models.Heartbeat, is identical to what the client has actually sent.
+// The request's body is left untouched.
+func readRawHeartbeats(r *http.Request) ([]interface{}, error) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	var rawData interface{}
+	if err := json.Unmarshal(body, &rawData); err != nil {
+		return nil, err
+	}
+
+	rawHeartbeats, isList := rawData.([]interface{})
+	if !isList {
+		rawHeartbeats = []interface{}{rawData}
+	}
+	return rawHeartbeats, nil
+}
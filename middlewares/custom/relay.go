@@ -0,0 +1,89 @@
+package relay
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/patrickmn/go-cache"
+)
+
+// RelayTargetsMiddleware is a middleware to forward heartbeats to all of the user's relay targets (see models.RelayTarget), in addition to the legacy WakaTime connection
+type RelayTargetsMiddleware struct {
+	relaySrvc services.IRelayService
+	hashCache *cache.Cache
+}
+
+func NewRelayTargetsMiddleware(relayService services.IRelayService) *RelayTargetsMiddleware {
+	return &RelayTargetsMiddleware{
+		relaySrvc: relayService,
+		hashCache: cache.New(10*time.Minute, 10*time.Minute),
+	}
+}
+
+func (m *RelayTargetsMiddleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.ServeHTTP(w, r, h.ServeHTTP)
+	})
+}
+
+// ServeHTTP forwards the heartbeats, which were accepted by the heartbeat handler, after it processed the request.
+// Invalid or rejected heartbeats are never relayed.
+func (m *RelayTargetsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.Method != http.MethodPost || r.Header.Get(models.RelayHeaderOriginInstance) == config.Get().InstanceId {
+		next(w, r)
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user == nil || !m.hasEnabledTargets(user) {
+		next(w, r)
+		return
+	}
+
+	rawHeartbeats, err := readRawHeartbeats(r)
+	if err != nil {
+		next(w, r)
+		return
+	}
+
+	r = withAcceptedHeartbeats(r)
+	next(w, r)
+
+	heartbeats, rawHeartbeats := getAcceptedHeartbeats(r, rawHeartbeats)
+
+	// heartbeats that were already seen before are skipped to prevent cyclic relays / loops, see WakatimeRelayMiddleware.filterByCache
+	newHeartbeats := make([]*models.Heartbeat, 0, len(heartbeats))
+	newRawHeartbeats := make([]interface{}, 0, len(heartbeats))
+	for i, hb := range heartbeats {
+		hash := user.ID + hb.Hash
+		if _, found := m.hashCache.Get(hash); found {
+			continue
+		}
+		m.hashCache.SetDefault(hash, true)
+		newHeartbeats = append(newHeartbeats, hb)
+		newRawHeartbeats = append(newRawHeartbeats, rawHeartbeats[i])
+	}
+	if len(newHeartbeats) == 0 {
+		return
+	}
+
+	m.relaySrvc.Forward(user, newHeartbeats, newRawHeartbeats, r.Header.Clone())
+}
+
+func (m *RelayTargetsMiddleware) hasEnabledTargets(user *models.User) bool {
+	targets, err := m.relaySrvc.GetByUser(user.ID)
+	if err != nil {
+		config.Log().Error("failed to get relay targets for user", "userID", user.ID, "error", err)
+		return false
+	}
+	for _, t := range targets {
+		if t.Enabled {
+			return true
+		}
+	}
+	return false
+}
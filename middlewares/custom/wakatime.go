@@ -10,7 +10,6 @@ import (
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
-	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/patrickmn/go-cache"
 	"io"
 	"log/slog"
@@ -45,30 +44,47 @@ func (m *WakatimeRelayMiddleware) Handler(h http.Handler) http.Handler {
 	})
 }
 
+// ServeHTTP relays the heartbeats, which were accepted by the heartbeat handler, after it processed the request
 func (m *WakatimeRelayMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	defer next(w, r)
-
 	ownInstanceId := config.Get().InstanceId
 	originInstanceId := r.Header.Get("X-Origin-Instance")
 
 	if r.Method != http.MethodPost || originInstanceId == ownInstanceId {
+		next(w, r)
 		return
 	}
 
 	user := middlewares.GetPrincipal(r)
 	if user == nil || user.WakatimeApiKey == "" {
+		next(w, r)
+		return
+	}
+
+	rawHeartbeats, err := readRawHeartbeats(r)
+	if err != nil {
+		next(w, r)
+		return
+	}
+
+	r = withAcceptedHeartbeats(r)
+	next(w, r)
+
+	heartbeats, rawHeartbeats := getAcceptedHeartbeats(r, rawHeartbeats)
+	if len(heartbeats) == 0 {
 		return
 	}
 
-	err := m.filterByCache(r)
+	newData, err := m.filterByCache(heartbeats, rawHeartbeats)
 	if err != nil {
 		slog.Warn("filter cache error", "error", err)
 		return
 	}
 
-	body, _ := io.ReadAll(r.Body)
-	r.Body.Close()
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
+	body, err := json.Marshal(newData)
+	if err != nil {
+		slog.Warn("failed to serialize heartbeats to relay", "error", err)
+		return
+	}
 
 	// prevent cycles
 	downstreamInstanceId := ownInstanceId
@@ -138,58 +154,27 @@ func (m *WakatimeRelayMiddleware) send(method, url string, body io.Reader, heade
 	}
 }
 
-// filterByCache takes an HTTP request, tries to parse the body contents as heartbeats, checks against a local cache for whether a heartbeat has already been relayed before according to its hash and in-place filters these from the request's raw json body.
-// This method operates on the raw body data (interface{}), because serialization of models.Heartbeat is not necessarily identical to what the CLI has actually sent.
+// filterByCache checks against a local cache for whether a heartbeat has already been relayed before according to its hash and returns the raw json representation of only those, which weren't.
+// Raw data (interface{}) is relayed, because serialization of models.Heartbeat is not necessarily identical to what the CLI has actually sent.
 // Purpose of this mechanism is mainly to prevent cyclic relays / loops.
-// Caution: this method does in-place changes to the request.
-func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
-	heartbeats, err := routeutils.ParseHeartbeats(r)
-	if err != nil {
-		return err
-	}
-
-	body, _ := io.ReadAll(r.Body)
-	r.Body.Close()
-	r.Body = io.NopCloser(bytes.NewBuffer(body))
-
-	var rawData interface{}
-	if err := json.NewDecoder(io.NopCloser(bytes.NewBuffer(body))).Decode(&rawData); err != nil {
-		return err
-	}
-
+func (m *WakatimeRelayMiddleware) filterByCache(heartbeats []*models.Heartbeat, rawHeartbeats []interface{}) ([]interface{}, error) {
 	newData := make([]interface{}, 0, len(heartbeats))
 
-	process := func(heartbeat *models.Heartbeat, rawData interface{}) {
-		heartbeat = heartbeat.Hashed()
+	for i, heartbeat := range heartbeats {
 		// we didn't see this particular heartbeat before
 		if _, found := m.hashCache.Get(heartbeat.Hash); !found {
 			m.hashCache.SetDefault(heartbeat.Hash, true)
-			newData = append(newData, rawData)
+			newData = append(newData, rawHeartbeats[i])
 		}
 	}
 
-	if _, isList := rawData.([]interface{}); isList {
-		for i, hb := range heartbeats {
-			process(hb, rawData.([]interface{})[i])
-		}
-	} else if len(heartbeats) > 0 {
-		process(heartbeats[0], rawData.(interface{}))
-	}
-
 	if len(newData) == 0 {
-		return errors.New("no new heartbeats to relay")
+		return nil, errors.New("no new heartbeats to relay")
 	}
 
 	if len(newData) != len(heartbeats) {
-		user := middlewares.GetPrincipal(r)
-		slog.Warn("only relaying partial heartbeats for user", "relayedCount", len(newData), "totalCount", len(heartbeats), "userID", user.ID)
-	}
-
-	buf := bytes.Buffer{}
-	if err := json.NewEncoder(&buf).Encode(newData); err != nil {
-		return err
+		slog.Warn("only relaying partial heartbeats for user", "relayedCount", len(newData), "totalCount", len(heartbeats), "userID", heartbeats[0].UserID)
 	}
-	r.Body = io.NopCloser(&buf)
 
-	return nil
+	return newData, nil
 }
@@ -10,6 +10,7 @@ const keyPrincipal = "principal"
 
 type PrincipalContainer struct {
 	principal *models.User
+	apiToken  *models.ApiToken // only set, if authenticated by a scoped api token
 }
 
 func (c *PrincipalContainer) SetPrincipal(user *models.User) {
@@ -20,6 +21,14 @@ func (c *PrincipalContainer) GetPrincipal() *models.User {
 	return c.principal
 }
 
+func (c *PrincipalContainer) SetApiToken(apiToken *models.ApiToken) {
+	c.apiToken = apiToken
+}
+
+func (c *PrincipalContainer) GetApiToken() *models.ApiToken {
+	return c.apiToken
+}
+
 func (c *PrincipalContainer) GetPrincipalIdentity() string {
 	if c.principal == nil {
 		return ""
@@ -69,3 +78,16 @@ func GetPrincipal(r *http.Request) *models.User {
 	}
 	return nil
 }
+
+func SetApiToken(r *http.Request, apiToken *models.ApiToken) {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		p.(*PrincipalContainer).SetApiToken(apiToken)
+	}
+}
+
+func GetApiToken(r *http.Request) *models.ApiToken {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		return p.(*PrincipalContainer).GetApiToken()
+	}
+	return nil
+}
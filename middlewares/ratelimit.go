@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/httprate"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+)
+
+// RateLimitMiddleware throttles requests per api key and per ip address, using token buckets shared by all endpoints of the same scope (e.g. conf.RateLimitHeartbeats).
+// It has to be placed after AuthenticateMiddleware, unauthenticated requests are only limited by ip address.
+type RateLimitMiddleware struct {
+	byApiKey *utils.RateLimiter
+	byIp     *utils.RateLimiter
+}
+
+func NewRateLimitMiddleware(scope string) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		byApiKey: conf.GetRateLimiter(scope, conf.RateLimitByApiKey),
+		byIp:     conf.GetRateLimiter(scope, conf.RateLimitByIp),
+	}
+}
+
+func (m *RateLimitMiddleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.ServeHTTP(w, r, h.ServeHTTP)
+	})
+}
+
+func (m *RateLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if m.byIp != nil {
+		if ip, err := httprate.KeyByRealIP(r); err == nil {
+			if ok, retryAfter := m.byIp.Allow(ip); !ok {
+				m.reject(w, retryAfter)
+				return
+			}
+		}
+	}
+
+	if m.byApiKey != nil {
+		if key := m.apiKeyIdentity(r); key != "" {
+			if ok, retryAfter := m.byApiKey.Allow(key); !ok {
+				m.reject(w, retryAfter)
+				return
+			}
+		}
+	}
+
+	next(w, r)
+}
+
+// apiKeyIdentity identifies the key the request was authenticated with, i.e. either one of the user's scoped api tokens or their main api key (or session)
+func (m *RateLimitMiddleware) apiKeyIdentity(r *http.Request) string {
+	if apiToken := GetApiToken(r); apiToken != nil {
+		return fmt.Sprintf("token:%d", apiToken.ID)
+	}
+	if user := GetPrincipal(r); user != nil {
+		return fmt.Sprintf("user:%s", user.ID)
+	}
+	return ""
+}
+
+func (m *RateLimitMiddleware) reject(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	w.Write([]byte(conf.ErrTooManyRequests))
+}
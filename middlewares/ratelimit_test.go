@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware_ServeHTTP(t *testing.T) {
+	sut := &RateLimitMiddleware{
+		byApiKey: utils.NewRateLimiter(1, time.Minute),
+		byIp:     utils.NewRateLimiter(3, time.Minute),
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(user *models.User) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/heartbeat", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user != nil {
+				SetPrincipal(r, user)
+			}
+			sut.Handler(next).ServeHTTP(w, r)
+		})).ServeHTTP(rec, r)
+		return rec
+	}
+
+	user1, user2 := &models.User{ID: "testuser01"}, &models.User{ID: "testuser02"}
+
+	assert.Equal(t, http.StatusOK, serve(user1).Code)
+
+	rec := serve(user1)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, serve(user2).Code)
+
+	// ip address exhausted (rejected requests consume ip tokens as well)
+	rec = serve(nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "20", rec.Header().Get("Retry-After"))
+}
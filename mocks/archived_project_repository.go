@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ArchivedProjectRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *ArchivedProjectRepositoryMock) GetByUser(s string) ([]*models.ArchivedProject, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.ArchivedProject), args.Error(1)
+}
+
+func (m *ArchivedProjectRepositoryMock) Insert(p *models.ArchivedProject) (*models.ArchivedProject, error) {
+	args := m.Called(p)
+	return args.Get(0).(*models.ArchivedProject), args.Error(1)
+}
+
+func (m *ArchivedProjectRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type GoalRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *GoalRepositoryMock) GetById(u uint) (*models.Goal, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) GetByUser(s string) ([]*models.Goal, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) GetAllNotify() ([]*models.Goal, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) Insert(g *models.Goal) (*models.Goal, error) {
+	args := m.Called(g)
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
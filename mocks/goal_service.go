@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type GoalServiceMock struct {
+	mock.Mock
+}
+
+func (m *GoalServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *GoalServiceMock) GetById(u uint) (*models.Goal, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *GoalServiceMock) GetByUser(s string) ([]*models.Goal, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Goal), args.Error(1)
+}
+
+func (m *GoalServiceMock) Create(g *models.Goal) (*models.Goal, error) {
+	args := m.Called(g)
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *GoalServiceMock) Delete(g *models.Goal) error {
+	args := m.Called(g)
+	return args.Error(0)
+}
+
+func (m *GoalServiceMock) GetProgress(g *models.Goal, u *models.User, t time.Time) (*models.GoalProgress, error) {
+	args := m.Called(g, u, t)
+	return args.Get(0).(*models.GoalProgress), args.Error(1)
+}
+
+func (m *GoalServiceMock) GetProgressHistory(g *models.Goal, u *models.User, n int) ([]*models.GoalProgress, error) {
+	args := m.Called(g, u, n)
+	return args.Get(0).([]*models.GoalProgress), args.Error(1)
+}
@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type HeartbeatRuleRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *HeartbeatRuleRepositoryMock) GetById(u uint) (*models.HeartbeatRule, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.HeartbeatRule), args.Error(1)
+}
+
+func (m *HeartbeatRuleRepositoryMock) GetByUser(s string) ([]*models.HeartbeatRule, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.HeartbeatRule), args.Error(1)
+}
+
+func (m *HeartbeatRuleRepositoryMock) Insert(c *models.HeartbeatRule) (*models.HeartbeatRule, error) {
+	args := m.Called(c)
+	return args.Get(0).(*models.HeartbeatRule), args.Error(1)
+}
+
+func (m *HeartbeatRuleRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type HeartbeatRuleServiceMock struct {
+	mock.Mock
+}
+
+func (m *HeartbeatRuleServiceMock) GetById(u uint) (*models.HeartbeatRule, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.HeartbeatRule), args.Error(1)
+}
+
+func (m *HeartbeatRuleServiceMock) GetByUser(s string) ([]*models.HeartbeatRule, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.HeartbeatRule), args.Error(1)
+}
+
+func (m *HeartbeatRuleServiceMock) Apply(u *models.User, h *models.Heartbeat) (bool, error) {
+	args := m.Called(u, h)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *HeartbeatRuleServiceMock) Create(r *models.HeartbeatRule) (*models.HeartbeatRule, error) {
+	args := m.Called(r)
+	return args.Get(0).(*models.HeartbeatRule), args.Error(1)
+}
+
+func (m *HeartbeatRuleServiceMock) Delete(r *models.HeartbeatRule) error {
+	args := m.Called(r)
+	return args.Error(0)
+}
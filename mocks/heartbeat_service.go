@@ -76,6 +76,11 @@ func (m *HeartbeatServiceMock) GetLatestByFilters(u *models.User, f *models.Filt
 	return args.Get(0).(*models.Heartbeat), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) GetFirstByFilters(u *models.User, f *models.Filters) (*models.Heartbeat, error) {
+	args := m.Called(u, f)
+	return args.Get(0).(*models.Heartbeat), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetEntitySetByUser(u uint8, user string) ([]string, error) {
 	args := m.Called(u, user)
 	return args.Get(0).([]string), args.Error(1)
@@ -91,6 +96,11 @@ func (m *HeartbeatServiceMock) DeleteByUser(u *models.User) error {
 	return args.Error(0)
 }
 
+func (m *HeartbeatServiceMock) SyncIdSequence() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func (m *HeartbeatServiceMock) DeleteByUserBefore(u *models.User, t time.Time) error {
 	args := m.Called(u, t)
 	return args.Error(0)
@@ -100,3 +110,8 @@ func (m *HeartbeatServiceMock) GetUserProjectStats(u *models.User, t, t2 time.Ti
 	args := m.Called(u, t, t2, p, b)
 	return args.Get(0).([]*models.ProjectStats), args.Error(1)
 }
+
+func (m *HeartbeatServiceMock) RenameProjects(u *models.User, p []string, s string) (int64, error) {
+	args := m.Called(u, p, s)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type MailServiceMock struct {
+	mock.Mock
+}
+
+func (m *MailServiceMock) SendPasswordReset(u *models.User, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendWakatimeFailureNotification(u *models.User, i int) error {
+	args := m.Called(u, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendRelayFailureNotification(u *models.User, t *models.RelayTarget, i int) error {
+	args := m.Called(u, t, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendImportNotification(u *models.User, d time.Duration, i int) error {
+	args := m.Called(u, d, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendReport(u *models.User, r *models.Report) error {
+	args := m.Called(u, r)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendSubscriptionNotification(u *models.User, b bool) error {
+	args := m.Called(u, b)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendGoalNotification(u *models.User, p *models.GoalProgress) error {
+	args := m.Called(u, p)
+	return args.Error(0)
+}
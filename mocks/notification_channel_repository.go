@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationChannelRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *NotificationChannelRepositoryMock) GetById(u uint) (*models.NotificationChannel, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) GetByUser(s string) ([]*models.NotificationChannel, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) Insert(c *models.NotificationChannel) (*models.NotificationChannel, error) {
+	args := m.Called(c)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationChannelRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationServiceMock struct {
+	mock.Mock
+}
+
+func (m *NotificationServiceMock) GetById(u uint) (*models.NotificationChannel, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) GetByUser(s string) ([]*models.NotificationChannel, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) Create(c *models.NotificationChannel) (*models.NotificationChannel, error) {
+	args := m.Called(c)
+	return args.Get(0).(*models.NotificationChannel), args.Error(1)
+}
+
+func (m *NotificationServiceMock) Delete(c *models.NotificationChannel) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) HasChannels(u *models.User, s string) bool {
+	args := m.Called(u, s)
+	return args.Bool(0)
+}
+
+func (m *NotificationServiceMock) Test(c *models.NotificationChannel) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendReport(u *models.User, r *models.Report) error {
+	args := m.Called(u, r)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendImportNotification(u *models.User, d time.Duration, i int) error {
+	args := m.Called(u, d, i)
+	return args.Error(0)
+}
+
+func (m *NotificationServiceMock) SendSubscriptionNotification(u *models.User, b bool) error {
+	args := m.Called(u, b)
+	return args.Error(0)
+}
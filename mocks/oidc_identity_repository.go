@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type OidcIdentityRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *OidcIdentityRepositoryMock) GetById(u uint) (*models.OidcIdentity, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.OidcIdentity), args.Error(1)
+}
+
+func (m *OidcIdentityRepositoryMock) GetBySubject(s1, s2 string) (*models.OidcIdentity, error) {
+	args := m.Called(s1, s2)
+	return args.Get(0).(*models.OidcIdentity), args.Error(1)
+}
+
+func (m *OidcIdentityRepositoryMock) GetByUser(s string) ([]*models.OidcIdentity, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.OidcIdentity), args.Error(1)
+}
+
+func (m *OidcIdentityRepositoryMock) Insert(i *models.OidcIdentity) (*models.OidcIdentity, error) {
+	args := m.Called(i)
+	return args.Get(0).(*models.OidcIdentity), args.Error(1)
+}
+
+func (m *OidcIdentityRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
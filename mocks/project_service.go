@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ProjectServiceMock struct {
+	mock.Mock
+}
+
+func (m *ProjectServiceMock) GetArchived(s string) ([]string, error) {
+	args := m.Called(s)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *ProjectServiceMock) Archive(u *models.User, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *ProjectServiceMock) Unarchive(u *models.User, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *ProjectServiceMock) Rename(u *models.User, s string, s2 string) error {
+	args := m.Called(u, s, s2)
+	return args.Error(0)
+}
+
+func (m *ProjectServiceMock) Merge(u *models.User, p []string, s string) error {
+	args := m.Called(u, p, s)
+	return args.Error(0)
+}
+
+func (m *ProjectServiceMock) IsRewriting(s string) bool {
+	args := m.Called(s)
+	return args.Bool(0)
+}
@@ -0,0 +1,78 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *RelayRepositoryMock) GetById(u uint) (*models.RelayTarget, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) GetByUser(s string) ([]*models.RelayTarget, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) Insert(t *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) Update(t *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *RelayRepositoryMock) IncrementFailures(u uint, s string) (int, error) {
+	args := m.Called(u, s)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) ResetFailures(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *RelayRepositoryMock) GetQueueItemsDue(t time.Time, i int) ([]*models.RelayQueueItem, error) {
+	args := m.Called(t, i)
+	return args.Get(0).([]*models.RelayQueueItem), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) CountQueueItems(u uint) (int64, error) {
+	args := m.Called(u)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) InsertQueueItem(i *models.RelayQueueItem) (*models.RelayQueueItem, error) {
+	args := m.Called(i)
+	return args.Get(0).(*models.RelayQueueItem), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) UpdateQueueItem(i *models.RelayQueueItem) (*models.RelayQueueItem, error) {
+	args := m.Called(i)
+	return args.Get(0).(*models.RelayQueueItem), args.Error(1)
+}
+
+func (m *RelayRepositoryMock) DeleteQueueItem(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *RelayRepositoryMock) DeleteQueueItemsBefore(t time.Time) (int64, error) {
+	args := m.Called(t)
+	return args.Get(0).(int64), args.Error(1)
+}
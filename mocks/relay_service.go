@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"net/http"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayServiceMock struct {
+	mock.Mock
+}
+
+func (m *RelayServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *RelayServiceMock) GetById(u uint) (*models.RelayTarget, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayServiceMock) GetByUser(s string) ([]*models.RelayTarget, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayServiceMock) Create(t *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayServiceMock) Update(t *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayServiceMock) Delete(t *models.RelayTarget) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
+
+func (m *RelayServiceMock) CountQueued(t *models.RelayTarget) (int64, error) {
+	args := m.Called(t)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *RelayServiceMock) Forward(u *models.User, heartbeats []*models.Heartbeat, rawHeartbeats []interface{}, header http.Header) {
+	m.Called(u, heartbeats, rawHeartbeats, header)
+}
+
+func (m *RelayServiceMock) ProcessQueue() {
+	m.Called()
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ReportSubscriptionRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *ReportSubscriptionRepositoryMock) GetById(u uint) (*models.ReportSubscription, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) GetByUser(s string) ([]*models.ReportSubscription, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) GetAll() ([]*models.ReportSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) Insert(s *models.ReportSubscription) (*models.ReportSubscription, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.ReportSubscription), args.Error(1)
+}
+
+func (m *ReportSubscriptionRepositoryMock) UpdateLastSent(u uint, t time.Time) error {
+	args := m.Called(u, t)
+	return args.Error(0)
+}
+
+func (m *ReportSubscriptionRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
@@ -40,3 +40,8 @@ func (m *SummaryRepositoryMock) DeleteByUserBefore(s string, t time.Time) error
 	args := m.Called(s, t)
 	return args.Error(0)
 }
+
+func (m *SummaryRepositoryMock) DeleteByUserWithin(s string, t time.Time, t2 time.Time) error {
+	args := m.Called(s, t, t2)
+	return args.Error(0)
+}
@@ -21,6 +21,11 @@ func (m *SummaryServiceMock) Retrieve(t time.Time, t2 time.Time, u *models.User,
 	return args.Get(0).(*models.Summary), args.Error(1)
 }
 
+func (m *SummaryServiceMock) ResolveFilters(u *models.User, f *models.Filters) *models.Filters {
+	args := m.Called(u, f)
+	return args.Get(0).(*models.Filters)
+}
+
 func (m *SummaryServiceMock) Summarize(t time.Time, t2 time.Time, u *models.User, f *models.Filters, d *time.Duration) (*models.Summary, error) {
 	args := m.Called(t, t2, u, d, f)
 	return args.Get(0).(*models.Summary), args.Error(1)
@@ -41,6 +46,11 @@ func (m *SummaryServiceMock) DeleteByUserBefore(s string, t time.Time) error {
 	return args.Error(0)
 }
 
+func (m *SummaryServiceMock) DeleteByUserWithin(s string, t time.Time, t2 time.Time) error {
+	args := m.Called(s, t, t2)
+	return args.Error(0)
+}
+
 func (m *SummaryServiceMock) Insert(s *models.Summary) error {
 	args := m.Called(s)
 	return args.Error(0)
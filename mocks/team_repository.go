@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type TeamRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *TeamRepositoryMock) GetAll() ([]*models.Team, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) GetById(u uint) (*models.Team, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) GetByUser(s string) ([]*models.Team, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) Insert(t *models.Team) (*models.Team, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.Team), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *TeamRepositoryMock) GetMembers(u uint) ([]*models.TeamMember, error) {
+	args := m.Called(u)
+	return args.Get(0).([]*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) GetMember(u uint, s string) (*models.TeamMember, error) {
+	args := m.Called(u, s)
+	return args.Get(0).(*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) InsertMember(t *models.TeamMember) (*models.TeamMember, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) UpdateMember(t *models.TeamMember) (*models.TeamMember, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.TeamMember), args.Error(1)
+}
+
+func (m *TeamRepositoryMock) DeleteMember(u uint, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
@@ -19,6 +19,11 @@ func (m *UserServiceMock) GetUserByKey(s string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) GetUserByToken(s string) (*models.User, *models.ApiToken, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Get(1).(*models.ApiToken), args.Error(2)
+}
+
 func (m *UserServiceMock) GetUserByEmail(s string) (*models.User, error) {
 	args := m.Called(s)
 	return args.Get(0).(*models.User), args.Error(1)
@@ -0,0 +1,53 @@
+package mocks
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookRepositoryMock struct {
+	BaseRepositoryMock
+	mock.Mock
+}
+
+func (m *WebhookRepositoryMock) GetById(u uint) (*models.Webhook, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) GetByUser(s string) ([]*models.Webhook, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) GetGlobal() ([]*models.Webhook, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) Insert(w *models.Webhook) (*models.Webhook, error) {
+	args := m.Called(w)
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *WebhookRepositoryMock) GetDeliveries(u uint, i int) ([]*models.WebhookDelivery, error) {
+	args := m.Called(u, i)
+	return args.Get(0).([]*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) InsertDelivery(d *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	args := m.Called(d)
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *WebhookRepositoryMock) DeleteDeliveriesBefore(t time.Time) error {
+	args := m.Called(t)
+	return args.Error(0)
+}
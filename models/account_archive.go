@@ -0,0 +1,163 @@
+package models
+
+import "time"
+
+const AccountArchiveVersion = 1
+
+const (
+	AccountArchiveFormatZip    = "zip"
+	AccountArchiveFormatNdjson = "ndjson"
+)
+
+// file names within zip archives
+const (
+	AccountArchiveFileManifest         = "manifest.json"
+	AccountArchiveFileUser             = "user.json"
+	AccountArchiveFileAliases          = "aliases.json"
+	AccountArchiveFileProjectLabels    = "project_labels.json"
+	AccountArchiveFileLanguageMappings = "language_mappings.json"
+	AccountArchiveFileHeartbeats       = "heartbeats.ndjson"
+)
+
+// record types within ndjson archives
+const (
+	AccountArchiveRecordManifest        = "manifest"
+	AccountArchiveRecordUser            = "user"
+	AccountArchiveRecordAlias           = "alias"
+	AccountArchiveRecordProjectLabel    = "project_label"
+	AccountArchiveRecordLanguageMapping = "language_mapping"
+	AccountArchiveRecordHeartbeat       = "heartbeat"
+)
+
+// AccountArchive holds everything contained in an account export, except for heartbeats, which are streamed separately
+type AccountArchive struct {
+	Manifest         *AccountArchiveManifest
+	User             *AccountArchiveUser
+	Aliases          []*ArchivedAlias
+	ProjectLabels    []*ProjectLabel
+	LanguageMappings []*LanguageMapping
+}
+
+type AccountArchiveManifest struct {
+	Version    int       `json:"version"`
+	UserID     string    `json:"user_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	AppVersion string    `json:"app_version"`
+}
+
+// AccountArchiveRecord is a single line of an ndjson archive
+type AccountArchiveRecord struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// AccountArchiveUser holds a user's preferences, but no credentials or other secrets
+type AccountArchiveUser struct {
+	Email                  string `json:"email"`
+	Location               string `json:"location"`
+	ShareDataMaxDays       int    `json:"share_data_max_days"`
+	ShareEditors           bool   `json:"share_editors"`
+	ShareLanguages         bool   `json:"share_languages"`
+	ShareProjects          bool   `json:"share_projects"`
+	ShareOSs               bool   `json:"share_oss"`
+	ShareMachines          bool   `json:"share_machines"`
+	ShareLabels            bool   `json:"share_labels"`
+	ShareActivityChart     bool   `json:"share_activity_chart"`
+	ReportsWeekly          bool   `json:"reports_weekly"`
+	PublicLeaderboard      bool   `json:"public_leaderboard"`
+	ExcludeUnknownProjects bool   `json:"exclude_unknown_projects"`
+	HeartbeatsTimeoutSec   int    `json:"heartbeats_timeout_sec"`
+}
+
+// ArchivedAlias mirrors Alias, whose fields lack explicit json names
+type ArchivedAlias struct {
+	Type  uint8  `json:"type"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ArchivedHeartbeat extends the public json representation of a heartbeat by the fields required to restore it as is.
+// Timestamps are shadowed, because CustomTime can't be unmarshalled from its own json representation.
+type ArchivedHeartbeat struct {
+	*Heartbeat
+	ID        uint64    `json:"id"`
+	Hash      string    `json:"hash"`
+	Origin    string    `json:"origin,omitempty"`
+	OriginId  string    `json:"origin_id,omitempty"`
+	Time      time.Time `json:"time"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewAccountArchiveUser(user *User) *AccountArchiveUser {
+	return &AccountArchiveUser{
+		Email:                  user.Email,
+		Location:               user.Location,
+		ShareDataMaxDays:       user.ShareDataMaxDays,
+		ShareEditors:           user.ShareEditors,
+		ShareLanguages:         user.ShareLanguages,
+		ShareProjects:          user.ShareProjects,
+		ShareOSs:               user.ShareOSs,
+		ShareMachines:          user.ShareMachines,
+		ShareLabels:            user.ShareLabels,
+		ShareActivityChart:     user.ShareActivityChart,
+		ReportsWeekly:          user.ReportsWeekly,
+		PublicLeaderboard:      user.PublicLeaderboard,
+		ExcludeUnknownProjects: user.ExcludeUnknownProjects,
+		HeartbeatsTimeoutSec:   user.HeartbeatsTimeoutSec,
+	}
+}
+
+// ApplyTo restores the archived preferences. E-mail addresses are only restored for users who don't have one yet, invalid time zones are skipped.
+func (u *AccountArchiveUser) ApplyTo(user *User) *User {
+	if user.Email == "" && ValidateEmail(u.Email) {
+		user.Email = u.Email
+	}
+	if u.Location != "" && ValidateTimezone(u.Location) {
+		user.Location = u.Location
+	}
+	user.ShareDataMaxDays = u.ShareDataMaxDays
+	user.ShareEditors = u.ShareEditors
+	user.ShareLanguages = u.ShareLanguages
+	user.ShareProjects = u.ShareProjects
+	user.ShareOSs = u.ShareOSs
+	user.ShareMachines = u.ShareMachines
+	user.ShareLabels = u.ShareLabels
+	user.ShareActivityChart = u.ShareActivityChart
+	user.ReportsWeekly = u.ReportsWeekly
+	user.PublicLeaderboard = u.PublicLeaderboard
+	user.ExcludeUnknownProjects = u.ExcludeUnknownProjects
+	if u.HeartbeatsTimeoutSec > 0 {
+		user.HeartbeatsTimeoutSec = u.HeartbeatsTimeoutSec
+	}
+	return user
+}
+
+func NewArchivedHeartbeat(hb *Heartbeat) *ArchivedHeartbeat {
+	return &ArchivedHeartbeat{
+		Heartbeat: hb,
+		ID:        hb.ID,
+		Hash:      hb.Hash,
+		Origin:    hb.Origin,
+		OriginId:  hb.OriginId,
+		Time:      hb.Time.T(),
+		CreatedAt: hb.CreatedAt.T(),
+	}
+}
+
+func (a *ArchivedHeartbeat) ToHeartbeat() *Heartbeat {
+	hb := a.Heartbeat
+	if hb == nil {
+		hb = &Heartbeat{}
+	}
+	hb.ID = a.ID
+	hb.Hash = a.Hash
+	hb.Origin = a.Origin
+	hb.OriginId = a.OriginId
+	hb.Time = CustomTime(a.Time)
+	hb.CreatedAt = CustomTime(a.CreatedAt)
+	return hb
+}
+
+func NewArchivedAlias(alias *Alias) *ArchivedAlias {
+	return &ArchivedAlias{Type: alias.Type, Key: alias.Key, Value: alias.Value}
+}
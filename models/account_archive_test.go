@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountArchiveUser_ApplyTo(t *testing.T) {
+	user := &User{ID: "user1", Email: "user1@example.org", Location: "Europe/Berlin"}
+
+	(&AccountArchiveUser{Email: "other@example.org", Location: "America/New_York", ShareProjects: true}).ApplyTo(user)
+	assert.Equal(t, "user1@example.org", user.Email)
+	assert.Equal(t, "America/New_York", user.Location)
+	assert.True(t, user.ShareProjects)
+
+	(&AccountArchiveUser{Location: "Not/AZone"}).ApplyTo(user)
+	assert.Equal(t, "America/New_York", user.Location)
+	assert.False(t, user.ShareProjects)
+
+	(&AccountArchiveUser{Location: ""}).ApplyTo(user)
+	assert.Equal(t, "America/New_York", user.Location)
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	ActivityChartFormatSvg  = "svg"
+	ActivityChartFormatPng  = "png"
+	ActivityChartFormatJson = "json" // raw data instead of an image, only supported by some charts
+)
+
+// ActivityChartMaxDays is the longest range an activity chart can cover
+const ActivityChartMaxDays = 2 * 366
+
+// ActivityChartParams specifies the range, data and appearance of an activity chart
+type ActivityChartParams struct {
+	From            time.Time
+	To              time.Time
+	Filters         *Filters
+	Format          string
+	DarkTheme       bool
+	HideAttribution bool
+	Colors          []string // hex codes of the colors to fade cells between, from least to most activity, theme default if empty
+}
+
+func (p *ActivityChartParams) IsValid() bool {
+	if p.Format != ActivityChartFormatSvg && p.Format != ActivityChartFormatPng && p.Format != ActivityChartFormatJson {
+		return false
+	}
+	if !p.From.Before(p.To) || p.To.Sub(p.From) > ActivityChartMaxDays*24*time.Hour {
+		return false
+	}
+	if len(p.Colors) == 1 {
+		return false
+	}
+	for _, c := range p.Colors {
+		if !utils.IsHexColor(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash identifies the chart for caching purposes, with the range being considered at day precision only
+func (p *ActivityChartParams) Hash() string {
+	return fmt.Sprintf("%s_%s_%s_%s_%v_%v_%s",
+		p.From.Format(time.DateOnly),
+		p.To.Format(time.DateOnly),
+		p.Filters.Hash(),
+		p.Format,
+		p.DarkTheme,
+		p.HideAttribution,
+		strings.Join(p.Colors, ","),
+	)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+)
+
+const (
+	ApiTokenScopeHeartbeatsWrite = "heartbeats:write"
+	ApiTokenScopeSummariesRead   = "summaries:read"
+	ApiTokenScopeMetricsRead     = "metrics:read"
+)
+
+// ApiToken is a named, optionally expiring api key with limited permissions, as an alternative to a user's all-powerful ApiKey
+type ApiToken struct {
+	ID         uint        `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"-" gorm:"not null; index:idx_api_token_user"`
+	Name       string      `json:"name" gorm:"not null; size:255"`
+	TokenHash  string      `json:"-" gorm:"not null; uniqueIndex:idx_api_token_hash; size:64"` // sha256 of the actual token, which is only shown once upon creation
+	Scopes     string      `json:"scopes" gorm:"not null; size:255"`                           // comma-separated list of scopes
+	ExpiresAt  *CustomTime `json:"expires_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastUsedAt *CustomTime `json:"last_used_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt  CustomTime  `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func ApiTokenScopes() []string {
+	return []string{ApiTokenScopeHeartbeatsWrite, ApiTokenScopeSummariesRead, ApiTokenScopeMetricsRead}
+}
+
+func ValidateApiTokenScope(scope string) bool {
+	return slice.Contain(ApiTokenScopes(), scope)
+}
+
+func HashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (t *ApiToken) ScopesList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+func (t *ApiToken) HasScope(scope string) bool {
+	return slice.Contain(t.ScopesList(), scope)
+}
+
+func (t *ApiToken) IsExpired() bool {
+	return t.ExpiresAt != nil && t.ExpiresAt.T().Before(time.Now())
+}
+
+func (t *ApiToken) IsValid() bool {
+	name := strings.TrimSpace(t.Name)
+	scopes := t.ScopesList()
+	return t.UserID != "" &&
+		len(name) >= 1 && len(name) <= 255 &&
+		len(t.TokenHash) == 64 &&
+		len(scopes) > 0 &&
+		slice.Every(scopes, func(i int, s string) bool { return ValidateApiTokenScope(s) })
+}
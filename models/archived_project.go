@@ -0,0 +1,14 @@
+package models
+
+// ArchivedProject marks one of a user's projects as finished, which hides it from the projects page and excludes it from leaderboards
+type ArchivedProject struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	User       *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string     `json:"-" gorm:"not null; index:idx_archived_project_user"`
+	ProjectKey string     `json:"project" gorm:"not null"`
+	CreatedAt  CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (p *ArchivedProject) IsValid() bool {
+	return p.UserID != "" && p.ProjectKey != ""
+}
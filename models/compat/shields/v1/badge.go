@@ -1,6 +1,12 @@
 package v1
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/models"
 )
@@ -8,15 +14,41 @@ import (
 // https://shields.io/endpoint
 
 const (
-	defaultLabel = "wakapi.dev"
-	defaultColor = "2F855A"
+	defaultLabel  = "wakapi.dev"
+	defaultColor  = "2F855A"
+	inactiveColor = "lightgrey"
+	otherColor    = "#9E9E9E"
+)
+
+const (
+	BadgeStyleFlat        = "flat"
+	BadgeStyleForTheBadge = "for-the-badge"
 )
 
+const (
+	languagesBadgeMaxSegments = 5
+	languagesBadgeMaxMessage  = 3
+)
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
 type BadgeData struct {
 	SchemaVersion int    `json:"schemaVersion"`
 	Label         string `json:"label"`
 	Message       string `json:"message"`
 	Color         string `json:"color"`
+	Style         string `json:"style,omitempty"`
+	// only used for rendering svg badges, as shields.io can only display text
+	Segments []*BadgeSegment `json:"-"`
+	Series   []float64       `json:"-"`
+	Caption  string          `json:"-"` // text next to the chart
+}
+
+// BadgeSegment is one part of a stacked bar badge, e.g. a language's share in the total coding time
+type BadgeSegment struct {
+	Title string
+	Share float64
+	Color string
 }
 
 func NewBadgeDataFrom(summary *models.Summary) *BadgeData {
@@ -27,3 +59,142 @@ func NewBadgeDataFrom(summary *models.Summary) *BadgeData {
 		Color:         defaultColor,
 	}
 }
+
+func NewGoalBadgeDataFrom(progress *models.GoalProgress) *BadgeData {
+	color := "yellow"
+	if progress.Reached() {
+		color = defaultColor
+	}
+	return &BadgeData{
+		SchemaVersion: 1,
+		Label:         progress.Goal.Title,
+		Message:       fmt.Sprintf("%s / %s (%d%%)", helpers.FmtWakatimeDuration(progress.Actual), helpers.FmtWakatimeDuration(progress.Target()), progress.Percent()),
+		Color:         color,
+	}
+}
+
+// NewLanguagesBadgeDataFrom builds a badge showing the shares of the summary's top languages as a stacked bar, colors are looked up by lowercase language name
+func NewLanguagesBadgeDataFrom(summary *models.Summary, colors map[string]string) *BadgeData {
+	total := summary.TotalTimeBy(models.SummaryLanguage)
+	languages := make(models.SummaryItems, len(summary.Languages))
+	copy(languages, summary.Languages)
+	sort.Sort(sort.Reverse(languages))
+
+	segments := make([]*BadgeSegment, 0, languagesBadgeMaxSegments+1)
+	messageParts := make([]string, 0, languagesBadgeMaxMessage)
+	var covered time.Duration
+
+	for _, item := range languages {
+		if total == 0 || item.TotalFixed() == 0 {
+			break
+		}
+		if len(segments) == languagesBadgeMaxSegments {
+			break
+		}
+
+		share := float64(item.TotalFixed()) / float64(total)
+		color, ok := colors[strings.ToLower(item.Key)]
+		if !ok {
+			color = otherColor
+		}
+
+		title := fmt.Sprintf("%s %d%%", item.Key, int(math.Round(share*100)))
+		segments = append(segments, &BadgeSegment{Title: title, Share: share, Color: color})
+		if len(messageParts) < languagesBadgeMaxMessage {
+			messageParts = append(messageParts, title)
+		}
+		covered += item.TotalFixed()
+	}
+
+	if total > 0 && covered < total {
+		share := float64(total-covered) / float64(total)
+		segments = append(segments, &BadgeSegment{Title: fmt.Sprintf("Other %d%%", int(math.Round(share*100))), Share: share, Color: otherColor})
+	}
+
+	badge := &BadgeData{
+		SchemaVersion: 1,
+		Label:         "languages",
+		Message:       strings.Join(messageParts, " · "),
+		Color:         defaultColor,
+		Segments:      segments,
+	}
+	if len(segments) == 0 {
+		badge.Message = "no data"
+		badge.Color = inactiveColor
+	}
+	return badge
+}
+
+// NewSparklineBadgeDataFrom builds a badge showing the coding time per day as a sparkline, followed by the total
+func NewSparklineBadgeDataFrom(dailyTotals []time.Duration) *BadgeData {
+	var total, maxTotal time.Duration
+	for _, d := range dailyTotals {
+		total += d
+		maxTotal = max(maxTotal, d)
+	}
+
+	series := make([]float64, len(dailyTotals))
+	ticks := make([]rune, len(dailyTotals))
+	for i, d := range dailyTotals {
+		if maxTotal > 0 {
+			series[i] = float64(d) / float64(maxTotal)
+		}
+		ticks[i] = sparkTicks[int(math.Round(series[i]*float64(len(sparkTicks)-1)))]
+	}
+
+	return &BadgeData{
+		SchemaVersion: 1,
+		Label:         defaultLabel,
+		Message:       fmt.Sprintf("%s %s", string(ticks), helpers.FmtWakatimeDuration(total)),
+		Color:         defaultColor,
+		Series:        series,
+		Caption:       helpers.FmtWakatimeDuration(total),
+	}
+}
+
+// NewStreakBadgeDataFrom builds a badge showing the number of consecutive days with coding activity, capped tells whether the streak might actually be even longer
+func NewStreakBadgeDataFrom(days int, capped bool) *BadgeData {
+	message := fmt.Sprintf("%d days", days)
+	if days == 1 {
+		message = "1 day"
+	}
+	if capped {
+		message = fmt.Sprintf("%d+ days", days)
+	}
+
+	color := defaultColor
+	if days == 0 {
+		color = inactiveColor
+	}
+
+	return &BadgeData{
+		SchemaVersion: 1,
+		Label:         "streak",
+		Message:       message,
+		Color:         color,
+	}
+}
+
+// NewRankBadgeDataFrom builds a badge showing the user's rank on the leaderboard, the item being nil if the user isn't listed on it
+func NewRankBadgeDataFrom(item *models.LeaderboardItemRanked) *BadgeData {
+	label := "rank"
+	if item != nil && item.Key != nil {
+		label = fmt.Sprintf("rank (%s)", *item.Key)
+	}
+
+	if item == nil {
+		return &BadgeData{
+			SchemaVersion: 1,
+			Label:         label,
+			Message:       "unranked",
+			Color:         inactiveColor,
+		}
+	}
+
+	return &BadgeData{
+		SchemaVersion: 1,
+		Label:         label,
+		Message:       fmt.Sprintf("#%d", item.Rank),
+		Color:         defaultColor,
+	}
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+// https://wakatime.com/developers#durations
+
+// DurationSliceTypes maps the supported values of the slice_by parameter to entity types
+var DurationSliceTypes = map[string]uint8{
+	"project":  models.SummaryProject,
+	"entity":   models.SummaryEntity,
+	"language": models.SummaryLanguage,
+	"editor":   models.SummaryEditor,
+	"os":       models.SummaryOS,
+	"machine":  models.SummaryMachine,
+	"category": models.SummaryCategory,
+	"branch":   models.SummaryBranch,
+}
+
+type DurationsViewModel struct {
+	Data     []*Duration `json:"data"`
+	Branches []string    `json:"branches"`
+	Start    string      `json:"start"`
+	End      string      `json:"end"`
+	Timezone string      `json:"timezone"`
+}
+
+// Duration is a span of continuous activity, the name of the field holding its key depends on what durations are sliced by (e.g. "project" or "language")
+type Duration struct {
+	SliceBy  string  `json:"-"`
+	Key      string  `json:"-"`
+	Time     float64 `json:"time"`     // unix timestamp in seconds
+	Duration float64 `json:"duration"` // seconds
+}
+
+func (d *Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		d.SliceBy:  d.Key,
+		"time":     d.Time,
+		"duration": d.Duration,
+	})
+}
+
+// NewDurationsFrom merges adjacent durations with the same key of the given type (after resolving aliases), as wakapi's durations are distinct by all of their entities instead
+func NewDurationsFrom(durations models.Durations, sliceBy string, resolveAlias models.AliasResolver) []*Duration {
+	sliceType := DurationSliceTypes[sliceBy]
+	data := make([]*Duration, 0, len(durations))
+
+	var latest *Duration
+	var latestStart, latestEnd time.Time
+
+	for _, d := range durations.Sorted() {
+		key := d.GetKey(sliceType)
+		if resolveAlias != nil {
+			key = resolveAlias(sliceType, key)
+		}
+
+		// consecutive durations are back to back, unless there was a break of more than the heartbeats timeout
+		if latest != nil && latest.Key == key && !d.Time.T().After(latestEnd.Add(time.Second)) {
+			if end := d.TimeEnd(); end.After(latestEnd) {
+				latestEnd = end
+				latest.Duration = latestEnd.Sub(latestStart).Seconds()
+			}
+			continue
+		}
+
+		latest = &Duration{
+			SliceBy:  sliceBy,
+			Key:      key,
+			Time:     float64(d.Time.T().UnixNano()) / float64(time.Second),
+			Duration: d.Duration.Seconds(),
+		}
+		latestStart, latestEnd = d.Time.T(), d.TimeEnd()
+		data = append(data, latest)
+	}
+
+	return data
+}
+
+// BranchesFrom lists the distinct, non-empty branches of the given durations
+func BranchesFrom(durations models.Durations) []string {
+	branchSet := make(map[string]bool)
+	for _, d := range durations {
+		if d.Branch != "" {
+			branchSet[d.Branch] = true
+		}
+	}
+
+	branches := make([]string, 0, len(branchSet))
+	for b := range branchSet {
+		branches = append(branches, b)
+	}
+	sort.Strings(branches)
+	return branches
+}
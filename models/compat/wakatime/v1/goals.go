@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+)
+
+// partially compatible with https://wakatime.com/developers#goals
+
+type GoalsViewModel struct {
+	Data       []*GoalData `json:"data"`
+	Total      int         `json:"total"`
+	TotalPages int         `json:"total_pages"`
+}
+
+type GoalViewModel struct {
+	Data *GoalData `json:"data"`
+}
+
+type GoalData struct {
+	ID                      string            `json:"id"`
+	Title                   string            `json:"title"`
+	Type                    string            `json:"type"`
+	Delta                   string            `json:"delta"`
+	Seconds                 int               `json:"seconds"`
+	Status                  string            `json:"status"`
+	StatusPercentCalculated int               `json:"status_percent_calculated"`
+	RangeText               string            `json:"range_text"`
+	Projects                []string          `json:"projects"`
+	Languages               []string          `json:"languages"`
+	Editors                 []string          `json:"editors"`
+	IgnoreDays              []string          `json:"ignore_days"`
+	IgnoreZeroDays          bool              `json:"ignore_zero_days"`
+	ImproveByPercent        *float64          `json:"improve_by_percent"`
+	IsEnabled               bool              `json:"is_enabled"`
+	IsInverse               bool              `json:"is_inverse"`
+	IsSnoozed               bool              `json:"is_snoozed"`
+	IsTweeting              bool              `json:"is_tweeting"`
+	CumulativeStatus        string            `json:"cumulative_status"`
+	AverageStatus           string            `json:"average_status"`
+	ChartData               []*GoalChartEntry `json:"chart_data"`
+	CreatedAt               models.CustomTime `json:"created_at"`
+	ModifiedAt              models.CustomTime `json:"modified_at"`
+}
+
+type GoalChartEntry struct {
+	ActualSeconds     float64         `json:"actual_seconds"`
+	ActualSecondsText string          `json:"actual_seconds_text"`
+	GoalSeconds       int             `json:"goal_seconds"`
+	GoalSecondsText   string          `json:"goal_seconds_text"`
+	Range             *GoalChartRange `json:"range"`
+	RangeStatus       string          `json:"range_status"`
+	RangeStatusReason string          `json:"range_status_reason"`
+}
+
+type GoalChartRange struct {
+	Date     string    `json:"date,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Text     string    `json:"text"`
+	Timezone string    `json:"timezone"`
+}
+
+// NewGoalFrom converts a goal and its progress history (latest first) to wakatime's format, whose chart data is ordered chronologically
+func NewGoalFrom(goal *models.Goal, history []*models.GoalProgress, now time.Time) *GoalData {
+	data := &GoalData{
+		ID:               fmt.Sprintf("%d", goal.ID),
+		Title:            goal.Title,
+		Type:             "coding",
+		Delta:            goal.Period,
+		Seconds:          goal.TargetSeconds,
+		Status:           models.GoalStatusPending,
+		RangeText:        fmt.Sprintf("%s per %s", helpers.FmtWakatimeDuration(goal.Target()), goal.Period),
+		Projects:         goal.ProjectsList(),
+		Languages:        goal.LanguagesList(),
+		Editors:          []string{},
+		IgnoreDays:       []string{},
+		IsEnabled:        true,
+		CumulativeStatus: models.GoalStatusSuccess,
+		AverageStatus:    models.GoalStatusSuccess,
+		ChartData:        make([]*GoalChartEntry, 0, len(history)),
+		CreatedAt:        goal.CreatedAt,
+		ModifiedAt:       goal.CreatedAt,
+	}
+
+	if len(history) > 0 {
+		data.Status = history[0].Status(now)
+		data.StatusPercentCalculated = history[0].Percent()
+	}
+
+	var actualSum time.Duration
+	for i := len(history) - 1; i >= 0; i-- {
+		p := history[i]
+		status := p.Status(now)
+		if status == models.GoalStatusFail {
+			data.CumulativeStatus = models.GoalStatusFail
+		}
+		actualSum += p.Actual
+
+		entry := &GoalChartEntry{
+			ActualSeconds:     p.Actual.Seconds(),
+			ActualSecondsText: helpers.FmtWakatimeDuration(p.Actual),
+			GoalSeconds:       goal.TargetSeconds,
+			GoalSecondsText:   helpers.FmtWakatimeDuration(goal.Target()),
+			Range: &GoalChartRange{
+				Start:    p.From,
+				End:      p.To,
+				Text:     helpers.FormatDateHuman(p.From),
+				Timezone: p.From.Location().String(),
+			},
+			RangeStatus:       status,
+			RangeStatusReason: fmt.Sprintf("coded %s of %s", helpers.FmtWakatimeDuration(p.Actual), helpers.FmtWakatimeDuration(goal.Target())),
+		}
+		if goal.Period == models.GoalPeriodDay {
+			entry.Range.Date = p.From.Format(time.DateOnly)
+		} else {
+			entry.Range.Text = fmt.Sprintf("%s until %s", helpers.FormatDateHuman(p.From), helpers.FormatDateHuman(p.To.AddDate(0, 0, -1)))
+		}
+		data.ChartData = append(data.ChartData, entry)
+	}
+
+	if len(history) > 0 && actualSum/time.Duration(len(history)) < goal.Target() {
+		data.AverageStatus = models.GoalStatusFail
+	}
+
+	return data
+}
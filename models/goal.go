@@ -0,0 +1,178 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/duke-git/lancet/v2/slice"
+)
+
+const (
+	GoalPeriodDay  = "day"
+	GoalPeriodWeek = "week"
+)
+
+const (
+	GoalStatusSuccess = "success"
+	GoalStatusFail    = "fail"
+	GoalStatusPending = "pending"
+)
+
+// Goal is a target amount of coding time per day or week, optionally restricted to certain projects, languages or labels
+type Goal struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	User          *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID        string     `json:"-" gorm:"not null; index:idx_goal_user"`
+	Title         string     `json:"title" gorm:"not null; size:255"`
+	Period        string     `json:"period" gorm:"not null; size:16"`
+	TargetSeconds int        `json:"target_seconds" gorm:"not null"`
+	Projects      string     `json:"-"` // comma-separated list of projects to count, all if empty
+	Languages     string     `json:"-"` // comma-separated list of languages to count, all if empty
+	Labels        string     `json:"-"` // comma-separated list of project labels to count, all if empty
+	Notify        bool       `json:"notify" gorm:"default:false; type:bool"`
+	CreatedAt     CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// GoalProgress is the amount of coding time towards a goal within one of its periods
+type GoalProgress struct {
+	Goal   *Goal
+	From   time.Time
+	To     time.Time
+	Actual time.Duration
+}
+
+// GoalResponse is the api representation of a goal along with its progress in the current and a few past periods (latest first)
+type GoalResponse struct {
+	*Goal
+	Projects  []string                `json:"projects"`
+	Languages []string                `json:"languages"`
+	Labels    []string                `json:"labels"`
+	Current   *GoalProgressResponse   `json:"current"`
+	History   []*GoalProgressResponse `json:"history"`
+}
+
+type GoalProgressResponse struct {
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	ActualSeconds float64   `json:"actual_seconds"`
+	TargetSeconds int       `json:"target_seconds"`
+	Percent       int       `json:"percent"`
+	Status        string    `json:"status"`
+}
+
+func GoalPeriods() []string {
+	return []string{GoalPeriodDay, GoalPeriodWeek}
+}
+
+func (g *Goal) Target() time.Duration {
+	return time.Duration(g.TargetSeconds) * time.Second
+}
+
+func (g *Goal) ProjectsList() []string {
+	return splitList(g.Projects)
+}
+
+func (g *Goal) LanguagesList() []string {
+	return splitList(g.Languages)
+}
+
+func (g *Goal) LabelsList() []string {
+	return splitList(g.Labels)
+}
+
+// Filters returns the summary filters to compute the goal's progress with, or nil if all coding activity counts
+func (g *Goal) Filters() *Filters {
+	filters := &Filters{}
+	if projects := g.ProjectsList(); len(projects) > 0 {
+		filters = filters.WithMultiple(SummaryProject, projects)
+	}
+	if languages := g.LanguagesList(); len(languages) > 0 {
+		filters = filters.WithMultiple(SummaryLanguage, languages)
+	}
+	if labels := g.LabelsList(); len(labels) > 0 {
+		filters = filters.WithMultiple(SummaryLabel, labels)
+	}
+	if filters.IsEmpty() {
+		return nil
+	}
+	return filters
+}
+
+// PeriodAt returns the start and end of the goal's period (day or week, starting monday) which contains the given time
+func (g *Goal) PeriodAt(t time.Time) (time.Time, time.Time) {
+	if g.Period == GoalPeriodWeek {
+		from := datetime.BeginOfWeek(t, time.Monday)
+		return from, from.AddDate(0, 0, 7)
+	}
+	from := datetime.BeginOfDay(t)
+	return from, from.AddDate(0, 0, 1)
+}
+
+func (g *Goal) IsValid() bool {
+	return strings.TrimSpace(g.Title) != "" &&
+		len(g.Title) <= 255 &&
+		slice.Contain(GoalPeriods(), g.Period) &&
+		g.TargetSeconds > 0 &&
+		(g.Period != GoalPeriodDay || g.TargetSeconds <= 24*60*60) &&
+		(g.Period != GoalPeriodWeek || g.TargetSeconds <= 7*24*60*60)
+}
+
+func (p *GoalProgress) Target() time.Duration {
+	return p.Goal.Target()
+}
+
+func (p *GoalProgress) Reached() bool {
+	return p.Actual >= p.Target()
+}
+
+// Percent returns the share of the target reached so far, capped at 100
+func (p *GoalProgress) Percent() int {
+	if p.Target() <= 0 {
+		return 0
+	}
+	return min(int(p.Actual*100/p.Target()), 100)
+}
+
+// Status tells whether the goal was reached in this period, and if not, whether the period is already over at the given time
+func (p *GoalProgress) Status(now time.Time) string {
+	if p.Reached() {
+		return GoalStatusSuccess
+	}
+	if now.Before(p.To) {
+		return GoalStatusPending
+	}
+	return GoalStatusFail
+}
+
+func NewGoalResponse(goal *Goal, progress []*GoalProgress, now time.Time) *GoalResponse {
+	response := &GoalResponse{
+		Goal:      goal,
+		Projects:  goal.ProjectsList(),
+		Languages: goal.LanguagesList(),
+		Labels:    goal.LabelsList(),
+		History:   make([]*GoalProgressResponse, 0, len(progress)),
+	}
+	for i, p := range progress {
+		pr := &GoalProgressResponse{
+			From:          p.From,
+			To:            p.To,
+			ActualSeconds: p.Actual.Seconds(),
+			TargetSeconds: goal.TargetSeconds,
+			Percent:       p.Percent(),
+			Status:        p.Status(now),
+		}
+		if i == 0 {
+			response.Current = pr
+		}
+		response.History = append(response.History, pr)
+	}
+	return response
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoal_PeriodAt(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	ts := time.Date(2024, 5, 16, 14, 30, 0, 0, tz) // a thursday
+
+	from, to := (&Goal{Period: GoalPeriodDay}).PeriodAt(ts)
+	assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2024, 5, 17, 0, 0, 0, 0, tz), to)
+
+	from, to = (&Goal{Period: GoalPeriodWeek}).PeriodAt(ts)
+	assert.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2024, 5, 20, 0, 0, 0, 0, tz), to)
+
+	from, _ = (&Goal{Period: GoalPeriodWeek}).PeriodAt(time.Date(2024, 5, 19, 23, 59, 0, 0, tz)) // a sunday
+	assert.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, tz), from)
+}
+
+func TestGoal_IsValid(t *testing.T) {
+	assert.True(t, (&Goal{Title: "Daily", Period: GoalPeriodDay, TargetSeconds: 3600}).IsValid())
+	assert.True(t, (&Goal{Title: "Weekly", Period: GoalPeriodWeek, TargetSeconds: 40 * 3600}).IsValid())
+	assert.False(t, (&Goal{Title: " ", Period: GoalPeriodDay, TargetSeconds: 3600}).IsValid())
+	assert.False(t, (&Goal{Title: "Daily", Period: "month", TargetSeconds: 3600}).IsValid())
+	assert.False(t, (&Goal{Title: "Daily", Period: GoalPeriodDay, TargetSeconds: 0}).IsValid())
+	assert.False(t, (&Goal{Title: "Daily", Period: GoalPeriodDay, TargetSeconds: 25 * 3600}).IsValid())
+}
+
+func TestGoal_Filters(t *testing.T) {
+	assert.Nil(t, (&Goal{}).Filters())
+
+	filters := (&Goal{Projects: "wakapi,anchr", Languages: "Go"}).Filters()
+	assert.Equal(t, OrFilter{"wakapi", "anchr"}, filters.Project)
+	assert.Equal(t, OrFilter{"Go"}, filters.Language)
+	assert.Empty(t, filters.Label)
+}
+
+func TestGoalProgress_Status(t *testing.T) {
+	goal := &Goal{Period: GoalPeriodDay, TargetSeconds: 3600}
+	from := time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	progress := &GoalProgress{Goal: goal, From: from, To: to, Actual: 30 * time.Minute}
+	assert.Equal(t, 50, progress.Percent())
+	assert.Equal(t, GoalStatusPending, progress.Status(from.Add(12*time.Hour)))
+	assert.Equal(t, GoalStatusFail, progress.Status(to))
+
+	progress.Actual = 2 * time.Hour
+	assert.Equal(t, 100, progress.Percent())
+	assert.Equal(t, GoalStatusSuccess, progress.Status(from.Add(12*time.Hour)))
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/becheran/wildmatch-go"
+	"github.com/duke-git/lancet/v2/slice"
+)
+
+const (
+	HeartbeatRuleFieldEntity  = "entity"
+	HeartbeatRuleFieldProject = "project"
+	HeartbeatRuleFieldBranch  = "branch"
+	HeartbeatRuleFieldMachine = "machine"
+)
+
+const (
+	HeartbeatRuleMatchGlob  = "glob"
+	HeartbeatRuleMatchRegex = "regex"
+)
+
+const (
+	HeartbeatRuleActionSetProject   = "set_project"
+	HeartbeatRuleActionDrop         = "drop"
+	HeartbeatRuleActionRedactEntity = "redact_entity"
+)
+
+// HeartbeatRule is a user-defined rule to rewrite or drop incoming heartbeats, before they're stored
+type HeartbeatRule struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_heartbeat_rule_user"`
+	Field     string     `json:"field" gorm:"not null; size:16"`      // heartbeat field to match against
+	MatchType string     `json:"match_type" gorm:"not null; size:16"` // either glob (wildcards) or regex
+	Pattern   string     `json:"pattern" gorm:"not null"`
+	Action    string     `json:"action" gorm:"not null; size:32"`
+	Value     string     `json:"value"` // project name to set, may reference capture groups of regex patterns (e.g. "$1")
+	CreatedAt CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	regex     *regexp.Regexp
+}
+
+func HeartbeatRuleFields() []string {
+	return []string{HeartbeatRuleFieldEntity, HeartbeatRuleFieldProject, HeartbeatRuleFieldBranch, HeartbeatRuleFieldMachine}
+}
+
+func HeartbeatRuleMatchTypes() []string {
+	return []string{HeartbeatRuleMatchGlob, HeartbeatRuleMatchRegex}
+}
+
+func HeartbeatRuleActions() []string {
+	return []string{HeartbeatRuleActionSetProject, HeartbeatRuleActionDrop, HeartbeatRuleActionRedactEntity}
+}
+
+// Compile prepares the rule's regex pattern for matching, it has to be called before applying the rule to heartbeats
+func (r *HeartbeatRule) Compile() error {
+	if r.MatchType != HeartbeatRuleMatchRegex {
+		return nil
+	}
+	regex, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return err
+	}
+	r.regex = regex
+	return nil
+}
+
+// Apply rewrites the heartbeat in-place if it matches the rule and returns whether it is to be dropped
+func (r *HeartbeatRule) Apply(hb *Heartbeat) bool {
+	value := r.fieldValue(hb)
+	if !r.matches(value) {
+		return false
+	}
+
+	switch r.Action {
+	case HeartbeatRuleActionDrop:
+		return true
+	case HeartbeatRuleActionSetProject:
+		if r.regex != nil {
+			match := r.regex.FindStringSubmatchIndex(value)
+			hb.Project = string(r.regex.ExpandString(nil, r.Value, value, match))
+		} else {
+			hb.Project = r.Value
+		}
+	case HeartbeatRuleActionRedactEntity:
+		hb.Entity = redactEntity(hb)
+	}
+	return false
+}
+
+func (r *HeartbeatRule) IsValid() bool {
+	if !slice.Contain(HeartbeatRuleFields(), r.Field) || !slice.Contain(HeartbeatRuleMatchTypes(), r.MatchType) || !slice.Contain(HeartbeatRuleActions(), r.Action) {
+		return false
+	}
+	if r.Pattern == "" || (r.Action == HeartbeatRuleActionSetProject && r.Value == "") {
+		return false
+	}
+	if r.MatchType == HeartbeatRuleMatchRegex {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *HeartbeatRule) matches(value string) bool {
+	if r.MatchType == HeartbeatRuleMatchRegex {
+		return r.regex != nil && r.regex.MatchString(value)
+	}
+	return wildmatch.NewWildMatch(r.Pattern).IsMatch(value)
+}
+
+func (r *HeartbeatRule) fieldValue(hb *Heartbeat) string {
+	switch r.Field {
+	case HeartbeatRuleFieldProject:
+		return hb.Project
+	case HeartbeatRuleFieldBranch:
+		return hb.Branch
+	case HeartbeatRuleFieldMachine:
+		return hb.Machine
+	default:
+		return hb.Entity
+	}
+}
+
+// redactEntity replaces the entity by its hash, while keeping file extensions for the language to still be detectable
+func redactEntity(hb *Heartbeat) string {
+	redacted := fmt.Sprintf("%x", sha256.Sum256([]byte(hb.Entity)))[:16]
+	if hb.Type == "file" {
+		fileName := hb.Entity[strings.LastIndexAny(hb.Entity, `/\`)+1:] // entities might be windows paths
+		redacted += filepath.Ext(fileName)
+	}
+	return redacted
+}
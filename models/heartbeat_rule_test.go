@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeatRule_Apply(t *testing.T) {
+	newHeartbeat := func() *Heartbeat {
+		return &Heartbeat{Entity: "/home/user/work/wakapi/models/user.go", Type: "file", Project: "unknown", Branch: "main", Machine: "laptop"}
+	}
+
+	rule := &HeartbeatRule{Field: HeartbeatRuleFieldEntity, MatchType: HeartbeatRuleMatchRegex, Pattern: `^/home/user/work/([^/]+)/`, Action: HeartbeatRuleActionSetProject, Value: "work-$1"}
+	assert.Nil(t, rule.Compile())
+	hb := newHeartbeat()
+	assert.False(t, rule.Apply(hb))
+	assert.Equal(t, "work-wakapi", hb.Project)
+
+	rule = &HeartbeatRule{Field: HeartbeatRuleFieldEntity, MatchType: HeartbeatRuleMatchGlob, Pattern: "/home/user/private/*", Action: HeartbeatRuleActionSetProject, Value: "private"}
+	hb = newHeartbeat()
+	assert.False(t, rule.Apply(hb))
+	assert.Equal(t, "unknown", hb.Project)
+
+	rule = &HeartbeatRule{Field: HeartbeatRuleFieldMachine, MatchType: HeartbeatRuleMatchGlob, Pattern: "lap*", Action: HeartbeatRuleActionDrop}
+	assert.True(t, rule.Apply(newHeartbeat()))
+
+	rule = &HeartbeatRule{Field: HeartbeatRuleFieldProject, MatchType: HeartbeatRuleMatchGlob, Pattern: "*", Action: HeartbeatRuleActionRedactEntity}
+	hb = newHeartbeat()
+	assert.False(t, rule.Apply(hb))
+	assert.Regexp(t, `^[0-9a-f]{16}\.go$`, hb.Entity)
+
+	hb = &Heartbeat{Entity: `C:\Users\user\work.d\Makefile`, Type: "file"}
+	rule.Apply(hb)
+	assert.Regexp(t, `^[0-9a-f]{16}$`, hb.Entity)
+}
+
+func TestHeartbeatRule_IsValid(t *testing.T) {
+	assert.True(t, (&HeartbeatRule{Field: HeartbeatRuleFieldEntity, MatchType: HeartbeatRuleMatchGlob, Pattern: "*/secret/*", Action: HeartbeatRuleActionDrop}).IsValid())
+	assert.False(t, (&HeartbeatRule{Field: HeartbeatRuleFieldEntity, MatchType: HeartbeatRuleMatchRegex, Pattern: "(", Action: HeartbeatRuleActionDrop}).IsValid())
+	assert.False(t, (&HeartbeatRule{Field: HeartbeatRuleFieldEntity, MatchType: HeartbeatRuleMatchGlob, Pattern: "*", Action: HeartbeatRuleActionSetProject}).IsValid())
+	assert.False(t, (&HeartbeatRule{Field: "language", MatchType: HeartbeatRuleMatchGlob, Pattern: "*", Action: HeartbeatRuleActionDrop}).IsValid())
+}
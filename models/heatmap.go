@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// HeatmapWeekdays are the days of week corresponding to the rows of a heatmap
+var HeatmapWeekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+// Heatmap is a user's coding activity within a given time range, binned by day of week and hour of day
+type Heatmap struct {
+	From     CustomTime     `json:"from" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	To       CustomTime     `json:"to" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Timezone string         `json:"timezone"`
+	Data     [7][24]float64 `json:"data"` // seconds of activity, rows are days of week (starting with monday) and columns are hours of day, both in the given timezone
+}
+
+func NewHeatmap(from, to time.Time, tz *time.Location) *Heatmap {
+	return &Heatmap{
+		From:     CustomTime(from.In(tz)),
+		To:       CustomTime(to.In(tz)),
+		Timezone: tz.String(),
+	}
+}
+
+// Add distributes the given span of activity across the bins it overlaps, as far as it falls into the heatmap's time range
+func (h *Heatmap) Add(start time.Time, d time.Duration) {
+	tz := h.From.T().Location()
+	end := start.Add(d)
+	if start.Before(h.From.T()) {
+		start = h.From.T()
+	}
+	if end.After(h.To.T()) {
+		end = h.To.T()
+	}
+
+	for t := start.In(tz); t.Before(end); {
+		// truncating to full hours in the target timezone, not in utc (some timezones have non-hour offsets)
+		next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, tz).Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		h.Data[(t.Weekday()+6)%7][t.Hour()] += next.Sub(t).Seconds()
+		t = next
+	}
+}
+
+func (h *Heatmap) Max() float64 {
+	var max float64
+	for _, row := range h.Data {
+		for _, v := range row {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	return max
+}
+
+func (h *Heatmap) Total() time.Duration {
+	var total float64
+	for _, row := range h.Data {
+		for _, v := range row {
+			total += v
+		}
+	}
+	return time.Duration(total * float64(time.Second))
+}
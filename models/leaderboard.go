@@ -11,6 +11,7 @@ type LeaderboardItem struct {
 	ID        uint          `json:"-" gorm:"primary_key; size:32"`
 	User      *User         `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	UserID    string        `json:"user_id" gorm:"not null; index:idx_leaderboard_user"`
+	TeamID    *uint         `json:"-" gorm:"index:idx_leaderboard_team"` // pointer because nullable, null for the public leaderboard
 	Interval  string        `json:"interval" gorm:"not null; size:32; index:idx_leaderboard_combined"`
 	By        *uint8        `json:"aggregated_by" gorm:"index:idx_leaderboard_combined"` // pointer because nullable
 	Total     time.Duration `json:"total" gorm:"not null" swaggertype:"primitive,integer"`
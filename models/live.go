@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LiveStatus is a snapshot of what a user is coding on right now, as streamed by the live activity api
+type LiveStatus struct {
+	UserID          string        `json:"user_id"`
+	Active          bool          `json:"active"` // whether the latest heartbeat is more recent than the user's heartbeats timeout
+	Project         string        `json:"project,omitempty"`
+	Language        string        `json:"language,omitempty"`
+	Editor          string        `json:"editor,omitempty"`
+	LastHeartbeatAt *time.Time    `json:"last_heartbeat_at,omitempty"`
+	TotalToday      time.Duration `json:"-"` // running total of today's coding time in the user's time zone
+	ComputedAt      time.Time     `json:"-"` // time at which the total was last computed from scratch
+}
+
+// At returns a copy of the status with its active flag evaluated at the given time
+func (s *LiveStatus) At(t time.Time, timeout time.Duration) *LiveStatus {
+	status := *s
+	status.Active = s.LastHeartbeatAt != nil && !t.Before(*s.LastHeartbeatAt) && t.Sub(*s.LastHeartbeatAt) <= timeout
+	return &status
+}
+
+// WithHeartbeat returns a copy of the status updated by the given, newly received heartbeat.
+// Time elapsed since the previous heartbeat is added to the running total, unless the user was idle in between, just like when computing durations.
+func (s *LiveStatus) WithHeartbeat(hb *Heartbeat, timeout time.Duration) *LiveStatus {
+	t := hb.Time.T()
+	if s.LastHeartbeatAt != nil && !t.After(*s.LastHeartbeatAt) {
+		return s // heartbeats sent in retrospect don't change what the user is currently doing
+	}
+
+	status := *s
+	if s.LastHeartbeatAt != nil && t.Sub(*s.LastHeartbeatAt) <= timeout {
+		status.TotalToday += t.Sub(*s.LastHeartbeatAt)
+	}
+	status.Project = hb.Project
+	status.Language = hb.Language
+	status.Editor = hb.Editor
+	status.LastHeartbeatAt = &t
+	return &status
+}
+
+// Redacted returns a copy of the status without any details the user doesn't publicly share
+func (s *LiveStatus) Redacted(user *User) *LiveStatus {
+	status := *s
+	if !user.SharesSummaryType(SummaryProject) {
+		status.Project = ""
+	}
+	if !user.SharesSummaryType(SummaryLanguage) {
+		status.Language = ""
+	}
+	if !user.SharesSummaryType(SummaryEditor) {
+		status.Editor = ""
+	}
+	return &status
+}
+
+func (s *LiveStatus) MarshalJSON() ([]byte, error) {
+	type alias LiveStatus
+	return json.Marshal(&struct {
+		*alias
+		TotalTodaySeconds float64 `json:"total_today_seconds"`
+	}{
+		alias:             (*alias)(s),
+		TotalTodaySeconds: s.TotalToday.Seconds(),
+	})
+}
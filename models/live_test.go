@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveStatus_At(t *testing.T) {
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	last := now.Add(-5 * time.Minute)
+
+	assert.True(t, (&LiveStatus{LastHeartbeatAt: &last}).At(now, 10*time.Minute).Active)
+	assert.False(t, (&LiveStatus{LastHeartbeatAt: &last}).At(now, 2*time.Minute).Active)
+	assert.False(t, (&LiveStatus{}).At(now, 10*time.Minute).Active)
+}
+
+func TestLiveStatus_WithHeartbeat(t *testing.T) {
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	last := now.Add(-5 * time.Minute)
+	sut := &LiveStatus{Project: "wakapi", LastHeartbeatAt: &last, TotalToday: time.Hour}
+
+	status := sut.WithHeartbeat(&Heartbeat{Project: "anchr", Language: "Go", Editor: "vscode", Time: CustomTime(now)}, 10*time.Minute)
+	assert.Equal(t, "anchr", status.Project)
+	assert.Equal(t, "Go", status.Language)
+	assert.Equal(t, now, *status.LastHeartbeatAt)
+	assert.Equal(t, time.Hour+5*time.Minute, status.TotalToday)
+	assert.Equal(t, "wakapi", sut.Project) // original left untouched
+
+	// idle in between
+	status = sut.WithHeartbeat(&Heartbeat{Project: "anchr", Time: CustomTime(now)}, 2*time.Minute)
+	assert.Equal(t, time.Hour, status.TotalToday)
+	assert.Equal(t, now, *status.LastHeartbeatAt)
+
+	// older heartbeat
+	status = sut.WithHeartbeat(&Heartbeat{Project: "anchr", Time: CustomTime(last.Add(-time.Minute))}, 10*time.Minute)
+	assert.Equal(t, "wakapi", status.Project)
+	assert.Equal(t, time.Hour, status.TotalToday)
+}
+
+func TestLiveStatus_Redacted(t *testing.T) {
+	sut := &LiveStatus{Project: "wakapi", Language: "Go", Editor: "vscode"}
+
+	status := sut.Redacted(&User{ShareDataMaxDays: -1, ShareLanguages: true})
+	assert.Empty(t, status.Project)
+	assert.Equal(t, "Go", status.Language)
+	assert.Empty(t, status.Editor)
+	assert.Equal(t, "wakapi", sut.Project)
+}
+
+func TestLiveStatus_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(&LiveStatus{UserID: "testuser01", Active: true, TotalToday: 90 * time.Second})
+	assert.Nil(t, err)
+
+	var result map[string]interface{}
+	assert.Nil(t, json.Unmarshal(data, &result))
+	assert.Equal(t, "testuser01", result["user_id"])
+	assert.Equal(t, true, result["active"])
+	assert.Equal(t, 90.0, result["total_today_seconds"])
+	assert.NotContains(t, result, "project")
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
+)
+
+const (
+	NotificationChannelSlack   = "slack"
+	NotificationChannelDiscord = "discord"
+	NotificationChannelMatrix  = "matrix"
+	NotificationChannelNtfy    = "ntfy"
+	NotificationChannelGotify  = "gotify"
+	NotificationChannelWebhook = "webhook"
+)
+
+const (
+	NotificationTypeReport          = "report"
+	NotificationTypeImport          = "import"
+	NotificationTypeWakatimeFailure = "wakatime_failure"
+	NotificationTypeSubscription    = "subscription"
+)
+
+// NotificationTypeTest is sent when manually testing a channel, channels can not subscribe to it
+const NotificationTypeTest = "test"
+
+// NotificationChannel is a chat or push service to send a user's notifications to, in addition to e-mail
+type NotificationChannel struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_notification_channel_user"`
+	Type      string     `json:"type" gorm:"not null; size:16"`
+	Url       string     `json:"-" gorm:"not null"`      // incoming webhook url, matrix homeserver, ntfy topic url or gotify server, depending on the type
+	Token     string     `json:"-"`                      // access token for matrix, application token for gotify, optional bearer token otherwise
+	Target    string     `json:"target"`                 // room id for matrix, unused otherwise
+	Events    string     `json:"events" gorm:"not null"` // comma-separated list of notification types
+	CreatedAt CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// Notification is the channel-agnostic, plain text representation of a message to a user
+type Notification struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Url     string `json:"url,omitempty"` // optional link to view details in wakapi
+}
+
+func NotificationChannelTypes() []string {
+	return []string{
+		NotificationChannelSlack,
+		NotificationChannelDiscord,
+		NotificationChannelMatrix,
+		NotificationChannelNtfy,
+		NotificationChannelGotify,
+		NotificationChannelWebhook,
+	}
+}
+
+func NotificationTypes() []string {
+	return []string{
+		NotificationTypeReport,
+		NotificationTypeImport,
+		NotificationTypeWakatimeFailure,
+		NotificationTypeSubscription,
+	}
+}
+
+func ValidateNotificationType(notificationType string) bool {
+	return slice.Contain(NotificationTypes(), notificationType)
+}
+
+func (c *NotificationChannel) EventsList() []string {
+	return splitList(c.Events)
+}
+
+func (c *NotificationChannel) Subscribes(notificationType string) bool {
+	return notificationType == NotificationTypeTest || slice.Contain(c.EventsList(), notificationType)
+}
+
+// Host returns the host name of the channel's url, which, unlike the full url, is safe to display
+func (c *NotificationChannel) Host() string {
+	if u, err := url.Parse(c.Url); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+func (c *NotificationChannel) IsValid() bool {
+	if !slice.Contain(NotificationChannelTypes(), c.Type) {
+		return false
+	}
+	u, err := url.Parse(c.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if !config.Get().Security.GetAllowedPrivateHosts().Permits(u.Hostname()) {
+		return false
+	}
+	if c.Type == NotificationChannelMatrix && (c.Token == "" || !strings.HasPrefix(c.Target, "!")) {
+		return false
+	}
+	if c.Type == NotificationChannelGotify && c.Token == "" {
+		return false
+	}
+	events := c.EventsList()
+	return len(events) > 0 && slice.Every[string](events, func(i int, e string) bool { return ValidateNotificationType(e) })
+}
+
+// Text renders the notification as a single plain text message
+func (n *Notification) Text() string {
+	text := n.Message
+	if n.Url != "" {
+		text += "\n\n" + n.Url
+	}
+	return text
+}
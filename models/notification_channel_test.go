@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationChannel_IsValid(t *testing.T) {
+	config.Set(config.Empty())
+
+	assert.True(t, (&NotificationChannel{Type: NotificationChannelNtfy, Url: "https://ntfy.sh/wakapi", Events: NotificationTypeReport}).IsValid())
+	assert.False(t, (&NotificationChannel{Type: "telegram", Url: "https://ntfy.sh/wakapi", Events: NotificationTypeReport}).IsValid())
+	assert.False(t, (&NotificationChannel{Type: NotificationChannelNtfy, Url: "https://ntfy.sh/wakapi", Events: "unknown"}).IsValid())
+	assert.False(t, (&NotificationChannel{Type: NotificationChannelGotify, Url: "https://gotify.example.org"}).IsValid())
+	assert.False(t, (&NotificationChannel{Type: NotificationChannelNtfy, Url: "http://localhost:8080/wakapi", Events: NotificationTypeReport}).IsValid())
+	assert.False(t, (&NotificationChannel{Type: NotificationChannelNtfy, Url: "http://169.254.169.254/latest", Events: NotificationTypeReport}).IsValid())
+
+	config.Get().Security.AllowedPrivateHosts = "192.168.178.0/24"
+	assert.True(t, (&NotificationChannel{Type: NotificationChannelNtfy, Url: "http://192.168.178.20/wakapi", Events: NotificationTypeReport}).IsValid())
+	assert.False(t, (&NotificationChannel{Type: NotificationChannelNtfy, Url: "http://169.254.169.254/latest", Events: NotificationTypeReport}).IsValid())
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const OidcStateCookieKey = "wakapi_oidc_state"
+
+// OidcIdentity links a user to an account at the configured openid connect provider, identified by issuer and subject
+type OidcIdentity struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_oidc_identity_user"`
+	Issuer    string     `json:"issuer" gorm:"not null; size:255; uniqueIndex:idx_oidc_identity_subject"`
+	Subject   string     `json:"subject" gorm:"not null; size:255; uniqueIndex:idx_oidc_identity_subject"`
+	Email     string     `json:"email"`
+	CreatedAt CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// OidcAuthState is kept in a short-lived, encrypted cookie in between redirecting to the provider and receiving its callback
+type OidcAuthState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	LinkUserId   string // set if an identity is to be linked to an already logged-in user instead of logging in
+}
+
+// OidcClaims are the relevant claims of a verified id token
+type OidcClaims struct {
+	Issuer            string                 `json:"iss"`
+	Subject           string                 `json:"sub"`
+	Audience          OidcAudience           `json:"aud"`
+	AuthorizedParty   string                 `json:"azp"`
+	ExpiresAt         int64                  `json:"exp"`
+	IssuedAt          int64                  `json:"iat"`
+	Nonce             string                 `json:"nonce"`
+	Email             string                 `json:"email"`
+	EmailVerified     bool                   `json:"email_verified"`
+	Name              string                 `json:"name"`
+	PreferredUsername string                 `json:"preferred_username"`
+	ZoneInfo          string                 `json:"zoneinfo"`
+	Raw               map[string]interface{} `json:"-"`
+}
+
+// OidcAudience is either a single string or an array of strings
+type OidcAudience []string
+
+// OidcProviderMetadata is the subset of an openid provider's discovery document (/.well-known/openid-configuration) that is used here
+type OidcProviderMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JwksUri               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+type OidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IdToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+func (a *OidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = OidcAudience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+func (a OidcAudience) Contains(clientId string) bool {
+	for _, aud := range a {
+		if aud == clientId {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *OidcClaims) Expired(now time.Time) bool {
+	return c.ExpiresAt == 0 || now.Unix() > c.ExpiresAt
+}
+
+// ClaimString returns the string value of an arbitrary claim, e.g. the one configured to derive usernames from
+func (c *OidcClaims) ClaimString(name string) string {
+	if v, ok := c.Raw[name].(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/becheran/wildmatch-go"
+	"github.com/muety/wakapi/config"
+)
+
+const (
+	RelayHeaderOrigin         = "X-Origin"
+	RelayHeaderOriginInstance = "X-Origin-Instance"
+)
+
+// RelayTarget is an upstream, WakaTime-compatible server (e.g. WakaTime itself or another Wakapi instance) that a user's incoming heartbeats are forwarded to
+type RelayTarget struct {
+	ID              uint       `json:"id" gorm:"primary_key"`
+	User            *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID          string     `json:"-" gorm:"not null; index:idx_relay_target_user"`
+	Name            string     `json:"name" gorm:"not null"`
+	ApiUrl          string     `json:"api_url" gorm:"not null"` // base url of the api, e.g. https://api.wakatime.com/api/v1
+	ApiKey          string     `json:"-" gorm:"not null"`
+	IncludeProjects string     `json:"include_projects"` // comma-separated list of project name patterns (wildcards allowed), all projects are included if empty
+	ExcludeProjects string     `json:"exclude_projects"` // comma-separated list of project name patterns (wildcards allowed), takes precedence over includes
+	Enabled         bool       `json:"enabled" gorm:"default:true; type:bool"`
+	FailureCount    int        `json:"failure_count"` // number of consecutive failed attempts, reset after the first successful one
+	LastError       string     `json:"last_error"`
+	CreatedAt       CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// RelayQueueItem is a batch of heartbeats, which could not be forwarded to a relay target and is waiting to be retried
+type RelayQueueItem struct {
+	ID             uint         `gorm:"primary_key"`
+	Target         *RelayTarget `gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	TargetID       uint         `gorm:"not null; index:idx_relay_queue_target"`
+	Payload        string       `gorm:"type:text"` // json array of heartbeats, as originally sent by the client
+	UserAgent      string
+	MachineName    string
+	OriginInstance string
+	Attempts       int
+	NextAttemptAt  CustomTime `gorm:"index:idx_relay_queue_next"`
+	CreatedAt      CustomTime
+}
+
+func (t *RelayTarget) HeartbeatsUrl() string {
+	return strings.TrimSuffix(t.ApiUrl, "/") + config.WakatimeApiHeartbeatsBulkUrl
+}
+
+func (t *RelayTarget) IncludeList() []string {
+	return splitPatterns(t.IncludeProjects)
+}
+
+func (t *RelayTarget) ExcludeList() []string {
+	return splitPatterns(t.ExcludeProjects)
+}
+
+// Matches returns whether heartbeats of the given project are to be forwarded to this target
+func (t *RelayTarget) Matches(project string) bool {
+	for _, p := range t.ExcludeList() {
+		if wildmatch.NewWildMatch(p).IsMatch(project) {
+			return false
+		}
+	}
+	includes := t.IncludeList()
+	if len(includes) == 0 {
+		return true
+	}
+	for _, p := range includes {
+		if wildmatch.NewWildMatch(p).IsMatch(project) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *RelayTarget) IsValid() bool {
+	u, err := url.Parse(t.ApiUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if !config.Get().Security.GetAllowedPrivateHosts().Permits(u.Hostname()) {
+		return false
+	}
+	return t.UserID != "" && strings.TrimSpace(t.Name) != "" && t.ApiKey != ""
+}
+
+func splitPatterns(s string) []string {
+	patterns := make([]string, 0)
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayTarget_Matches(t *testing.T) {
+	all := &RelayTarget{}
+	assert.True(t, all.Matches("wakapi"))
+	assert.True(t, all.Matches(""))
+
+	included := &RelayTarget{IncludeProjects: "wakapi, acme-*"}
+	assert.True(t, included.Matches("wakapi"))
+	assert.True(t, included.Matches("acme-web"))
+	assert.False(t, included.Matches("anchr"))
+
+	excluded := &RelayTarget{IncludeProjects: "acme-*", ExcludeProjects: "acme-secret,private*"}
+	assert.True(t, excluded.Matches("acme-web"))
+	assert.False(t, excluded.Matches("acme-secret"))
+	assert.False(t, (&RelayTarget{ExcludeProjects: "private*"}).Matches("private-notes"))
+	assert.True(t, (&RelayTarget{ExcludeProjects: "private*"}).Matches("wakapi"))
+}
+
+func TestRelayTarget_IsValid(t *testing.T) {
+	config.Set(config.Empty())
+
+	assert.True(t, (&RelayTarget{UserID: "user1", Name: "WakaTime", ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: "key"}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", Name: " ", ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: "key"}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", Name: "WakaTime", ApiUrl: "ftp://example.org", ApiKey: "key"}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", Name: "WakaTime", ApiUrl: "https://api.wakatime.com/api/v1"}).IsValid())
+}
+
+func TestRelayTarget_HeartbeatsUrl(t *testing.T) {
+	assert.Equal(t, "https://wakapi.example.org/api/compat/wakatime/v1/users/current/heartbeats.bulk", (&RelayTarget{ApiUrl: "https://wakapi.example.org/api/compat/wakatime/v1/"}).HeartbeatsUrl())
+	assert.False(t, (&RelayTarget{UserID: "user1", Name: "Local", ApiUrl: "http://localhost:3000/api", ApiKey: "key"}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", Name: "Internal", ApiUrl: "http://10.0.0.5/api", ApiKey: "key"}).IsValid())
+
+	config.Get().Security.AllowedPrivateHosts = "wakapi.internal, 10.0.0.0/24"
+	assert.True(t, (&RelayTarget{UserID: "user1", Name: "Internal", ApiUrl: "http://10.0.0.5/api", ApiKey: "key"}).IsValid())
+	assert.True(t, (&RelayTarget{UserID: "user1", Name: "Internal", ApiUrl: "https://wakapi.internal/api", ApiKey: "key"}).IsValid())
+	assert.False(t, (&RelayTarget{UserID: "user1", Name: "Internal", ApiUrl: "http://10.0.1.5/api", ApiKey: "key"}).IsValid())
+}
@@ -8,4 +8,24 @@ type Report struct {
 	User           *User
 	Summary        *Summary
 	DailySummaries []*Summary
+	Subscription   *ReportSubscription // nil for the regular weekly report
+}
+
+// ShowSection tells whether the given section is to be included in the report, which is the case for all sections of the regular weekly report
+func (r *Report) ShowSection(section string) bool {
+	return r.Subscription == nil || r.Subscription.HasSection(section)
+}
+
+// Recipients returns the user's own address, followed by the subscription's additional ones
+func (r *Report) Recipients() MailAddresses {
+	recipients := MailAddresses{}
+	if r.User != nil && r.User.Email != "" {
+		recipients = append(recipients, MailAddress(r.User.Email))
+	}
+	if r.Subscription != nil {
+		for _, address := range r.Subscription.RecipientsList() {
+			recipients = append(recipients, MailAddress(address))
+		}
+	}
+	return recipients
 }
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/duke-git/lancet/v2/slice"
+)
+
+const (
+	ReportCadenceDaily   = "daily"
+	ReportCadenceWeekly  = "weekly"
+	ReportCadenceMonthly = "monthly"
+)
+
+const (
+	ReportSectionProjects         = "projects"
+	ReportSectionLanguages        = "languages"
+	ReportSectionEditors          = "editors"
+	ReportSectionOperatingSystems = "operating_systems"
+	ReportSectionMachines         = "machines"
+	ReportSectionDaily            = "daily"
+)
+
+// ReportSubscriptionMaxRecipients is the maximum number of addresses a report is sent to in addition to the user's own one
+const ReportSubscriptionMaxRecipients = 5
+
+// ReportSubscription is a user-defined e-mail report, sent at the end of every day, week (starting monday) or month in the user's time zone
+type ReportSubscription struct {
+	ID         uint        `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"-" gorm:"not null; index:idx_report_subscription_user"`
+	Cadence    string      `json:"cadence" gorm:"not null; size:16"`
+	Hour       int         `json:"hour" gorm:"not null"` // hour of day (in the user's time zone) to send the report at
+	Projects   string      `json:"-"`                    // comma-separated list of projects to report on, all if empty
+	Labels     string      `json:"-"`                    // comma-separated list of project labels to report on, all if empty
+	Recipients string      `json:"-"`                    // comma-separated list of additional e-mail addresses
+	Sections   string      `json:"-"`                    // comma-separated list of sections to include
+	LastSentAt *CustomTime `json:"last_sent_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt  CustomTime  `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func ReportCadences() []string {
+	return []string{ReportCadenceDaily, ReportCadenceWeekly, ReportCadenceMonthly}
+}
+
+func ReportSections() []string {
+	return []string{ReportSectionProjects, ReportSectionLanguages, ReportSectionEditors, ReportSectionOperatingSystems, ReportSectionMachines, ReportSectionDaily}
+}
+
+func (s *ReportSubscription) ProjectsList() []string {
+	return splitList(s.Projects)
+}
+
+func (s *ReportSubscription) LabelsList() []string {
+	return splitList(s.Labels)
+}
+
+func (s *ReportSubscription) RecipientsList() []string {
+	return splitList(s.Recipients)
+}
+
+func (s *ReportSubscription) SectionsList() []string {
+	return splitList(s.Sections)
+}
+
+func (s *ReportSubscription) HasSection(section string) bool {
+	return slice.Contain(s.SectionsList(), section)
+}
+
+// Filters returns the summary filters to generate the report with, or nil if it covers all coding activity
+func (s *ReportSubscription) Filters() *Filters {
+	filters := &Filters{}
+	if projects := s.ProjectsList(); len(projects) > 0 {
+		filters = filters.WithMultiple(SummaryProject, projects)
+	}
+	if labels := s.LabelsList(); len(labels) > 0 {
+		filters = filters.WithMultiple(SummaryLabel, labels)
+	}
+	if filters.IsEmpty() {
+		return nil
+	}
+	return filters
+}
+
+// PeriodBefore returns the start and end of the most recent complete day, week or month before the given time
+func (s *ReportSubscription) PeriodBefore(t time.Time) (time.Time, time.Time) {
+	switch s.Cadence {
+	case ReportCadenceWeekly:
+		to := datetime.BeginOfWeek(t, time.Monday)
+		return to.AddDate(0, 0, -7), to
+	case ReportCadenceMonthly:
+		to := datetime.BeginOfMonth(t)
+		return to.AddDate(0, -1, 0), to
+	default:
+		to := datetime.BeginOfDay(t)
+		return to.AddDate(0, 0, -1), to
+	}
+}
+
+// IsDue tells whether the report should be sent at the given time (in the user's time zone), i.e. whether a new period has started and the report for the previous one wasn't sent, yet
+func (s *ReportSubscription) IsDue(t time.Time) bool {
+	if t.Hour() != s.Hour {
+		return false
+	}
+	if s.Cadence == ReportCadenceWeekly && t.Weekday() != time.Monday {
+		return false
+	}
+	if s.Cadence == ReportCadenceMonthly && t.Day() != 1 {
+		return false
+	}
+	_, periodEnd := s.PeriodBefore(t)
+	return s.LastSentAt == nil || s.LastSentAt.T().Before(periodEnd)
+}
+
+func (s *ReportSubscription) IsValid() bool {
+	if !slice.Contain(ReportCadences(), s.Cadence) || s.Hour < 0 || s.Hour > 23 {
+		return false
+	}
+	sections := s.SectionsList()
+	if len(sections) == 0 {
+		return false
+	}
+	for _, section := range sections {
+		if !slice.Contain(ReportSections(), section) {
+			return false
+		}
+	}
+	recipients := s.RecipientsList()
+	if len(recipients) > ReportSubscriptionMaxRecipients {
+		return false
+	}
+	for _, r := range recipients {
+		if !MailAddress(r).Valid() || strings.ContainsAny(r, " <>") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportSubscription_PeriodBefore(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	ts := time.Date(2024, 5, 1, 8, 0, 0, 0, tz) // a wednesday
+
+	from, to := (&ReportSubscription{Cadence: ReportCadenceDaily}).PeriodBefore(ts)
+	assert.Equal(t, time.Date(2024, 4, 30, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, tz), to)
+
+	from, to = (&ReportSubscription{Cadence: ReportCadenceWeekly}).PeriodBefore(ts)
+	assert.Equal(t, time.Date(2024, 4, 22, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2024, 4, 29, 0, 0, 0, 0, tz), to)
+
+	from, to = (&ReportSubscription{Cadence: ReportCadenceMonthly}).PeriodBefore(ts)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, tz), from)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, tz), to)
+}
+
+func TestReportSubscription_IsDue(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	monday := time.Date(2024, 5, 6, 8, 0, 0, 0, tz)
+	tuesday := time.Date(2024, 5, 7, 8, 0, 0, 0, tz)
+	firstOfMonth := time.Date(2024, 6, 1, 8, 0, 0, 0, tz) // a saturday
+
+	daily := &ReportSubscription{Cadence: ReportCadenceDaily, Hour: 8}
+	assert.True(t, daily.IsDue(tuesday))
+	assert.False(t, daily.IsDue(tuesday.Add(time.Hour)))
+
+	sentAt := CustomTime(tuesday)
+	daily.LastSentAt = &sentAt
+	assert.False(t, daily.IsDue(tuesday))
+	assert.True(t, daily.IsDue(tuesday.AddDate(0, 0, 1)))
+
+	weekly := &ReportSubscription{Cadence: ReportCadenceWeekly, Hour: 8}
+	assert.True(t, weekly.IsDue(monday))
+	assert.False(t, weekly.IsDue(tuesday))
+
+	monthly := &ReportSubscription{Cadence: ReportCadenceMonthly, Hour: 8}
+	assert.True(t, monthly.IsDue(firstOfMonth))
+	assert.False(t, monthly.IsDue(monday))
+}
+
+func TestReportSubscription_IsValid(t *testing.T) {
+	assert.True(t, (&ReportSubscription{Cadence: ReportCadenceDaily, Hour: 8, Sections: "projects,daily"}).IsValid())
+	assert.True(t, (&ReportSubscription{Cadence: ReportCadenceMonthly, Hour: 0, Sections: "languages", Recipients: "boss@example.org"}).IsValid())
+	assert.False(t, (&ReportSubscription{Cadence: "yearly", Hour: 8, Sections: "projects"}).IsValid())
+	assert.False(t, (&ReportSubscription{Cadence: ReportCadenceDaily, Hour: 24, Sections: "projects"}).IsValid())
+	assert.False(t, (&ReportSubscription{Cadence: ReportCadenceDaily, Hour: 8}).IsValid())
+	assert.False(t, (&ReportSubscription{Cadence: ReportCadenceDaily, Hour: 8, Sections: "projects,branches"}).IsValid())
+	assert.False(t, (&ReportSubscription{Cadence: ReportCadenceDaily, Hour: 8, Sections: "projects", Recipients: "not-an-address"}).IsValid())
+	assert.False(t, (&ReportSubscription{Cadence: ReportCadenceDaily, Hour: 8, Sections: "projects", Recipients: "a@b.de,c@d.de,e@f.de,g@h.de,i@j.de,k@l.de"}).IsValid())
+}
+
+func TestReport_Recipients(t *testing.T) {
+	user := &User{ID: "testuser", Email: "user@example.org"}
+	assert.Equal(t, MailAddresses{"user@example.org"}, (&Report{User: user}).Recipients())
+
+	report := &Report{User: user, Subscription: &ReportSubscription{Recipients: "boss@example.org", Sections: "projects"}}
+	assert.Equal(t, MailAddresses{"user@example.org", "boss@example.org"}, report.Recipients())
+	assert.True(t, report.ShowSection(ReportSectionProjects))
+	assert.False(t, report.ShowSection(ReportSectionLanguages))
+	assert.True(t, (&Report{User: user}).ShowSection(ReportSectionLanguages))
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleAdmin  = "admin"
+	TeamRoleMember = "member"
+)
+
+type Team struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	Name      string     `json:"name" gorm:"not null; size:255"`
+	CreatedAt CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // filled by gorm, see https://gorm.io/docs/conventions.html#CreatedAt
+}
+
+type TeamMember struct {
+	ID        uint       `json:"-" gorm:"primary_key"`
+	Team      *Team      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	TeamID    uint       `json:"team_id" gorm:"not null; index:idx_team_member_team; uniqueIndex:idx_team_member_composite"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"user_id" gorm:"not null; index:idx_team_member_user; uniqueIndex:idx_team_member_composite"`
+	Role      string     `json:"role" gorm:"not null; size:32; default:member"`
+	CreatedAt CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// TeamMemberTotal is a member's contribution to a team summary
+type TeamMemberTotal struct {
+	UserID string
+	Total  time.Duration
+	Shared bool // false, if the member opted out of sharing any data for the requested interval
+}
+
+// TeamSummary is the sum of all team members' individual summaries, each redacted according to the respective user's sharing settings
+type TeamSummary struct {
+	*Summary
+	Members []*TeamMemberTotal
+}
+
+func (t *Team) IsValid() bool {
+	name := strings.TrimSpace(t.Name)
+	return len(name) >= 1 && len(name) <= 255
+}
+
+func (m *TeamMember) IsValid() bool {
+	return m.TeamID != 0 && m.UserID != "" && ValidateTeamRole(m.Role)
+}
+
+func (m *TeamMember) IsOwner() bool {
+	return m.Role == TeamRoleOwner
+}
+
+// CanManage returns whether the member is allowed to invite, remove or promote other members
+func (m *TeamMember) CanManage() bool {
+	return m.Role == TeamRoleOwner || m.Role == TeamRoleAdmin
+}
+
+func ValidateTeamRole(role string) bool {
+	return role == TeamRoleOwner || role == TeamRoleAdmin || role == TeamRoleMember
+}
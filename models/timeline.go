@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimelineGroupTypes maps the supported ways to group a timeline's rows to entity types
+var TimelineGroupTypes = map[string]uint8{
+	"project": SummaryProject,
+	"branch":  SummaryBranch,
+	"entity":  SummaryEntity,
+}
+
+// Timeline is a day's (or any other time range's) coding activity, laid out as bars on a time axis, one row per project (or branch or file)
+type Timeline struct {
+	From     time.Time
+	To       time.Time
+	Rows     []*TimelineRow
+	Sessions []*TimelineSession
+	Idle     []*TimelineBar // breaks between sessions
+}
+
+type TimelineRow struct {
+	Key   string
+	Total time.Duration
+	Bars  []*TimelineBar
+}
+
+type TimelineBar struct {
+	Start     time.Time
+	End       time.Time
+	Project   string
+	Languages []string
+}
+
+// TimelineSession is a span of activity without any breaks longer than the heartbeats timeout in between
+type TimelineSession struct {
+	Start time.Time
+	End   time.Time
+	Total time.Duration
+	Keys  []string
+}
+
+// NewTimeline builds a timeline from the given (sorted) durations, where consecutive durations of the same key are joined into a single bar and durations at most idleThreshold apart are joined into a session
+func NewTimeline(durations Durations, from, to time.Time, groupBy uint8, idleThreshold time.Duration, resolveAlias AliasResolver) *Timeline {
+	tz := from.Location()
+	timeline := &Timeline{
+		From:     from,
+		To:       to,
+		Rows:     []*TimelineRow{},
+		Sessions: []*TimelineSession{},
+		Idle:     []*TimelineBar{},
+	}
+
+	rows := make(map[string]*TimelineRow)
+
+	for _, d := range durations {
+		project := d.GetKey(SummaryProject)
+		if resolveAlias != nil {
+			project = resolveAlias(SummaryProject, project)
+		}
+
+		key := project
+		if groupBy == SummaryBranch {
+			key = fmt.Sprintf("%s / %s", project, d.GetKey(SummaryBranch))
+		} else if groupBy == SummaryEntity {
+			key = d.GetKey(SummaryEntity)
+		}
+
+		start, end := d.Time.T().In(tz), d.TimeEnd().In(tz)
+		language := d.GetKey(SummaryLanguage)
+
+		row, ok := rows[key]
+		if !ok {
+			row = &TimelineRow{Key: key, Bars: []*TimelineBar{}}
+			rows[key] = row
+			timeline.Rows = append(timeline.Rows, row)
+		}
+		row.Total += d.Duration
+
+		// bars
+		if n := len(row.Bars); n > 0 && !start.After(row.Bars[n-1].End.Add(time.Second)) {
+			bar := row.Bars[n-1]
+			if end.After(bar.End) {
+				bar.End = end
+			}
+			if !containsString(bar.Languages, language) {
+				bar.Languages = append(bar.Languages, language)
+			}
+		} else {
+			row.Bars = append(row.Bars, &TimelineBar{Start: start, End: end, Project: project, Languages: []string{language}})
+		}
+
+		// sessions
+		if n := len(timeline.Sessions); n > 0 && start.Sub(timeline.Sessions[n-1].End) <= idleThreshold {
+			session := timeline.Sessions[n-1]
+			if end.After(session.End) {
+				session.End = end
+			}
+			session.Total += d.Duration
+			if !containsString(session.Keys, key) {
+				session.Keys = append(session.Keys, key)
+			}
+		} else {
+			if n > 0 {
+				timeline.Idle = append(timeline.Idle, &TimelineBar{Start: timeline.Sessions[n-1].End, End: start})
+			}
+			timeline.Sessions = append(timeline.Sessions, &TimelineSession{Start: start, End: end, Total: d.Duration, Keys: []string{key}})
+		}
+	}
+
+	sort.SliceStable(timeline.Rows, func(i, j int) bool {
+		return timeline.Rows[i].Total > timeline.Rows[j].Total
+	})
+
+	return timeline
+}
+
+func (t *Timeline) Total() (total time.Duration) {
+	for _, s := range t.Sessions {
+		total += s.Total
+	}
+	return total
+}
+
+func (b *TimelineBar) Duration() time.Duration {
+	return b.End.Sub(b.Start)
+}
+
+func (b *TimelineBar) LanguagesString() string {
+	return strings.Join(b.Languages, ", ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
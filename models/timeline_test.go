@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTimeline(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, tz)
+	to := from.AddDate(0, 0, 1)
+	t0 := from.Add(10 * time.Hour)
+
+	durations := Durations{
+		{Time: CustomTime(t0.UTC()), Duration: 10 * time.Minute, Project: "wakapi", Language: "Go", Branch: "master"},
+		{Time: CustomTime(t0.Add(10 * time.Minute)), Duration: 5 * time.Minute, Project: "wakapi", Language: "JavaScript", Branch: "feature"},
+		{Time: CustomTime(t0.Add(15 * time.Minute)), Duration: 5 * time.Minute, Project: "wakapi-legacy", Language: "Go", Branch: "master"},
+		{Time: CustomTime(t0.Add(2 * time.Hour)), Duration: 20 * time.Minute, Project: "anchr", Language: "Go", Branch: "master"},
+	}
+
+	resolve := func(t uint8, k string) string {
+		if k == "wakapi-legacy" {
+			return "wakapi"
+		}
+		return k
+	}
+
+	timeline := NewTimeline(durations, from, to, SummaryProject, 10*time.Minute, resolve)
+
+	assert.Len(t, timeline.Rows, 2)
+	assert.Equal(t, "wakapi", timeline.Rows[0].Key)
+	assert.Equal(t, 20*time.Minute, timeline.Rows[0].Total)
+	assert.Len(t, timeline.Rows[0].Bars, 1)
+	assert.Equal(t, t0, timeline.Rows[0].Bars[0].Start)
+	assert.Equal(t, tz, timeline.Rows[0].Bars[0].Start.Location())
+	assert.Equal(t, 20*time.Minute, timeline.Rows[0].Bars[0].Duration())
+	assert.Equal(t, "Go, JavaScript", timeline.Rows[0].Bars[0].LanguagesString())
+
+	assert.Len(t, timeline.Sessions, 2)
+	assert.Equal(t, []string{"wakapi"}, timeline.Sessions[0].Keys)
+	assert.Equal(t, t0.Add(20*time.Minute), timeline.Sessions[0].End)
+	assert.Len(t, timeline.Idle, 1)
+	assert.Equal(t, 100*time.Minute, timeline.Idle[0].Duration())
+	assert.Equal(t, 40*time.Minute, timeline.Total())
+
+	timeline = NewTimeline(durations, from, to, SummaryBranch, 10*time.Minute, resolve)
+	assert.Len(t, timeline.Rows, 3)
+	assert.Equal(t, "anchr / master", timeline.Rows[0].Key)
+	assert.Equal(t, "wakapi / master", timeline.Rows[1].Key)
+	assert.Len(t, timeline.Rows[1].Bars, 2)
+	assert.Equal(t, []string{"wakapi / master", "wakapi / feature"}, timeline.Sessions[0].Keys)
+}
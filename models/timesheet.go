@@ -0,0 +1,189 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	TimesheetGroupDay  = "day"
+	TimesheetGroupWeek = "week"
+
+	TimesheetRoundingUp      = "up"
+	TimesheetRoundingDown    = "down"
+	TimesheetRoundingNearest = "nearest"
+
+	TimesheetFormatJson = "json"
+	TimesheetFormatCsv  = "csv"
+	TimesheetFormatHtml = "html"
+)
+
+// TimesheetMaxDays is the longest range a timesheet can cover
+const TimesheetMaxDays = 366
+
+// TimesheetParams specifies which projects and which range to bill and how
+type TimesheetParams struct {
+	From         time.Time
+	To           time.Time
+	Projects     []string           // projects to include, all if empty
+	Label        string             // project label to include all projects of, alternatively to projects
+	GroupBy      string             // day or week
+	Rounding     time.Duration      // increment to round each project's time per day or week to, no rounding if zero
+	RoundingMode string             // up, down or nearest
+	Rates        map[string]float64 // hourly rates by project label
+	DefaultRate  float64            // hourly rate for projects without a label or without a rate for any of their labels
+	Currency     string
+}
+
+func (p *TimesheetParams) IsValid() bool {
+	if !p.From.Before(p.To) || p.To.Sub(p.From) > TimesheetMaxDays*24*time.Hour {
+		return false
+	}
+	if p.GroupBy != TimesheetGroupDay && p.GroupBy != TimesheetGroupWeek {
+		return false
+	}
+	if p.RoundingMode != TimesheetRoundingUp && p.RoundingMode != TimesheetRoundingDown && p.RoundingMode != TimesheetRoundingNearest {
+		return false
+	}
+	if p.Rounding < 0 || p.Rounding > 24*time.Hour || p.DefaultRate < 0 {
+		return false
+	}
+	for _, r := range p.Rates {
+		if r < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Round rounds the given duration according to the timesheet's rounding rules
+func (p *TimesheetParams) Round(d time.Duration) time.Duration {
+	if p.Rounding <= 0 {
+		return d
+	}
+	switch p.RoundingMode {
+	case TimesheetRoundingUp:
+		return time.Duration(math.Ceil(float64(d)/float64(p.Rounding))) * p.Rounding
+	case TimesheetRoundingDown:
+		return d.Truncate(p.Rounding)
+	default:
+		return d.Round(p.Rounding)
+	}
+}
+
+// Rate returns the hourly rate for a project with the given labels, where the first label with a rate wins
+func (p *TimesheetParams) Rate(labels []string) (string, float64) {
+	for _, l := range labels {
+		if r, ok := p.Rates[l]; ok {
+			return l, r
+		}
+	}
+	if len(labels) > 0 {
+		return labels[0], p.DefaultRate
+	}
+	return "", p.DefaultRate
+}
+
+type Timesheet struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	GroupBy  string            `json:"group_by"`
+	Currency string            `json:"currency"`
+	Entries  []*TimesheetEntry `json:"entries"`
+}
+
+func (t *Timesheet) MarshalJSON() ([]byte, error) {
+	type alias Timesheet
+	return json.Marshal(&struct {
+		*alias
+		TotalDuration float64 `json:"total_duration"` // seconds
+		TotalBilled   float64 `json:"total_billed"`   // seconds
+		TotalAmount   float64 `json:"total_amount"`
+	}{
+		alias:         (*alias)(t),
+		TotalDuration: t.TotalDuration().Seconds(),
+		TotalBilled:   t.TotalBilled().Seconds(),
+		TotalAmount:   t.TotalAmount(),
+	})
+}
+
+// TimesheetEntry is the time spent on a single project within a day or week
+type TimesheetEntry struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Project  string        `json:"project"`
+	Label    string        `json:"label"`
+	Duration time.Duration `json:"-"`
+	Billed   time.Duration `json:"-"`
+	Rate     float64       `json:"rate"`
+}
+
+func (e *TimesheetEntry) MarshalJSON() ([]byte, error) {
+	type alias TimesheetEntry
+	return json.Marshal(&struct {
+		*alias
+		Duration float64 `json:"duration"` // seconds
+		Billed   float64 `json:"billed"`   // seconds
+		Amount   float64 `json:"amount"`
+	}{
+		alias:    (*alias)(e),
+		Duration: e.Duration.Seconds(),
+		Billed:   e.Billed.Seconds(),
+		Amount:   e.Amount(),
+	})
+}
+
+func (e *TimesheetEntry) Amount() float64 {
+	return math.Round(e.Billed.Hours()*e.Rate*100) / 100
+}
+
+func (t *Timesheet) TotalDuration() (total time.Duration) {
+	for _, e := range t.Entries {
+		total += e.Duration
+	}
+	return total
+}
+
+func (t *Timesheet) TotalBilled() (total time.Duration) {
+	for _, e := range t.Entries {
+		total += e.Billed
+	}
+	return total
+}
+
+func (t *Timesheet) TotalAmount() (total float64) {
+	for _, e := range t.Entries {
+		total += e.Amount()
+	}
+	return math.Round(total*100) / 100
+}
+
+// WriteCsv writes one line per entry, with durations in decimal hours
+func (t *Timesheet) WriteCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	records := [][]string{{"from", "to", "project", "label", "hours", "billed_hours", "rate", "amount", "currency"}}
+	for _, e := range t.Entries {
+		records = append(records, []string{
+			e.From.Format(time.DateOnly),
+			e.To.Add(-1 * time.Second).Format(time.DateOnly), // inclusive
+			e.Project,
+			e.Label,
+			fmt.Sprintf("%.2f", e.Duration.Hours()),
+			fmt.Sprintf("%.2f", e.Billed.Hours()),
+			fmt.Sprintf("%.2f", e.Rate),
+			fmt.Sprintf("%.2f", e.Amount()),
+			t.Currency,
+		})
+	}
+	return writer.WriteAll(records)
+}
+
+// Filename is a suggestion for how to name the timesheet when downloading it
+func (t *Timesheet) Filename(ext string) string {
+	return strings.Join([]string{"timesheet", t.From.Format(time.DateOnly), t.To.Add(-1 * time.Second).Format(time.DateOnly)}, "_") + "." + ext
+}
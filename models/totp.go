@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const TotpChallengeCookieKey = "wakapi_2fa"
+
+// TotpChallenge is kept in a short-lived, encrypted cookie in between a successful first login step (password or single sign-on) and entering a one-time code.
+// Only once the second step was passed, the actual auth cookie is set.
+type TotpChallenge struct {
+	UserId    string
+	Enroll    bool // user has no second factor set up yet, but it is mandatory on this server
+	ExpiresAt int64
+}
+
+type TotpLogin struct {
+	Code string `schema:"code"`
+}
+
+func (c *TotpChallenge) Expired(now time.Time) bool {
+	return now.Unix() > c.ExpiresAt
+}
+
+func (u *User) HasTotp() bool {
+	return u.TotpEnabled && u.TotpSecret != ""
+}
+
+func (u *User) RecoveryCodesLeft() int {
+	if u.TotpRecoveryCodes == "" {
+		return 0
+	}
+	return len(strings.Split(u.TotpRecoveryCodes, ","))
+}
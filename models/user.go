@@ -39,6 +39,7 @@ type User struct {
 	ShareLabels            bool        `json:"-" gorm:"default:false; type:bool"`
 	ShareActivityChart     bool        `json:"-" gorm:"default:false; type:bool"`
 	IsAdmin                bool        `json:"-" gorm:"default:false; type:bool"`
+	IsDisabled             bool        `json:"-" gorm:"default:false; type:bool"` // disabled users can neither log in nor send heartbeats, set by an admin
 	HasData                bool        `json:"-" gorm:"default:false; type:bool"`
 	WakatimeApiKey         string      `json:"-"` // for relay middleware and imports
 	WakatimeApiUrl         string      `json:"-"` // for relay middleware and imports
@@ -50,7 +51,11 @@ type User struct {
 	StripeCustomerId       string      `json:"-"`
 	InvitedBy              string      `json:"-"`
 	ExcludeUnknownProjects bool        `json:"-"`
-	HeartbeatsTimeoutSec   int         `json:"-" gorm:"default:600"` // https://github.com/muety/wakapi/issues/156
+	HeartbeatsTimeoutSec   int         `json:"-" gorm:"default:600"`              // https://github.com/muety/wakapi/issues/156
+	TotpSecret             string      `json:"-"`                                 // base32-encoded, set upon starting two-factor enrollment
+	TotpEnabled            bool        `json:"-" gorm:"default:false; type:bool"` // only true after enrollment was confirmed with a valid code
+	TotpRecoveryCodes      string      `json:"-"`                                 // comma-separated sha256 hashes of unused recovery codes
+	TotpLastCounter        int64       `json:"-"`                                 // time step of the last accepted code, to prevent replays
 }
 
 type Login struct {
@@ -74,6 +79,7 @@ type SetPasswordRequest struct {
 	Password       string `schema:"password"`
 	PasswordRepeat string `schema:"password_repeat"`
 	Token          string `schema:"token"`
+	Code           string `schema:"code"` // one-time or recovery code, required if two-factor authentication is enabled
 }
 
 type ResetPasswordRequest struct {
@@ -194,6 +200,28 @@ func (u *User) AnyDataShared() bool {
 	return u.ShareDataMaxDays != 0 && (u.ShareEditors || u.ShareLanguages || u.ShareProjects || u.ShareOSs || u.ShareMachines || u.ShareLabels)
 }
 
+// SharesSummaryType returns whether the user publicly shares statistics of the given summary type (e.g. SummaryLanguage)
+func (u *User) SharesSummaryType(entityType uint8) bool {
+	if u.ShareDataMaxDays == 0 {
+		return false
+	}
+	switch entityType {
+	case SummaryProject, SummaryBranch, SummaryEntity:
+		return u.ShareProjects
+	case SummaryLanguage:
+		return u.ShareLanguages
+	case SummaryEditor:
+		return u.ShareEditors
+	case SummaryOS:
+		return u.ShareOSs
+	case SummaryMachine:
+		return u.ShareMachines
+	case SummaryLabel:
+		return u.ShareLabels
+	}
+	return true
+}
+
 func (c *CredentialsReset) IsValid() bool {
 	return ValidatePassword(c.PasswordNew) &&
 		c.PasswordNew == c.PasswordRepeat
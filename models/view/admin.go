@@ -0,0 +1,28 @@
+package view
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+type AdminViewModel struct {
+	SharedLoggedInViewModel
+	Users                []*AdminUserItem
+	Queues               []*config.JobQueueMetrics
+	SubscriptionsEnabled bool
+}
+
+type AdminUserItem struct {
+	User       *models.User
+	Heartbeats int64
+}
+
+func (s *AdminViewModel) WithSuccess(m string) *AdminViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *AdminViewModel) WithError(m string) *AdminViewModel {
+	s.SetError(m)
+	return s
+}
@@ -6,11 +6,22 @@ type LoginViewModel struct {
 	AllowSignup bool
 	CaptchaId   string
 	InviteCode  string
+	OidcEnabled bool
+	OidcName    string
 }
 
 type SetPasswordViewModel struct {
 	LoginViewModel
-	Token string
+	Token       string
+	RequireTotp bool // user has two-factor authentication enabled and needs to confirm the reset with a code
+}
+
+type TotpLoginViewModel struct {
+	LoginViewModel
+	Enroll        bool     // user has to set up two-factor authentication first
+	QrCode        string   // data uri of the qr code to scan during enrollment
+	Secret        string   // for manual entry during enrollment
+	RecoveryCodes []string // only set right after enrollment, as they can not be retrieved afterward
 }
 
 func (s *LoginViewModel) WithSuccess(m string) *LoginViewModel {
@@ -22,3 +33,13 @@ func (s *LoginViewModel) WithError(m string) *LoginViewModel {
 	s.SetError(m)
 	return s
 }
+
+func (s *TotpLoginViewModel) WithSuccess(m string) *TotpLoginViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TotpLoginViewModel) WithError(m string) *TotpLoginViewModel {
+	s.SetError(m)
+	return s
+}
@@ -2,18 +2,42 @@ package view
 
 import (
 	"fmt"
+	"image/color"
+	"net/url"
+	"time"
+
 	"github.com/duke-git/lancet/v2/mathutil"
 	"github.com/duke-git/lancet/v2/slice"
+	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/utils"
-	"image/color"
 )
 
 type ProjectsViewModel struct {
 	SharedLoggedInViewModel
-	Projects   []*models.ProjectStats
-	PageParams *utils.PageParams
-	maxCount   int64
+	Projects      []*models.ProjectStats
+	PageParams    *utils.PageParams
+	TotalProjects int
+	ShowArchived  bool
+	maxCount      int64
+}
+
+type ProjectViewModel struct {
+	SharedLoggedInViewModel
+	Project       string
+	FirstActivity time.Time
+	LastActivity  time.Time
+	Summary       *models.Summary
+	Trend         []*ProjectTrendItem
+	Archived      bool
+	Rewriting     bool // whether a rename or merge job of the user is in progress
+	OtherProjects []string
+}
+
+// ProjectTrendItem is a project's total coding time within one week
+type ProjectTrendItem struct {
+	From  time.Time
+	Total time.Duration
 }
 
 func (s *ProjectsViewModel) LangIcon(lang string) string {
@@ -26,6 +50,18 @@ func (s *ProjectsViewModel) BackgroundIntensity(idx int) string {
 	return fadeColorToTransparent("#047857", intensity)
 }
 
+func (s *ProjectsViewModel) TotalPages() int {
+	return mathutil.Max((s.TotalProjects+s.PageParams.PageSize-1)/s.PageParams.PageSize, 1)
+}
+
+func (s *ProjectsViewModel) HasPreviousPage() bool {
+	return s.PageParams.Page > 1
+}
+
+func (s *ProjectsViewModel) HasNextPage() bool {
+	return s.PageParams.Page < s.TotalPages()
+}
+
 func (s *ProjectsViewModel) WithSuccess(m string) *ProjectsViewModel {
 	s.SetSuccess(m)
 	return s
@@ -45,6 +81,85 @@ func (s *ProjectsViewModel) getMaxCount() int64 {
 	return mathutil.Max(s.maxCount, 1)
 }
 
+func (s *ProjectViewModel) LangIcon(lang string) string {
+	return GetLanguageIcon(lang)
+}
+
+func (s *ProjectViewModel) TotalTime() time.Duration {
+	return s.Summary.TotalTime()
+}
+
+func (s *ProjectViewModel) TopLanguages() models.SummaryItems {
+	return s.topItems(models.SummaryLanguage)
+}
+
+func (s *ProjectViewModel) TopBranches() models.SummaryItems {
+	return s.topItems(models.SummaryBranch)
+}
+
+func (s *ProjectViewModel) TopEntities() models.SummaryItems {
+	return s.topItems(models.SummaryEntity)
+}
+
+func (s *ProjectViewModel) TopMachines() models.SummaryItems {
+	return s.topItems(models.SummaryMachine)
+}
+
+// Percentage returns the share of the given item in the project's total coding time
+func (s *ProjectViewModel) Percentage(item *models.SummaryItem) int {
+	total := s.TotalTime()
+	if total == 0 {
+		return 0
+	}
+	return int(item.TotalFixed() * 100 / total)
+}
+
+// TrendPercentage returns the given week's coding time relative to the most active week's one
+func (s *ProjectViewModel) TrendPercentage(item *ProjectTrendItem) int {
+	maxTotal := mathutil.Max(slice.Map[*ProjectTrendItem, time.Duration](s.Trend, func(i int, t *ProjectTrendItem) time.Duration {
+		return t.Total
+	})...)
+	if maxTotal == 0 {
+		return 0
+	}
+	return int(item.Total * 100 / maxTotal)
+}
+
+func (s *ProjectViewModel) SummaryUrl() string {
+	return fmt.Sprintf("summary?interval=any&project=%s", url.QueryEscape(s.Project))
+}
+
+// ActivityChartUrl links to the activity chart, filtered to the project, for the year up to its last activity
+func (s *ProjectViewModel) ActivityChartUrl() string {
+	to := s.LastActivity.In(s.User.TZ()).AddDate(0, 0, 1)
+	query := url.Values{}
+	query.Set("project", s.Project)
+	query.Set("from", to.AddDate(-1, 0, 0).Format(conf.SimpleDateFormat))
+	query.Set("to", to.Format(conf.SimpleDateFormat))
+	return fmt.Sprintf("api/activity/chart/%s.svg?dark&noattr&%s", url.PathEscape(s.User.ID), query.Encode())
+}
+
+func (s *ProjectViewModel) WithSuccess(m string) *ProjectViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *ProjectViewModel) WithError(m string) *ProjectViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *ProjectViewModel) topItems(entityType uint8) models.SummaryItems {
+	if s.Summary == nil {
+		return models.SummaryItems{}
+	}
+	items := *s.Summary.GetByType(entityType)
+	if len(items) > 10 {
+		return items[:10]
+	}
+	return items
+}
+
 func fadeColorToTransparent(colorHex string, transparency float64) string {
 	left := utils.HexToRGBA(colorHex)
 	right := &color.RGBA{R: left.R, G: left.G, B: left.B, A: uint8(transparency * 255)}
@@ -8,6 +8,7 @@ import (
 type SettingsViewModel struct {
 	SharedLoggedInViewModel
 	LanguageMappings      []*models.LanguageMapping
+	HeartbeatRules        []*models.HeartbeatRule
 	Aliases               []*SettingsVMCombinedAlias
 	Labels                []*SettingsVMCombinedLabel
 	Projects              []string
@@ -16,6 +17,19 @@ type SettingsViewModel struct {
 	UserFirstData         time.Time
 	SupportContact        string
 	InviteLink            string
+	ApiTokens             []*models.ApiToken
+	NewApiToken           string // only set right after creating a token, as it can not be retrieved afterward
+	Webhooks              []*SettingsVMWebhook
+	RelayTargets          []*SettingsVMRelayTarget
+	Goals                 []*models.Goal
+	ReportSubscriptions   []*models.ReportSubscription
+	NotificationChannels  []*models.NotificationChannel
+	OidcIdentities        []*models.OidcIdentity
+	OidcEnabled           bool
+	OidcName              string
+	TotpRequired          bool     // two-factor authentication is mandatory for this user and can not be disabled
+	TotpQrCode            string   // data uri of the qr code to scan, only set during a pending setup
+	RecoveryCodes         []string // only set right after enabling two-factor authentication or regenerating the codes
 	ReadmeCardCustomTitle string
 }
 
@@ -25,6 +39,16 @@ type SettingsVMCombinedAlias struct {
 	Values []string
 }
 
+type SettingsVMWebhook struct {
+	*models.Webhook
+	Deliveries []*models.WebhookDelivery
+}
+
+type SettingsVMRelayTarget struct {
+	*models.RelayTarget
+	Queued int64 // number of heartbeat batches waiting to be retried
+}
+
 type SettingsVMCombinedLabel struct {
 	Key    string
 	Values []string
@@ -43,3 +67,68 @@ func (s *SettingsViewModel) WithError(m string) *SettingsViewModel {
 	s.SetError(m)
 	return s
 }
+
+func (s *SettingsViewModel) ApiTokenScopes() []string {
+	return models.ApiTokenScopes()
+}
+
+func (s *SettingsViewModel) WebhookEvents() []string {
+	return models.WebhookEvents()
+}
+
+func (s *SettingsViewModel) GoalPeriods() []string {
+	return models.GoalPeriods()
+}
+
+func (s *SettingsViewModel) ReportCadences() []string {
+	return models.ReportCadences()
+}
+
+func (s *SettingsViewModel) ReportSections() []string {
+	return models.ReportSections()
+}
+
+func (s *SettingsViewModel) ReportHours() []int {
+	hours := make([]int, 24)
+	for i := range hours {
+		hours[i] = i
+	}
+	return hours
+}
+
+func (s *SettingsViewModel) ReportSubscriptionMaxRecipients() int {
+	return models.ReportSubscriptionMaxRecipients
+}
+
+func (s *SettingsViewModel) HeartbeatRuleFields() []string {
+	return models.HeartbeatRuleFields()
+}
+
+func (s *SettingsViewModel) HeartbeatRuleMatchTypes() []string {
+	return models.HeartbeatRuleMatchTypes()
+}
+
+func (s *SettingsViewModel) HeartbeatRuleActions() []string {
+	return models.HeartbeatRuleActions()
+}
+
+func (s *SettingsViewModel) NotificationChannelTypes() []string {
+	return models.NotificationChannelTypes()
+}
+
+func (s *SettingsViewModel) NotificationTypes() []string {
+	return models.NotificationTypes()
+}
+
+// HasReportChannel tells whether the user can receive reports, either by e-mail or on a notification channel
+func (s *SettingsViewModel) HasReportChannel() bool {
+	if s.User.Email != "" {
+		return true
+	}
+	for _, c := range s.NotificationChannels {
+		if c.Subscribes(models.NotificationTypeReport) {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,7 @@ type SummaryViewModel struct {
 	LanguageColors      map[string]string
 	OSColors            map[string]string
 	DailyStats          []*DailyProjectsViewModel
+	Goals               []*models.GoalProgress
 	RawQuery            string
 	UserFirstData       time.Time
 	DataRetentionMonths int
@@ -0,0 +1,85 @@
+package view
+
+import (
+	"github.com/muety/wakapi/models"
+)
+
+var teamIntervals = []*models.IntervalKey{
+	models.IntervalToday,
+	models.IntervalThisWeek,
+	models.IntervalPast7Days,
+	models.IntervalPast30Days,
+	models.IntervalPast6Months,
+}
+
+type TeamsViewModel struct {
+	SharedLoggedInViewModel
+	Teams       []*models.Team
+	InvitedTeam *models.Team // team to confirm joining, if the page was opened through an invite link
+	InviteCode  string
+}
+
+type TeamViewModel struct {
+	SharedLoggedInViewModel
+	Team        *models.Team
+	Membership  *models.TeamMember
+	Members     []*models.TeamMember
+	Summary     *models.TeamSummary
+	Leaderboard []*models.LeaderboardItemRanked
+	Interval    *models.IntervalKey
+	InviteLink  string
+}
+
+func (s *TeamsViewModel) WithSuccess(m string) *TeamsViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TeamsViewModel) WithError(m string) *TeamsViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *TeamViewModel) WithSuccess(m string) *TeamViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TeamViewModel) WithError(m string) *TeamViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *TeamViewModel) Intervals() []*models.IntervalKey {
+	return teamIntervals
+}
+
+func (s *TeamViewModel) IntervalLabel() string {
+	if s.Interval == nil {
+		return ""
+	}
+	return s.Interval.GetHumanReadable()
+}
+
+func (s *TeamViewModel) LangIcon(lang string) string {
+	return GetLanguageIcon(lang)
+}
+
+func (s *TeamViewModel) TopProjects() models.SummaryItems {
+	return s.topItems(models.SummaryProject)
+}
+
+func (s *TeamViewModel) TopLanguages() models.SummaryItems {
+	return s.topItems(models.SummaryLanguage)
+}
+
+func (s *TeamViewModel) topItems(entityType uint8) models.SummaryItems {
+	if s.Summary == nil {
+		return models.SummaryItems{}
+	}
+	items := *s.Summary.GetByType(entityType)
+	if len(items) > 10 {
+		return items[:10]
+	}
+	return items
+}
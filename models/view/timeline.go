@@ -0,0 +1,69 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+type TimelineViewModel struct {
+	SharedLoggedInViewModel
+	Date     time.Time
+	Today    time.Time
+	GroupBy  string
+	Timeline *models.Timeline
+}
+
+func (s *TimelineViewModel) DateString() string {
+	return s.Date.Format(conf.SimpleDateFormat)
+}
+
+func (s *TimelineViewModel) PrevDate() string {
+	return s.Date.AddDate(0, 0, -1).Format(conf.SimpleDateFormat)
+}
+
+func (s *TimelineViewModel) NextDate() string {
+	return s.Date.AddDate(0, 0, 1).Format(conf.SimpleDateFormat)
+}
+
+func (s *TimelineViewModel) HasNext() bool {
+	return s.Date.Before(s.Today)
+}
+
+// Hours returns the hours of day to label the time axis with
+func (s *TimelineViewModel) Hours() []int {
+	return []int{0, 3, 6, 9, 12, 15, 18, 21}
+}
+
+func (s *TimelineViewModel) HourStyle(hour int) string {
+	return fmt.Sprintf("left: %.2f%%;", s.offset(s.Date.Add(time.Duration(hour)*time.Hour)))
+}
+
+// BarStyle positions a bar relative to the entire day
+func (s *TimelineViewModel) BarStyle(bar *models.TimelineBar) string {
+	left, right := s.offset(bar.Start), s.offset(bar.End)
+	return fmt.Sprintf("left: %.2f%%; width: max(%.2f%%, 2px);", left, right-left)
+}
+
+func (s *TimelineViewModel) WithSuccess(m string) *TimelineViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TimelineViewModel) WithError(m string) *TimelineViewModel {
+	s.SetError(m)
+	return s
+}
+
+func (s *TimelineViewModel) offset(t time.Time) float64 {
+	from, to := s.Timeline.From, s.Timeline.To
+	if t.Before(from) {
+		t = from
+	}
+	if t.After(to) {
+		t = to
+	}
+	return float64(t.Sub(from)) / float64(to.Sub(from)) * 100
+}
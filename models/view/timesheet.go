@@ -0,0 +1,81 @@
+package view
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+type TimesheetViewModel struct {
+	SharedLoggedInViewModel
+	Params    *models.TimesheetParams
+	Timesheet *models.Timesheet
+	Labels    []string
+	Query     url.Values
+}
+
+func (s *TimesheetViewModel) FromString() string {
+	return s.Params.From.Format(conf.SimpleDateFormat)
+}
+
+// ToString returns the timesheet's inclusive end date
+func (s *TimesheetViewModel) ToString() string {
+	return s.Params.To.Add(-1 * time.Second).Format(conf.SimpleDateFormat)
+}
+
+func (s *TimesheetViewModel) ProjectsString() string {
+	return strings.Join(s.Params.Projects, ",")
+}
+
+func (s *TimesheetViewModel) RatesString() string {
+	rates := make([]string, 0, len(s.Params.Rates))
+	for label, rate := range s.Params.Rates {
+		rates = append(rates, fmt.Sprintf("%s:%g", label, rate))
+	}
+	sort.Strings(rates)
+	return strings.Join(rates, ",")
+}
+
+func (s *TimesheetViewModel) RoundingMinutes() int {
+	return int(s.Params.Rounding / time.Minute)
+}
+
+// RoundingOptions lists the selectable rounding increments in minutes
+func (s *TimesheetViewModel) RoundingOptions() []int {
+	return []int{0, 5, 6, 10, 15, 30, 60}
+}
+
+// Url links to the given timesheet export with the currently selected params
+func (s *TimesheetViewModel) Url(path string, extraParams ...string) string {
+	query := url.Values{}
+	for k, v := range s.Query {
+		query[k] = v
+	}
+	for i := 0; i+1 < len(extraParams); i += 2 {
+		query.Set(extraParams[i], extraParams[i+1])
+	}
+	return fmt.Sprintf("%s?%s", path, query.Encode())
+}
+
+func (s *TimesheetViewModel) PeriodString(e *models.TimesheetEntry) string {
+	from, to := e.From.Format(conf.SimpleDateFormat), e.To.Add(-1*time.Second).Format(conf.SimpleDateFormat)
+	if from == to {
+		return from
+	}
+	return fmt.Sprintf("%s – %s", from, to)
+}
+
+func (s *TimesheetViewModel) WithSuccess(m string) *TimesheetViewModel {
+	s.SetSuccess(m)
+	return s
+}
+
+func (s *TimesheetViewModel) WithError(m string) *TimesheetViewModel {
+	s.SetError(m)
+	return s
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+)
+
+// WebhookEventPing is sent when manually testing a webhook, it is not published on the event bus
+const WebhookEventPing = "ping"
+
+const (
+	WebhookHeaderEvent     = "X-Wakapi-Event"
+	WebhookHeaderDelivery  = "X-Wakapi-Delivery"
+	WebhookHeaderSignature = "X-Wakapi-Signature"
+)
+
+// Webhook is an http endpoint to notify about application events. Webhooks without a user (only creatable by admins) receive events of all users.
+type Webhook struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    *string    `json:"-" gorm:"index:idx_webhook_user"`
+	Url       string     `json:"url" gorm:"not null"`
+	Secret    string     `json:"-" gorm:"not null; size:64"` // key to sign payloads with, see Sign()
+	Events    string     `json:"events" gorm:"not null"`     // comma-separated list of event names
+	Enabled   bool       `json:"enabled" gorm:"default:true; type:bool"`
+	CreatedAt CustomTime `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// WebhookDelivery is a log entry for a single attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID         uint       `json:"id" gorm:"primary_key"`
+	Webhook    *Webhook   `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	WebhookID  uint       `json:"-" gorm:"not null; index:idx_webhook_delivery_webhook"`
+	DeliveryID string     `json:"delivery_id" gorm:"size:36"` // shared by all attempts of the same delivery
+	Event      string     `json:"event" gorm:"size:64"`
+	Attempt    int        `json:"attempt"`
+	StatusCode int        `json:"status_code"`
+	Error      string     `json:"error"`
+	Success    bool       `json:"success" gorm:"type:bool"`
+	DurationMs int64      `json:"duration_ms"`
+	CreatedAt  CustomTime `json:"created_at" gorm:"index:idx_webhook_delivery_created" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// WebhookPayload is the json body posted to webhook urls
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	UserID    string      `json:"user_id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookUserData is the representation of a user within webhook payloads, which, unlike the user itself, doesn't include any secrets
+type WebhookUserData struct {
+	ID        string     `json:"id"`
+	Email     string     `json:"email"`
+	Location  string     `json:"location"`
+	CreatedAt CustomTime `json:"created_at"`
+}
+
+func WebhookEvents() []string {
+	return []string{
+		config.EventHeartbeatCreate,
+		config.EventSummaryCreate,
+		config.EventUserUpdate,
+		config.EventUserDelete,
+		config.EventProjectLabelCreate,
+		config.EventProjectLabelDelete,
+		config.EventLanguageMappingsChanged,
+		config.EventWakatimeFailure,
+	}
+}
+
+func ValidateWebhookEvent(event string) bool {
+	return slice.Contain(WebhookEvents(), event)
+}
+
+func NewWebhookUserData(user *User) *WebhookUserData {
+	return &WebhookUserData{ID: user.ID, Email: user.Email, Location: user.Location, CreatedAt: user.CreatedAt}
+}
+
+func (w *Webhook) EventsList() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+func (w *Webhook) Subscribes(event string) bool {
+	return event == WebhookEventPing || slice.Contain(w.EventsList(), event)
+}
+
+func (w *Webhook) IsGlobal() bool {
+	return w.UserID == nil
+}
+
+// Sign computes the hex-encoded hmac-sha256 of the given payload, to be sent as "sha256=<signature>" for receivers to verify authenticity
+func (w *Webhook) Sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsValid checks the webhook's url and events. Webhooks of individual users must not point to local or private hosts, only admins' global webhooks may.
+func (w *Webhook) IsValid() bool {
+	u, err := url.Parse(w.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	if !w.IsGlobal() && !utils.IsPublicHost(u.Hostname()) {
+		return false
+	}
+	events := w.EventsList()
+	return len(events) > 0 && slice.Every[string](events, func(i int, e string) bool { return ValidateWebhookEvent(e) })
+}
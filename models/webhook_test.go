@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_IsValid(t *testing.T) {
+	userId := "testuser"
+
+	assert.True(t, (&Webhook{UserID: &userId, Url: "https://example.org/hook", Events: config.EventSummaryCreate}).IsValid())
+	assert.False(t, (&Webhook{UserID: &userId, Url: "ftp://example.org/hook", Events: config.EventSummaryCreate}).IsValid())
+	assert.False(t, (&Webhook{UserID: &userId, Url: "https://example.org/hook", Events: "foo"}).IsValid())
+	assert.False(t, (&Webhook{UserID: &userId, Url: "https://example.org/hook"}).IsValid())
+
+	// only global webhooks, created by admins, may point to local or private hosts
+	for _, u := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://192.168.0.10/hook", "http://169.254.169.254/latest/meta-data"} {
+		assert.False(t, (&Webhook{UserID: &userId, Url: u, Events: config.EventSummaryCreate}).IsValid(), u)
+		assert.True(t, (&Webhook{Url: u, Events: config.EventSummaryCreate}).IsValid(), u)
+	}
+}
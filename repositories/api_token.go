@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ApiTokenRepository struct {
+	BaseRepository
+}
+
+func NewApiTokenRepository(db *gorm.DB) *ApiTokenRepository {
+	return &ApiTokenRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *ApiTokenRepository) GetByUser(userId string) ([]*models.ApiToken, error) {
+	if userId == "" {
+		return []*models.ApiToken{}, nil
+	}
+	var tokens []*models.ApiToken
+	if err := r.db.
+		Where(&models.ApiToken{UserID: userId}).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *ApiTokenRepository) GetByHash(tokenHash string) (*models.ApiToken, error) {
+	if tokenHash == "" {
+		return nil, errors.New("invalid input")
+	}
+	token := &models.ApiToken{}
+	if err := r.db.
+		Where(&models.ApiToken{TokenHash: tokenHash}).
+		First(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *ApiTokenRepository) Insert(token *models.ApiToken) (*models.ApiToken, error) {
+	if !token.IsValid() {
+		return nil, errors.New("invalid api token")
+	}
+	if err := r.db.Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *ApiTokenRepository) UpdateLastUsed(token *models.ApiToken, t time.Time) error {
+	return r.db.
+		Model(&models.ApiToken{}).
+		Where("id = ?", token.ID).
+		Update("last_used_at", t).Error
+}
+
+func (r *ApiTokenRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.ApiToken{}).Error
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ArchivedProjectRepository struct {
+	BaseRepository
+}
+
+func NewArchivedProjectRepository(db *gorm.DB) *ArchivedProjectRepository {
+	return &ArchivedProjectRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *ArchivedProjectRepository) GetByUser(userId string) ([]*models.ArchivedProject, error) {
+	if userId == "" {
+		return []*models.ArchivedProject{}, nil
+	}
+	var projects []*models.ArchivedProject
+	if err := r.db.
+		Where(&models.ArchivedProject{UserID: userId}).
+		Order("project_key asc").
+		Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *ArchivedProjectRepository) Insert(project *models.ArchivedProject) (*models.ArchivedProject, error) {
+	if !project.IsValid() {
+		return nil, errors.New("invalid archived project")
+	}
+	if err := r.db.Create(project).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (r *ArchivedProjectRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.ArchivedProject{}).Error
+}
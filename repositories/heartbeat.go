@@ -140,6 +140,7 @@ func (r *HeartbeatRepository) GetLatestByFilters(user *models.User, filterMap ma
 	var heartbeat *models.Heartbeat
 
 	q := r.db.
+		Model(&models.Heartbeat{}).
 		Where(&models.Heartbeat{UserID: user.ID}).
 		Order("time desc")
 	q = filteredQuery(q, filterMap)
@@ -150,6 +151,21 @@ func (r *HeartbeatRepository) GetLatestByFilters(user *models.User, filterMap ma
 	return heartbeat, nil
 }
 
+func (r *HeartbeatRepository) GetFirstByFilters(user *models.User, filterMap map[string][]string) (*models.Heartbeat, error) {
+	var heartbeat *models.Heartbeat
+
+	q := r.db.
+		Model(&models.Heartbeat{}).
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Order("time asc")
+	q = filteredQuery(q, filterMap)
+
+	if err := q.Limit(1).Scan(&heartbeat).Error; err != nil {
+		return nil, err
+	}
+	return heartbeat, nil
+}
+
 func (r *HeartbeatRepository) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	var result []*models.TimeByUser
 	r.db.Raw("with agg as (select " + utils.QuoteSql(r.db, "user_id, min(time) as %s", "time") + " from heartbeats group by user_id) " +
@@ -254,6 +270,42 @@ func (r *HeartbeatRepository) DeleteByUser(user *models.User) error {
 	return nil
 }
 
+// DeleteDuplicates removes heartbeats of the given user that only differ by their id (and hash), e.g. as a result of imports from before hashes were introduced.
+// Of each set of duplicates, the one with the highest id is kept.
+func (r *HeartbeatRepository) DeleteDuplicates(user *models.User) (int64, error) {
+	duplicates := r.db.
+		Table("heartbeats t1").
+		Select("t1.id").
+		Joins("INNER JOIN heartbeats t2 ON t1.user_id = t2.user_id AND t1.id < t2.id AND t1.time = t2.time AND t1.entity = t2.entity AND t1.is_write = t2.is_write AND t1.branch = t2.branch AND t1.editor = t2.editor AND t1.machine = t2.machine AND t1.operating_system = t2.operating_system").
+		Where("t1.user_id = ?", user.ID)
+
+	// wrapped in a derived table, because mysql doesn't allow to delete from a table that is also referenced in a sub query
+	result := r.db.
+		Where("id IN (?)", r.db.Table("(?) as dups", duplicates).Select("id")).
+		Delete(models.Heartbeat{})
+	return result.RowsAffected, result.Error
+}
+
+// RenameProjects permanently assigns all of a user's heartbeats of the given projects to the target project.
+// Hashes are left untouched on purpose, so that heartbeats re-sent by clients (e.g. from their offline queue) are still recognized as duplicates.
+func (r *HeartbeatRepository) RenameProjects(user *models.User, projects []string, target string) (int64, error) {
+	result := r.db.
+		Model(&models.Heartbeat{}).
+		Where("user_id = ?", user.ID).
+		Where("project IN ?", projects).
+		Update("project", target)
+	return result.RowsAffected, result.Error
+}
+
+// SyncIdSequence advances the primary key sequence past the highest id after heartbeats were inserted with explicit ids.
+// Only required for postgres, other dialects take care of this on their own.
+func (r *HeartbeatRepository) SyncIdSequence() error {
+	if !r.config.Db.IsPostgres() {
+		return nil
+	}
+	return r.db.Exec("SELECT setval(pg_get_serial_sequence('heartbeats', 'id'), COALESCE((SELECT MAX(id) FROM heartbeats), 0) + 1, false)").Error
+}
+
 func (r *HeartbeatRepository) DeleteByUserBefore(user *models.User, t time.Time) error {
 	if err := r.db.
 		Where("user_id = ?", user.ID).
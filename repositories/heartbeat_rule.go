@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type HeartbeatRuleRepository struct {
+	BaseRepository
+}
+
+func NewHeartbeatRuleRepository(db *gorm.DB) *HeartbeatRuleRepository {
+	return &HeartbeatRuleRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *HeartbeatRuleRepository) GetById(id uint) (*models.HeartbeatRule, error) {
+	rule := &models.HeartbeatRule{}
+	if err := r.db.Where("id = ?", id).First(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *HeartbeatRuleRepository) GetByUser(userId string) ([]*models.HeartbeatRule, error) {
+	if userId == "" {
+		return []*models.HeartbeatRule{}, nil
+	}
+	var rules []*models.HeartbeatRule
+	if err := r.db.
+		Where(&models.HeartbeatRule{UserID: userId}).
+		Order("created_at asc").
+		Order("id asc").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *HeartbeatRuleRepository) Insert(rule *models.HeartbeatRule) (*models.HeartbeatRule, error) {
+	if !rule.IsValid() {
+		return nil, errors.New("invalid heartbeat rule")
+	}
+	if err := r.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *HeartbeatRuleRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.HeartbeatRule{}).Error
+}
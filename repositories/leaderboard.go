@@ -29,13 +29,14 @@ func (r *LeaderboardRepository) CountAllByUser(userId string) (int64, error) {
 	err := r.db.
 		Table("leaderboard_items").
 		Where("user_id = ?", userId).
+		Where("team_id is null").
 		Count(&count).Error
 	return count, err
 }
 
 func (r *LeaderboardRepository) CountUsers(excludeZero bool) (int64, error) {
 	var count int64
-	q := r.db.Table("leaderboard_items").Distinct("user_id")
+	q := r.db.Table("leaderboard_items").Distinct("user_id").Where("team_id is null")
 	if excludeZero {
 		q = q.Where("total > 0")
 	}
@@ -44,6 +45,14 @@ func (r *LeaderboardRepository) CountUsers(excludeZero bool) (int64, error) {
 }
 
 func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalKey, by *uint8, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
+	return r.getAllAggregatedByTeamAndInterval(nil, key, by, limit, skip)
+}
+
+func (r *LeaderboardRepository) GetAllAggregatedByTeamAndInterval(teamId uint, key *models.IntervalKey, by *uint8, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
+	return r.getAllAggregatedByTeamAndInterval(&teamId, key, by, limit, skip)
+}
+
+func (r *LeaderboardRepository) getAllAggregatedByTeamAndInterval(teamId *uint, key *models.IntervalKey, by *uint8, limit, skip int) ([]*models.LeaderboardItemRanked, error) {
 	// TODO: distinct by (user, key) to filter out potential duplicates ?
 
 	var items []*models.LeaderboardItemRanked
@@ -52,6 +61,7 @@ func (r *LeaderboardRepository) GetAllAggregatedByInterval(key *models.IntervalK
 		Select("*, rank() over (partition by \"key\" order by total desc) as \"rank\"").
 		Where("\"interval\" in ?", *key)
 	subq = utils.WhereNullable(subq, "\"by\"", by)
+	subq = utils.WhereNullable(subq, "team_id", teamId)
 
 	q := r.db.Table("(?) as ranked", subq)
 	q = r.withPaging(q, limit, skip)
@@ -67,7 +77,8 @@ func (r *LeaderboardRepository) GetAggregatedByUserAndInterval(userId string, ke
 	subq := r.db.
 		Table("leaderboard_items").
 		Select("*, rank() over (partition by \"key\" order by total desc) as \"rank\"").
-		Where("\"interval\" in ?", *key)
+		Where("\"interval\" in ?", *key).
+		Where("team_id is null")
 	subq = utils.WhereNullable(subq, "\"by\"", by)
 
 	q := r.db.Table("(?) as ranked", subq).Where("user_id = ?", userId)
@@ -82,6 +93,7 @@ func (r *LeaderboardRepository) GetAggregatedByUserAndInterval(userId string, ke
 func (r *LeaderboardRepository) DeleteByUser(userId string) error {
 	if err := r.db.
 		Where("user_id = ?", userId).
+		Where("team_id is null").
 		Delete(models.LeaderboardItem{}).Error; err != nil {
 		return err
 	}
@@ -92,6 +104,26 @@ func (r *LeaderboardRepository) DeleteByUserAndInterval(userId string, key *mode
 	if err := r.db.
 		Where("user_id = ?", userId).
 		Where("\"interval\" in ?", *key).
+		Where("team_id is null").
+		Delete(models.LeaderboardItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *LeaderboardRepository) DeleteByTeam(teamId uint) error {
+	if err := r.db.
+		Where("team_id = ?", teamId).
+		Delete(models.LeaderboardItem{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *LeaderboardRepository) DeleteByTeamAndInterval(teamId uint, key *models.IntervalKey) error {
+	if err := r.db.
+		Where("team_id = ?", teamId).
+		Where("\"interval\" in ?", *key).
 		Delete(models.LeaderboardItem{}).Error; err != nil {
 		return err
 	}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type NotificationChannelRepository struct {
+	BaseRepository
+}
+
+func NewNotificationChannelRepository(db *gorm.DB) *NotificationChannelRepository {
+	return &NotificationChannelRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *NotificationChannelRepository) GetById(id uint) (*models.NotificationChannel, error) {
+	channel := &models.NotificationChannel{}
+	if err := r.db.Where("id = ?", id).First(channel).Error; err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (r *NotificationChannelRepository) GetByUser(userId string) ([]*models.NotificationChannel, error) {
+	if userId == "" {
+		return []*models.NotificationChannel{}, nil
+	}
+	var channels []*models.NotificationChannel
+	if err := r.db.
+		Where(&models.NotificationChannel{UserID: userId}).
+		Order("created_at asc").
+		Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (r *NotificationChannelRepository) Insert(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	if !channel.IsValid() {
+		return nil, errors.New("invalid notification channel")
+	}
+	if err := r.db.Create(channel).Error; err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (r *NotificationChannelRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.NotificationChannel{}).Error
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type OidcIdentityRepository struct {
+	BaseRepository
+}
+
+func NewOidcIdentityRepository(db *gorm.DB) *OidcIdentityRepository {
+	return &OidcIdentityRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *OidcIdentityRepository) GetById(id uint) (*models.OidcIdentity, error) {
+	identity := &models.OidcIdentity{}
+	if err := r.db.Where(&models.OidcIdentity{ID: id}).First(identity).Error; err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *OidcIdentityRepository) GetBySubject(issuer, subject string) (*models.OidcIdentity, error) {
+	if issuer == "" || subject == "" {
+		return nil, errors.New("invalid input")
+	}
+	identity := &models.OidcIdentity{}
+	if err := r.db.
+		Where(&models.OidcIdentity{Issuer: issuer, Subject: subject}).
+		First(identity).Error; err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *OidcIdentityRepository) GetByUser(userId string) ([]*models.OidcIdentity, error) {
+	if userId == "" {
+		return []*models.OidcIdentity{}, nil
+	}
+	var identities []*models.OidcIdentity
+	if err := r.db.
+		Where(&models.OidcIdentity{UserID: userId}).
+		Order("created_at asc").
+		Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *OidcIdentityRepository) Insert(identity *models.OidcIdentity) (*models.OidcIdentity, error) {
+	if identity.UserID == "" || identity.Issuer == "" || identity.Subject == "" {
+		return nil, errors.New("invalid oidc identity")
+	}
+	if err := r.db.Create(identity).Error; err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (r *OidcIdentityRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.OidcIdentity{}).Error
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type RelayRepository struct {
+	BaseRepository
+}
+
+func NewRelayRepository(db *gorm.DB) *RelayRepository {
+	return &RelayRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *RelayRepository) GetById(id uint) (*models.RelayTarget, error) {
+	target := &models.RelayTarget{}
+	if err := r.db.Where("id = ?", id).First(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayRepository) GetByUser(userId string) ([]*models.RelayTarget, error) {
+	if userId == "" {
+		return []*models.RelayTarget{}, nil
+	}
+	var targets []*models.RelayTarget
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Order("created_at asc").
+		Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *RelayRepository) Insert(target *models.RelayTarget) (*models.RelayTarget, error) {
+	if !target.IsValid() {
+		return nil, errors.New("invalid relay target")
+	}
+	if err := r.db.Create(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayRepository) Update(target *models.RelayTarget) (*models.RelayTarget, error) {
+	if !target.IsValid() {
+		return nil, errors.New("invalid relay target")
+	}
+	updateMap := map[string]interface{}{
+		"name":             target.Name,
+		"api_url":          target.ApiUrl,
+		"api_key":          target.ApiKey,
+		"include_projects": target.IncludeProjects,
+		"exclude_projects": target.ExcludeProjects,
+		"enabled":          target.Enabled,
+		"failure_count":    target.FailureCount,
+		"last_error":       target.LastError,
+	}
+	if err := r.db.Model(target).Updates(updateMap).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.RelayTarget{}).Error
+}
+
+// IncrementFailures atomically increases the target's failure counter and returns its new value
+func (r *RelayRepository) IncrementFailures(id uint, lastError string) (int, error) {
+	var count int
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RelayTarget{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"failure_count": gorm.Expr("failure_count + 1"),
+				"last_error":    lastError,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RelayTarget{}).
+			Where("id = ?", id).
+			Pluck("failure_count", &count).Error
+	})
+	return count, err
+}
+
+// ResetFailures clears the target's failure counter after a successful attempt
+func (r *RelayRepository) ResetFailures(id uint) error {
+	return r.db.Model(&models.RelayTarget{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"failure_count": 0,
+			"last_error":    "",
+		}).Error
+}
+
+func (r *RelayRepository) GetQueueItemsDue(t time.Time, limit int) ([]*models.RelayQueueItem, error) {
+	var items []*models.RelayQueueItem
+	if err := r.db.
+		Preload("Target").
+		Preload("Target.User").
+		Where("next_attempt_at <= ?", t).
+		Where("target_id in (?)", r.db.Model(&models.RelayTarget{}).Select("id").Where("enabled = ?", true)).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *RelayRepository) CountQueueItems(targetId uint) (int64, error) {
+	var count int64
+	if err := r.db.
+		Model(&models.RelayQueueItem{}).
+		Where("target_id = ?", targetId).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *RelayRepository) InsertQueueItem(item *models.RelayQueueItem) (*models.RelayQueueItem, error) {
+	if err := r.db.Omit("Target").Create(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *RelayRepository) UpdateQueueItem(item *models.RelayQueueItem) (*models.RelayQueueItem, error) {
+	if err := r.db.Model(item).Updates(map[string]interface{}{
+		"attempts":        item.Attempts,
+		"next_attempt_at": item.NextAttemptAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *RelayRepository) DeleteQueueItem(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.RelayQueueItem{}).Error
+}
+
+func (r *RelayRepository) DeleteQueueItemsBefore(t time.Time) (int64, error) {
+	result := r.db.
+		Where("created_at < ?", t).
+		Delete(models.RelayQueueItem{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ReportSubscriptionRepository struct {
+	BaseRepository
+}
+
+func NewReportSubscriptionRepository(db *gorm.DB) *ReportSubscriptionRepository {
+	return &ReportSubscriptionRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *ReportSubscriptionRepository) GetById(id uint) (*models.ReportSubscription, error) {
+	subscription := &models.ReportSubscription{}
+	if err := r.db.Where("id = ?", id).First(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *ReportSubscriptionRepository) GetByUser(userId string) ([]*models.ReportSubscription, error) {
+	if userId == "" {
+		return []*models.ReportSubscription{}, nil
+	}
+	var subscriptions []*models.ReportSubscription
+	if err := r.db.
+		Where(&models.ReportSubscription{UserID: userId}).
+		Order("created_at asc").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetAll returns all report subscriptions, including their users
+func (r *ReportSubscriptionRepository) GetAll() ([]*models.ReportSubscription, error) {
+	var subscriptions []*models.ReportSubscription
+	if err := r.db.
+		Preload("User").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *ReportSubscriptionRepository) Insert(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	if !subscription.IsValid() {
+		return nil, errors.New("invalid report subscription")
+	}
+	if err := r.db.Create(subscription).Error; err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *ReportSubscriptionRepository) UpdateLastSent(id uint, t time.Time) error {
+	return r.db.
+		Model(&models.ReportSubscription{}).
+		Where("id = ?", id).
+		Update("last_sent_at", models.CustomTime(t)).Error
+}
+
+func (r *ReportSubscriptionRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.ReportSubscription{}).Error
+}
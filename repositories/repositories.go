@@ -30,6 +30,7 @@ type IHeartbeatRepository interface {
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
 	GetAllWithinByFilters(time.Time, time.Time, *models.User, map[string][]string) ([]*models.Heartbeat, error)
 	GetLatestByFilters(*models.User, map[string][]string) (*models.Heartbeat, error)
+	GetFirstByFilters(*models.User, map[string][]string) (*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
 	GetLastByUsers() ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
@@ -43,6 +44,9 @@ type IHeartbeatRepository interface {
 	DeleteBefore(time.Time) error
 	DeleteByUser(*models.User) error
 	DeleteByUserBefore(*models.User, time.Time) error
+	SyncIdSequence() error
+	DeleteDuplicates(*models.User) (int64, error)
+	RenameProjects(*models.User, []string, string) (int64, error)
 	GetUserProjectStats(*models.User, time.Time, time.Time, int, int) ([]*models.ProjectStats, error)
 }
 
@@ -80,6 +84,13 @@ type ILanguageMappingRepository interface {
 	Delete(uint) error
 }
 
+type IArchivedProjectRepository interface {
+	IBaseRepository
+	GetByUser(string) ([]*models.ArchivedProject, error)
+	Insert(*models.ArchivedProject) (*models.ArchivedProject, error)
+	Delete(uint) error
+}
+
 type IProjectLabelRepository interface {
 	IBaseRepository
 	GetAll() ([]*models.ProjectLabel, error)
@@ -97,6 +108,7 @@ type ISummaryRepository interface {
 	GetLastByUser() ([]*models.TimeByUser, error)
 	DeleteByUser(string) error
 	DeleteByUserBefore(string, time.Time) error
+	DeleteByUserWithin(string, time.Time, time.Time) error
 }
 
 type IUserRepository interface {
@@ -126,4 +138,103 @@ type ILeaderboardRepository interface {
 	DeleteByUserAndInterval(string, *models.IntervalKey) error
 	GetAllAggregatedByInterval(*models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
 	GetAggregatedByUserAndInterval(string, *models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
+	GetAllAggregatedByTeamAndInterval(uint, *models.IntervalKey, *uint8, int, int) ([]*models.LeaderboardItemRanked, error)
+	DeleteByTeam(uint) error
+	DeleteByTeamAndInterval(uint, *models.IntervalKey) error
+}
+
+type IApiTokenRepository interface {
+	IBaseRepository
+	GetByUser(string) ([]*models.ApiToken, error)
+	GetByHash(string) (*models.ApiToken, error)
+	Insert(*models.ApiToken) (*models.ApiToken, error)
+	UpdateLastUsed(*models.ApiToken, time.Time) error
+	Delete(uint) error
+}
+
+type IGoalRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.Goal, error)
+	GetByUser(string) ([]*models.Goal, error)
+	GetAllNotify() ([]*models.Goal, error)
+	Insert(*models.Goal) (*models.Goal, error)
+	Delete(uint) error
+}
+
+type INotificationChannelRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.NotificationChannel, error)
+	GetByUser(string) ([]*models.NotificationChannel, error)
+	Insert(*models.NotificationChannel) (*models.NotificationChannel, error)
+	Delete(uint) error
+}
+
+type IHeartbeatRuleRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.HeartbeatRule, error)
+	GetByUser(string) ([]*models.HeartbeatRule, error)
+	Insert(*models.HeartbeatRule) (*models.HeartbeatRule, error)
+	Delete(uint) error
+}
+
+type IReportSubscriptionRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.ReportSubscription, error)
+	GetByUser(string) ([]*models.ReportSubscription, error)
+	GetAll() ([]*models.ReportSubscription, error)
+	Insert(*models.ReportSubscription) (*models.ReportSubscription, error)
+	UpdateLastSent(uint, time.Time) error
+	Delete(uint) error
+}
+
+type IWebhookRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.Webhook, error)
+	GetByUser(string) ([]*models.Webhook, error)
+	GetGlobal() ([]*models.Webhook, error)
+	Insert(*models.Webhook) (*models.Webhook, error)
+	Delete(uint) error
+	GetDeliveries(uint, int) ([]*models.WebhookDelivery, error)
+	InsertDelivery(*models.WebhookDelivery) (*models.WebhookDelivery, error)
+	DeleteDeliveriesBefore(time.Time) error
+}
+
+type IRelayRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.RelayTarget, error)
+	GetByUser(string) ([]*models.RelayTarget, error)
+	Insert(*models.RelayTarget) (*models.RelayTarget, error)
+	Update(*models.RelayTarget) (*models.RelayTarget, error)
+	Delete(uint) error
+	IncrementFailures(uint, string) (int, error)
+	ResetFailures(uint) error
+	GetQueueItemsDue(time.Time, int) ([]*models.RelayQueueItem, error)
+	CountQueueItems(uint) (int64, error)
+	InsertQueueItem(*models.RelayQueueItem) (*models.RelayQueueItem, error)
+	UpdateQueueItem(*models.RelayQueueItem) (*models.RelayQueueItem, error)
+	DeleteQueueItem(uint) error
+	DeleteQueueItemsBefore(time.Time) (int64, error)
+}
+
+type ITeamRepository interface {
+	IBaseRepository
+	GetAll() ([]*models.Team, error)
+	GetById(uint) (*models.Team, error)
+	GetByUser(string) ([]*models.Team, error)
+	Insert(*models.Team) (*models.Team, error)
+	Delete(uint) error
+	GetMembers(uint) ([]*models.TeamMember, error)
+	GetMember(uint, string) (*models.TeamMember, error)
+	InsertMember(*models.TeamMember) (*models.TeamMember, error)
+	UpdateMember(*models.TeamMember) (*models.TeamMember, error)
+	DeleteMember(uint, string) error
+}
+
+type IOidcIdentityRepository interface {
+	IBaseRepository
+	GetById(uint) (*models.OidcIdentity, error)
+	GetBySubject(string, string) (*models.OidcIdentity, error)
+	GetByUser(string) ([]*models.OidcIdentity, error)
+	Insert(*models.OidcIdentity) (*models.OidcIdentity, error)
+	Delete(uint) error
 }
@@ -142,6 +142,17 @@ func (r *SummaryRepository) DeleteByUserBefore(userId string, t time.Time) error
 	return nil
 }
 
+func (r *SummaryRepository) DeleteByUserWithin(userId string, from, to time.Time) error {
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Where("from_time >= ?", from.Local()).
+		Where("to_time <= ?", to.Local()).
+		Delete(models.Summary{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 // inplace
 func (r *SummaryRepository) populateItems(summaries []*models.Summary, conditions []clause.Interface) error {
 	var items []*models.SummaryItem
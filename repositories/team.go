@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type TeamRepository struct {
+	BaseRepository
+}
+
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *TeamRepository) GetAll() ([]*models.Team, error) {
+	var teams []*models.Team
+	if err := r.db.Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (r *TeamRepository) GetById(id uint) (*models.Team, error) {
+	team := &models.Team{}
+	if err := r.db.Where(&models.Team{ID: id}).First(team).Error; err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (r *TeamRepository) GetByUser(userId string) ([]*models.Team, error) {
+	if userId == "" {
+		return []*models.Team{}, nil
+	}
+	var teams []*models.Team
+	if err := r.db.
+		Model(&models.Team{}).
+		Joins("inner join team_members on team_members.team_id = teams.id").
+		Where("team_members.user_id = ?", userId).
+		Order("teams.name asc").
+		Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (r *TeamRepository) Insert(team *models.Team) (*models.Team, error) {
+	if !team.IsValid() {
+		return nil, errors.New("invalid team")
+	}
+	if err := r.db.Create(team).Error; err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+func (r *TeamRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("team_id = ?", id).Delete(models.TeamMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(models.Team{}).Error
+	})
+}
+
+func (r *TeamRepository) GetMembers(teamId uint) ([]*models.TeamMember, error) {
+	var members []*models.TeamMember
+	if err := r.db.
+		Preload("User").
+		Where(&models.TeamMember{TeamID: teamId}).
+		Order("user_id asc").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *TeamRepository) GetMember(teamId uint, userId string) (*models.TeamMember, error) {
+	member := &models.TeamMember{}
+	if err := r.db.
+		Where(&models.TeamMember{TeamID: teamId, UserID: userId}).
+		First(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *TeamRepository) InsertMember(member *models.TeamMember) (*models.TeamMember, error) {
+	if !member.IsValid() {
+		return nil, errors.New("invalid team member")
+	}
+	if err := r.db.Create(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *TeamRepository) UpdateMember(member *models.TeamMember) (*models.TeamMember, error) {
+	if !member.IsValid() {
+		return nil, errors.New("invalid team member")
+	}
+	if err := r.db.
+		Model(&models.TeamMember{}).
+		Where("team_id = ? and user_id = ?", member.TeamID, member.UserID).
+		Update("role", member.Role).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *TeamRepository) DeleteMember(teamId uint, userId string) error {
+	return r.db.
+		Where("team_id = ? and user_id = ?", teamId, userId).
+		Delete(models.TeamMember{}).Error
+}
@@ -142,6 +142,7 @@ func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 		"wakatime_api_key":         user.WakatimeApiKey,
 		"wakatime_api_url":         user.WakatimeApiUrl,
 		"has_data":                 user.HasData,
+		"is_disabled":              user.IsDisabled,
 		"reset_token":              user.ResetToken,
 		"location":                 user.Location,
 		"reports_weekly":           user.ReportsWeekly,
@@ -152,6 +153,10 @@ func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 		"invited_by":               user.InvitedBy,
 		"exclude_unknown_projects": user.ExcludeUnknownProjects,
 		"heartbeats_timeout_sec":   user.HeartbeatsTimeoutSec,
+		"totp_secret":              user.TotpSecret,
+		"totp_enabled":             user.TotpEnabled,
+		"totp_recovery_codes":      user.TotpRecoveryCodes,
+		"totp_last_counter":        user.TotpLastCounter,
 	}
 
 	result := r.db.Model(user).Updates(updateMap)
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	BaseRepository
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{BaseRepository: NewBaseRepository(db)}
+}
+
+func (r *WebhookRepository) GetById(id uint) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	if err := r.db.Where("id = ?", id).First(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetByUser(userId string) ([]*models.Webhook, error) {
+	if userId == "" {
+		return []*models.Webhook{}, nil
+	}
+	var webhooks []*models.Webhook
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Order("created_at desc").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) GetGlobal() ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if err := r.db.
+		Where("user_id is null").
+		Order("created_at desc").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Insert(webhook *models.Webhook) (*models.Webhook, error) {
+	if !webhook.IsValid() {
+		return nil, errors.New("invalid webhook")
+	}
+	if err := r.db.Create(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.Webhook{}).Error
+}
+
+func (r *WebhookRepository) GetDeliveries(webhookId uint, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	if err := r.db.
+		Where("webhook_id = ?", webhookId).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) InsertDelivery(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	if err := r.db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (r *WebhookRepository) DeleteDeliveriesBefore(t time.Time) error {
+	return r.db.
+		Where("created_at < ?", t).
+		Delete(models.WebhookDelivery{}).Error
+}
@@ -0,0 +1,268 @@
+package routes
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type AdminHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	heartbeatSrvc   services.IHeartbeatService
+	durationSrvc    services.IDurationService
+	summarySrvc     services.ISummaryService
+	aggregationSrvc services.IAggregationService
+}
+
+type targetUserAction func(w http.ResponseWriter, r *http.Request, target *models.User) actionResult
+
+func NewAdminHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, durationService services.IDurationService, summaryService services.ISummaryService, aggregationService services.IAggregationService) *AdminHandler {
+	return &AdminHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatService,
+		durationSrvc:    durationService,
+		summarySrvc:     summaryService,
+		aggregationSrvc: aggregationService,
+	}
+}
+
+func (h *AdminHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+		h.requireAdmin,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+
+	router.Mount("/admin", r)
+}
+
+func (h *AdminHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	if err := templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w)); err != nil {
+		conf.Log().Request(r).Error("failed to get admin page", "error", err)
+	}
+}
+
+func (h *AdminHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError("missing form values"))
+		return
+	}
+
+	action := r.PostForm.Get("action")
+	r.PostForm.Del("action")
+
+	actionFunc := h.dispatchAction(action)
+	if actionFunc == nil {
+		slog.Warn("failed to dispatch action", "action", action)
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError("unknown action requests"))
+		return
+	}
+
+	result := actionFunc(w, r)
+
+	// action responded itself
+	if result.code == -1 {
+		return
+	}
+
+	if result.error != "" {
+		w.WriteHeader(result.code)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithError(result.error))
+		return
+	}
+	if result.success != "" {
+		w.WriteHeader(result.code)
+		templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w).WithSuccess(result.success))
+		return
+	}
+	templates[conf.AdminTemplate].Execute(w, h.buildViewModel(r, w))
+}
+
+func (h *AdminHandler) dispatchAction(action string) action {
+	switch action {
+	case "create_user":
+		return h.actionCreateUser
+	case "toggle_disabled":
+		return h.withTargetUser(h.actionToggleDisabled)
+	case "delete_user":
+		return h.withTargetUser(h.actionDeleteUser)
+	case "reset_apikey":
+		return h.withTargetUser(h.actionResetApiKey)
+	case "regenerate_durations":
+		return h.withTargetUser(h.actionRegenerateDurations)
+	case "regenerate_summaries":
+		return h.withTargetUser(h.actionRegenerateSummaries)
+	}
+	return nil
+}
+
+// withTargetUser resolves the user an action is to be performed on from the request's form values
+func (h *AdminHandler) withTargetUser(f targetUserAction) action {
+	return func(w http.ResponseWriter, r *http.Request) actionResult {
+		target, err := h.userSrvc.GetUserById(r.PostForm.Get("user_id"))
+		if err != nil {
+			return actionResult{http.StatusNotFound, "", "user not found", nil}
+		}
+		return f(w, r, target)
+	}
+}
+
+func (h *AdminHandler) actionCreateUser(w http.ResponseWriter, r *http.Request) actionResult {
+	signup := &models.Signup{
+		Username: r.PostForm.Get("username"),
+		Email:    r.PostForm.Get("email"),
+		Password: r.PostForm.Get("password"),
+		Location: middlewares.GetPrincipal(r).Location,
+	}
+
+	if !models.ValidateUsername(signup.Username) || !models.ValidatePassword(signup.Password) || (signup.Email != "" && !models.ValidateEmail(signup.Email)) {
+		return actionResult{http.StatusBadRequest, "", "invalid parameters", nil}
+	}
+
+	user, created, err := h.userSrvc.CreateOrGet(signup, r.PostForm.Get("is_admin") == "true")
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create new user", "error", err)
+		return actionResult{http.StatusInternalServerError, "", "failed to create new user", nil}
+	}
+	if !created {
+		return actionResult{http.StatusConflict, "", "user already existing", nil}
+	}
+
+	slog.Info("admin created new user", "adminID", middlewares.GetPrincipal(r).ID, "userID", user.ID)
+	return actionResult{http.StatusCreated, fmt.Sprintf("user '%s' created successfully", user.ID), "", nil}
+}
+
+func (h *AdminHandler) actionToggleDisabled(w http.ResponseWriter, r *http.Request, target *models.User) actionResult {
+	if target.ID == middlewares.GetPrincipal(r).ID {
+		return actionResult{http.StatusForbidden, "", "you can't disable your own account", nil}
+	}
+
+	target.IsDisabled = !target.IsDisabled
+	if _, err := h.userSrvc.Update(target); err != nil {
+		conf.Log().Request(r).Error("failed to toggle disabled state", "userID", target.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	if target.IsDisabled {
+		return actionResult{http.StatusOK, fmt.Sprintf("user '%s' disabled", target.ID), "", nil}
+	}
+	return actionResult{http.StatusOK, fmt.Sprintf("user '%s' enabled", target.ID), "", nil}
+}
+
+func (h *AdminHandler) actionDeleteUser(w http.ResponseWriter, r *http.Request, target *models.User) actionResult {
+	if target.ID == middlewares.GetPrincipal(r).ID {
+		return actionResult{http.StatusForbidden, "", "you can't delete your own account from here, please use the settings page", nil}
+	}
+
+	if err := h.userSrvc.Delete(target); err != nil {
+		conf.Log().Request(r).Error("failed to delete user", "userID", target.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	slog.Info("admin deleted user", "adminID", middlewares.GetPrincipal(r).ID, "userID", target.ID)
+	return actionResult{http.StatusOK, fmt.Sprintf("user '%s' deleted", target.ID), "", nil}
+}
+
+func (h *AdminHandler) actionResetApiKey(w http.ResponseWriter, r *http.Request, target *models.User) actionResult {
+	if _, err := h.userSrvc.ResetApiKey(target); err != nil {
+		conf.Log().Request(r).Error("failed to reset api key", "userID", target.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, fmt.Sprintf("api key of user '%s' was reset", target.ID), "", nil}
+}
+
+func (h *AdminHandler) actionRegenerateDurations(w http.ResponseWriter, r *http.Request, target *models.User) actionResult {
+	go h.durationSrvc.Regenerate(target, true)
+	return actionResult{http.StatusAccepted, fmt.Sprintf("durations of user '%s' are being regenerated", target.ID), "", nil}
+}
+
+func (h *AdminHandler) actionRegenerateSummaries(w http.ResponseWriter, r *http.Request, target *models.User) actionResult {
+	go func(user *models.User, r *http.Request) {
+		if err := regenerateUserSummaries(user, h.summarySrvc, h.aggregationSrvc); err != nil {
+			conf.Log().Request(r).Error("failed to regenerate summaries for user", "userID", user.ID, "error", err)
+		}
+	}(target, r)
+	return actionResult{http.StatusAccepted, fmt.Sprintf("summaries of user '%s' are being regenerated - this may take a up to a couple of minutes", target.ID), "", nil}
+}
+
+func (h *AdminHandler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := middlewares.GetPrincipal(r); user == nil || !user.IsAdmin {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(conf.ErrForbidden))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *AdminHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.AdminViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	users, err := h.userSrvc.GetAll()
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get users for admin page", "error", err)
+		return &view.AdminViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+
+	counts, err := h.heartbeatSrvc.CountByUsers(users)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to count heartbeats for admin page", "error", err)
+	}
+	countsByUser := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		countsByUser[c.User] = c.Count
+	}
+
+	items := make([]*view.AdminUserItem, len(users))
+	for i, u := range users {
+		items[i] = &view.AdminUserItem{User: u, Heartbeats: countsByUser[u.ID]}
+	}
+
+	queues := conf.GetQueueMetrics()
+	sort.Slice(queues, func(i, j int) bool {
+		return queues[i].Queue < queues[j].Queue
+	})
+
+	vm := &view.AdminViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Users:                items,
+		Queues:               queues,
+		SubscriptionsEnabled: h.config.Subscriptions.Enabled,
+	}
+	return routeutils.WithSessionMessages(vm, r, w)
+}
@@ -1,23 +1,28 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/condition"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
-	"net/http"
-	"regexp"
-	"strings"
-	"time"
 )
 
 var userWithExtPattern *regexp.Regexp
 
 func init() {
-	userWithExtPattern = regexp.MustCompile(`\.svg$`)
+	userWithExtPattern = regexp.MustCompile(`^(.+)\.(svg|png|json)$`)
 }
 
 type ActivityApiHandler struct {
@@ -37,51 +42,165 @@ func NewActivityApiHandler(userService services.IUserService, activityService se
 func (h *ActivityApiHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
 	r.Use(
-		middlewares.NewAuthenticateMiddleware(h.userService).WithOptionalFor("/api/activity/chart/").Handler,
-		middleware.Compress(9, "image/svg+xml"),
+		middlewares.NewAuthenticateMiddleware(h.userService).WithOptionalFor("/api/activity/chart/", "/api/activity/heatmap/").WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler,
+		middleware.Compress(9, "image/svg+xml", "application/json"), // png is compressed already
 	)
 	r.Get("/chart/{userWithExt}", h.GetActivityChart)
+	r.Get("/heatmap/{userWithExt}", h.GetHeatmap)
 
 	router.Mount("/activity", r)
 }
 
+// GetActivityChart renders a user's activity chart (svg or png, depending on the requested file extension)
+// Query params:
+// - interval: any interval key, e.g. "last_6_months" (default: "last_12_months"), alternatively "from" and "to" dates
+// - project, language, label, ...: only count activity matching the given entity (see summary api)
+// - colors: comma-separated list of at least two hex colors to fade between, from least to most activity
+// - dark, noattr: dark theme, no attribution
 func (h *ActivityApiHandler) GetActivityChart(w http.ResponseWriter, r *http.Request) {
+	requestedUser, params, ok := h.loadChartRequest(w, r)
+	if !ok {
+		return
+	}
+	if params.Format == models.ActivityChartFormatJson {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	chart, err := h.activityService.GetChart(requestedUser, params, utils.IsNoCache(r, 6*time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to get activity chart for user", "userID", requestedUser.ID, "error", err)
+		return
+	}
+
+	h.writeChart(w, chart, params)
+}
+
+// GetHeatmap renders a user's activity by day of week and hour of day (in the user's timezone) as svg, png or json, depending on the requested file extension
+// Accepts the same query params as the activity chart. The json representation contains seconds of activity for every weekday (starting with monday) and hour.
+func (h *ActivityApiHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	requestedUser, params, ok := h.loadChartRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if params.Format == models.ActivityChartFormatJson {
+		heatmap, err := h.activityService.GetHeatmap(requestedUser, params, utils.IsNoCache(r, 6*time.Hour))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			conf.Log().Request(r).Error("failed to get heatmap for user", "userID", requestedUser.ID, "error", err)
+			return
+		}
+		helpers.RespondJSON(w, r, http.StatusOK, heatmap)
+		return
+	}
+
+	chart, err := h.activityService.GetHeatmapChart(requestedUser, params, utils.IsNoCache(r, 6*time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to get heatmap chart for user", "userID", requestedUser.ID, "error", err)
+		return
+	}
+
+	h.writeChart(w, chart, params)
+}
+
+// loadChartRequest resolves the requested user and chart parameters and checks permissions, writing an error response if any of this fails
+func (h *ActivityApiHandler) loadChartRequest(w http.ResponseWriter, r *http.Request) (*models.User, *models.ActivityChartParams, bool) {
 	authorizedUser := middlewares.GetPrincipal(r)
 
 	// chi currently doesn't support dots in parameters of routes containing a dot themselves, this is a workaround
 	// https://github.com/go-chi/chi/issues/758
 	// https://github.com/go-chi/chi/pull/811
 	userWithExt := chi.URLParam(r, "userWithExt")
-	if !strings.HasSuffix(userWithExt, ".svg") {
+	match := userWithExtPattern.FindStringSubmatch(userWithExt)
+	if match == nil {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write([]byte(conf.ErrNotFound))
-		return
+		return nil, nil, false
 	}
-	requestedUser, err := h.userService.GetUserById(userWithExtPattern.ReplaceAllString(userWithExt, ""))
+	requestedUser, err := h.userService.GetUserById(match[1])
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
-		return
+		return nil, nil, false
+	}
+
+	params, err := h.parseChartParams(r, requestedUser, match[2])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return nil, nil, false
 	}
 
 	if authorizedUser == nil || authorizedUser.ID != requestedUser.ID {
 		if !requestedUser.ShareActivityChart {
 			w.WriteHeader(http.StatusForbidden)
-			return
+			return nil, nil, false
+		}
+		for _, entityType := range models.SummaryTypes() {
+			if params.Filters.CountByType(entityType) > 0 && !requestedUser.SharesSummaryType(entityType) {
+				w.WriteHeader(http.StatusForbidden)
+				return nil, nil, false
+			}
 		}
 	}
 
-	paramDark := r.URL.Query().Has("dark") && r.URL.Query().Get("dark") != "false"
-	paramNoAttr := r.URL.Query().Has("noattr") && r.URL.Query().Get("noattr") != "false" // no attribution (no wakapi logo in bottom left corner)
-
-	chart, err := h.activityService.GetChart(requestedUser, models.IntervalPast12Months, paramDark, paramNoAttr, utils.IsNoCache(r, 6*time.Hour))
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		conf.Log().Request(r).Error("failed to get activity chart for user", "userID", requestedUser.ID, "error", err)
-		return
-	}
+	return requestedUser, params, true
+}
 
-	w.Header().Set("Content-Type", "image/svg+xml")
+func (h *ActivityApiHandler) writeChart(w http.ResponseWriter, chart []byte, params *models.ActivityChartParams) {
+	w.Header().Set("Content-Type", condition.TernaryOperator[bool, string](params.Format == models.ActivityChartFormatPng, "image/png", "image/svg+xml"))
 	w.Header().Set("Cache-Control", "max-age=21600") // 6 hours
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(chart))
+	w.Write(chart)
+}
+
+func (h *ActivityApiHandler) parseChartParams(r *http.Request, user *models.User, format string) (*models.ActivityChartParams, error) {
+	query := r.URL.Query()
+
+	params := &models.ActivityChartParams{
+		Format:          format,
+		Filters:         helpers.ParseSummaryFilters(r),
+		DarkTheme:       query.Has("dark") && query.Get("dark") != "false",
+		HideAttribution: query.Has("noattr") && query.Get("noattr") != "false", // no attribution (no wakapi logo in bottom left corner)
+	}
+
+	if query.Has("from") || query.Has("to") {
+		from, err := helpers.ParseDateTimeTZ(query.Get("from"), user.TZ())
+		if err != nil {
+			return nil, errors.New("invalid 'from' parameter")
+		}
+		to, err := helpers.ParseDateTimeTZ(query.Get("to"), user.TZ())
+		if err != nil {
+			return nil, errors.New("invalid 'to' parameter")
+		}
+		params.From, params.To = from, to
+	} else {
+		interval := query.Get("interval")
+		if interval == "" {
+			interval = (*models.IntervalPast12Months)[0]
+		}
+		err, from, to := helpers.ResolveIntervalRawTZ(interval, user.TZ())
+		if err != nil {
+			return nil, errors.New("invalid 'interval' parameter")
+		}
+		params.From, params.To = from, to
+	}
+
+	if q := query.Get("colors"); q != "" {
+		for _, c := range strings.Split(q, ",") {
+			c = strings.TrimSpace(c)
+			if !strings.HasPrefix(c, "#") {
+				c = "#" + c // hash sign would have to be url-encoded
+			}
+			params.Colors = append(params.Colors, c)
+		}
+	}
+
+	if !params.IsValid() {
+		return nil, fmt.Errorf("invalid chart parameters (formats: svg, png, json; max. range: %d days; at least two colors)", models.ActivityChartMaxDays)
+	}
+	return params, nil
 }
@@ -2,8 +2,6 @@ package api
 
 import (
 	"fmt"
-	"github.com/duke-git/lancet/v2/maputil"
-	"github.com/duke-git/lancet/v2/slice"
 	"github.com/go-chi/chi/v5"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
@@ -12,31 +10,46 @@ import (
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
-	"github.com/narqo/go-badge"
 	"github.com/patrickmn/go-cache"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 type BadgeHandler struct {
-	config      *conf.Config
-	cache       *cache.Cache
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	cache           *cache.Cache
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	goalSrvc        services.IGoalService
+	badgeSrvc       services.IBadgeService
+	leaderboardSrvc services.ILeaderboardService
 }
 
-func NewBadgeHandler(userService services.IUserService, summaryService services.ISummaryService) *BadgeHandler {
+func NewBadgeHandler(userService services.IUserService, summaryService services.ISummaryService, goalService services.IGoalService, badgeService services.IBadgeService, leaderboardService services.ILeaderboardService) *BadgeHandler {
 	return &BadgeHandler{
-		config:      conf.Get(),
-		cache:       cache.New(time.Hour, time.Hour),
-		userSrvc:    userService,
-		summarySrvc: summaryService,
+		config:          conf.Get(),
+		cache:           cache.New(time.Hour, time.Hour),
+		userSrvc:        userService,
+		summarySrvc:     summaryService,
+		goalSrvc:        goalService,
+		badgeSrvc:       badgeService,
+		leaderboardSrvc: leaderboardService,
 	}
 }
 
 func (h *BadgeHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
-	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor("/api/badge/").Handler)
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor("/api/badge/").WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+	r.Get("/{user}/goal/{id}", h.GetGoal)
+	r.Get("/{user}/languages", h.GetLanguages)
+	r.Get("/{user}/languages/*", h.GetLanguages)
+	r.Get("/{user}/sparkline", h.GetSparkline)
+	r.Get("/{user}/sparkline/*", h.GetSparkline)
+	r.Get("/{user}/streak", h.GetStreak)
+	r.Get("/{user}/streak/*", h.GetStreak)
+	r.Get("/{user}/rank", h.GetRank)
+	r.Get("/{user}/rank/*", h.GetRank)
 	r.Get("/{user}/*", h.Get)
 	router.Mount("/badge", r)
 }
@@ -78,7 +91,84 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	badgeData := v1.NewBadgeDataFrom(summary)
+	h.respondBadge(w, r, cacheKey, v1.NewBadgeDataFrom(summary))
+}
+
+func (h *BadgeHandler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	goalId, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.getCustom(w, r, "goal_"+chi.URLParam(r, "id"), func(authorizedUser, user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadGoalBadgeData(h.goalSrvc, uint(goalId), authorizedUser, user)
+	})
+}
+
+func (h *BadgeHandler) GetLanguages(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "languages", func(authorizedUser, user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadLanguagesBadgeData(h.summarySrvc, r.URL.Path, authorizedUser, user)
+	})
+}
+
+func (h *BadgeHandler) GetSparkline(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "sparkline", func(authorizedUser, user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadSparklineBadgeData(h.badgeSrvc, r.URL.Path, authorizedUser, user)
+	})
+}
+
+func (h *BadgeHandler) GetStreak(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "streak", func(authorizedUser, user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadStreakBadgeData(h.badgeSrvc, r.URL.Path, authorizedUser, user)
+	})
+}
+
+func (h *BadgeHandler) GetRank(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "rank", func(authorizedUser, user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadRankBadgeData(h.leaderboardSrvc, r.URL.Path, authorizedUser, user)
+	})
+}
+
+// getCustom responds with a badge of a kind other than the total coding time one, whose data is produced by the given loader function
+func (h *BadgeHandler) getCustom(w http.ResponseWriter, r *http.Request, kind string, load func(authorizedUser, user *models.User) (*v1.BadgeData, error, int)) {
+	authorizedUser := middlewares.GetPrincipal(r)
+	user, err := h.userSrvc.GetUserById(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// include requesting user, as permissions depend on it
+	var authorizedUserId string
+	if authorizedUser != nil {
+		authorizedUserId = authorizedUser.ID
+	}
+
+	cacheKey := fmt.Sprintf("%s_%s_%s_%s_%s", user.ID, kind, authorizedUserId, r.URL.Path, r.URL.RawQuery)
+	noCache := utils.IsNoCache(r, 1*time.Hour)
+	if cacheResult, ok := h.cache.Get(cacheKey); ok && !noCache {
+		respondSvg(w, cacheResult.([]byte))
+		return
+	}
+
+	badgeData, err, status := load(authorizedUser, user)
+	if err != nil && status >= http.StatusInternalServerError {
+		conf.Log().Request(r).Error("failed to load badge data", "kind", kind, "userID", user.ID, "error", err)
+		w.WriteHeader(status)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(status)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	h.respondBadge(w, r, cacheKey, badgeData)
+}
+
+func (h *BadgeHandler) respondBadge(w http.ResponseWriter, r *http.Request, cacheKey string, badgeData *v1.BadgeData) {
 	if customLabel := r.URL.Query().Get("label"); customLabel != "" {
 		badgeData.Label = customLabel
 	}
@@ -86,11 +176,15 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 		badgeData.Color = customColor
 	}
 
-	if badgeData.Color[0:1] != "#" && !slice.Contain(maputil.Keys(badge.ColorScheme), badgeData.Color) {
-		badgeData.Color = "#" + badgeData.Color
-	}
+	badgeData.Style = routeutils.GetBadgeStyle(r)
 
-	badgeSvg, err := badge.RenderBytes(badgeData.Label, badgeData.Message, badge.Color(badgeData.Color))
+	badgeSvg, err := h.badgeSrvc.Render(badgeData)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to render badge", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
 	h.cache.SetDefault(cacheKey, badgeSvg)
 	respondSvg(w, badgeSvg)
 }
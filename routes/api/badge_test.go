@@ -1,11 +1,13 @@
 package api
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"io"
@@ -37,6 +39,32 @@ var (
 			},
 		},
 	}
+
+	goal1 = models.Goal{
+		ID:            1,
+		UserID:        "user1",
+		Title:         "go",
+		Period:        models.GoalPeriodDay,
+		TargetSeconds: 3600,
+		Languages:     "go",
+	}
+
+	goal2 = models.Goal{
+		ID:            2,
+		UserID:        "user1",
+		Title:         "wakapi",
+		Period:        models.GoalPeriodDay,
+		TargetSeconds: 3600,
+		Projects:      "wakapi",
+	}
+
+	goal3 = models.Goal{
+		ID:            3,
+		UserID:        "user1",
+		Title:         "daily",
+		Period:        models.GoalPeriodDay,
+		TargetSeconds: 3600,
+	}
 )
 
 func TestBadgeHandler_Get(t *testing.T) {
@@ -53,7 +81,16 @@ func TestBadgeHandler_Get(t *testing.T) {
 	summaryServiceMock := new(mocks.SummaryServiceMock)
 	summaryServiceMock.On("Aliased", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), &user1, mock.AnythingOfType("types.SummaryRetriever"), mock.AnythingOfType("*models.Filters"), mock.AnythingOfType("*time.Duration"), mock.Anything).Return(&summary1, nil)
 
-	badgeHandler := NewBadgeHandler(userServiceMock, summaryServiceMock)
+	goalServiceMock := new(mocks.GoalServiceMock)
+	goalServiceMock.On("GetById", uint(1)).Return(&goal1, nil)
+	goalServiceMock.On("GetById", uint(2)).Return(&goal2, nil)
+	goalServiceMock.On("GetById", uint(3)).Return(&goal3, nil)
+	goalServiceMock.On("GetProgress", &goal1, &user1, mock.AnythingOfType("time.Time")).Return(&models.GoalProgress{Goal: &goal1, Actual: 45 * time.Minute}, nil)
+	goalServiceMock.On("GetProgress", &goal3, &user1, mock.AnythingOfType("time.Time")).Return((*models.GoalProgress)(nil), errors.New("database is locked"))
+
+	summaryServiceMock.On("Retrieve", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), &user1, mock.Anything, mock.Anything).Return(&summary1, nil)
+
+	badgeHandler := NewBadgeHandler(userServiceMock, summaryServiceMock, goalServiceMock, services.NewBadgeService(summaryServiceMock), nil)
 	badgeHandler.RegisterRoutes(apiRouter)
 
 	t.Run("when requesting badge", func(t *testing.T) {
@@ -118,6 +155,151 @@ func TestBadgeHandler_Get(t *testing.T) {
 			assert.False(t, strings.HasPrefix(string(data), "<svg"))
 		})
 	})
+
+	t.Run("when requesting badge in another style", func(t *testing.T) {
+		t.Run("should return badge in that style", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/interval:week/language:go?style=for-the-badge", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			data, _ := io.ReadAll(res.Body)
+			assert.Contains(t, string(data), "<svg")
+			assert.Contains(t, string(data), "0 HRS 12 MINS")
+			assert.Contains(t, string(data), `height="28.00"`)
+		})
+	})
+
+	t.Run("when requesting top languages badge", func(t *testing.T) {
+		t.Run("should return stacked bar", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/languages/interval:week", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			data, _ := io.ReadAll(res.Body)
+			assert.Contains(t, string(data), "<title>go 100%</title>")
+		})
+
+		t.Run("should not return badge if shared interval exceeded", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/languages/interval:last_year", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		})
+	})
+
+	t.Run("when requesting sparkline badge", func(t *testing.T) {
+		t.Run("should return sparkline of last 30 days", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/sparkline", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			data, _ := io.ReadAll(res.Body)
+			assert.Contains(t, string(data), "<polyline")
+		})
+	})
+
+	t.Run("when requesting streak badge", func(t *testing.T) {
+		t.Run("should only look back as far as data is shared", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/streak", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			data, _ := io.ReadAll(res.Body)
+			assert.Contains(t, string(data), "31+ days") // today plus 30 days back
+		})
+	})
+
+	t.Run("when requesting rank badge", func(t *testing.T) {
+		t.Run("should not return badge if leaderboard disabled", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/rank", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		})
+	})
+
+	t.Run("when requesting goal badge", func(t *testing.T) {
+		t.Run("should return badge", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/goal/1", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			data, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Errorf("unextected error. Error: %s", err)
+			}
+
+			assert.True(t, strings.HasPrefix(string(data), "<svg"))
+			assert.Contains(t, string(data), "0 hrs 45 mins / 1 hrs 0 mins (75%)")
+		})
+
+		t.Run("should not return badge if goal restricted to entity type not shared", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/goal/2", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		})
+
+		t.Run("should not expose internal errors", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/badge/user1/goal/3", nil)
+
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+
+			data, _ := io.ReadAll(res.Body)
+			assert.Equal(t, config.ErrInternalServerError, string(data))
+		})
+	})
 }
 
 func TestBadgeHandler_EntityPattern(t *testing.T) {
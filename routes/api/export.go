@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type ExportApiHandler struct {
+	config        *conf.Config
+	userService   services.IUserService
+	exportService services.IExportService
+}
+
+func NewExportApiHandler(userService services.IUserService, exportService services.IExportService) *ExportApiHandler {
+	return &ExportApiHandler{
+		userService:   userService,
+		exportService: exportService,
+		config:        conf.Get(),
+	}
+}
+
+func (h *ExportApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userService).Handler)
+	r.Get("/", h.Get)
+
+	router.Mount("/export", r)
+}
+
+// @Summary Export all of the user's data (heartbeats, aliases, project labels, language mappings and preferences) as an archive, which can be imported again on another instance
+// @ID get-export
+// @Tags export
+// @Produce application/zip
+// @Produce application/x-ndjson
+// @Param format query string false "Archive format" Enums(zip, ndjson)
+// @Security ApiKeyAuth
+// @Success 200
+// @Router /export [get]
+func (h *ExportApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized) // should actually never happen
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.AccountArchiveFormatZip
+	}
+
+	routeutils.WriteAccountArchive(w, r, h.exportService, user, format)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+type GoalsApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	goalSrvc services.IGoalService
+}
+
+func NewGoalsApiHandler(userService services.IUserService, goalService services.IGoalService) *GoalsApiHandler {
+	return &GoalsApiHandler{
+		userSrvc: userService,
+		goalSrvc: goalService,
+		config:   conf.Get(),
+	}
+}
+
+func (h *GoalsApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+	r.Get("/", h.GetAll)
+	r.Get("/{id}", h.Get)
+
+	router.Mount("/goals", r)
+}
+
+// @Summary List the user's coding goals along with their progress in the current and past periods
+// @ID get-goals
+// @Tags goals
+// @Produce json
+// @Param periods query int false "Number of periods to report progress for, including the current one (default 7)"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.GoalResponse
+// @Router /goals [get]
+func (h *GoalsApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized) // should actually never happen
+		return
+	}
+
+	goals, err := h.goalSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch goals", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	periods := parsePeriods(r)
+	now := time.Now()
+	response := make([]*models.GoalResponse, 0, len(goals))
+	for _, goal := range goals {
+		history, err := h.goalSrvc.GetProgressHistory(goal, user, periods)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to compute goal progress", "goalID", goal.ID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		response = append(response, models.NewGoalResponse(goal, history, now))
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Retrieve a single coding goal along with its progress in the current and past periods
+// @ID get-goal
+// @Tags goals
+// @Produce json
+// @Param id path int true "Goal ID"
+// @Param periods query int false "Number of periods to report progress for, including the current one (default 7)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GoalResponse
+// @Router /goals/{id} [get]
+func (h *GoalsApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized) // should actually never happen
+		return
+	}
+
+	goalId, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid goal id"))
+		return
+	}
+
+	goal, err := h.goalSrvc.GetById(uint(goalId))
+	if err != nil || goal.UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("goal not found"))
+		return
+	}
+
+	history, err := h.goalSrvc.GetProgressHistory(goal, user, parsePeriods(r))
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute goal progress", "goalID", goal.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, models.NewGoalResponse(goal, history, time.Now()))
+}
+
+// parsePeriods reads the number of periods to report progress for, falling back to the service's default if absent or invalid
+func parsePeriods(r *http.Request) int {
+	periods, err := strconv.Atoi(r.URL.Query().Get("periods"))
+	if err != nil || periods < 1 {
+		return 0
+	}
+	return min(periods, 52)
+}
@@ -1,6 +1,11 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
 	"github.com/duke-git/lancet/v2/condition"
 	"github.com/go-chi/chi/v5"
 	"github.com/muety/wakapi/helpers"
@@ -18,26 +23,47 @@ import (
 	"github.com/muety/wakapi/models"
 )
 
+const (
+	heartbeatRejectionMalformed = "malformed"
+	heartbeatRejectionInvalid   = "invalid"
+	heartbeatRejectionTooOld    = "too_old"
+	heartbeatRejectionFuture    = "future"
+)
+
+// number of rejected heartbeats by reason, exposed as metrics
+var heartbeatRejections = map[string]*atomic.Int64{
+	heartbeatRejectionMalformed: {},
+	heartbeatRejectionInvalid:   {},
+	heartbeatRejectionTooOld:    {},
+	heartbeatRejectionFuture:    {},
+}
+
 type HeartbeatApiHandler struct {
 	config              *conf.Config
 	userSrvc            services.IUserService
 	heartbeatSrvc       services.IHeartbeatService
 	languageMappingSrvc services.ILanguageMappingService
+	relaySrvc           services.IRelayService
+	heartbeatRuleSrvc   services.IHeartbeatRuleService
 }
 
-func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService) *HeartbeatApiHandler {
+func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService, relayService services.IRelayService, heartbeatRuleService services.IHeartbeatRuleService) *HeartbeatApiHandler {
 	return &HeartbeatApiHandler{
 		config:              conf.Get(),
 		userSrvc:            userService,
 		heartbeatSrvc:       heartbeatService,
 		languageMappingSrvc: languageMappingService,
+		relaySrvc:           relayService,
+		heartbeatRuleSrvc:   heartbeatRuleService,
 	}
 }
 
 func (h *HeartbeatApiHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
 		r.Use(
-			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalForMethods(http.MethodOptions).Handler,
+			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalForMethods(http.MethodOptions).WithAcceptedScopes(models.ApiTokenScopeHeartbeatsWrite).Handler,
+			middlewares.NewRateLimitMiddleware(conf.RateLimitHeartbeats).Handler,
+			customMiddleware.NewRelayTargetsMiddleware(h.relaySrvc).Handler, // relay middlewares only forward heartbeats accepted by the handler
 			customMiddleware.NewWakatimeRelayMiddleware().Handler,
 		)
 		// see https://github.com/muety/wakapi/issues/203
@@ -58,12 +84,14 @@ func (h *HeartbeatApiHandler) RegisterRoutes(router chi.Router) {
 }
 
 // @Summary Push a new heartbeat
+// @Description When pushing multiple heartbeats, valid ones are accepted, even if others are rejected. The response then contains a status code and an error message for every heartbeat, in the order they were sent.
 // @ID post-heartbeat
 // @Tags heartbeat
 // @Accept json
 // @Param heartbeat body models.Heartbeat true "A single heartbeat"
 // @Security ApiKeyAuth
-// @Success 201
+// @Success 201 {object} v1.HeartbeatResponseViewModel
+// @Success 202 {object} v1.HeartbeatResponseViewModel "some heartbeats were rejected"
 // @Router /heartbeat [post]
 func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
@@ -71,10 +99,10 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
-	var heartbeats []*models.Heartbeat
-	heartbeats, err = routeutils.ParseHeartbeats(r)
+	heartbeats, isBulk, err := routeutils.ParseHeartbeatsBulk(r)
 	if err != nil {
 		conf.Log().Request(r).Error("error occurred", "error", err)
+		countHeartbeatRejection(heartbeatRejectionMalformed)
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(err.Error()))
 		return
@@ -84,11 +112,17 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 	opSys, editor, _ := utils.ParseUserAgent(userAgent)
 	machineName := r.Header.Get("X-Machine-Name")
 
-	for _, hb := range heartbeats {
+	accepted := make([]*models.Heartbeat, 0, len(heartbeats))
+	acceptedIndices := make([]int, 0, len(heartbeats)) // positions of accepted heartbeats in the request, for them to be relayed
+	rejections := make([]error, len(heartbeats))       // one per heartbeat, nil if accepted
+	var numRejected int
+
+	for i, hb := range heartbeats {
 		if hb == nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid heartbeat object"))
-			return
+			countHeartbeatRejection(heartbeatRejectionMalformed)
+			rejections[i] = errors.New("invalid heartbeat object")
+			numRejected++
+			continue
 		}
 
 		// TODO: unit test this
@@ -111,23 +145,43 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		hb.Editor = editor
 		hb.UserAgent = userAgent
 
-		if !hb.Valid() || !hb.Timely(h.config.App.HeartbeatsMaxAge()) {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid heartbeat object"))
-			return
+		if reason, err := h.validate(hb); err != nil {
+			countHeartbeatRejection(reason)
+			rejections[i] = err
+			numRejected++
+			continue
+		}
+
+		// user-defined rewrite rules, applied before hashing for the rewritten heartbeat to be what's stored
+		if drop, err := h.heartbeatRuleSrvc.Apply(user, hb); err != nil {
+			conf.Log().Request(r).Error("failed to apply heartbeat rules", "userID", user.ID, "error", err)
+		} else if drop {
+			continue // discarded on the user's behalf, thus not reported as an error
 		}
 
 		hb.Hashed()
+		accepted = append(accepted, hb)
+		acceptedIndices = append(acceptedIndices, i)
 	}
 
-	if err := h.heartbeatSrvc.InsertBatch(heartbeats); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(conf.ErrInternalServerError))
-		conf.Log().Request(r).Error("failed to batch-insert heartbeats", "error", err)
+	// single heartbeats are rejected as a whole, like before
+	if !isBulk && numRejected > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(rejections[0].Error()))
 		return
 	}
 
-	if !user.HasData {
+	if len(accepted) > 0 {
+		if err := h.heartbeatSrvc.InsertBatch(accepted); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			conf.Log().Request(r).Error("failed to batch-insert heartbeats", "error", err)
+			return
+		}
+		customMiddleware.SetAcceptedHeartbeats(r, accepted, acceptedIndices)
+	}
+
+	if !user.HasData && len(accepted) > 0 {
 		user.HasData = true
 		if _, err := h.userSrvc.Update(user); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -137,30 +191,56 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	defer func() {}()
+	status := http.StatusCreated
+	if numRejected > 0 {
+		status = http.StatusAccepted // like wakatime, in case of partial success
+	}
 
-	helpers.RespondJSON(w, r, http.StatusCreated, constructSuccessResponse(&heartbeats))
+	helpers.RespondJSON(w, r, status, constructResponse(rejections))
+}
+
+// validate checks whether the heartbeat can be stored and, if not, returns the reason for rejecting it (e.g. heartbeatRejectionTooOld) along with a human-readable error
+func (h *HeartbeatApiHandler) validate(hb *models.Heartbeat) (string, error) {
+	if !hb.Valid() {
+		return heartbeatRejectionInvalid, errors.New("invalid heartbeat object")
+	}
+	if maxAge := h.config.App.HeartbeatsMaxAge(); !hb.Timely(maxAge) {
+		if hb.Time.T().After(time.Now()) {
+			return heartbeatRejectionFuture, errors.New("heartbeat time is in the future")
+		}
+		return heartbeatRejectionTooOld, fmt.Errorf("heartbeat is older than %d days", int(maxAge.Hours()/24))
+	}
+	return "", nil
 }
 
 // construct wakatime response format https://wakatime.com/developers#heartbeats (well, not quite...)
-func constructSuccessResponse(heartbeats *[]*models.Heartbeat) *v1.HeartbeatResponseViewModel {
+func constructResponse(rejections []error) *v1.HeartbeatResponseViewModel {
 	vm := &v1.HeartbeatResponseViewModel{
-		Responses: make([][]interface{}, len(*heartbeats)),
+		Responses: make([][]interface{}, len(rejections)),
 	}
 
-	for i := range *heartbeats {
+	for i, err := range rejections {
 		r := make([]interface{}, 2)
-		r[0] = &v1.HeartbeatResponseData{
-			Data:  nil, // see comment in struct declaration for details
-			Error: nil,
+		if err != nil {
+			r[0] = &v1.HeartbeatResponseData{Error: err.Error()}
+			r[1] = http.StatusBadRequest
+		} else {
+			r[0] = &v1.HeartbeatResponseData{
+				Data:  nil, // see comment in struct declaration for details
+				Error: nil,
+			}
+			r[1] = http.StatusCreated
 		}
-		r[1] = http.StatusCreated
 		vm.Responses[i] = r
 	}
 
 	return vm
 }
 
+func countHeartbeatRejection(reason string) {
+	heartbeatRejections[reason].Add(1)
+}
+
 // inplace!
 func fillPlaceholders(hb *models.Heartbeat, user *models.User, srv services.IHeartbeatService) *models.Heartbeat {
 	// wakatime has a special keyword that indicates to use the most recent project for a given heartbeat
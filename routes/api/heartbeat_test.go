@@ -1,16 +1,23 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	customMiddleware "github.com/muety/wakapi/middlewares/custom"
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHeartbeatHandler_Options(t *testing.T) {
@@ -24,7 +31,7 @@ func TestHeartbeatHandler_Options(t *testing.T) {
 	userServiceMock := new(mocks.UserServiceMock)
 	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
 
-	heartbeatHandler := NewHeartbeatApiHandler(userServiceMock, heartbeatServiceMock, nil)
+	heartbeatHandler := NewHeartbeatApiHandler(userServiceMock, heartbeatServiceMock, nil, nil, nil)
 	heartbeatHandler.RegisterRoutes(apiRouter)
 
 	t.Run("when receiving cors preflight request", func(t *testing.T) {
@@ -104,3 +111,184 @@ func Test_fillPlaceholders(t *testing.T) {
 		})
 	})
 }
+
+func TestHeartbeatHandler_Post(t *testing.T) {
+	cfg := config.Empty()
+	cfg.App.HeartbeatMaxAge = "720h"
+	cfg.InstanceId = "wakapi-test"
+	config.Set(cfg)
+
+	user := &models.User{ID: "testuser01", HasData: true}
+
+	noRules := new(mocks.HeartbeatRuleServiceMock)
+	noRules.On("Apply", user, mock.Anything).Return(false, nil)
+
+	serve := func(heartbeatServiceMock *mocks.HeartbeatServiceMock, heartbeatRuleServiceMock *mocks.HeartbeatRuleServiceMock, body string) *httptest.ResponseRecorder {
+		sut := NewHeartbeatApiHandler(new(mocks.UserServiceMock), heartbeatServiceMock, nil, nil, heartbeatRuleServiceMock)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/heartbeats", strings.NewReader(body))
+		middlewares.NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewares.SetPrincipal(r, user)
+			sut.Post(w, r)
+		})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	serveRelayed := func(heartbeatServiceMock *mocks.HeartbeatServiceMock, heartbeatRuleServiceMock *mocks.HeartbeatRuleServiceMock, relayServiceMock *mocks.RelayServiceMock, body string) *httptest.ResponseRecorder {
+		sut := NewHeartbeatApiHandler(new(mocks.UserServiceMock), heartbeatServiceMock, nil, relayServiceMock, heartbeatRuleServiceMock)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/heartbeats", strings.NewReader(body))
+		relayed := customMiddleware.NewRelayTargetsMiddleware(relayServiceMock).Handler(http.HandlerFunc(sut.Post))
+		middlewares.NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewares.SetPrincipal(r, user)
+			relayed.ServeHTTP(w, r)
+		})).ServeHTTP(rec, req)
+		return rec
+	}
+
+	now := time.Now().Unix()
+	valid := fmt.Sprintf(`{"entity": "main.go", "type": "file", "time": %d}`, now)
+	tooOld := fmt.Sprintf(`{"entity": "main.go", "type": "file", "time": %d}`, now-int64((60*24*time.Hour).Seconds()))
+	future := fmt.Sprintf(`{"entity": "main.go", "type": "file", "time": %d}`, now+int64((2*time.Hour).Seconds()))
+
+	t.Run("when receiving partially valid heartbeats", func(t *testing.T) {
+		t.Run("should accept valid ones and report errors for the rest", func(t *testing.T) {
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+			heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+
+			rec := serve(heartbeatServiceMock, noRules, fmt.Sprintf("[%s, %s, null, %s]", tooOld, valid, future))
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+
+			inserted := heartbeatServiceMock.Calls[0].Arguments.Get(0).([]*models.Heartbeat)
+			assert.Len(t, inserted, 1)
+
+			var response struct {
+				Responses [][]interface{} `json:"responses"`
+			}
+			assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Len(t, response.Responses, 4)
+			assert.Equal(t, 400.0, response.Responses[0][1])
+			assert.Equal(t, "heartbeat is older than 30 days", response.Responses[0][0].(map[string]interface{})["error"])
+			assert.Equal(t, 201.0, response.Responses[1][1])
+			assert.Nil(t, response.Responses[1][0].(map[string]interface{})["error"])
+			assert.Equal(t, 400.0, response.Responses[2][1])
+			assert.Equal(t, 400.0, response.Responses[3][1])
+			assert.Equal(t, "heartbeat time is in the future", response.Responses[3][0].(map[string]interface{})["error"])
+		})
+
+		t.Run("should respond with created if all were accepted", func(t *testing.T) {
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+			heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+
+			rec := serve(heartbeatServiceMock, noRules, fmt.Sprintf("[%s, %s]", valid, valid))
+			assert.Equal(t, http.StatusCreated, rec.Code)
+		})
+
+		t.Run("should reject a single invalid heartbeat as a whole", func(t *testing.T) {
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+
+			rec := serve(heartbeatServiceMock, noRules, tooOld)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			heartbeatServiceMock.AssertNotCalled(t, "InsertBatch", mock.Anything)
+		})
+
+		t.Run("should silently discard heartbeats dropped by rules", func(t *testing.T) {
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+			heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+			heartbeatRuleServiceMock := new(mocks.HeartbeatRuleServiceMock)
+			heartbeatRuleServiceMock.On("Apply", user, mock.MatchedBy(func(hb *models.Heartbeat) bool { return hb.Entity == "secret.go" })).Return(true, nil)
+			heartbeatRuleServiceMock.On("Apply", user, mock.Anything).Return(false, nil)
+
+			dropped := fmt.Sprintf(`{"entity": "secret.go", "type": "file", "time": %d}`, now)
+			rec := serve(heartbeatServiceMock, heartbeatRuleServiceMock, fmt.Sprintf("[%s, %s]", valid, dropped))
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			inserted := heartbeatServiceMock.Calls[0].Arguments.Get(0).([]*models.Heartbeat)
+			assert.Len(t, inserted, 1)
+			assert.Equal(t, "main.go", inserted[0].Entity)
+		})
+	})
+
+	t.Run("when relaying heartbeats", func(t *testing.T) {
+		t.Run("should only forward accepted ones after they were stored", func(t *testing.T) {
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+			heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+			relayServiceMock := new(mocks.RelayServiceMock)
+			relayServiceMock.On("GetByUser", user.ID).Return([]*models.RelayTarget{{ID: 1, Enabled: true}}, nil)
+			relayServiceMock.On("Forward", user, mock.Anything, mock.Anything, mock.Anything).Return()
+
+			rec := serveRelayed(heartbeatServiceMock, noRules, relayServiceMock, fmt.Sprintf("[%s, %s, null]", tooOld, valid))
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+
+			relayServiceMock.AssertNumberOfCalls(t, "Forward", 1)
+			relayed := relayServiceMock.Calls[1].Arguments.Get(1).([]*models.Heartbeat)
+			rawRelayed := relayServiceMock.Calls[1].Arguments.Get(2).([]interface{})
+			assert.Len(t, relayed, 1)
+			assert.Len(t, rawRelayed, 1)
+			assert.Equal(t, float64(now), rawRelayed[0].(map[string]interface{})["time"])
+		})
+
+		t.Run("should not forward anything if storing failed", func(t *testing.T) {
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+			heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(errors.New("failed"))
+			relayServiceMock := new(mocks.RelayServiceMock)
+			relayServiceMock.On("GetByUser", user.ID).Return([]*models.RelayTarget{{ID: 1, Enabled: true}}, nil)
+
+			rec := serveRelayed(heartbeatServiceMock, noRules, relayServiceMock, valid)
+			assert.Equal(t, http.StatusInternalServerError, rec.Code)
+			relayServiceMock.AssertNotCalled(t, "Forward", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		t.Run("should neither forward dropped nor redacted heartbeats", func(t *testing.T) {
+			received := make(chan []map[string]interface{}, 1)
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var data []map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&data)
+				received <- data
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer upstream.Close()
+
+			relayUser := &models.User{ID: "testuser02", HasData: true, WakatimeApiKey: "wakatime-key", WakatimeApiUrl: upstream.URL}
+
+			heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+			heartbeatServiceMock.On("InsertBatch", mock.Anything).Return(nil)
+			heartbeatRuleRepositoryMock := new(mocks.HeartbeatRuleRepositoryMock)
+			heartbeatRuleRepositoryMock.On("GetByUser", relayUser.ID).Return([]*models.HeartbeatRule{
+				{Field: models.HeartbeatRuleFieldProject, MatchType: models.HeartbeatRuleMatchGlob, Pattern: "secret-*", Action: models.HeartbeatRuleActionDrop},
+				{Field: models.HeartbeatRuleFieldEntity, MatchType: models.HeartbeatRuleMatchGlob, Pattern: "/home/*", Action: models.HeartbeatRuleActionRedactEntity},
+			}, nil)
+			relayServiceMock := new(mocks.RelayServiceMock)
+			relayServiceMock.On("GetByUser", relayUser.ID).Return([]*models.RelayTarget{{ID: 1, Enabled: true}}, nil)
+			relayServiceMock.On("Forward", relayUser, mock.Anything, mock.Anything, mock.Anything).Return()
+
+			dropped := fmt.Sprintf(`{"entity": "main.go", "type": "file", "project": "secret-project", "time": %d}`, now)
+			redacted := fmt.Sprintf(`{"entity": "/home/alice/private/main.go", "type": "file", "project": "wakapi", "time": %d}`, now)
+
+			sut := NewHeartbeatApiHandler(new(mocks.UserServiceMock), heartbeatServiceMock, nil, relayServiceMock, services.NewHeartbeatRuleService(heartbeatRuleRepositoryMock))
+			handler := customMiddleware.NewRelayTargetsMiddleware(relayServiceMock).Handler(customMiddleware.NewWakatimeRelayMiddleware().Handler(http.HandlerFunc(sut.Post)))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/heartbeats", strings.NewReader(fmt.Sprintf("[%s, %s]", dropped, redacted)))
+			middlewares.NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				middlewares.SetPrincipal(r, relayUser)
+				handler.ServeHTTP(w, r)
+			})).ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			relayServiceMock.AssertNumberOfCalls(t, "Forward", 1)
+			rawRelayed := relayServiceMock.Calls[1].Arguments.Get(2).([]interface{})
+			assert.Len(t, rawRelayed, 1)
+			assert.Equal(t, "wakapi", rawRelayed[0].(map[string]interface{})["project"])
+			assert.Regexp(t, `^[0-9a-f]{16}\.go$`, rawRelayed[0].(map[string]interface{})["entity"])
+
+			select {
+			case data := <-received:
+				assert.Len(t, data, 1)
+				assert.Equal(t, "wakapi", data[0]["project"])
+				assert.Regexp(t, `^[0-9a-f]{16}\.go$`, data[0]["entity"])
+			case <-time.After(5 * time.Second):
+				t.Fatal("heartbeats were not relayed to wakatime")
+			}
+		})
+	})
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+const (
+	// interval at which idle states are re-evaluated and keep-alive comments are sent to prevent proxies from closing the connection
+	liveKeepAliveInterval = 30 * time.Second
+	// streams are closed after this duration, clients (e.g. browsers' EventSource) are expected to reconnect
+	liveMaxStreamDuration = 1 * time.Hour
+)
+
+type LiveApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	teamSrvc services.ITeamService
+	liveSrvc services.ILiveService
+}
+
+func NewLiveApiHandler(userService services.IUserService, teamService services.ITeamService, liveService services.ILiveService) *LiveApiHandler {
+	return &LiveApiHandler{
+		userSrvc: userService,
+		teamSrvc: teamService,
+		liveSrvc: liveService,
+		config:   conf.Get(),
+	}
+}
+
+func (h *LiveApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+	r.Get("/team/{id}", h.GetTeam)
+	r.Get("/{user}", h.Get)
+
+	router.Mount("/live", r)
+}
+
+// liveUser is a user whose live status is streamed, along with whether details have to be redacted according to their sharing settings
+type liveUser struct {
+	user   *models.User
+	redact bool
+}
+
+// @Summary Stream a user's live coding status as server-sent events
+// @Description Emits a `status` event with the current state right away and another one whenever a new heartbeat comes in or the user turns idle. Other users' statuses are only available if they share their data publicly and are redacted according to their sharing settings.
+// @ID get-live-status
+// @Tags live
+// @Produce text/event-stream
+// @Param user path string true "User ID to stream (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.LiveStatus
+// @Router /live/{user} [get]
+func (h *LiveApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	principal := middlewares.GetPrincipal(r)
+	if principal == nil {
+		w.WriteHeader(http.StatusUnauthorized) // should actually never happen
+		return
+	}
+
+	userId := chi.URLParam(r, "user")
+	if userId == "current" || userId == principal.ID {
+		h.stream(w, r, []*liveUser{{user: principal}})
+		return
+	}
+
+	user, err := h.userSrvc.GetUserById(userId)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("user not found"))
+		return
+	}
+	if !principal.IsAdmin && user.ShareDataMaxDays == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(conf.ErrForbidden))
+		return
+	}
+
+	h.stream(w, r, []*liveUser{{user: user, redact: !principal.IsAdmin}})
+}
+
+// @Summary Stream the live coding statuses of all members of a team as server-sent events
+// @Description Emits a `status` event for every member right away and another one whenever a member's state changes. Members who don't share their data publicly are left out, others' statuses are redacted according to their sharing settings.
+// @ID get-live-team-status
+// @Tags live
+// @Produce text/event-stream
+// @Param id path int true "Team ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.LiveStatus
+// @Router /live/team/{id} [get]
+func (h *LiveApiHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	principal := middlewares.GetPrincipal(r)
+	if principal == nil {
+		w.WriteHeader(http.StatusUnauthorized) // should actually never happen
+		return
+	}
+
+	teamId, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid team id"))
+		return
+	}
+
+	team, err := h.teamSrvc.GetById(uint(teamId))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("team not found"))
+		return
+	}
+	if _, err := h.teamSrvc.GetMember(team, principal.ID); err != nil {
+		w.WriteHeader(http.StatusNotFound) // don't disclose the team's existence to non-members
+		w.Write([]byte("team not found"))
+		return
+	}
+
+	members, err := h.teamSrvc.GetMembers(team)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch team members", "teamID", team.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	users := make([]*liveUser, 0, len(members))
+	for _, m := range members {
+		if m.UserID == principal.ID {
+			users = append(users, &liveUser{user: principal})
+		} else if m.User != nil && m.User.ShareDataMaxDays != 0 {
+			users = append(users, &liveUser{user: m.User, redact: true})
+		}
+	}
+
+	h.stream(w, r, users)
+}
+
+func (h *LiveApiHandler) stream(w http.ResponseWriter, r *http.Request, users []*liveUser) {
+	usersById := make(map[string]*liveUser, len(users))
+	userIds := make([]string, 0, len(users))
+	for _, u := range users {
+		usersById[u.user.ID] = u
+		userIds = append(userIds, u.user.ID)
+	}
+
+	// subscribe before fetching initial statuses to not miss any updates in between
+	updates, cancel := h.liveSrvc.Subscribe(userIds...)
+	defer cancel()
+
+	statuses := make(map[string]*models.LiveStatus, len(users))
+	for _, u := range users {
+		status, err := h.liveSrvc.GetStatus(u.user)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to get live status", "userID", u.user.ID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		statuses[u.user.ID] = status
+	}
+
+	// streams outlive the server's regular write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(liveMaxStreamDuration + liveKeepAliveInterval)); err != nil {
+		conf.Log().Request(r).Warn("failed to extend write deadline for live stream", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable response buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	send := func(status *models.LiveStatus) error {
+		u := usersById[status.UserID]
+		if u.redact {
+			status = status.Redacted(u.user)
+		}
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for _, u := range users {
+		if err := send(statuses[u.user.ID]); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(liveKeepAliveInterval)
+	defer ticker.Stop()
+	deadline := time.After(liveMaxStreamDuration)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			return
+		case status, ok := <-updates:
+			if !ok {
+				return
+			}
+			statuses[status.UserID] = status
+			if err := send(status); err != nil {
+				return
+			}
+		case now := <-ticker.C:
+			// users turn idle without any further heartbeats coming in
+			for _, u := range users {
+				previous := statuses[u.user.ID]
+				current := previous.At(now, u.user.HeartbeatsTimeout())
+				if current.Active == previous.Active {
+					continue
+				}
+				statuses[u.user.ID] = current
+				if err := send(current); err != nil {
+					return
+				}
+			}
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -46,6 +46,9 @@ const (
 	DescJobQueueEnqueued      = "Number of jobs currently enqueued"
 	DescJobQueueTotalFinished = "Total number of processed jobs"
 
+	DescRateLimitRejected  = "Total number of requests rejected by rate limiting"
+	DescHeartbeatsRejected = "Total number of heartbeats rejected during ingestion"
+
 	DescMemAlloc        = "Total number of bytes currently allocated for heap"
 	DescMemSys          = "Total number of bytes currently obtained from the OS"
 	DescMemHeapSys      = "Total number of bytes currently obtained from the OS for heap"
@@ -90,7 +93,7 @@ func (h *MetricsHandler) RegisterRoutes(router chi.Router) {
 	slog.Info("exposing prometheus metrics under /api/metrics")
 
 	r := chi.NewRouter()
-	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeMetricsRead).Handler)
 	r.Get("/", h.Get)
 
 	router.Mount("/metrics", r)
@@ -376,6 +379,24 @@ func (h *MetricsHandler) getUserMetrics(user *models.User) (*mm.Metrics, error)
 		})
 	}
 
+	for reason, count := range heartbeatRejections {
+		metrics = append(metrics, &mm.CounterMetric{
+			Name:   MetricsPrefix + "_heartbeats_rejected_total",
+			Value:  count.Load(),
+			Desc:   DescHeartbeatsRejected,
+			Labels: []mm.Label{{Key: "reason", Value: reason}},
+		})
+	}
+
+	for _, rm := range conf.GetRateLimitMetrics() {
+		metrics = append(metrics, &mm.CounterMetric{
+			Name:   MetricsPrefix + "_rate_limit_rejected_total",
+			Value:  rm.Rejected,
+			Desc:   DescRateLimitRejected,
+			Labels: []mm.Label{{Key: "scope", Value: rm.Scope}, {Key: "by", Value: rm.By}},
+		})
+	}
+
 	return &metrics, nil
 }
 
@@ -8,6 +8,7 @@ import (
 
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
 )
 
@@ -27,7 +28,8 @@ func NewSummaryApiHandler(userService services.IUserService, summaryService serv
 
 func (h *SummaryApiHandler) RegisterRoutes(router chi.Router) {
 	r := chi.NewRouter()
-	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+	r.Use(middlewares.NewRateLimitMiddleware(conf.RateLimitSummaries).Handler)
 	r.Get("/", h.Get)
 
 	router.Mount("/summary", r)
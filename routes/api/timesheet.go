@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type TimesheetApiHandler struct {
+	config           *conf.Config
+	userSrvc         services.IUserService
+	timesheetService services.ITimesheetService
+}
+
+func NewTimesheetApiHandler(userService services.IUserService, timesheetService services.ITimesheetService) *TimesheetApiHandler {
+	return &TimesheetApiHandler{
+		userSrvc:         userService,
+		timesheetService: timesheetService,
+		config:           conf.Get(),
+	}
+}
+
+func (h *TimesheetApiHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+	r.Get("/", h.Get)
+
+	router.Mount("/timesheet", r)
+}
+
+// @Summary Retrieve the time spent on the user's projects per day or week, rounded and priced for billing
+// @ID get-timesheet
+// @Tags timesheet
+// @Produce json
+// @Produce text/csv
+// @Param interval query string false "Interval identifier, defaults to 'last_month'" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2024-03-01'), alternatively to interval"
+// @Param to query string false "End date, inclusive if given as date only (e.g. '2024-03-31')"
+// @Param project query string false "Comma-separated list of projects to include, defaults to all"
+// @Param label query string false "Project label to include all projects of"
+// @Param group_by query string false "Period to sum up time by" Enums(day, week)
+// @Param rounding query int false "Minutes to round each project's time per period to"
+// @Param rounding_mode query string false "How to round" Enums(up, down, nearest)
+// @Param rates query string false "Hourly rates per project label (e.g. 'work:80,oss:0')"
+// @Param rate query number false "Hourly rate for projects without a label rate"
+// @Param currency query string false "Currency of the rates (e.g. 'EUR')"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Timesheet
+// @Failure 400 {string} string "bad request"
+// @Router /timesheet [get]
+func (h *TimesheetApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized) // should actually never happen
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.TimesheetFormatJson
+	}
+	if format != models.TimesheetFormatJson && format != models.TimesheetFormatCsv {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unsupported 'format' parameter"))
+		return
+	}
+
+	params, err := helpers.ParseTimesheetParams(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	timesheet, err := h.timesheetService.Generate(user, params)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to generate timesheet", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	if format == models.TimesheetFormatCsv {
+		routeutils.WriteTimesheetCsv(w, r, timesheet)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, timesheet)
+}
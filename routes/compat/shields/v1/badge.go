@@ -7,6 +7,7 @@ import (
 	"github.com/muety/wakapi/models/types"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"net/http"
+	"strconv"
 	"time"
 
 	conf "github.com/muety/wakapi/config"
@@ -17,23 +18,38 @@ import (
 )
 
 type BadgeHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
-	cache       *cache.Cache
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	goalSrvc        services.IGoalService
+	badgeSrvc       services.IBadgeService
+	leaderboardSrvc services.ILeaderboardService
+	cache           *cache.Cache
 }
 
-func NewBadgeHandler(summaryService services.ISummaryService, userService services.IUserService) *BadgeHandler {
+func NewBadgeHandler(summaryService services.ISummaryService, userService services.IUserService, goalService services.IGoalService, badgeService services.IBadgeService, leaderboardService services.ILeaderboardService) *BadgeHandler {
 	return &BadgeHandler{
-		summarySrvc: summaryService,
-		userSrvc:    userService,
-		cache:       cache.New(time.Hour, time.Hour),
-		config:      conf.Get(),
+		summarySrvc:     summaryService,
+		userSrvc:        userService,
+		goalSrvc:        goalService,
+		badgeSrvc:       badgeService,
+		leaderboardSrvc: leaderboardService,
+		cache:           cache.New(time.Hour, time.Hour),
+		config:          conf.Get(),
 	}
 }
 
 func (h *BadgeHandler) RegisterRoutes(router chi.Router) {
 	// no auth middleware here, handler itself resolves the user
+	router.Get("/compat/shields/v1/{user}/goal/{id}", h.GetGoal)
+	router.Get("/compat/shields/v1/{user}/languages", h.GetLanguages)
+	router.Get("/compat/shields/v1/{user}/languages/*", h.GetLanguages)
+	router.Get("/compat/shields/v1/{user}/sparkline", h.GetSparkline)
+	router.Get("/compat/shields/v1/{user}/sparkline/*", h.GetSparkline)
+	router.Get("/compat/shields/v1/{user}/streak", h.GetStreak)
+	router.Get("/compat/shields/v1/{user}/streak/*", h.GetStreak)
+	router.Get("/compat/shields/v1/{user}/rank", h.GetRank)
+	router.Get("/compat/shields/v1/{user}/rank/*", h.GetRank)
 	router.Get("/compat/shields/v1/{user}/*", h.Get)
 }
 
@@ -45,6 +61,7 @@ func (h *BadgeHandler) RegisterRoutes(router chi.Router) {
 // @Param user path string true "User ID to fetch data for"
 // @Param interval path string true "Interval to aggregate data for" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
 // @Param filter path string true "Filter to apply (e.g. 'project:wakapi' or 'language:Go')"
+// @Param style query string false "Badge style" Enums(flat, for-the-badge)
 // @Success 200 {object} v1.BadgeData
 // @Router /compat/shields/v1/{user}/{interval}/{filter} [get]
 func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -64,7 +81,7 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	cacheKey := fmt.Sprintf("%s_%v_%s", user.ID, *interval.Key, filters.Hash())
 	if cacheResult, ok := h.cache.Get(cacheKey); ok {
-		helpers.RespondJSON(w, r, http.StatusOK, cacheResult.(*v1.BadgeData))
+		h.respondBadge(w, r, cacheResult.(*v1.BadgeData))
 		return
 	}
 
@@ -84,7 +101,131 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	vm := v1.NewBadgeDataFrom(summary)
 	h.cache.SetDefault(cacheKey, vm)
-	helpers.RespondJSON(w, r, http.StatusOK, vm)
+	h.respondBadge(w, r, vm)
+}
+
+// @Summary Get goal badge data
+// @Description Retrieve the progress towards a goal within its current period in a format compatible with [Shields.io](https://shields.io/endpoint). Requires the goal's entities to be shared publicly.
+// @ID get-badge-goal
+// @Tags badges
+// @Produce json
+// @Param user path string true "User ID to fetch data for"
+// @Param id path int true "Goal ID"
+// @Param style query string false "Badge style" Enums(flat, for-the-badge)
+// @Success 200 {object} v1.BadgeData
+// @Router /compat/shields/v1/{user}/goal/{id} [get]
+func (h *BadgeHandler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	goalId, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.getCustom(w, r, "goal_"+chi.URLParam(r, "id"), func(user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadGoalBadgeData(h.goalSrvc, uint(goalId), nil, user)
+	})
+}
+
+// @Summary Get top languages badge data
+// @Description Retrieve the shares of the top languages within a given range (e.g. one week), optionally for a given entity (e.g. a project), in a format compatible with [Shields.io](https://shields.io/endpoint). Requires languages to be shared publicly.
+// @ID get-badge-languages
+// @Tags badges
+// @Produce json
+// @Param user path string true "User ID to fetch data for"
+// @Param interval path string true "Interval to aggregate data for" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param filter path string false "Filter to apply (e.g. 'project:wakapi')"
+// @Param style query string false "Badge style" Enums(flat, for-the-badge)
+// @Success 200 {object} v1.BadgeData
+// @Router /compat/shields/v1/{user}/languages/{interval}/{filter} [get]
+func (h *BadgeHandler) GetLanguages(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "languages", func(user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadLanguagesBadgeData(h.summarySrvc, r.URL.Path, nil, user)
+	})
+}
+
+// @Summary Get sparkline badge data
+// @Description Retrieve the coding time per day within a given range (last 30 days by default) as a text sparkline, followed by the total, in a format compatible with [Shields.io](https://shields.io/endpoint)
+// @ID get-badge-sparkline
+// @Tags badges
+// @Produce json
+// @Param user path string true "User ID to fetch data for"
+// @Param interval path string false "Interval to aggregate data for" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year)
+// @Param filter path string false "Filter to apply (e.g. 'project:wakapi' or 'language:Go')"
+// @Param style query string false "Badge style" Enums(flat, for-the-badge)
+// @Success 200 {object} v1.BadgeData
+// @Router /compat/shields/v1/{user}/sparkline/{interval}/{filter} [get]
+func (h *BadgeHandler) GetSparkline(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "sparkline", func(user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadSparklineBadgeData(h.badgeSrvc, r.URL.Path, nil, user)
+	})
+}
+
+// @Summary Get streak badge data
+// @Description Retrieve the number of consecutive days with coding activity up until today in a format compatible with [Shields.io](https://shields.io/endpoint). Only looks back as far as data is shared publicly.
+// @ID get-badge-streak
+// @Tags badges
+// @Produce json
+// @Param user path string true "User ID to fetch data for"
+// @Param filter path string false "Filter to apply (e.g. 'project:wakapi' or 'language:Go')"
+// @Param style query string false "Badge style" Enums(flat, for-the-badge)
+// @Success 200 {object} v1.BadgeData
+// @Router /compat/shields/v1/{user}/streak/{filter} [get]
+func (h *BadgeHandler) GetStreak(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "streak", func(user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadStreakBadgeData(h.badgeSrvc, r.URL.Path, nil, user)
+	})
+}
+
+// @Summary Get leaderboard rank badge data
+// @Description Retrieve the user's rank on the public leaderboard, either in total or for a given language, in a format compatible with [Shields.io](https://shields.io/endpoint)
+// @ID get-badge-rank
+// @Tags badges
+// @Produce json
+// @Param user path string true "User ID to fetch data for"
+// @Param filter path string false "Language to get the rank for (e.g. 'language:Go')"
+// @Param style query string false "Badge style" Enums(flat, for-the-badge)
+// @Success 200 {object} v1.BadgeData
+// @Router /compat/shields/v1/{user}/rank/{filter} [get]
+func (h *BadgeHandler) GetRank(w http.ResponseWriter, r *http.Request) {
+	h.getCustom(w, r, "rank", func(user *models.User) (*v1.BadgeData, error, int) {
+		return routeutils.LoadRankBadgeData(h.leaderboardSrvc, r.URL.Path, nil, user)
+	})
+}
+
+func (h *BadgeHandler) getCustom(w http.ResponseWriter, r *http.Request, kind string, load func(user *models.User) (*v1.BadgeData, error, int)) {
+	user, err := h.userSrvc.GetUserById(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%s_%s_%s", user.ID, kind, r.URL.Path)
+	vm, ok := h.cache.Get(cacheKey)
+	if !ok {
+		badgeData, err, status := load(user)
+		if err != nil && status >= http.StatusInternalServerError {
+			conf.Log().Request(r).Error("failed to load badge data", "kind", kind, "userID", user.ID, "error", err)
+			w.WriteHeader(status)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		if err != nil {
+			w.WriteHeader(status)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		h.cache.SetDefault(cacheKey, badgeData)
+		vm = badgeData
+	}
+
+	h.respondBadge(w, r, vm.(*v1.BadgeData))
+}
+
+func (h *BadgeHandler) respondBadge(w http.ResponseWriter, r *http.Request, vm *v1.BadgeData) {
+	// copy, as cached item must not be modified
+	badgeData := *vm
+	badgeData.Style = routeutils.GetBadgeStyle(r)
+	helpers.RespondJSON(w, r, http.StatusOK, &badgeData)
 }
 
 func (h *BadgeHandler) loadUserSummary(user *models.User, interval *models.IntervalKey, filters *models.Filters) (*models.Summary, error, int) {
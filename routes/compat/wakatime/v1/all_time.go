@@ -30,7 +30,8 @@ func NewAllTimeHandler(userService services.IUserService, summaryService service
 
 func (h *AllTimeHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+		r.Use(middlewares.NewRateLimitMiddleware(conf.RateLimitSummaries).Handler)
 		r.Get("/compat/wakatime/v1/users/{user}/all_time_since_today", h.Get)
 	})
 }
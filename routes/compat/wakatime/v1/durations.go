@@ -0,0 +1,155 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type DurationsHandler struct {
+	config       *conf.Config
+	userSrvc     services.IUserService
+	durationSrvc services.IDurationService
+	summarySrvc  services.ISummaryService
+	aliasSrvc    services.IAliasService
+}
+
+func NewDurationsHandler(userService services.IUserService, durationService services.IDurationService, summaryService services.ISummaryService, aliasService services.IAliasService) *DurationsHandler {
+	return &DurationsHandler{
+		userSrvc:     userService,
+		durationSrvc: durationService,
+		summarySrvc:  summaryService,
+		aliasSrvc:    aliasService,
+		config:       conf.Get(),
+	}
+}
+
+func (h *DurationsHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+		r.Use(middlewares.NewRateLimitMiddleware(conf.RateLimitSummaries).Handler)
+		r.Get("/v1/users/{user}/durations", h.Get)
+		r.Get("/compat/wakatime/v1/users/{user}/durations", h.Get)
+	})
+}
+
+// @Summary Retrieve a user's coding activity for the given day as a list of durations
+// @Description Mimics https://wakatime.com/developers#durations, except for parameter writes_only, which is ignored
+// @ID get-wakatime-durations
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param date query string true "Requested day (e.g. '2021-02-07'), durations are returned from 12am until 11:59pm in the user's timezone"
+// @Param project query string false "Project to filter by"
+// @Param branches query string false "Comma-separated list of branches to filter by"
+// @Param timeout query int false "Heartbeats timeout in minutes to join durations by, defaults to the user's preference"
+// @Param timezone query string false "Timezone to interpret the date in (e.g. 'Europe/Berlin'), defaults to the user's timezone"
+// @Param slice_by query string false "Type of entity to slice durations by" Enums(project, entity, language, editor, os, machine, category, branch)
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.DurationsViewModel
+// @Failure 400 {string} string "bad request"
+// @Router /compat/wakatime/v1/users/{user}/durations [get]
+func (h *DurationsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	params := r.URL.Query()
+
+	timezone := user.TZ()
+	if tzParam := params.Get("timezone"); tzParam != "" {
+		if timezone, err = time.LoadLocation(tzParam); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid 'timezone' parameter"))
+			return
+		}
+	}
+
+	date, err := time.ParseInLocation(conf.SimpleDateFormat, params.Get("date"), timezone)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("missing or invalid 'date' parameter"))
+		return
+	}
+
+	sliceBy := params.Get("slice_by")
+	if sliceBy == "" {
+		sliceBy = "project"
+	}
+	if _, ok := v1.DurationSliceTypes[sliceBy]; !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unsupported 'slice_by' parameter"))
+		return
+	}
+
+	var timeout *time.Duration
+	if timeoutParam := params.Get("timeout"); timeoutParam != "" {
+		val, err := strconv.Atoi(timeoutParam)
+		dur := time.Duration(val) * time.Minute
+		if err != nil || dur < models.MinHeartbeatsTimeout || dur > models.MaxHeartbeatsTimeout {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid 'timeout' parameter"))
+			return
+		}
+		timeout = &dur
+	}
+
+	filters := &models.Filters{}
+	if project := params.Get("project"); project != "" {
+		filters.With(models.SummaryProject, project)
+	}
+	filters = h.summarySrvc.ResolveFilters(user, filters)
+
+	rangeFrom, rangeTo := datetime.BeginOfDay(date), datetime.EndOfDay(date)
+
+	durations, err := h.durationSrvc.Get(rangeFrom, rangeTo, user, filters, timeout, false)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		conf.Log().Request(r).Error("failed to retrieve durations", "error", err)
+		return
+	}
+
+	branches := v1.BranchesFrom(durations) // all branches of the day, before filtering by them
+	if branchesParam := params.Get("branches"); branchesParam != "" {
+		wantedBranches := models.OrFilter{}
+		for _, b := range strings.Split(branchesParam, ",") {
+			wantedBranches = append(wantedBranches, strings.TrimSpace(b))
+		}
+		filtered := make(models.Durations, 0, len(durations))
+		for _, d := range durations {
+			if wantedBranches.MatchAny(d.Branch) {
+				filtered = append(filtered, d)
+			}
+		}
+		durations = filtered
+	}
+
+	vm := &v1.DurationsViewModel{
+		Data:     v1.NewDurationsFrom(durations, sliceBy, h.resolveAlias(user)),
+		Branches: branches,
+		Start:    rangeFrom.UTC().Format(time.RFC3339),
+		End:      rangeTo.UTC().Format(time.RFC3339),
+		Timezone: timezone.String(),
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+func (h *DurationsHandler) resolveAlias(user *models.User) models.AliasResolver {
+	return func(t uint8, k string) string {
+		s, _ := h.aliasSrvc.GetAliasOrDefault(user.ID, t, k)
+		return s
+	}
+}
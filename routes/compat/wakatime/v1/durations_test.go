@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDurationsHandler_Get(t *testing.T) {
+	config.Set(config.Empty())
+
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	router.Mount("/api", apiRouter)
+
+	user := &models.User{ID: "AdminUser", ApiKey: "admin-user-api-key", Location: "Europe/Berlin"}
+	tz, _ := time.LoadLocation(user.Location)
+	t0 := time.Date(2024, 3, 1, 10, 0, 0, 0, tz)
+
+	durations := models.Durations{
+		{Time: models.CustomTime(t0), Duration: 10 * time.Minute, Project: "wakapi", Language: "Go", Branch: "master"},
+		{Time: models.CustomTime(t0.Add(10 * time.Minute)), Duration: 5 * time.Minute, Project: "wakapi", Language: "JavaScript", Branch: "master"},
+		{Time: models.CustomTime(t0.Add(15 * time.Minute)), Duration: 5 * time.Minute, Project: "wakapi-legacy", Language: "Go", Branch: "feature"},
+		{Time: models.CustomTime(t0.Add(2 * time.Hour)), Duration: 20 * time.Minute, Project: "anchr", Language: "Go", Branch: "master"},
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", user.ID).Return(user, nil)
+	userServiceMock.On("GetUserByKey", user.ApiKey).Return(user, nil)
+
+	durationServiceMock := new(mocks.DurationServiceMock)
+	durationServiceMock.On("Get", mock.Anything, mock.Anything, user, mock.Anything, mock.Anything, false).Return(durations, nil)
+
+	summaryServiceMock := new(mocks.SummaryServiceMock)
+	summaryServiceMock.On("ResolveFilters", user, mock.Anything).Return(&models.Filters{})
+
+	aliasServiceMock := new(mocks.AliasServiceMock)
+	aliasServiceMock.On("GetAliasOrDefault", user.ID, models.SummaryProject, "wakapi-legacy").Return("wakapi", nil)
+	for _, key := range []string{"wakapi", "anchr"} {
+		aliasServiceMock.On("GetAliasOrDefault", user.ID, models.SummaryProject, key).Return(key, nil)
+	}
+	for _, key := range []string{"Go", "JavaScript"} {
+		aliasServiceMock.On("GetAliasOrDefault", user.ID, models.SummaryLanguage, key).Return(key, nil)
+	}
+
+	sut := NewDurationsHandler(userServiceMock, durationServiceMock, summaryServiceMock, aliasServiceMock)
+	sut.RegisterRoutes(apiRouter)
+
+	request := func(query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/compat/wakatime/v1/users/{user}/durations?"+query, nil)
+		req = withUrlParam(req, "user", "current")
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", base64.StdEncoding.EncodeToString([]byte(user.ApiKey))))
+		router.ServeHTTP(rec, req)
+
+		var result map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		return rec, result
+	}
+
+	t.Run("should merge adjacent durations by project", func(t *testing.T) {
+		rec, result := request("date=2024-03-01")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		data := result["data"].([]interface{})
+		assert.Len(t, data, 2)
+		assert.Equal(t, "wakapi", data[0].(map[string]interface{})["project"])
+		assert.Equal(t, float64(t0.Unix()), data[0].(map[string]interface{})["time"])
+		assert.Equal(t, float64(20*60), data[0].(map[string]interface{})["duration"])
+		assert.Equal(t, "anchr", data[1].(map[string]interface{})["project"])
+		assert.Equal(t, []interface{}{"feature", "master"}, result["branches"])
+		assert.Equal(t, "Europe/Berlin", result["timezone"])
+		assert.Equal(t, "2024-02-29T23:00:00Z", result["start"])
+	})
+
+	t.Run("should slice by language", func(t *testing.T) {
+		_, result := request("date=2024-03-01&slice_by=language")
+
+		data := result["data"].([]interface{})
+		assert.Len(t, data, 4)
+		assert.Equal(t, "Go", data[0].(map[string]interface{})["language"])
+		assert.NotContains(t, data[0].(map[string]interface{}), "project")
+		assert.Equal(t, "JavaScript", data[1].(map[string]interface{})["language"])
+	})
+
+	t.Run("should filter by branches", func(t *testing.T) {
+		_, result := request("date=2024-03-01&branches=feature")
+
+		data := result["data"].([]interface{})
+		assert.Len(t, data, 1)
+		assert.Equal(t, float64(5*60), data[0].(map[string]interface{})["duration"])
+	})
+
+	t.Run("should pass timeout and timezone", func(t *testing.T) {
+		rec, result := request("date=2024-03-01&timeout=15&timezone=UTC")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "UTC", result["timezone"])
+
+		call := durationServiceMock.Calls[len(durationServiceMock.Calls)-1]
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), call.Arguments.Get(0).(time.Time))
+		assert.Equal(t, 15*time.Minute, *call.Arguments.Get(4).(*time.Duration))
+	})
+
+	t.Run("should be available under wakatime api path", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/current/durations?date=2024-03-01", nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", base64.StdEncoding.EncodeToString([]byte(user.ApiKey))))
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("should reject invalid params", func(t *testing.T) {
+		for _, query := range []string{"", "date=01.03.2024", "date=2024-03-01&slice_by=dependencies", "date=2024-03-01&timeout=0", "date=2024-03-01&timezone=Mars/Olympus"} {
+			rec, _ := request(query)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type GoalsHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	goalSrvc services.IGoalService
+}
+
+func NewGoalsHandler(userService services.IUserService, goalService services.IGoalService) *GoalsHandler {
+	return &GoalsHandler{
+		userSrvc: userService,
+		goalSrvc: goalService,
+		config:   conf.Get(),
+	}
+}
+
+func (h *GoalsHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+		r.Get("/compat/wakatime/v1/users/{user}/goals", h.GetAll)
+		r.Get("/compat/wakatime/v1/users/{user}/goals/{id}", h.Get)
+	})
+}
+
+// @Summary List the user's goals along with their progress
+// @Description Mimics https://wakatime.com/developers#goals
+// @ID get-wakatime-goals
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.GoalsViewModel
+// @Router /compat/wakatime/v1/users/{user}/goals [get]
+func (h *GoalsHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	goals, err := h.goalSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch goals", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	now := time.Now()
+	data := make([]*v1.GoalData, 0, len(goals))
+	for _, goal := range goals {
+		history, err := h.goalSrvc.GetProgressHistory(goal, user, 0)
+		if err != nil {
+			conf.Log().Request(r).Error("failed to compute goal progress", "goalID", goal.ID, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(conf.ErrInternalServerError))
+			return
+		}
+		data = append(data, v1.NewGoalFrom(goal, history, now))
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &v1.GoalsViewModel{Data: data, Total: len(data), TotalPages: 1})
+}
+
+// @Summary Retrieve a single goal along with its progress
+// @Description Mimics https://wakatime.com/developers#goals
+// @ID get-wakatime-goal
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param id path string true "Goal ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.GoalViewModel
+// @Router /compat/wakatime/v1/users/{user}/goals/{id} [get]
+func (h *GoalsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	goalId, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid goal id"))
+		return
+	}
+
+	goal, err := h.goalSrvc.GetById(uint(goalId))
+	if err != nil || goal.UserID != user.ID {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("goal not found"))
+		return
+	}
+
+	history, err := h.goalSrvc.GetProgressHistory(goal, user, 0)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to compute goal progress", "goalID", goal.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, &v1.GoalViewModel{Data: v1.NewGoalFrom(goal, history, time.Now())})
+}
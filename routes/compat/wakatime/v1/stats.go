@@ -31,7 +31,8 @@ func NewStatsHandler(userService services.IUserService, summaryService services.
 func (h *StatsHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
 		r.Use(
-			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor("/").Handler,
+			middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor("/").WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler,
+			middlewares.NewRateLimitMiddleware(conf.RateLimitSummaries).Handler,
 		)
 		r.Get("/v1/users/{user}/stats/{range}", h.Get)
 		r.Get("/compat/wakatime/v1/users/{user}/stats/{range}", h.Get)
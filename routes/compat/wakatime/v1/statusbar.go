@@ -36,7 +36,8 @@ func NewStatusBarHandler(userService services.IUserService, summaryService servi
 
 func (h *StatusBarHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead, models.ApiTokenScopeHeartbeatsWrite).Handler)
+		r.Use(middlewares.NewRateLimitMiddleware(conf.RateLimitSummaries).Handler)
 		r.Get("/users/{user}/statusbar/{range}", h.Get)
 		r.Get("/v1/users/{user}/statusbar/{range}", h.Get)
 		r.Get("/compat/wakatime/v1/users/{user}/statusbar/{range}", h.Get)
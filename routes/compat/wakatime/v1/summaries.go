@@ -34,7 +34,8 @@ func NewSummariesHandler(userService services.IUserService, summaryService servi
 
 func (h *SummariesHandler) RegisterRoutes(router chi.Router) {
 	router.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+		r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithAcceptedScopes(models.ApiTokenScopeSummariesRead).Handler)
+		r.Use(middlewares.NewRateLimitMiddleware(conf.RateLimitSummaries).Handler)
 		r.Get("/compat/wakatime/v1/users/{user}/summaries", h.Get)
 	})
 }
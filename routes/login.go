@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/dchest/captcha"
 	"github.com/go-chi/chi/v5"
@@ -19,19 +21,24 @@ import (
 	"time"
 )
 
+// lifetime of the two-factor challenge cookie, i.e. the time a user has to enter their one-time code after logging in with their password
+const totpChallengeMaxAgeSec = 5 * 60
+
 type LoginHandler struct {
 	config       *conf.Config
 	userSrvc     services.IUserService
 	mailSrvc     services.IMailService
 	keyValueSrvc services.IKeyValueService
+	totpSrvc     services.ITotpService
 }
 
-func NewLoginHandler(userService services.IUserService, mailService services.IMailService, keyValueService services.IKeyValueService) *LoginHandler {
+func NewLoginHandler(userService services.IUserService, mailService services.IMailService, keyValueService services.IKeyValueService, totpService services.ITotpService) *LoginHandler {
 	return &LoginHandler{
 		config:       conf.Get(),
 		userSrvc:     userService,
 		mailSrvc:     mailService,
 		keyValueSrvc: keyValueService,
+		totpSrvc:     totpService,
 	}
 }
 
@@ -40,6 +47,10 @@ func (h *LoginHandler) RegisterRoutes(router chi.Router) {
 	router.
 		With(httprate.LimitByRealIP(h.config.Security.GetLoginMaxRate())).
 		Post("/login", h.PostLogin)
+	router.Get("/login/2fa", h.GetTotp)
+	router.
+		With(httprate.LimitByRealIP(h.config.Security.GetLoginMaxRate())).
+		Post("/login/2fa", h.PostTotp)
 	router.Get("/signup", h.GetSignup)
 	router.
 		With(httprate.LimitByRealIP(h.config.Security.GetSignupMaxRate())).
@@ -110,6 +121,22 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.IsDisabled {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("your account has been disabled"))
+		return
+	}
+
+	if challenged, err := beginTotpChallenge(w, user, h.totpSrvc); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to encode two-factor challenge cookie", "error", err)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("internal server error"))
+		return
+	} else if challenged {
+		http.Redirect(w, r, fmt.Sprintf("%s/login/2fa", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
 	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, login.Username)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -125,6 +152,98 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
 }
 
+// GetTotp shows the second login step, i.e. a prompt for a one-time code, or, if two-factor authentication is mandatory but not yet set up, the enrollment
+func (h *LoginHandler) GetTotp(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user, challenge, err := h.getTotpChallenge(r)
+	if err != nil {
+		routeutils.SetError(r, w, "login session expired, please try again")
+		http.Redirect(w, r, fmt.Sprintf("%s/login", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	if challenge.Enroll && user.TotpSecret == "" {
+		if user, err = h.totpSrvc.Setup(user); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			conf.Log().Request(r).Error("failed to set up two-factor authentication", "userID", user.ID, "error", err)
+			templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r, w, false).WithError("internal server error"))
+			return
+		}
+	}
+
+	templates[conf.TotpLoginTemplate].Execute(w, h.buildTotpViewModel(r, w, user, challenge))
+}
+
+func (h *LoginHandler) PostTotp(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user, challenge, err := h.getTotpChallenge(r)
+	if err != nil {
+		routeutils.SetError(r, w, "login session expired, please try again")
+		http.Redirect(w, r, fmt.Sprintf("%s/login", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	var totpLogin models.TotpLogin
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TotpLoginTemplate].Execute(w, h.buildTotpViewModel(r, w, user, challenge).WithError("missing parameters"))
+		return
+	}
+	if err := loginDecoder.Decode(&totpLogin, r.PostForm); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TotpLoginTemplate].Execute(w, h.buildTotpViewModel(r, w, user, challenge).WithError("missing parameters"))
+		return
+	}
+
+	var recoveryCodes []string
+	if challenge.Enroll && !user.HasTotp() {
+		recoveryCodes, err = h.totpSrvc.Enable(user, totpLogin.Code)
+	} else {
+		err = h.totpSrvc.Verify(user, totpLogin.Code)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrTotpInvalidCode) {
+			w.WriteHeader(http.StatusUnauthorized)
+			templates[conf.TotpLoginTemplate].Execute(w, h.buildTotpViewModel(r, w, user, challenge).WithError("invalid code"))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to verify two-factor authentication code", "userID", user.ID, "error", err)
+		templates[conf.TotpLoginTemplate].Execute(w, h.buildTotpViewModel(r, w, user, challenge).WithError("internal server error"))
+		return
+	}
+
+	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to encode secure cookie", "error", err)
+		templates[conf.TotpLoginTemplate].Execute(w, h.buildTotpViewModel(r, w, user, challenge).WithError("internal server error"))
+		return
+	}
+
+	user.LastLoggedInAt = models.CustomTime(time.Now())
+	h.userSrvc.Update(user)
+
+	http.SetCookie(w, rootCookie(h.config.GetClearCookie(models.TotpChallengeCookieKey)))
+	http.SetCookie(w, rootCookie(h.config.CreateCookie(models.AuthCookieKey, encoded)))
+
+	// recovery codes are only shown once, right after enrollment
+	if len(recoveryCodes) > 0 {
+		vm := h.buildTotpViewModel(r, w, user, challenge)
+		vm.RecoveryCodes = recoveryCodes
+		templates[conf.TotpLoginTemplate].Execute(w, vm.WithSuccess("two-factor authentication was set up successfully"))
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+}
+
 func (h *LoginHandler) PostLogout(w http.ResponseWriter, r *http.Request) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -250,6 +369,9 @@ func (h *LoginHandler) GetSetPassword(w http.ResponseWriter, r *http.Request) {
 		LoginViewModel: *h.buildViewModel(r, w, false),
 		Token:          token,
 	}
+	if user, err := h.userSrvc.GetUserByResetToken(token); err == nil {
+		vm.RequireTotp = user.HasTotp()
+	}
 
 	templates[conf.SetPasswordTemplate].Execute(w, vm)
 }
@@ -284,6 +406,20 @@ func (h *LoginHandler) PostSetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// a password reset must not circumvent the second factor, otherwise access to the mailbox alone would be sufficient to take over the account
+	if user.HasTotp() {
+		if err := h.totpSrvc.Verify(user, setRequest.Code); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			vm := &view.SetPasswordViewModel{
+				LoginViewModel: *h.buildViewModel(r, w, false).WithError("invalid two-factor authentication code"),
+				Token:          setRequest.Token,
+				RequireTotp:    true,
+			}
+			templates[conf.SetPasswordTemplate].Execute(w, vm)
+			return
+		}
+	}
+
 	user.Password = setRequest.Password
 	user.ResetToken = ""
 	if hash, err := utils.HashPassword(user.Password, h.config.Security.PasswordSalt); err != nil {
@@ -353,6 +489,45 @@ func (h *LoginHandler) PostResetPassword(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, h.config.Server.BasePath, http.StatusFound)
 }
 
+// getTotpChallenge decodes the pending two-factor challenge and returns the user who passed the first login step
+func (h *LoginHandler) getTotpChallenge(r *http.Request) (*models.User, *models.TotpChallenge, error) {
+	cookie, err := r.Cookie(models.TotpChallengeCookieKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var challenge models.TotpChallenge
+	if err := h.config.Security.SecureCookie.Decode(models.TotpChallengeCookieKey, cookie.Value, &challenge); err != nil {
+		return nil, nil, err
+	}
+	if challenge.Expired(time.Now()) {
+		return nil, nil, errors.New("two-factor challenge expired")
+	}
+
+	user, err := h.userSrvc.GetUserById(challenge.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, &challenge, nil
+}
+
+func (h *LoginHandler) buildTotpViewModel(r *http.Request, w http.ResponseWriter, user *models.User, challenge *models.TotpChallenge) *view.TotpLoginViewModel {
+	vm := &view.TotpLoginViewModel{
+		LoginViewModel: *h.buildViewModel(r, w, false),
+		Enroll:         challenge.Enroll && !user.HasTotp(),
+	}
+
+	if vm.Enroll && user.TotpSecret != "" {
+		vm.Secret = user.TotpSecret
+		var err error
+		if vm.QrCode, err = totpQrCodeUri(user, h.totpSrvc); err != nil {
+			conf.Log().Request(r).Error("failed to render two-factor qr code", "userID", user.ID, "error", err)
+		}
+	}
+
+	return vm
+}
+
 func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter, withCaptcha bool) *view.LoginViewModel {
 	numUsers, _ := h.userSrvc.Count()
 
@@ -361,6 +536,8 @@ func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter, wi
 		TotalUsers:      int(numUsers),
 		AllowSignup:     h.config.IsDev() || h.config.Security.AllowSignup,
 		InviteCode:      r.URL.Query().Get("invite"),
+		OidcEnabled:     h.config.Security.Oidc.Enabled,
+		OidcName:        h.config.Security.Oidc.Name,
 	}
 
 	if withCaptcha {
@@ -369,3 +546,38 @@ func (h *LoginHandler) buildViewModel(r *http.Request, w http.ResponseWriter, wi
 
 	return routeutils.WithSessionMessages(vm, r, w)
 }
+
+// beginTotpChallenge defers the actual login until the user passed the second factor (or set it up, in case it is mandatory).
+// It returns true if a challenge was issued, in which case the caller must redirect to the second login step instead of setting the auth cookie.
+func beginTotpChallenge(w http.ResponseWriter, user *models.User, totpSrvc services.ITotpService) (bool, error) {
+	config := conf.Get()
+
+	enroll := !user.HasTotp() && totpSrvc.IsRequired(user)
+	if !user.HasTotp() && !enroll {
+		return false, nil
+	}
+
+	challenge := &models.TotpChallenge{
+		UserId:    user.ID,
+		Enroll:    enroll,
+		ExpiresAt: time.Now().Add(totpChallengeMaxAgeSec * time.Second).Unix(),
+	}
+	encoded, err := config.Security.SecureCookie.Encode(models.TotpChallengeCookieKey, challenge)
+	if err != nil {
+		return false, err
+	}
+
+	cookie := rootCookie(config.CreateCookie(models.TotpChallengeCookieKey, encoded))
+	cookie.MaxAge = totpChallengeMaxAgeSec
+	http.SetCookie(w, cookie)
+	return true, nil
+}
+
+// totpQrCodeUri renders the user's pending two-factor secret as a qr code to be embedded as a data uri
+func totpQrCodeUri(user *models.User, totpSrvc services.ITotpService) (string, error) {
+	qr, err := totpSrvc.QrCode(user)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(qr), nil
+}
@@ -0,0 +1,208 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httprate"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+// lifetime of the state cookie, i.e. the time a user has to log in at the provider
+const oidcStateMaxAgeSec = 10 * 60
+
+type OidcHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	oidcSrvc services.IOidcService
+	totpSrvc services.ITotpService
+}
+
+func NewOidcHandler(userService services.IUserService, oidcService services.IOidcService, totpService services.ITotpService) *OidcHandler {
+	return &OidcHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		oidcSrvc: oidcService,
+		totpSrvc: totpService,
+	}
+}
+
+func (h *OidcHandler) RegisterRoutes(router chi.Router) {
+	if !h.config.Security.Oidc.Enabled {
+		return
+	}
+
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor("/login/oidc").Handler,
+		httprate.LimitByRealIP(h.config.Security.GetLoginMaxRate()),
+	)
+	r.Get("/", h.GetLogin)
+	r.Get("/callback", h.GetCallback)
+
+	router.Mount("/login/oidc", r)
+}
+
+// GetLogin redirects to the provider's authorization endpoint. If called with ?link=true by a logged-in user, the
+// resulting identity is linked to that user's account instead.
+func (h *OidcHandler) GetLogin(w http.ResponseWriter, r *http.Request) {
+	var linkUserId string
+	if r.URL.Query().Get("link") == "true" {
+		user := middlewares.GetPrincipal(r)
+		if user == nil {
+			h.redirectError(w, r, "unauthorized", "login")
+			return
+		}
+		linkUserId = user.ID
+	} else if cookie, err := r.Cookie(models.AuthCookieKey); err == nil && cookie.Value != "" {
+		http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	state := h.oidcSrvc.NewAuthState(linkUserId)
+	authUrl, err := h.oidcSrvc.AuthCodeUrl(state)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to build oidc authorization url", "error", err)
+		h.redirectError(w, r, "single sign-on is currently unavailable", "login")
+		return
+	}
+
+	encoded, err := h.config.Security.SecureCookie.Encode(models.OidcStateCookieKey, state)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to encode oidc state cookie", "error", err)
+		h.redirectError(w, r, "internal server error", "login")
+		return
+	}
+
+	cookie := rootCookie(h.config.CreateCookie(models.OidcStateCookieKey, encoded))
+	cookie.MaxAge = oidcStateMaxAgeSec
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+func (h *OidcHandler) GetCallback(w http.ResponseWriter, r *http.Request) {
+	state, err := h.popState(w, r)
+	if err != nil {
+		h.redirectError(w, r, "login session expired, please try again", "login")
+		return
+	}
+
+	redirectTarget := "login"
+	if state.LinkUserId != "" {
+		redirectTarget = "settings#account"
+	}
+
+	q := r.URL.Query()
+	if q.Get("state") != state.State {
+		h.redirectError(w, r, "invalid login state, please try again", redirectTarget)
+		return
+	}
+	if errCode := q.Get("error"); errCode != "" {
+		conf.Log().Request(r).Warn("oidc provider returned error", "error", errCode, "description", q.Get("error_description"))
+		h.redirectError(w, r, fmt.Sprintf("single sign-on failed (%s)", errCode), redirectTarget)
+		return
+	}
+
+	claims, err := h.oidcSrvc.Exchange(q.Get("code"), state)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to exchange oidc authorization code", "error", err)
+		h.redirectError(w, r, "single sign-on failed", redirectTarget)
+		return
+	}
+
+	if state.LinkUserId != "" {
+		h.link(w, r, state.LinkUserId, claims)
+		return
+	}
+
+	user, created, err := h.oidcSrvc.ResolveUser(claims)
+	if err != nil {
+		if errors.Is(err, services.ErrOidcUnknownUser) {
+			h.redirectError(w, r, "no account is linked to this identity, please log in with your password and connect it in the settings first", redirectTarget)
+			return
+		}
+		conf.Log().Request(r).Error("failed to resolve user for oidc identity", "subject", claims.Subject, "error", err)
+		h.redirectError(w, r, "failed to log in", redirectTarget)
+		return
+	}
+
+	if user.IsDisabled {
+		h.redirectError(w, r, "your account has been disabled", redirectTarget)
+		return
+	}
+
+	if created {
+		routeutils.SetSuccess(r, w, "account created successfully")
+	}
+
+	if challenged, err := beginTotpChallenge(w, user, h.totpSrvc); err != nil {
+		conf.Log().Request(r).Error("failed to encode two-factor challenge cookie", "error", err)
+		h.redirectError(w, r, "internal server error", redirectTarget)
+		return
+	} else if challenged {
+		http.Redirect(w, r, fmt.Sprintf("%s/login/2fa", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to encode secure cookie", "error", err)
+		h.redirectError(w, r, "internal server error", redirectTarget)
+		return
+	}
+
+	user.LastLoggedInAt = models.CustomTime(time.Now())
+	h.userSrvc.Update(user)
+
+	http.SetCookie(w, rootCookie(h.config.CreateCookie(models.AuthCookieKey, encoded)))
+	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+}
+
+func (h *OidcHandler) link(w http.ResponseWriter, r *http.Request, userId string, claims *models.OidcClaims) {
+	// the user who initiated linking must still be the one logged in
+	user := middlewares.GetPrincipal(r)
+	if user == nil || user.ID != userId {
+		h.redirectError(w, r, "unauthorized", "login")
+		return
+	}
+
+	if _, err := h.oidcSrvc.Link(user, claims); err != nil {
+		if errors.Is(err, services.ErrOidcAlreadyLinked) {
+			h.redirectError(w, r, "this identity is already connected to another account", "settings#account")
+			return
+		}
+		conf.Log().Request(r).Error("failed to link oidc identity", "userID", user.ID, "error", err)
+		h.redirectError(w, r, "failed to connect account", "settings#account")
+		return
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("successfully connected your %s account", h.config.Security.Oidc.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/settings#account", h.config.Server.BasePath), http.StatusFound)
+}
+
+// popState decodes and clears the state cookie, so that every authorization response can only be used once
+func (h *OidcHandler) popState(w http.ResponseWriter, r *http.Request) (*models.OidcAuthState, error) {
+	cookie, err := r.Cookie(models.OidcStateCookieKey)
+	if err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, rootCookie(h.config.GetClearCookie(models.OidcStateCookieKey)))
+
+	var state models.OidcAuthState
+	if err := h.config.Security.SecureCookie.Decode(models.OidcStateCookieKey, cookie.Value, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (h *OidcHandler) redirectError(w http.ResponseWriter, r *http.Request, message, target string) {
+	routeutils.SetError(r, w, message)
+	http.Redirect(w, r, fmt.Sprintf("%s/%s", h.config.Server.BasePath, target), http.StatusFound)
+}
@@ -1,6 +1,16 @@
 package routes
 
 import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/duke-git/lancet/v2/slice"
 	"github.com/go-chi/chi/v5"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
@@ -9,21 +19,29 @@ import (
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
-	"net/http"
-	"time"
+)
+
+const (
+	projectsPageSize    = 24
+	projectsMaxPageSize = 96
+	projectTrendWeeks   = 12
 )
 
 type ProjectsHandler struct {
 	config           *conf.Config
 	userService      services.IUserService
 	heartbeatService services.IHeartbeatService
+	summaryService   services.ISummaryService
+	projectService   services.IProjectService
 }
 
-func NewProjectsHandler(userService services.IUserService, heartbeatService services.IHeartbeatService) *ProjectsHandler {
+func NewProjectsHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, summaryService services.ISummaryService, projectService services.IProjectService) *ProjectsHandler {
 	return &ProjectsHandler{
 		config:           conf.Get(),
 		userService:      userService,
 		heartbeatService: heartbeatService,
+		summaryService:   summaryService,
+		projectService:   projectService,
 	}
 }
 
@@ -35,6 +53,8 @@ func (h *ProjectsHandler) RegisterRoutes(router chi.Router) {
 			WithRedirectErrorMessage("unauthorized").Handler,
 	)
 	r.Get("/", h.GetIndex)
+	r.Get("/{project}", h.GetProject)
+	r.Post("/{project}", h.PostProject)
 
 	router.Mount("/projects", r)
 }
@@ -48,6 +68,161 @@ func (h *ProjectsHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *ProjectsHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	project := projectFromPath(r)
+
+	latest, err := h.heartbeatService.GetLatestByFilters(user, models.NewFiltersWith(models.SummaryProject, project))
+	if err != nil || latest == nil {
+		routeutils.SetError(r, w, "project not found")
+		http.Redirect(w, r, fmt.Sprintf("%s/projects", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	h.renderProject(w, r, user, project, latest.Time.T(), http.StatusOK, "", "")
+}
+
+func (h *ProjectsHandler) PostProject(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	project := projectFromPath(r)
+
+	latest, err := h.heartbeatService.GetLatestByFilters(user, models.NewFiltersWith(models.SummaryProject, project))
+	if err != nil || latest == nil {
+		routeutils.SetError(r, w, "project not found")
+		http.Redirect(w, r, fmt.Sprintf("%s/projects", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderProject(w, r, user, project, latest.Time.T(), http.StatusBadRequest, "", "missing form values")
+		return
+	}
+
+	action := r.PostForm.Get("action")
+	r.PostForm.Del("action")
+
+	actionFunc := h.dispatchAction(action, user, project)
+	if actionFunc == nil {
+		slog.Warn("failed to dispatch action", "action", action)
+		h.renderProject(w, r, user, project, latest.Time.T(), http.StatusBadRequest, "", "unknown action requests")
+		return
+	}
+
+	result := actionFunc(w, r)
+
+	// action responded itself
+	if result.code == -1 {
+		return
+	}
+
+	h.renderProject(w, r, user, project, latest.Time.T(), result.code, result.success, result.error)
+}
+
+func (h *ProjectsHandler) dispatchAction(action string, user *models.User, project string) action {
+	switch action {
+	case "archive":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionArchive(w, r, user, project)
+		}
+	case "unarchive":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionUnarchive(w, r, user, project)
+		}
+	case "rename":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionRename(w, r, user, project)
+		}
+	case "merge":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionMerge(w, r, user, project)
+		}
+	}
+	return nil
+}
+
+func (h *ProjectsHandler) actionArchive(w http.ResponseWriter, r *http.Request, user *models.User, project string) actionResult {
+	if err := h.projectService.Archive(user, project); err != nil {
+		conf.Log().Request(r).Error("failed to archive project", "userID", user.ID, "project", project, "error", err)
+		return actionResult{http.StatusInternalServerError, "", "failed to archive project", nil}
+	}
+	return actionResult{http.StatusOK, "project archived, it will no longer be listed and won't count towards leaderboards", "", nil}
+}
+
+func (h *ProjectsHandler) actionUnarchive(w http.ResponseWriter, r *http.Request, user *models.User, project string) actionResult {
+	if err := h.projectService.Unarchive(user, project); err != nil {
+		conf.Log().Request(r).Error("failed to unarchive project", "userID", user.ID, "project", project, "error", err)
+		return actionResult{http.StatusInternalServerError, "", "failed to unarchive project", nil}
+	}
+	return actionResult{http.StatusOK, "project restored from archive", "", nil}
+}
+
+func (h *ProjectsHandler) actionRename(w http.ResponseWriter, r *http.Request, user *models.User, project string) actionResult {
+	newName := strings.TrimSpace(r.PostForm.Get("new_name"))
+	if newName == "" || newName == project {
+		return actionResult{http.StatusBadRequest, "", "invalid project name", nil}
+	}
+
+	if err := h.projectService.Rename(user, project, newName); err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("project '%s' is being renamed to '%s', this might take a few minutes", project, newName))
+	http.Redirect(w, r, fmt.Sprintf("%s/projects", h.config.Server.BasePath), http.StatusFound)
+	return actionResult{-1, "", "", nil}
+}
+
+func (h *ProjectsHandler) actionMerge(w http.ResponseWriter, r *http.Request, user *models.User, project string) actionResult {
+	projects := slice.Filter[string](r.PostForm["projects"], func(i int, p string) bool {
+		return p != project
+	})
+	if len(projects) == 0 {
+		return actionResult{http.StatusBadRequest, "", "no projects selected", nil}
+	}
+
+	if err := h.projectService.Merge(user, projects, project); err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("%d project(s) are being merged into '%s', this might take a few minutes", len(projects), project))
+	http.Redirect(w, r, fmt.Sprintf("%s/projects", h.config.Server.BasePath), http.StatusFound)
+	return actionResult{-1, "", "", nil}
+}
+
+func (h *ProjectsHandler) renderProject(w http.ResponseWriter, r *http.Request, user *models.User, project string, lastActivity time.Time, code int, successMsg, errorMsg string) {
+	vm, err := h.buildProjectViewModel(r, w, user, project, lastActivity)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to load project details", "userID", user.ID, "project", project, "error", err)
+		code, successMsg, errorMsg = http.StatusInternalServerError, "", criticalError
+		vm = &view.ProjectViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, nil),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+			Project: project,
+		}
+	}
+
+	if errorMsg != "" {
+		vm.WithError(errorMsg)
+	} else if successMsg != "" {
+		vm.WithSuccess(successMsg)
+	}
+
+	w.WriteHeader(code)
+	if err := templates[conf.ProjectTemplate].Execute(w, vm); err != nil {
+		conf.Log().Request(r).Error("failed to get project page", "error", err)
+	}
+}
+
 func (h *ProjectsHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.ProjectsViewModel {
 	user := middlewares.GetPrincipal(r)
 	if user == nil { // this should actually never occur, because of auth middleware
@@ -55,15 +230,15 @@ func (h *ProjectsHandler) buildViewModel(r *http.Request, w http.ResponseWriter)
 		return h.buildViewModel(r, w).WithError("unauthorized")
 	}
 
-	pageParams := utils.ParsePageParamsWithDefault(r, 1, 24)
-	// note: pagination is not fully implemented, yet
-	// count function to get total item / total pages is missing
-	// and according ui (+ optionally search bar) is missing, too
-
-	var err error
-	var projects []*models.ProjectStats
+	pageParams := utils.ParsePageParamsWithDefault(r, 1, projectsPageSize)
+	if pageParams.PageSize <= 0 || pageParams.PageSize > projectsMaxPageSize {
+		pageParams.PageSize = projectsPageSize
+	}
+	showArchived := r.URL.Query().Get("archived") == "true"
 
-	projects, err = h.heartbeatService.GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), pageParams, false)
+	// all projects are fetched and paginated here, because limit / offset doesn't speed up the query
+	// and the complete list is what's kept in cache (see housekeeping service)
+	projects, err := h.heartbeatService.GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), nil, false)
 	if err != nil {
 		conf.Log().Request(r).Error("error while fetching project stats", "userID", user.ID, "error", err)
 		return &view.ProjectsViewModel{
@@ -72,17 +247,113 @@ func (h *ProjectsHandler) buildViewModel(r *http.Request, w http.ResponseWriter)
 				User:            user,
 				ApiKey:          user.ApiKey,
 			},
+			PageParams:   pageParams,
+			ShowArchived: showArchived,
 		}
 	}
 
+	archived, err := h.projectService.GetArchived(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching archived projects", "userID", user.ID, "error", err)
+	}
+	projects = slice.Filter[*models.ProjectStats](projects, func(i int, p *models.ProjectStats) bool {
+		return slice.Contain(archived, p.Project) == showArchived
+	})
+
 	vm := &view.ProjectsViewModel{
 		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
 			SharedViewModel: view.NewSharedViewModel(h.config, nil),
 			User:            user,
 			ApiKey:          user.ApiKey,
 		},
-		Projects:   projects,
-		PageParams: pageParams,
+		PageParams:    pageParams,
+		TotalProjects: len(projects),
+		ShowArchived:  showArchived,
 	}
+
+	pageParams.Page = min(max(pageParams.Page, 1), vm.TotalPages())
+	vm.Projects = utils.SubSlice[*models.ProjectStats](projects, uint(pageParams.Offset()), uint(pageParams.Offset()+pageParams.Limit()))
+
 	return routeutils.WithSessionMessages(vm, r, w)
 }
+
+func (h *ProjectsHandler) buildProjectViewModel(r *http.Request, w http.ResponseWriter, user *models.User, project string, lastActivity time.Time) (*view.ProjectViewModel, error) {
+	filters := models.NewFiltersWith(models.SummaryProject, project)
+
+	// project stats only cover activity until the beginning of today, so brand-new projects are missing in there
+	firstActivity := lastActivity
+	otherProjects := make([]string, 0)
+	if allStats, err := h.heartbeatService.GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), nil, false); err == nil {
+		for _, stats := range allStats {
+			if stats.Project == project {
+				firstActivity = stats.First.T()
+			} else if stats.Project != "" {
+				otherProjects = append(otherProjects, stats.Project)
+			}
+		}
+		sort.Strings(otherProjects)
+	}
+
+	archived, err := h.projectService.GetArchived(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := h.summaryService.Aliased(datetime.BeginOfDay(firstActivity.In(user.TZ())), time.Now(), user, h.summaryService.Retrieve, filters, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	trend, err := h.fetchTrend(user, project, lastActivity)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := &view.ProjectViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Project:       project,
+		FirstActivity: firstActivity.In(user.TZ()),
+		LastActivity:  lastActivity.In(user.TZ()),
+		Summary:       summary,
+		Trend:         trend,
+		Archived:      slice.Contain(archived, project),
+		Rewriting:     h.projectService.IsRewriting(user.ID),
+		OtherProjects: otherProjects,
+	}
+	return routeutils.WithSessionMessages(vm, r, w), nil
+}
+
+// fetchTrend computes the project's weekly coding time for the last couple of weeks up to the given time
+func (h *ProjectsHandler) fetchTrend(user *models.User, project string, until time.Time) ([]*view.ProjectTrendItem, error) {
+	// only total time is relevant, allows for using pre-generated summaries
+	filters := models.NewFiltersWith(models.SummaryProject, project).WithSelectFilteredOnly()
+
+	to := datetime.BeginOfWeek(until.In(user.TZ()), time.Monday).AddDate(0, 0, 7)
+	from := to.AddDate(0, 0, -7*projectTrendWeeks)
+
+	trend := make([]*view.ProjectTrendItem, 0, projectTrendWeeks)
+	for _, interval := range utils.SplitRangeByWeeks(from, to) {
+		summary, err := h.summaryService.Aliased(interval[0], interval[1], user, h.summaryService.Retrieve, filters, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		trend = append(trend, &view.ProjectTrendItem{From: interval[0], Total: summary.TotalTime()})
+	}
+	return trend, nil
+}
+
+// projectFromPath returns the requested project's name, which might contain escaped slashes
+func projectFromPath(r *http.Request) string {
+	project := chi.URLParam(r, "project")
+	if r.URL.RawPath == "" { // chi only routes by the raw path if it differs from the decoded one
+		return project
+	}
+	if unescaped, err := url.PathUnescape(project); err == nil {
+		return unescaped
+	}
+	return project
+}
@@ -0,0 +1,215 @@
+package routes
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProjectsHandler_GetIndex(t *testing.T) {
+	config.Set(config.Empty())
+	config.Get().Env = "dev"
+
+	if cwd, _ := os.Getwd(); strings.HasSuffix(cwd, "routes") {
+		os.Chdir("..")
+	}
+
+	projects := make([]*models.ProjectStats, 30)
+	for i := range projects {
+		projects[i] = &models.ProjectStats{UserId: user1.ID, Project: fmt.Sprintf("project-%02d", i), Count: int64(i + 1)}
+	}
+
+	router := chi.NewRouter()
+	router.Use(middlewares.NewPrincipalMiddleware())
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", user1.ApiKey).Return(&user1, nil)
+
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("GetUserProjectStats", &user1, time.Time{}, mock.Anything, (*utils.PageParams)(nil), false).Return(projects, nil)
+
+	projectServiceMock := new(mocks.ProjectServiceMock)
+	projectServiceMock.On("GetArchived", user1.ID).Return([]string{"project-03", "project-04"}, nil)
+
+	NewProjectsHandler(userServiceMock, heartbeatServiceMock, new(mocks.SummaryServiceMock), projectServiceMock).RegisterRoutes(router)
+
+	request := func(query string) string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/projects?api_key="+user1.ApiKey+query, nil)
+		router.ServeHTTP(rec, req)
+		res := rec.Result()
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		data, _ := io.ReadAll(res.Body)
+		return string(data)
+	}
+
+	t.Run("when requesting the first page", func(t *testing.T) {
+		t.Run("should show the first projects and the total count", func(t *testing.T) {
+			body := request("")
+			assert.Contains(t, body, "projects/project-00")
+			assert.Contains(t, body, "projects/project-25")
+			assert.NotContains(t, body, "projects/project-26")
+			assert.NotContains(t, body, "projects/project-03") // archived
+			assert.Contains(t, body, "Page 1 of 2 (28 projects)")
+		})
+	})
+
+	t.Run("when requesting the last page", func(t *testing.T) {
+		t.Run("should show the remaining projects", func(t *testing.T) {
+			body := request("&page=2")
+			assert.NotContains(t, body, "projects/project-25")
+			assert.Contains(t, body, "projects/project-29")
+			assert.Contains(t, body, "Page 2 of 2 (28 projects)")
+		})
+	})
+
+	t.Run("when requesting a page out of range", func(t *testing.T) {
+		t.Run("should show the last page", func(t *testing.T) {
+			body := request("&page=5")
+			assert.Contains(t, body, "projects/project-29")
+			assert.Contains(t, body, "Page 2 of 2 (28 projects)")
+		})
+	})
+
+	t.Run("when requesting archived projects", func(t *testing.T) {
+		t.Run("should show only those", func(t *testing.T) {
+			body := request("&archived=true")
+			assert.Contains(t, body, "projects/project-03")
+			assert.Contains(t, body, "projects/project-04")
+			assert.NotContains(t, body, "projects/project-05")
+		})
+	})
+}
+
+func TestProjectsHandler_GetProject(t *testing.T) {
+	config.Set(config.Empty())
+	config.Get().Env = "dev"
+
+	if cwd, _ := os.Getwd(); strings.HasSuffix(cwd, "routes") {
+		os.Chdir("..")
+	}
+
+	lastActivity := time.Date(2024, 5, 8, 14, 0, 0, 0, time.UTC)
+
+	router := chi.NewRouter()
+	router.Use(middlewares.NewPrincipalMiddleware())
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", user1.ApiKey).Return(&user1, nil)
+
+	heartbeatServiceMock := new(mocks.HeartbeatServiceMock)
+	heartbeatServiceMock.On("GetLatestByFilters", &user1, models.NewFiltersWith(models.SummaryProject, "wakapi/server")).Return(&models.Heartbeat{Project: "wakapi/server", Time: models.CustomTime(lastActivity)}, nil)
+	heartbeatServiceMock.On("GetLatestByFilters", &user1, models.NewFiltersWith(models.SummaryProject, "unknown")).Return((*models.Heartbeat)(nil), nil)
+	heartbeatServiceMock.On("GetUserProjectStats", &user1, time.Time{}, mock.Anything, (*utils.PageParams)(nil), false).Return([]*models.ProjectStats{
+		{UserId: user1.ID, Project: "wakapi/server", First: models.CustomTime(lastActivity.AddDate(0, -1, 0)), Last: models.CustomTime(lastActivity)},
+	}, nil)
+
+	summary := &models.Summary{
+		Projects:  []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi/server", Total: 7200}},
+		Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Go", Total: 5400}, {Type: models.SummaryLanguage, Key: "HTML", Total: 1800}},
+		Branches:  []*models.SummaryItem{{Type: models.SummaryBranch, Key: "feature/project-page", Total: 7200}},
+		Entities:  []*models.SummaryItem{{Type: models.SummaryEntity, Key: "/home/me/wakapi/routes/projects.go", Total: 7200}},
+		Machines:  []*models.SummaryItem{{Type: models.SummaryMachine, Key: "devbox", Total: 7200}},
+	}
+	summaryServiceMock := new(mocks.SummaryServiceMock)
+	summaryServiceMock.On("Aliased", mock.Anything, mock.Anything, &user1, mock.Anything, mock.Anything, mock.Anything, false).Return(summary, nil)
+
+	projectServiceMock := new(mocks.ProjectServiceMock)
+	projectServiceMock.On("GetArchived", user1.ID).Return([]string{}, nil)
+	projectServiceMock.On("IsRewriting", user1.ID).Return(false)
+	projectServiceMock.On("Archive", &user1, "wakapi/server").Return(nil)
+	projectServiceMock.On("Rename", &user1, "wakapi/server", "wakapi").Return(nil)
+
+	NewProjectsHandler(userServiceMock, heartbeatServiceMock, summaryServiceMock, projectServiceMock).RegisterRoutes(router)
+
+	t.Run("when requesting an existing project", func(t *testing.T) {
+		t.Run("should show its details", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/projects/wakapi%2Fserver?api_key="+user1.ApiKey, nil)
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			data, _ := io.ReadAll(res.Body)
+			body := string(data)
+			assert.Contains(t, body, "feature/project-page")
+			assert.Contains(t, body, "/home/me/wakapi/routes/projects.go")
+			assert.Contains(t, body, "devbox")
+			assert.Contains(t, body, "75 %") // go's share of the project's total time
+			assert.Contains(t, body, "project=wakapi%2Fserver")
+			summaryServiceMock.AssertNumberOfCalls(t, "Aliased", 1+projectTrendWeeks)
+		})
+	})
+
+	t.Run("when requesting an unknown project", func(t *testing.T) {
+		t.Run("should redirect to the projects page", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/projects/unknown?api_key="+user1.ApiKey, nil)
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusFound, res.StatusCode)
+			assert.Equal(t, "/projects", res.Header.Get("Location"))
+		})
+	})
+
+	t.Run("when archiving a project", func(t *testing.T) {
+		t.Run("should archive it and show its details", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/projects/wakapi%2Fserver?api_key="+user1.ApiKey, strings.NewReader("action=archive"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			projectServiceMock.AssertCalled(t, "Archive", &user1, "wakapi/server")
+		})
+	})
+
+	t.Run("when renaming a project", func(t *testing.T) {
+		t.Run("should start renaming and redirect to the projects page", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/projects/wakapi%2Fserver?api_key="+user1.ApiKey, strings.NewReader("action=rename&new_name=wakapi"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusFound, res.StatusCode)
+			assert.Equal(t, "/projects", res.Header.Get("Location"))
+			projectServiceMock.AssertCalled(t, "Rename", &user1, "wakapi/server", "wakapi")
+		})
+	})
+
+	t.Run("when renaming a project without a new name", func(t *testing.T) {
+		t.Run("should fail", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/projects/wakapi%2Fserver?api_key="+user1.ApiKey, strings.NewReader("action=rename&new_name=+"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			router.ServeHTTP(rec, req)
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			projectServiceMock.AssertNumberOfCalls(t, "Rename", 1)
+		})
+	})
+}
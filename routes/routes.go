@@ -4,6 +4,8 @@ import (
 	"github.com/duke-git/lancet/v2/strutil"
 	"github.com/muety/wakapi/helpers"
 	"html/template"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/duke-git/lancet/v2/datetime"
@@ -35,6 +37,7 @@ func DefaultTemplateFuncs() template.FuncMap {
 		"add":            add,
 		"capitalize":     strutil.Capitalize,
 		"lower":          strings.ToLower,
+		"pathEscape":     url.PathEscape,
 		"toRunes":        utils.ToRunes,
 		"localTZOffset":  utils.LocalTZOffset,
 		"entityTypes":    models.SummaryTypes,
@@ -115,6 +118,14 @@ func defaultErrorRedirectTarget() string {
 	return config.Get().Server.BasePath + "/"
 }
 
+// rootCookie scopes a cookie to the whole application, as the browser would otherwise default its path to the one of the current request (e.g. /login/oidc) if no base path is configured
+func rootCookie(cookie *http.Cookie) *http.Cookie {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	return cookie
+}
+
 func add(i, j int) int {
 	return i + j
 }
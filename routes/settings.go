@@ -1,12 +1,15 @@
 package routes
 
 import (
+	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/duke-git/lancet/v2/condition"
 	"github.com/go-chi/chi/v5"
 	"github.com/gofrs/uuid/v5"
 	"github.com/muety/wakapi/helpers"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
@@ -37,9 +40,19 @@ type SettingsHandler struct {
 	aliasSrvc           services.IAliasService
 	aggregationSrvc     services.IAggregationService
 	languageMappingSrvc services.ILanguageMappingService
+	heartbeatRuleSrvc   services.IHeartbeatRuleService
 	projectLabelSrvc    services.IProjectLabelService
 	keyValueSrvc        services.IKeyValueService
 	mailSrvc            services.IMailService
+	apiTokenSrvc        services.IApiTokenService
+	exportSrvc          services.IExportService
+	webhookSrvc         services.IWebhookService
+	relaySrvc           services.IRelayService
+	goalSrvc            services.IGoalService
+	oidcSrvc            services.IOidcService
+	totpSrvc            services.ITotpService
+	reportSrvc          services.IReportService
+	notificationSrvc    services.INotificationService
 	httpClient          *http.Client
 	aggregationLocks    map[string]bool
 }
@@ -54,6 +67,8 @@ type actionResult struct {
 }
 
 const valueInviteCode = "invite_code"
+const valueApiToken = "api_token"
+const valueRecoveryCodes = "recovery_codes"
 
 var credentialsDecoder = schema.NewDecoder()
 
@@ -67,6 +82,16 @@ func NewSettingsHandler(
 	projectLabelService services.IProjectLabelService,
 	keyValueService services.IKeyValueService,
 	mailService services.IMailService,
+	apiTokenService services.IApiTokenService,
+	exportService services.IExportService,
+	webhookService services.IWebhookService,
+	relayService services.IRelayService,
+	goalService services.IGoalService,
+	oidcService services.IOidcService,
+	totpService services.ITotpService,
+	reportService services.IReportService,
+	notificationService services.INotificationService,
+	heartbeatRuleService services.IHeartbeatRuleService,
 ) *SettingsHandler {
 	return &SettingsHandler{
 		config:              conf.Get(),
@@ -79,6 +104,16 @@ func NewSettingsHandler(
 		heartbeatSrvc:       heartbeatService,
 		keyValueSrvc:        keyValueService,
 		mailSrvc:            mailService,
+		apiTokenSrvc:        apiTokenService,
+		exportSrvc:          exportService,
+		webhookSrvc:         webhookService,
+		relaySrvc:           relayService,
+		goalSrvc:            goalService,
+		oidcSrvc:            oidcService,
+		totpSrvc:            totpService,
+		reportSrvc:          reportService,
+		notificationSrvc:    notificationService,
+		heartbeatRuleSrvc:   heartbeatRuleService,
 		httpClient:          &http.Client{Timeout: 10 * time.Second},
 		aggregationLocks:    make(map[string]bool),
 	}
@@ -109,7 +144,15 @@ func (h *SettingsHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
 		loadTemplates()
 	}
 
-	if err := r.ParseForm(); err != nil {
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// file uploads (e.g. heartbeat imports)
+		r.Body = http.MaxBytesReader(w, r.Body, int64(h.config.App.ImportMaxFileSizeMb)<<20)
+		err = r.ParseMultipartForm(32 << 20)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		templates[conf.SettingsTemplate].Execute(w, h.buildViewModel(r, w, nil).WithError("missing form values"))
 		return
@@ -168,6 +211,10 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionDeleteLanguageMapping
 	case "add_mapping":
 		return h.actionAddLanguageMapping
+	case "add_heartbeat_rule":
+		return h.actionAddHeartbeatRule
+	case "delete_heartbeat_rule":
+		return h.actionDeleteHeartbeatRule
 	case "update_sharing":
 		return h.actionUpdateSharing
 	case "update_leaderboard":
@@ -176,6 +223,12 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionSetWakatimeApiKey
 	case "import_wakatime":
 		return h.actionImportWakatime
+	case "import_file":
+		return h.actionImportFile
+	case "export_data":
+		return h.actionExportData
+	case "restore_data":
+		return h.actionRestoreData
 	case "regenerate_summaries":
 		return h.actionRegenerateSummaries
 	case "clear_data":
@@ -184,6 +237,46 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionDeleteUser
 	case "generate_invite":
 		return h.actionGenerateInvite
+	case "add_api_token":
+		return h.actionAddApiToken
+	case "delete_api_token":
+		return h.actionDeleteApiToken
+	case "add_webhook":
+		return h.actionAddWebhook
+	case "delete_webhook":
+		return h.actionDeleteWebhook
+	case "test_webhook":
+		return h.actionTestWebhook
+	case "add_relay_target":
+		return h.actionAddRelayTarget
+	case "toggle_relay_target":
+		return h.actionToggleRelayTarget
+	case "delete_relay_target":
+		return h.actionDeleteRelayTarget
+	case "add_goal":
+		return h.actionAddGoal
+	case "delete_goal":
+		return h.actionDeleteGoal
+	case "add_notification_channel":
+		return h.actionAddNotificationChannel
+	case "delete_notification_channel":
+		return h.actionDeleteNotificationChannel
+	case "test_notification_channel":
+		return h.actionTestNotificationChannel
+	case "add_report_subscription":
+		return h.actionAddReportSubscription
+	case "delete_report_subscription":
+		return h.actionDeleteReportSubscription
+	case "unlink_oidc":
+		return h.actionUnlinkOidc
+	case "setup_totp":
+		return h.actionSetupTotp
+	case "enable_totp":
+		return h.actionEnableTotp
+	case "disable_totp":
+		return h.actionDisableTotp
+	case "regenerate_recovery_codes":
+		return h.actionRegenerateRecoveryCodes
 	case "update_unknown_projects":
 		return h.actionUpdateExcludeUnknownProjects
 	case "update_heartbeats_timeout":
@@ -572,6 +665,53 @@ func (h *SettingsHandler) actionAddLanguageMapping(w http.ResponseWriter, r *htt
 	return actionResult{http.StatusOK, "mapping added successfully", "", nil}
 }
 
+func (h *SettingsHandler) actionAddHeartbeatRule(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	user := middlewares.GetPrincipal(r)
+
+	rule := &models.HeartbeatRule{
+		UserID:    user.ID,
+		Field:     r.PostFormValue("field"),
+		MatchType: r.PostFormValue("match_type"),
+		Pattern:   r.PostFormValue("pattern"),
+		Action:    r.PostFormValue("rule_action"),
+		Value:     r.PostFormValue("value"),
+	}
+
+	if _, err := h.heartbeatRuleSrvc.Create(rule); err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid rule, please check the pattern", nil}
+	}
+
+	return actionResult{http.StatusOK, "rule added successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteHeartbeatRule(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	id, err := strconv.Atoi(r.PostFormValue("rule_id"))
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	rule, err := h.heartbeatRuleSrvc.GetById(uint(id))
+	if err != nil || rule == nil {
+		return actionResult{http.StatusNotFound, "", "rule not found", nil}
+	} else if rule.UserID != user.ID {
+		return actionResult{http.StatusForbidden, "", "not allowed to delete rule", nil}
+	}
+
+	if err := h.heartbeatRuleSrvc.Delete(rule); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete rule", nil}
+	}
+
+	return actionResult{http.StatusOK, "rule deleted successfully", "", nil}
+}
+
 func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http.Request) actionResult {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -614,17 +754,11 @@ func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Re
 	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
 	kvKeyLastImportSuccess := fmt.Sprintf("%s_%s", conf.KeyLastImportSuccess, user.ID)
 
-	if !h.config.IsDev() {
-		lastImport, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImport).Value)
-		if time.Now().Sub(lastImport) < time.Duration(h.config.App.ImportBackoffMin)*time.Minute {
-			return actionResult{
-				http.StatusTooManyRequests,
-				"",
-				fmt.Sprintf("Too many data imports - you are only allowed to request an import every %d minutes.", h.config.App.ImportBackoffMin),
-				nil,
-			}
-		}
+	if result := h.checkImportBackoff(user); result != nil {
+		return *result
+	}
 
+	if !h.config.IsDev() {
 		lastImportSuccess, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImportSuccess).Value)
 		if time.Now().Sub(lastImportSuccess) < time.Duration(h.config.App.ImportMaxRate)*time.Hour {
 			return actionResult{
@@ -663,55 +797,192 @@ func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Re
 			Value: time.Now().Format(time.RFC822),
 		})
 
-		count := 0
-		batch := make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
+		h.insertImportedHeartbeats(user, r, stream, start, countBefore)
+	}(user, r)
 
-		insert := func(batch []*models.Heartbeat) {
-			if err := h.heartbeatSrvc.InsertBatch(batch); err != nil {
-				slog.Warn("failed to insert imported heartbeat, already existing?", "error", err)
-			}
-		}
+	h.keyValueSrvc.PutString(&models.KeyStringValue{
+		Key:   kvKeyLastImport,
+		Value: time.Now().Format(time.RFC822),
+	})
 
-		for hb := range stream {
-			count++
-			batch = append(batch, hb)
+	return actionResult{http.StatusAccepted, "Import started. This will take several minutes. Please check back later.", "", nil}
+}
 
-			if len(batch) == h.config.App.ImportBatchSize {
-				insert(batch)
-				batch = make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
-			}
-		}
-		if len(batch) > 0 {
-			insert(batch)
-		}
+func (h *SettingsHandler) actionImportFile(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-		countAfter, _ := h.heartbeatSrvc.CountByUser(user)
-		slog.Info("downloaded heartbeats for user", "count", count, "userID", user.ID, "importedCount", countAfter-countBefore)
+	if !h.config.App.ImportEnabled {
+		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
+	}
 
-		h.regenerateSummaries(user)
+	user := middlewares.GetPrincipal(r)
+	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
 
-		if !user.HasData {
-			user.HasData = true
-			if _, err := h.userSrvc.Update(user); err != nil {
-				conf.Log().Request(r).Error("failed to set 'has_data' flag for user", "userID", user.ID, "error", err)
-			}
-		}
+	if result := h.checkImportBackoff(user); result != nil {
+		return *result
+	}
 
-		if user.Email != "" {
-			if err := h.mailSrvc.SendImportNotification(user, time.Now().Sub(start), int(countAfter-countBefore)); err != nil {
-				conf.Log().Request(r).Error("failed to send import notification mail", "userID", user.ID, "error", err)
-			} else {
-				slog.Info("sent import notification mail", "userID", user.ID)
-			}
+	data, err := readUploadedFile(r)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+
+	var importer imports.DataImporter
+	switch r.PostFormValue("format") {
+	case "json":
+		importer = imports.NewJsonHeartbeatsImporter(bytes.NewReader(data))
+	case "csv":
+		mapping, err := imports.ParseCsvColumnMapping(r.PostFormValue("csv_mapping"))
+		if err != nil {
+			return actionResult{http.StatusBadRequest, "", fmt.Sprintf("invalid column mapping: %v", err), nil}
 		}
-	}(user, r)
+		importer = imports.NewCsvHeartbeatsImporter(bytes.NewReader(data), mapping, strings.TrimSpace(r.PostFormValue("csv_time_format")))
+	default:
+		return actionResult{http.StatusBadRequest, "", "unsupported file format", nil}
+	}
+
+	start := time.Now()
+	countBefore, _ := h.heartbeatSrvc.CountByUser(user)
+
+	stream, err := importer.ImportAll(user)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("failed to import file: %v", err), nil}
+	}
+
+	go h.insertImportedHeartbeats(user, r, stream, start, countBefore)
 
 	h.keyValueSrvc.PutString(&models.KeyStringValue{
 		Key:   kvKeyLastImport,
 		Value: time.Now().Format(time.RFC822),
 	})
 
-	return actionResult{http.StatusAccepted, "Import started. This will take several minutes. Please check back later.", "", nil}
+	return actionResult{http.StatusAccepted, "Import started. Depending on the size of your file, this may take a few minutes. Please check back later.", "", nil}
+}
+
+func (h *SettingsHandler) actionExportData(w http.ResponseWriter, r *http.Request) actionResult {
+	user := middlewares.GetPrincipal(r)
+
+	format := r.PostFormValue("format")
+	if format != models.AccountArchiveFormatNdjson {
+		format = models.AccountArchiveFormatZip
+	}
+
+	routeutils.WriteAccountArchive(w, r, h.exportSrvc, user, format)
+	return actionResult{-1, "", "", nil}
+}
+
+func (h *SettingsHandler) actionRestoreData(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.ImportEnabled {
+		return actionResult{http.StatusForbidden, "", "imports are disabled on this server", nil}
+	}
+
+	user := middlewares.GetPrincipal(r)
+	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
+
+	if result := h.checkImportBackoff(user); result != nil {
+		return *result
+	}
+
+	data, err := readUploadedFile(r)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+
+	// heartbeats always get new ids, retaining the original ones is only offered to admins via 'ctl users restore'
+	importer, err := imports.NewWakapiArchiveImporter(data, false)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("invalid archive: %v", err), nil}
+	}
+
+	if err := h.exportSrvc.Restore(user, importer.Archive()); err != nil {
+		conf.Log().Request(r).Error("failed to restore account data", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	start := time.Now()
+	countBefore, _ := h.heartbeatSrvc.CountByUser(user)
+
+	stream, err := importer.ImportAll(user)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("invalid archive: %v", err), nil}
+	}
+
+	go h.insertImportedHeartbeats(user, r, stream, start, countBefore)
+
+	h.keyValueSrvc.PutString(&models.KeyStringValue{
+		Key:   kvKeyLastImport,
+		Value: time.Now().Format(time.RFC822),
+	})
+
+	return actionResult{http.StatusAccepted, "Settings restored and heartbeat import started. Depending on the size of your archive, this may take a few minutes. Please check back later.", "", nil}
+}
+
+// checkImportBackoff yields an error result in case the user has attempted another import only shortly before
+func (h *SettingsHandler) checkImportBackoff(user *models.User) *actionResult {
+	if h.config.IsDev() {
+		return nil
+	}
+
+	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
+	lastImport, _ := time.Parse(time.RFC822, h.keyValueSrvc.MustGetString(kvKeyLastImport).Value)
+	if time.Now().Sub(lastImport) < time.Duration(h.config.App.ImportBackoffMin)*time.Minute {
+		return &actionResult{
+			http.StatusTooManyRequests,
+			"",
+			fmt.Sprintf("Too many data imports - you are only allowed to request an import every %d minutes.", h.config.App.ImportBackoffMin),
+			nil,
+		}
+	}
+	return nil
+}
+
+// insertImportedHeartbeats consumes an importer's stream, inserts heartbeats in batches (duplicates are skipped based on their hash) and notifies the user once done
+func (h *SettingsHandler) insertImportedHeartbeats(user *models.User, r *http.Request, stream <-chan *models.Heartbeat, start time.Time, countBefore int64) {
+	count := 0
+	batch := make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
+
+	insert := func(batch []*models.Heartbeat) {
+		if err := h.heartbeatSrvc.InsertBatch(batch); err != nil {
+			slog.Warn("failed to insert imported heartbeat, already existing?", "error", err)
+		}
+	}
+
+	for hb := range stream {
+		count++
+		batch = append(batch, hb)
+
+		if len(batch) == h.config.App.ImportBatchSize {
+			insert(batch)
+			batch = make([]*models.Heartbeat, 0, h.config.App.ImportBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		insert(batch)
+	}
+
+	countAfter, _ := h.heartbeatSrvc.CountByUser(user)
+	slog.Info("imported heartbeats for user", "count", count, "userID", user.ID, "importedCount", countAfter-countBefore)
+
+	h.regenerateSummaries(user)
+
+	if !user.HasData {
+		user.HasData = true
+		if _, err := h.userSrvc.Update(user); err != nil {
+			conf.Log().Request(r).Error("failed to set 'has_data' flag for user", "userID", user.ID, "error", err)
+		}
+	}
+
+	if err := h.notificationSrvc.SendImportNotification(user, time.Now().Sub(start), int(countAfter-countBefore)); err != nil {
+		conf.Log().Request(r).Error("failed to send import notification", "userID", user.ID, "error", err)
+	} else {
+		slog.Info("sent import notification", "userID", user.ID)
+	}
 }
 
 func (h *SettingsHandler) actionRegenerateSummaries(w http.ResponseWriter, r *http.Request) actionResult {
@@ -806,97 +1077,661 @@ func (h *SettingsHandler) actionGenerateInvite(w http.ResponseWriter, r *http.Re
 	}
 }
 
-func (h *SettingsHandler) validateWakatimeKey(apiKey string, baseUrl string) bool {
-	if baseUrl == "" {
-		baseUrl = conf.WakatimeApiUrl
+func (h *SettingsHandler) actionAddApiToken(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
 	}
 
-	headers := http.Header{
-		"Accept": []string{"application/json"},
-		"Authorization": []string{
-			fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(apiKey))),
-		},
+	user := middlewares.GetPrincipal(r)
+
+	scopes := r.PostForm["scopes"]
+	for _, scope := range scopes {
+		if !models.ValidateApiTokenScope(scope) {
+			return actionResult{http.StatusBadRequest, "", "invalid scope", nil}
+		}
+	}
+	if len(scopes) == 0 {
+		return actionResult{http.StatusBadRequest, "", "at least one scope is required", nil}
 	}
 
-	request, err := http.NewRequest(
-		http.MethodGet,
-		baseUrl+conf.WakatimeApiUserUrl,
-		nil,
-	)
+	var expiresAt *time.Time
+	if expiryDays, err := strconv.Atoi(r.PostFormValue("expiry_days")); err == nil && expiryDays > 0 {
+		t := time.Now().AddDate(0, 0, expiryDays)
+		expiresAt = &t
+	}
+
+	_, token, err := h.apiTokenSrvc.Create(user, r.PostFormValue("name"), scopes, expiresAt)
 	if err != nil {
-		return false
+		conf.Log().Request(r).Error("failed to create api token", "userID", user.ID, "error", err)
+		return actionResult{http.StatusBadRequest, "", "failed to create api token - perhaps invalid name?", nil}
 	}
 
-	request.Header = headers
+	return actionResult{
+		http.StatusOK,
+		"Successfully created new api token (see below)",
+		"",
+		&map[string]interface{}{
+			valueApiToken: token,
+		},
+	}
+}
 
-	if _, err = utils.RaiseForStatus(h.httpClient.Do(request)); err != nil {
-		return false
+func (h *SettingsHandler) actionDeleteApiToken(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
 	}
 
-	return true
+	user := middlewares.GetPrincipal(r)
+
+	tokenId, err := strconv.ParseUint(r.PostFormValue("id"), 10, 32)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	tokens, err := h.apiTokenSrvc.GetByUser(user.ID)
+	if err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not revoke api token", nil}
+	}
+
+	for _, t := range tokens {
+		if t.ID == uint(tokenId) {
+			if err := h.apiTokenSrvc.Delete(t); err != nil {
+				return actionResult{http.StatusInternalServerError, "", "could not revoke api token", nil}
+			}
+			return actionResult{http.StatusOK, "api token revoked successfully", "", nil}
+		}
+	}
+	return actionResult{http.StatusNotFound, "", "api token not found", nil}
 }
 
-func (h *SettingsHandler) regenerateSummaries(user *models.User) error {
-	slog.Info("clearing summaries and durations for user", "userID", user.ID)
+func (h *SettingsHandler) actionAddWebhook(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-	if err := h.summarySrvc.DeleteByUser(user.ID); err != nil {
-		conf.Log().Error("failed to clear summaries", "error", err)
-		return err
+	user := middlewares.GetPrincipal(r)
+
+	events := r.PostForm["events"]
+	for _, event := range events {
+		if !models.ValidateWebhookEvent(event) {
+			return actionResult{http.StatusBadRequest, "", "invalid event", nil}
+		}
+	}
+	if len(events) == 0 {
+		return actionResult{http.StatusBadRequest, "", "at least one event is required", nil}
 	}
 
-	if err := h.aggregationSrvc.AggregateSummaries(datastructure.New(user.ID)); err != nil { // involves regenerating durations as well
-		conf.Log().Error("failed to regenerate summaries", "error", err)
-		return err
+	global := r.PostFormValue("global") == "true"
+	if global && !user.IsAdmin {
+		return actionResult{http.StatusForbidden, "", "only admins can create webhooks for all users", nil}
 	}
 
-	return nil
+	if _, err := h.webhookSrvc.Create(user, r.PostFormValue("url"), events, global); err != nil {
+		conf.Log().Request(r).Error("failed to create webhook", "userID", user.ID, "error", err)
+		return actionResult{http.StatusBadRequest, "", "failed to create webhook - perhaps invalid url or not a public host?", nil}
+	}
+
+	return actionResult{http.StatusOK, "Successfully created new webhook", "", nil}
 }
 
-func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter, args *map[string]interface{}) *view.SettingsViewModel {
+func (h *SettingsHandler) actionDeleteWebhook(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
 	user := middlewares.GetPrincipal(r)
 
-	// mappings
-	mappings, _ := h.languageMappingSrvc.GetByUser(user.ID)
+	webhook, result := h.getOwnedWebhook(user, r.PostFormValue("id"))
+	if result != nil {
+		return *result
+	}
 
-	// aliases
-	aliases, err := h.aliasSrvc.GetByUser(user.ID)
-	if err != nil {
-		conf.Log().Request(r).Error("error while building alias map", "error", err)
-		return &view.SettingsViewModel{
-			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
-				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
-				User:            user,
-				ApiKey:          user.ApiKey,
-			},
-		}
+	if err := h.webhookSrvc.Delete(webhook); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete webhook", nil}
 	}
-	aliasMap := make(map[string][]*models.Alias)
-	for _, a := range aliases {
-		k := fmt.Sprintf("%s_%d", a.Key, a.Type)
-		if _, ok := aliasMap[k]; !ok {
-			aliasMap[k] = []*models.Alias{a}
-		} else {
-			aliasMap[k] = append(aliasMap[k], a)
-		}
+	return actionResult{http.StatusOK, "webhook deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionTestWebhook(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
 	}
 
-	combinedAliases := make([]*view.SettingsVMCombinedAlias, 0)
-	for _, l := range aliasMap {
-		ca := &view.SettingsVMCombinedAlias{
-			Key:    l[0].Key,
-			Type:   l[0].Type,
-			Values: make([]string, len(l)),
-		}
-		for i, a := range l {
-			ca.Values[i] = a.Value
-		}
-		combinedAliases = append(combinedAliases, ca)
+	user := middlewares.GetPrincipal(r)
+
+	webhook, result := h.getOwnedWebhook(user, r.PostFormValue("id"))
+	if result != nil {
+		return *result
 	}
 
-	// labels
-	labelMap, err := h.projectLabelSrvc.GetByUserGroupedInverted(user.ID)
+	delivery, err := h.webhookSrvc.Ping(webhook, user)
 	if err != nil {
-		conf.Log().Request(r).Error("error while building settings project label map", "error", err)
+		conf.Log().Request(r).Error("failed to send webhook ping", "webhookID", webhook.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", "could not send test event", nil}
+	}
+	if !delivery.Success {
+		return actionResult{http.StatusOK, "", fmt.Sprintf("test event could not be delivered (%s)", delivery.Error), nil}
+	}
+	return actionResult{http.StatusOK, fmt.Sprintf("test event delivered successfully (status %d)", delivery.StatusCode), "", nil}
+}
+
+// getOwnedWebhook fetches a webhook by its id, given that it either belongs to the user or is a global one and the user is an admin
+func (h *SettingsHandler) getOwnedWebhook(user *models.User, id string) (*models.Webhook, *actionResult) {
+	webhookId, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	webhook, err := h.webhookSrvc.GetById(uint(webhookId))
+	if err != nil || (webhook.IsGlobal() && !user.IsAdmin) || (!webhook.IsGlobal() && *webhook.UserID != user.ID) {
+		return nil, &actionResult{http.StatusNotFound, "", "webhook not found", nil}
+	}
+	return webhook, nil
+}
+
+func (h *SettingsHandler) actionAddRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	target := &models.RelayTarget{
+		UserID:          user.ID,
+		Name:            r.PostFormValue("name"),
+		ApiUrl:          r.PostFormValue("api_url"),
+		ApiKey:          strings.TrimSpace(r.PostFormValue("api_key")),
+		IncludeProjects: r.PostFormValue("include_projects"),
+		ExcludeProjects: r.PostFormValue("exclude_projects"),
+	}
+	if target.ApiUrl == "" {
+		target.ApiUrl = conf.WakatimeApiUrl
+	}
+
+	if _, err := h.relaySrvc.Create(target); err != nil {
+		conf.Log().Request(r).Error("failed to create relay target", "userID", user.ID, "error", err)
+		return actionResult{http.StatusBadRequest, "", "failed to add relay target - perhaps invalid url?", nil}
+	}
+
+	return actionResult{http.StatusOK, "Successfully added new relay target", "", nil}
+}
+
+func (h *SettingsHandler) actionToggleRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	target, result := h.getOwnedRelayTarget(user, r.PostFormValue("id"))
+	if result != nil {
+		return *result
+	}
+
+	target.Enabled = !target.Enabled
+	if target.Enabled {
+		target.FailureCount = 0
+		target.LastError = ""
+	}
+	if _, err := h.relaySrvc.Update(target); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not update relay target", nil}
+	}
+
+	if target.Enabled {
+		return actionResult{http.StatusOK, "relay target enabled", "", nil}
+	}
+	return actionResult{http.StatusOK, "relay target paused", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteRelayTarget(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	target, result := h.getOwnedRelayTarget(user, r.PostFormValue("id"))
+	if result != nil {
+		return *result
+	}
+
+	if err := h.relaySrvc.Delete(target); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete relay target", nil}
+	}
+	return actionResult{http.StatusOK, "relay target deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) getOwnedRelayTarget(user *models.User, id string) (*models.RelayTarget, *actionResult) {
+	targetId, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	target, err := h.relaySrvc.GetById(uint(targetId))
+	if err != nil || target.UserID != user.ID {
+		return nil, &actionResult{http.StatusNotFound, "", "relay target not found", nil}
+	}
+	return target, nil
+}
+
+func (h *SettingsHandler) actionAddGoal(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	hours, _ := strconv.Atoi(r.PostFormValue("target_hours"))
+	minutes, _ := strconv.Atoi(r.PostFormValue("target_minutes"))
+	if hours < 0 || minutes < 0 {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	goal := &models.Goal{
+		UserID:        user.ID,
+		Title:         strings.TrimSpace(r.PostFormValue("title")),
+		Period:        r.PostFormValue("period"),
+		TargetSeconds: hours*3600 + minutes*60,
+		Projects:      joinListInput(r.PostFormValue("projects")),
+		Languages:     joinListInput(r.PostFormValue("languages")),
+		Labels:        joinListInput(r.PostFormValue("labels")),
+		Notify:        r.PostFormValue("notify") == "true" && user.Email != "",
+	}
+	if !goal.IsValid() {
+		return actionResult{http.StatusBadRequest, "", "invalid goal - target must be more than zero and fit into the chosen period", nil}
+	}
+
+	if _, err := h.goalSrvc.Create(goal); err != nil {
+		conf.Log().Request(r).Error("failed to create goal", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", "failed to create goal", nil}
+	}
+
+	return actionResult{http.StatusOK, "Successfully created new goal", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteGoal(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	goalId, err := strconv.ParseUint(r.PostFormValue("id"), 10, 32)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	goal, err := h.goalSrvc.GetById(uint(goalId))
+	if err != nil || goal.UserID != user.ID {
+		return actionResult{http.StatusNotFound, "", "goal not found", nil}
+	}
+
+	if err := h.goalSrvc.Delete(goal); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete goal", nil}
+	}
+	return actionResult{http.StatusOK, "goal deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionAddNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	events := r.PostForm["events"]
+	for _, event := range events {
+		if !models.ValidateNotificationType(event) {
+			return actionResult{http.StatusBadRequest, "", "invalid notification type", nil}
+		}
+	}
+	if len(events) == 0 {
+		return actionResult{http.StatusBadRequest, "", "at least one notification type is required", nil}
+	}
+
+	channel := &models.NotificationChannel{
+		UserID: user.ID,
+		Type:   r.PostFormValue("type"),
+		Url:    r.PostFormValue("url"),
+		Token:  r.PostFormValue("token"),
+		Target: r.PostFormValue("target"),
+		Events: strings.Join(events, ","),
+	}
+
+	if _, err := h.notificationSrvc.Create(channel); err != nil {
+		conf.Log().Request(r).Error("failed to create notification channel", "userID", user.ID, "error", err)
+		return actionResult{http.StatusBadRequest, "", "failed to create notification channel - perhaps invalid url or missing token / room id?", nil}
+	}
+
+	return actionResult{http.StatusOK, "Successfully added new notification channel", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	channel, result := h.getOwnedNotificationChannel(user, r.PostFormValue("id"))
+	if result != nil {
+		return *result
+	}
+
+	if err := h.notificationSrvc.Delete(channel); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete notification channel", nil}
+	}
+	return actionResult{http.StatusOK, "notification channel deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionTestNotificationChannel(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	channel, result := h.getOwnedNotificationChannel(user, r.PostFormValue("id"))
+	if result != nil {
+		return *result
+	}
+
+	if err := h.notificationSrvc.Test(channel); err != nil {
+		conf.Log().Request(r).Warn("failed to send test notification", "channelID", channel.ID, "error", err)
+		return actionResult{http.StatusOK, "", "test notification could not be delivered - please check the channel's url and credentials", nil}
+	}
+	return actionResult{http.StatusOK, "test notification delivered successfully", "", nil}
+}
+
+func (h *SettingsHandler) getOwnedNotificationChannel(user *models.User, id string) (*models.NotificationChannel, *actionResult) {
+	channelId, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, &actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	channel, err := h.notificationSrvc.GetById(uint(channelId))
+	if err != nil || channel.UserID != user.ID {
+		return nil, &actionResult{http.StatusNotFound, "", "notification channel not found", nil}
+	}
+	return channel, nil
+}
+
+func (h *SettingsHandler) actionAddReportSubscription(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user.Email == "" && !h.notificationSrvc.HasChannels(user, models.NotificationTypeReport) {
+		return actionResult{http.StatusBadRequest, "", "you need to set an e-mail address or notification channel first", nil}
+	}
+
+	hour, err := strconv.Atoi(r.PostFormValue("hour"))
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	subscription := &models.ReportSubscription{
+		UserID:     user.ID,
+		Cadence:    r.PostFormValue("cadence"),
+		Hour:       hour,
+		Projects:   joinListInput(r.PostFormValue("projects")),
+		Labels:     joinListInput(r.PostFormValue("labels")),
+		Recipients: joinListInput(r.PostFormValue("recipients")),
+		Sections:   strings.Join(r.PostForm["sections"], ","),
+	}
+	if !subscription.IsValid() {
+		return actionResult{http.StatusBadRequest, "", fmt.Sprintf("invalid report - choose at least one section and at most %d valid recipients", models.ReportSubscriptionMaxRecipients), nil}
+	}
+
+	if _, err := h.reportSrvc.CreateSubscription(subscription); err != nil {
+		conf.Log().Request(r).Error("failed to create report subscription", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", "failed to create report", nil}
+	}
+
+	return actionResult{http.StatusOK, "Successfully created new report", "", nil}
+}
+
+func (h *SettingsHandler) actionDeleteReportSubscription(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	subscriptionId, err := strconv.ParseUint(r.PostFormValue("id"), 10, 32)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	subscription, err := h.reportSrvc.GetSubscriptionById(uint(subscriptionId))
+	if err != nil || subscription.UserID != user.ID {
+		return actionResult{http.StatusNotFound, "", "report not found", nil}
+	}
+
+	if err := h.reportSrvc.DeleteSubscription(subscription); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not delete report", nil}
+	}
+	return actionResult{http.StatusOK, "report deleted successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionUnlinkOidc(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	identityId, err := strconv.ParseUint(r.PostFormValue("id"), 10, 32)
+	if err != nil {
+		return actionResult{http.StatusBadRequest, "", "invalid input", nil}
+	}
+
+	identity, err := h.oidcSrvc.GetById(uint(identityId))
+	if err != nil || identity.UserID != user.ID {
+		return actionResult{http.StatusNotFound, "", "identity not found", nil}
+	}
+
+	if err := h.oidcSrvc.Delete(identity); err != nil {
+		return actionResult{http.StatusInternalServerError, "", "could not disconnect account", nil}
+	}
+	return actionResult{http.StatusOK, "account disconnected successfully", "", nil}
+}
+
+func (h *SettingsHandler) actionSetupTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if _, err := h.totpSrvc.Setup(user); err != nil {
+		if errors.Is(err, services.ErrTotpAlreadyEnabled) {
+			return actionResult{http.StatusConflict, "", err.Error(), nil}
+		}
+		conf.Log().Request(r).Error("failed to set up two-factor authentication", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "scan the qr code with your authenticator app and enter the code to confirm", "", nil}
+}
+
+func (h *SettingsHandler) actionEnableTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	recoveryCodes, err := h.totpSrvc.Enable(user, r.PostFormValue("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTotpInvalidCode):
+			return actionResult{http.StatusBadRequest, "", "invalid code, please try again", nil}
+		case errors.Is(err, services.ErrTotpAlreadyEnabled), errors.Is(err, services.ErrTotpNotEnabled):
+			return actionResult{http.StatusConflict, "", err.Error(), nil}
+		}
+		conf.Log().Request(r).Error("failed to enable two-factor authentication", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	return actionResult{
+		http.StatusOK,
+		"two-factor authentication enabled successfully (see recovery codes below)",
+		"",
+		&map[string]interface{}{
+			valueRecoveryCodes: recoveryCodes,
+		},
+	}
+}
+
+func (h *SettingsHandler) actionDisableTotp(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	// a pending, unconfirmed setup can be cancelled right away, while disabling requires a valid code
+	if user.HasTotp() {
+		if err := h.totpSrvc.Verify(user, r.PostFormValue("code")); err != nil {
+			return actionResult{http.StatusUnauthorized, "", "invalid code", nil}
+		}
+	}
+
+	if _, err := h.totpSrvc.Disable(user); err != nil {
+		if errors.Is(err, services.ErrTotpRequired) {
+			return actionResult{http.StatusForbidden, "", err.Error(), nil}
+		}
+		conf.Log().Request(r).Error("failed to disable two-factor authentication", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+	return actionResult{http.StatusOK, "two-factor authentication disabled", "", nil}
+}
+
+func (h *SettingsHandler) actionRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) actionResult {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	if err := h.totpSrvc.Verify(user, r.PostFormValue("code")); err != nil {
+		return actionResult{http.StatusUnauthorized, "", "invalid code", nil}
+	}
+
+	recoveryCodes, err := h.totpSrvc.RegenerateRecoveryCodes(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to regenerate recovery codes", "userID", user.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", conf.ErrInternalServerError, nil}
+	}
+
+	return actionResult{
+		http.StatusOK,
+		"new recovery codes generated successfully (see below)",
+		"",
+		&map[string]interface{}{
+			valueRecoveryCodes: recoveryCodes,
+		},
+	}
+}
+
+func (h *SettingsHandler) validateWakatimeKey(apiKey string, baseUrl string) bool {
+	if baseUrl == "" {
+		baseUrl = conf.WakatimeApiUrl
+	}
+
+	headers := http.Header{
+		"Accept": []string{"application/json"},
+		"Authorization": []string{
+			fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(apiKey))),
+		},
+	}
+
+	request, err := http.NewRequest(
+		http.MethodGet,
+		baseUrl+conf.WakatimeApiUserUrl,
+		nil,
+	)
+	if err != nil {
+		return false
+	}
+
+	request.Header = headers
+
+	if _, err = utils.RaiseForStatus(h.httpClient.Do(request)); err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (h *SettingsHandler) regenerateSummaries(user *models.User) error {
+	return regenerateUserSummaries(user, h.summarySrvc, h.aggregationSrvc)
+}
+
+// regenerateUserSummaries deletes all of a user's summaries and re-aggregates them from scratch
+func regenerateUserSummaries(user *models.User, summarySrvc services.ISummaryService, aggregationSrvc services.IAggregationService) error {
+	slog.Info("clearing summaries and durations for user", "userID", user.ID)
+
+	if err := summarySrvc.DeleteByUser(user.ID); err != nil {
+		conf.Log().Error("failed to clear summaries", "error", err)
+		return err
+	}
+
+	if err := aggregationSrvc.AggregateSummaries(datastructure.New(user.ID)); err != nil { // involves regenerating durations as well
+		conf.Log().Error("failed to regenerate summaries", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter, args *map[string]interface{}) *view.SettingsViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	// mappings
+	mappings, _ := h.languageMappingSrvc.GetByUser(user.ID)
+
+	// heartbeat rules
+	heartbeatRules, _ := h.heartbeatRuleSrvc.GetByUser(user.ID)
+
+	// aliases
+	aliases, err := h.aliasSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while building alias map", "error", err)
+		return &view.SettingsViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+	aliasMap := make(map[string][]*models.Alias)
+	for _, a := range aliases {
+		k := fmt.Sprintf("%s_%d", a.Key, a.Type)
+		if _, ok := aliasMap[k]; !ok {
+			aliasMap[k] = []*models.Alias{a}
+		} else {
+			aliasMap[k] = append(aliasMap[k], a)
+		}
+	}
+
+	combinedAliases := make([]*view.SettingsVMCombinedAlias, 0)
+	for _, l := range aliasMap {
+		ca := &view.SettingsVMCombinedAlias{
+			Key:    l[0].Key,
+			Type:   l[0].Type,
+			Values: make([]string, len(l)),
+		}
+		for i, a := range l {
+			ca.Values[i] = a.Value
+		}
+		combinedAliases = append(combinedAliases, ca)
+	}
+
+	// labels
+	labelMap, err := h.projectLabelSrvc.GetByUserGroupedInverted(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while building settings project label map", "error", err)
 		return &view.SettingsViewModel{
 			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
 				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
@@ -947,6 +1782,108 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter,
 		firstData, _ = time.Parse(time.RFC822Z, firstDataKv.Value)
 	}
 
+	// api tokens
+	apiTokens, err := h.apiTokenSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching api tokens", "error", err)
+		return &view.SettingsViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+
+	// webhooks
+	webhooks, err := h.webhookSrvc.GetByUser(user.ID)
+	if err == nil && user.IsAdmin {
+		var globalWebhooks []*models.Webhook
+		if globalWebhooks, err = h.webhookSrvc.GetGlobal(); err == nil {
+			webhooks = append(append([]*models.Webhook{}, webhooks...), globalWebhooks...) // copy to not alter cached slices
+		}
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching webhooks", "error", err)
+		return &view.SettingsViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+	webhookVms := make([]*view.SettingsVMWebhook, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries, err := h.webhookSrvc.GetDeliveries(webhook, 5)
+		if err != nil {
+			conf.Log().Request(r).Error("error while fetching webhook deliveries", "webhookID", webhook.ID, "error", err)
+		}
+		webhookVms[i] = &view.SettingsVMWebhook{Webhook: webhook, Deliveries: deliveries}
+	}
+
+	// relay targets
+	relayTargets, err := h.relaySrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching relay targets", "error", err)
+		return &view.SettingsViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+	relayTargetVms := make([]*view.SettingsVMRelayTarget, len(relayTargets))
+	for i, target := range relayTargets {
+		queued, err := h.relaySrvc.CountQueued(target)
+		if err != nil {
+			conf.Log().Request(r).Error("error while counting queued relay items", "targetID", target.ID, "error", err)
+		}
+		relayTargetVms[i] = &view.SettingsVMRelayTarget{RelayTarget: target, Queued: queued}
+	}
+
+	// goals
+	goals, err := h.goalSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching goals", "error", err)
+		return &view.SettingsViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+
+	// notification channels
+	notificationChannels, err := h.notificationSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching notification channels", "error", err)
+	}
+
+	// custom reports
+	reportSubscriptions, err := h.reportSrvc.GetSubscriptionsByUser(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching report subscriptions", "error", err)
+	}
+
+	// oidc identities
+	var oidcIdentities []*models.OidcIdentity
+	if h.config.Security.Oidc.Enabled {
+		if oidcIdentities, err = h.oidcSrvc.GetByUser(user.ID); err != nil {
+			conf.Log().Request(r).Error("error while fetching oidc identities", "error", err)
+		}
+	}
+
+	// two-factor authentication
+	var totpQrCode string
+	if user.TotpSecret != "" && !user.HasTotp() {
+		if totpQrCode, err = totpQrCodeUri(user, h.totpSrvc); err != nil {
+			conf.Log().Request(r).Error("failed to render two-factor qr code", "userID", user.ID, "error", err)
+		}
+	}
+
 	// invite link
 	inviteCode := getVal[string](args, valueInviteCode, "")
 	inviteLink := condition.TernaryOperator[bool, string](inviteCode == "", "", fmt.Sprintf("%s/signup?invite=%s", h.config.Server.GetPublicUrl(), inviteCode))
@@ -957,15 +1894,29 @@ func (h *SettingsHandler) buildViewModel(r *http.Request, w http.ResponseWriter,
 			User:            user,
 			ApiKey:          user.ApiKey,
 		},
-		LanguageMappings:    mappings,
-		Aliases:             combinedAliases,
-		Labels:              combinedLabels,
-		Projects:            projects,
-		UserFirstData:       firstData,
-		SubscriptionPrice:   subscriptionPrice,
-		SupportContact:      h.config.App.SupportContact,
-		DataRetentionMonths: h.config.App.DataRetentionMonths,
-		InviteLink:          inviteLink,
+		LanguageMappings:     mappings,
+		HeartbeatRules:       heartbeatRules,
+		Aliases:              combinedAliases,
+		Labels:               combinedLabels,
+		Projects:             projects,
+		UserFirstData:        firstData,
+		SubscriptionPrice:    subscriptionPrice,
+		SupportContact:       h.config.App.SupportContact,
+		DataRetentionMonths:  h.config.App.DataRetentionMonths,
+		InviteLink:           inviteLink,
+		ApiTokens:            apiTokens,
+		NewApiToken:          getVal[string](args, valueApiToken, ""),
+		Webhooks:             webhookVms,
+		RelayTargets:         relayTargetVms,
+		Goals:                goals,
+		ReportSubscriptions:  reportSubscriptions,
+		NotificationChannels: notificationChannels,
+		OidcIdentities:       oidcIdentities,
+		OidcEnabled:          h.config.Security.Oidc.Enabled,
+		OidcName:             h.config.Security.Oidc.Name,
+		TotpRequired:         h.totpSrvc.IsRequired(user),
+		TotpQrCode:           totpQrCode,
+		RecoveryCodes:        getVal[[]string](args, valueRecoveryCodes, nil),
 	}
 
 	// readme card params
@@ -987,6 +1938,17 @@ func (h *SettingsHandler) isAggregationLocked(userId string) bool {
 	return locked
 }
 
+// joinListInput normalizes a comma-separated user input by trimming whitespace and dropping empty entries
+func joinListInput(input string) string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
 func getVal[T any](values *map[string]interface{}, key string, fallback T) T {
 	if values == nil {
 		return fallback
@@ -998,3 +1960,18 @@ func getVal[T any](values *map[string]interface{}, key string, fallback T) T {
 	}
 	return val.(T)
 }
+
+// readUploadedFile reads the uploaded file entirely, because the request body is gone once the handler has returned
+func readUploadedFile(r *http.Request) ([]byte, error) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, errors.New("missing or invalid file")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.New("failed to read file")
+	}
+	return data, nil
+}
@@ -26,13 +26,15 @@ type SummaryHandler struct {
 	userSrvc     services.IUserService
 	summarySrvc  services.ISummaryService
 	keyValueSrvc services.IKeyValueService
+	goalSrvc     services.IGoalService
 }
 
-func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, keyValueService services.IKeyValueService) *SummaryHandler {
+func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, keyValueService services.IKeyValueService, goalService services.IGoalService) *SummaryHandler {
 	return &SummaryHandler{
 		summarySrvc:  summaryService,
 		userSrvc:     userService,
 		keyValueSrvc: keyValueService,
+		goalSrvc:     goalService,
 		config:       conf.Get(),
 	}
 }
@@ -103,6 +105,11 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	goals, err := h.fetchGoalProgress(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to load goal progress", "error", err)
+	}
+
 	vm := view.SummaryViewModel{
 		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
 			SharedViewModel: view.NewSharedViewModel(h.config, nil),
@@ -118,6 +125,7 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 		UserFirstData:       firstData,
 		DataRetentionMonths: h.config.App.DataRetentionMonths,
 		DailyStats:          dailyStats,
+		Goals:               goals,
 	}
 
 	templates[conf.SummaryTemplate].Execute(w, vm)
@@ -131,6 +139,24 @@ func (h *SummaryHandler) buildViewModel(r *http.Request, w http.ResponseWriter)
 	}, r, w)
 }
 
+func (h *SummaryHandler) fetchGoalProgress(user *models.User) ([]*models.GoalProgress, error) {
+	goals, err := h.goalSrvc.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	progress := make([]*models.GoalProgress, 0, len(goals))
+	for _, goal := range goals {
+		p, err := h.goalSrvc.GetProgress(goal, user, now)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
 func (h *SummaryHandler) fetchSplitSummaries(params *models.SummaryParams) ([]*models.Summary, error) {
 	summaries := make([]*models.Summary, 0)
 	intervals := utils.SplitRangeByDays(params.From, params.To)
@@ -0,0 +1,411 @@
+package routes
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type TeamsHandler struct {
+	config             *conf.Config
+	userService        services.IUserService
+	teamService        services.ITeamService
+	leaderboardService services.ILeaderboardService
+}
+
+func NewTeamsHandler(userService services.IUserService, teamService services.ITeamService, leaderboardService services.ILeaderboardService) *TeamsHandler {
+	return &TeamsHandler{
+		config:             conf.Get(),
+		userService:        userService,
+		teamService:        teamService,
+		leaderboardService: leaderboardService,
+	}
+}
+
+func (h *TeamsHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userService).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Post("/", h.PostIndex)
+	r.Get("/join", h.GetJoin)
+	r.Post("/join", h.PostJoin)
+	r.Get("/{id}", h.GetTeam)
+	r.Post("/{id}", h.PostTeam)
+
+	router.Mount("/teams", r)
+}
+
+func (h *TeamsHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	if err := templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w)); err != nil {
+		conf.Log().Request(r).Error("failed to get teams page", "error", err)
+	}
+}
+
+func (h *TeamsHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithError("missing form values"))
+		return
+	}
+
+	team, err := h.teamService.Create(&models.Team{Name: r.PostForm.Get("name")}, user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create team", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamsTemplate].Execute(w, h.buildIndexViewModel(r, w).WithError("failed to create team - perhaps invalid name?"))
+		return
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("team '%s' created successfully", team.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/teams/%d", h.config.Server.BasePath, team.ID), http.StatusFound)
+}
+
+// GetJoin asks the user for confirmation before joining the team referred to by the invite link, see PostJoin
+func (h *TeamsHandler) GetJoin(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	inviteCode := r.URL.Query().Get("invite")
+
+	team, err := h.teamService.GetInvite(inviteCode)
+	if err != nil {
+		routeutils.SetError(r, w, err.Error())
+		http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	vm := h.buildIndexViewModel(r, w)
+	vm.InvitedTeam = team
+	vm.InviteCode = inviteCode
+	if err := templates[conf.TeamsTemplate].Execute(w, vm); err != nil {
+		conf.Log().Request(r).Error("failed to get teams page", "error", err)
+	}
+}
+
+func (h *TeamsHandler) PostJoin(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+
+	if err := r.ParseForm(); err != nil {
+		routeutils.SetError(r, w, "missing form values")
+		http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	team, err := h.teamService.RedeemInvite(r.PostForm.Get("invite"), user)
+	if err != nil {
+		routeutils.SetError(r, w, err.Error())
+		http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("you joined team '%s'", team.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/teams/%d", h.config.Server.BasePath, team.ID), http.StatusFound)
+}
+
+func (h *TeamsHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	team, member := h.resolveTeam(r)
+	if team == nil {
+		routeutils.SetError(r, w, "team not found")
+		http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	if err := templates[conf.TeamTemplate].Execute(w, h.buildTeamViewModel(r, w, team, member, nil)); err != nil {
+		conf.Log().Request(r).Error("failed to get team page", "error", err)
+	}
+}
+
+func (h *TeamsHandler) PostTeam(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	team, member := h.resolveTeam(r)
+	if team == nil {
+		routeutils.SetError(r, w, "team not found")
+		http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamTemplate].Execute(w, h.buildTeamViewModel(r, w, team, member, nil).WithError("missing form values"))
+		return
+	}
+
+	action := r.PostForm.Get("action")
+	r.PostForm.Del("action")
+
+	actionFunc := h.dispatchAction(action, team, member)
+	if actionFunc == nil {
+		slog.Warn("failed to dispatch action", "action", action)
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamTemplate].Execute(w, h.buildTeamViewModel(r, w, team, member, nil).WithError("unknown action requests"))
+		return
+	}
+
+	result := actionFunc(w, r)
+
+	// action responded itself
+	if result.code == -1 {
+		return
+	}
+
+	// membership might have changed
+	member, _ = h.teamService.GetMember(team, member.UserID)
+
+	if result.error != "" {
+		w.WriteHeader(result.code)
+		templates[conf.TeamTemplate].Execute(w, h.buildTeamViewModel(r, w, team, member, result.values).WithError(result.error))
+		return
+	}
+	if result.success != "" {
+		w.WriteHeader(result.code)
+		templates[conf.TeamTemplate].Execute(w, h.buildTeamViewModel(r, w, team, member, result.values).WithSuccess(result.success))
+		return
+	}
+	templates[conf.TeamTemplate].Execute(w, h.buildTeamViewModel(r, w, team, member, result.values))
+}
+
+func (h *TeamsHandler) dispatchAction(action string, team *models.Team, member *models.TeamMember) action {
+	switch action {
+	case "generate_invite":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionGenerateInvite(w, r, team, member)
+		}
+	case "update_role":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionUpdateRole(w, r, team, member)
+		}
+	case "remove_member":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionRemoveMember(w, r, team, member)
+		}
+	case "leave_team":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionLeaveTeam(w, r, team, member)
+		}
+	case "delete_team":
+		return func(w http.ResponseWriter, r *http.Request) actionResult {
+			return h.actionDeleteTeam(w, r, team, member)
+		}
+	}
+	return nil
+}
+
+func (h *TeamsHandler) actionGenerateInvite(w http.ResponseWriter, r *http.Request, team *models.Team, member *models.TeamMember) actionResult {
+	if !member.CanManage() {
+		return actionResult{http.StatusForbidden, "", "not allowed to invite members", nil}
+	}
+
+	inviteCode, err := h.teamService.GenerateInvite(team, middlewares.GetPrincipal(r))
+	if err != nil {
+		return actionResult{http.StatusInternalServerError, "", "failed to generate invite code", nil}
+	}
+
+	return actionResult{
+		http.StatusOK,
+		"Successfully generated new invite code (see below)",
+		"",
+		&map[string]interface{}{
+			valueInviteCode: inviteCode,
+		},
+	}
+}
+
+func (h *TeamsHandler) actionUpdateRole(w http.ResponseWriter, r *http.Request, team *models.Team, member *models.TeamMember) actionResult {
+	userId, role := r.PostForm.Get("user_id"), r.PostForm.Get("role")
+
+	if !models.ValidateTeamRole(role) {
+		return actionResult{http.StatusBadRequest, "", "invalid role", nil}
+	}
+	// only owners may grant or revoke ownership
+	if !member.CanManage() || (!member.IsOwner() && role == models.TeamRoleOwner) {
+		return actionResult{http.StatusForbidden, "", "not allowed to change roles", nil}
+	}
+	if target, err := h.teamService.GetMember(team, userId); err != nil {
+		return actionResult{http.StatusNotFound, "", "member not found", nil}
+	} else if target.IsOwner() && !member.IsOwner() {
+		return actionResult{http.StatusForbidden, "", "not allowed to change roles", nil}
+	}
+
+	if _, err := h.teamService.UpdateMemberRole(team, userId, role); err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+	return actionResult{http.StatusOK, "role updated successfully", "", nil}
+}
+
+func (h *TeamsHandler) actionRemoveMember(w http.ResponseWriter, r *http.Request, team *models.Team, member *models.TeamMember) actionResult {
+	userId := r.PostForm.Get("user_id")
+
+	if !member.CanManage() {
+		return actionResult{http.StatusForbidden, "", "not allowed to remove members", nil}
+	}
+	if target, err := h.teamService.GetMember(team, userId); err != nil {
+		return actionResult{http.StatusNotFound, "", "member not found", nil}
+	} else if target.IsOwner() && !member.IsOwner() {
+		return actionResult{http.StatusForbidden, "", "not allowed to remove an owner", nil}
+	}
+
+	if err := h.teamService.RemoveMember(team, userId); err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+	return actionResult{http.StatusOK, "member removed successfully", "", nil}
+}
+
+func (h *TeamsHandler) actionLeaveTeam(w http.ResponseWriter, r *http.Request, team *models.Team, member *models.TeamMember) actionResult {
+	if err := h.teamService.RemoveMember(team, member.UserID); err != nil {
+		return actionResult{http.StatusBadRequest, "", err.Error(), nil}
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("you left team '%s'", team.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+	return actionResult{-1, "", "", nil}
+}
+
+func (h *TeamsHandler) actionDeleteTeam(w http.ResponseWriter, r *http.Request, team *models.Team, member *models.TeamMember) actionResult {
+	if !member.IsOwner() {
+		return actionResult{http.StatusForbidden, "", "only owners may delete a team", nil}
+	}
+
+	if err := h.teamService.Delete(team); err != nil {
+		conf.Log().Request(r).Error("failed to delete team", "teamID", team.ID, "error", err)
+		return actionResult{http.StatusInternalServerError, "", "failed to delete team", nil}
+	}
+
+	routeutils.SetSuccess(r, w, fmt.Sprintf("team '%s' deleted successfully", team.Name))
+	http.Redirect(w, r, fmt.Sprintf("%s/teams", h.config.Server.BasePath), http.StatusFound)
+	return actionResult{-1, "", "", nil}
+}
+
+// resolveTeam returns the team referred to by the request, given the principal is a member of it
+func (h *TeamsHandler) resolveTeam(r *http.Request) (*models.Team, *models.TeamMember) {
+	user := middlewares.GetPrincipal(r)
+
+	teamId, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+	team, err := h.teamService.GetById(uint(teamId))
+	if err != nil {
+		return nil, nil
+	}
+	member, err := h.teamService.GetMember(team, user.ID)
+	if err != nil {
+		return nil, nil
+	}
+	return team, member
+}
+
+func (h *TeamsHandler) buildIndexViewModel(r *http.Request, w http.ResponseWriter) *view.TeamsViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	teams, err := h.teamService.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching teams", "userID", user.ID, "error", err)
+		return &view.TeamsViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+		}
+	}
+
+	vm := &view.TeamsViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Teams: teams,
+	}
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func (h *TeamsHandler) buildTeamViewModel(r *http.Request, w http.ResponseWriter, team *models.Team, member *models.TeamMember, args *map[string]interface{}) *view.TeamViewModel {
+	user := middlewares.GetPrincipal(r)
+
+	interval, err := helpers.ParseInterval(r.URL.Query().Get("interval"))
+	if err != nil {
+		interval = models.IntervalPast7Days
+	}
+	_, from, to := helpers.ResolveIntervalTZ(interval, user.TZ())
+
+	summary, err := h.teamService.GetSummary(team, from, to, helpers.ParseSummaryFilters(r))
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching team summary", "teamID", team.ID, "error", err)
+		return &view.TeamViewModel{
+			SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+				SharedViewModel: view.NewSharedViewModel(h.config, &view.Messages{Error: criticalError}),
+				User:            user,
+				ApiKey:          user.ApiKey,
+			},
+			Team:       team,
+			Membership: member,
+			Interval:   interval,
+		}
+	}
+
+	members, err := h.teamService.GetMembers(team)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching team members", "teamID", team.ID, "error", err)
+	}
+
+	var leaderboard []*models.LeaderboardItemRanked
+	if h.leaderboardService != nil {
+		if leaderboard, err = h.leaderboardService.GetAggregatedByTeamAndInterval(team.ID, h.leaderboardService.GetDefaultScope(), nil, true); err != nil {
+			conf.Log().Request(r).Error("error while fetching team leaderboard", "teamID", team.ID, "error", err)
+		}
+	}
+
+	inviteCode := getVal[string](args, valueInviteCode, "")
+	inviteLink := ""
+	if inviteCode != "" {
+		inviteLink = fmt.Sprintf("%s/teams/join?invite=%s", h.config.Server.GetPublicUrl(), inviteCode)
+	}
+
+	vm := &view.TeamViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Team:        team,
+		Membership:  member,
+		Members:     members,
+		Summary:     summary,
+		Leaderboard: leaderboard,
+		Interval:    interval,
+		InviteLink:  inviteLink,
+	}
+	return routeutils.WithSessionMessages(vm, r, w)
+}
@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type TimelineHandler struct {
+	config          *conf.Config
+	userService     services.IUserService
+	durationService services.IDurationService
+	aliasService    services.IAliasService
+}
+
+func NewTimelineHandler(userService services.IUserService, durationService services.IDurationService, aliasService services.IAliasService) *TimelineHandler {
+	return &TimelineHandler{
+		config:          conf.Get(),
+		userService:     userService,
+		durationService: durationService,
+		aliasService:    aliasService,
+	}
+}
+
+func (h *TimelineHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userService).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+
+	router.Mount("/timeline", r)
+}
+
+func (h *TimelineHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	if err := templates[conf.TimelineTemplate].Execute(w, h.buildViewModel(r, w)); err != nil {
+		conf.Log().Request(r).Error("failed to get timeline page", "error", err)
+	}
+}
+
+func (h *TimelineHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.TimelineViewModel {
+	user := middlewares.GetPrincipal(r)
+	if user == nil { // this should actually never occur, because of auth middleware
+		w.WriteHeader(http.StatusUnauthorized)
+		return h.buildViewModel(r, w).WithError("unauthorized")
+	}
+
+	today := datetime.BeginOfDay(time.Now().In(user.TZ()))
+
+	vm := &view.TimelineViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Date:     today,
+		Today:    today,
+		GroupBy:  "project",
+		Timeline: models.NewTimeline(models.Durations{}, today, today.AddDate(0, 0, 1), models.SummaryProject, 0, nil),
+	}
+
+	if dateParam := r.URL.Query().Get("date"); dateParam != "" {
+		date, err := time.ParseInLocation(conf.SimpleDateFormat, dateParam, user.TZ())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return vm.WithError("invalid date")
+		}
+		vm.Date = date
+	}
+
+	if groupByParam := r.URL.Query().Get("group_by"); groupByParam != "" {
+		if _, ok := models.TimelineGroupTypes[groupByParam]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return vm.WithError("invalid grouping")
+		}
+		vm.GroupBy = groupByParam
+	}
+
+	from, to := vm.Date, vm.Date.AddDate(0, 0, 1)
+
+	durations, err := h.durationService.Get(from, to, user, nil, nil, false)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching durations", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return vm.WithError(criticalError)
+	}
+
+	vm.Timeline = models.NewTimeline(durations.Sorted(), from, to, models.TimelineGroupTypes[vm.GroupBy], user.HeartbeatsTimeout(), h.resolveAlias(user))
+	return routeutils.WithSessionMessages(vm, r, w)
+}
+
+func (h *TimelineHandler) resolveAlias(user *models.User) models.AliasResolver {
+	return func(t uint8, k string) string {
+		s, _ := h.aliasService.GetAliasOrDefault(user.ID, t, k)
+		return s
+	}
+}
@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+)
+
+type TimesheetHandler struct {
+	config              *conf.Config
+	userService         services.IUserService
+	timesheetService    services.ITimesheetService
+	projectLabelService services.IProjectLabelService
+}
+
+func NewTimesheetHandler(userService services.IUserService, timesheetService services.ITimesheetService, projectLabelService services.IProjectLabelService) *TimesheetHandler {
+	return &TimesheetHandler{
+		config:              conf.Get(),
+		userService:         userService,
+		timesheetService:    timesheetService,
+		projectLabelService: projectLabelService,
+	}
+}
+
+func (h *TimesheetHandler) RegisterRoutes(router chi.Router) {
+	r := chi.NewRouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userService).
+			WithRedirectTarget(defaultErrorRedirectTarget()).
+			WithRedirectErrorMessage("unauthorized").Handler,
+	)
+	r.Get("/", h.GetIndex)
+	r.Get("/invoice", h.GetInvoice)
+
+	router.Mount("/timesheet", r)
+}
+
+func (h *TimesheetHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+	if err := templates[conf.TimesheetTemplate].Execute(w, h.buildViewModel(r, w)); err != nil {
+		conf.Log().Request(r).Error("failed to get timesheet page", "error", err)
+	}
+}
+
+// GetInvoice renders the timesheet as a standalone, printable document, which is sent as a file download if requested
+func (h *TimesheetHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	vm := h.buildViewModel(r, w)
+	if vm.Timesheet == nil {
+		if err := templates[conf.TimesheetTemplate].Execute(w, vm); err != nil {
+			conf.Log().Request(r).Error("failed to get timesheet page", "error", err)
+		}
+		return
+	}
+
+	if r.URL.Query().Get("download") == "true" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", vm.Timesheet.Filename(models.TimesheetFormatHtml)))
+	}
+	if err := templates[conf.TimesheetInvoiceTemplate].Execute(w, vm); err != nil {
+		conf.Log().Request(r).Error("failed to get timesheet invoice", "error", err)
+	}
+}
+
+func (h *TimesheetHandler) buildViewModel(r *http.Request, w http.ResponseWriter) *view.TimesheetViewModel {
+	user := middlewares.GetPrincipal(r)
+	if user == nil { // this should actually never occur, because of auth middleware
+		w.WriteHeader(http.StatusUnauthorized)
+		return h.buildViewModel(r, w).WithError("unauthorized")
+	}
+
+	query := r.URL.Query()
+	query.Del("download")
+
+	vm := &view.TimesheetViewModel{
+		SharedLoggedInViewModel: view.SharedLoggedInViewModel{
+			SharedViewModel: view.NewSharedViewModel(h.config, nil),
+			User:            user,
+			ApiKey:          user.ApiKey,
+		},
+		Labels: []string{},
+		Query:  query,
+	}
+
+	labels, err := h.projectLabelService.GetByUserGroupedInverted(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching project labels", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return vm.WithError(criticalError)
+	}
+	for l := range labels {
+		vm.Labels = append(vm.Labels, l)
+	}
+	sort.Strings(vm.Labels)
+
+	params, err := helpers.ParseTimesheetParams(r)
+	if err != nil {
+		err, from, to := helpers.ResolveIntervalTZ(models.IntervalLastMonth, user.TZ())
+		if err != nil {
+			conf.Log().Request(r).Error("failed to resolve default timesheet interval", "error", err)
+		}
+		vm.Params = &models.TimesheetParams{From: from, To: to, GroupBy: models.TimesheetGroupDay, RoundingMode: models.TimesheetRoundingUp}
+		w.WriteHeader(http.StatusBadRequest)
+		return vm.WithError("invalid timesheet parameters")
+	}
+	vm.Params = params
+
+	timesheet, err := h.timesheetService.Generate(user, params)
+	if err != nil {
+		conf.Log().Request(r).Error("error while generating timesheet", "userID", user.ID, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return vm.WithError(criticalError)
+	}
+	vm.Timesheet = timesheet
+
+	return routeutils.WithSessionMessages(vm, r, w)
+}
@@ -2,9 +2,17 @@ package utils
 
 import (
 	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/models"
-	"regexp"
+	v1 "github.com/muety/wakapi/models/compat/shields/v1"
+	"github.com/muety/wakapi/services"
 )
 
 const (
@@ -23,13 +31,6 @@ func init() {
 }
 
 func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User) (*models.KeyedInterval, *models.Filters, error) {
-	isSameUser := authorizedUser != nil && authorizedUser.ID == requestedUser.ID
-
-	var filterEntity, filterKey string
-	if groups := entityFilterReg.FindStringSubmatch(reqPath); len(groups) > 2 {
-		filterEntity, filterKey = groups[1], groups[2]
-	}
-
 	var intervalKey = models.IntervalPast30Days
 	if groups := intervalReg.FindStringSubmatch(reqPath); len(groups) > 1 {
 		if i, err := helpers.ParseInterval(groups[1]); err == nil {
@@ -43,12 +44,30 @@ func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User)
 		Key:      intervalKey,
 	}
 
-	minStart := rangeTo.AddDate(0, 0, -requestedUser.ShareDataMaxDays)
+	maxDays := GetBadgeMaxDays(authorizedUser, requestedUser)
+	minStart := rangeTo.AddDate(0, 0, -maxDays)
 	// negative value means no limit
-	if rangeFrom.Before(minStart) && requestedUser.ShareDataMaxDays >= 0 && !isSameUser {
+	if rangeFrom.Before(minStart) && maxDays >= 0 {
 		return nil, nil, errors.New("requested time range too broad")
 	}
 
+	filters, err := GetBadgeFilters(reqPath, authorizedUser, requestedUser)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return interval, filters, nil
+}
+
+// GetBadgeFilters parses the entity filter (e.g. "project:wakapi") from a badge request path, given the requested user shares data about that type of entity
+func GetBadgeFilters(reqPath string, authorizedUser, requestedUser *models.User) (*models.Filters, error) {
+	isSameUser := authorizedUser != nil && authorizedUser.ID == requestedUser.ID
+
+	var filterEntity, filterKey string
+	if groups := entityFilterReg.FindStringSubmatch(reqPath); len(groups) > 2 {
+		filterEntity, filterKey = groups[1], groups[2]
+	}
+
 	var permitEntity bool
 	var filters *models.Filters
 	switch filterEntity {
@@ -78,8 +97,151 @@ func GetBadgeParams(reqPath string, authorizedUser, requestedUser *models.User)
 	}
 
 	if !permitEntity && !isSameUser {
-		return nil, nil, errors.New("user did not opt in to share entity-specific data")
+		return nil, errors.New("user did not opt in to share entity-specific data")
 	}
 
-	return interval, filters, nil
+	return filters, nil
+}
+
+// GetBadgeMaxDays returns how many days back the authorized (or anonymous) user may look into the requested user's data, where a negative value means no limit
+func GetBadgeMaxDays(authorizedUser, requestedUser *models.User) int {
+	if authorizedUser != nil && authorizedUser.ID == requestedUser.ID {
+		return -1
+	}
+	return requestedUser.ShareDataMaxDays
+}
+
+// CheckBadgeEntityPermitted tells whether a badge may show a breakdown of the requested user's coding time by the given entity type (e.g. languages)
+func CheckBadgeEntityPermitted(entityType uint8, authorizedUser, requestedUser *models.User) error {
+	if authorizedUser != nil && authorizedUser.ID == requestedUser.ID {
+		return nil
+	}
+	if !requestedUser.SharesSummaryType(entityType) {
+		return errors.New("user did not opt in to share entity-specific data")
+	}
+	return nil
+}
+
+// GetBadgeStyle returns the requested badge style, if valid, or an empty string otherwise
+func GetBadgeStyle(r *http.Request) string {
+	if style := r.URL.Query().Get("style"); style == v1.BadgeStyleFlat || style == v1.BadgeStyleForTheBadge {
+		return style
+	}
+	return ""
+}
+
+// CheckGoalBadgePermitted tells whether the goal's progress may be shown to the authorized (or anonymous) user, which requires the goal's owner to share all entities the goal is restricted to
+func CheckGoalBadgePermitted(goal *models.Goal, authorizedUser, requestedUser *models.User) error {
+	if authorizedUser != nil && authorizedUser.ID == requestedUser.ID {
+		return nil
+	}
+	if (len(goal.ProjectsList()) > 0 && !requestedUser.ShareProjects) ||
+		(len(goal.LanguagesList()) > 0 && !requestedUser.ShareLanguages) ||
+		(len(goal.LabelsList()) > 0 && !requestedUser.ShareLabels) {
+		return errors.New("user did not opt in to share entity-specific data")
+	}
+	return nil
+}
+
+// LoadLanguagesBadgeData computes the top languages badge for the interval and filters given in the request path
+func LoadLanguagesBadgeData(ss services.ISummaryService, reqPath string, authorizedUser, requestedUser *models.User) (*v1.BadgeData, error, int) {
+	interval, filters, err := GetBadgeParams(reqPath, authorizedUser, requestedUser)
+	if err != nil {
+		return nil, err, http.StatusForbidden
+	}
+	if err := CheckBadgeEntityPermitted(models.SummaryLanguage, authorizedUser, requestedUser); err != nil {
+		return nil, err, http.StatusForbidden
+	}
+
+	summary, err, status := LoadUserSummaryByParams(ss, &models.SummaryParams{
+		From:    interval.Start,
+		To:      interval.End,
+		User:    requestedUser,
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, err, status
+	}
+
+	return v1.NewLanguagesBadgeDataFrom(summary, config.Get().App.GetLanguageColors()), nil, http.StatusOK
+}
+
+// LoadSparklineBadgeData computes the daily coding time badge for the interval (last 30 days by default) and filters given in the request path
+func LoadSparklineBadgeData(bs services.IBadgeService, reqPath string, authorizedUser, requestedUser *models.User) (*v1.BadgeData, error, int) {
+	interval, filters, err := GetBadgeParams(reqPath, authorizedUser, requestedUser)
+	if err != nil {
+		return nil, err, http.StatusForbidden
+	}
+
+	totals, err := bs.GetDailyTotals(requestedUser, interval.Start, interval.End, filters)
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+
+	return v1.NewSparklineBadgeDataFrom(totals), nil, http.StatusOK
+}
+
+// LoadStreakBadgeData computes the current streak badge for the filters given in the request path, looking back only as far as the requested user shares data
+func LoadStreakBadgeData(bs services.IBadgeService, reqPath string, authorizedUser, requestedUser *models.User) (*v1.BadgeData, error, int) {
+	filters, err := GetBadgeFilters(reqPath, authorizedUser, requestedUser)
+	if err != nil {
+		return nil, err, http.StatusForbidden
+	}
+
+	streak, capped, err := bs.GetStreak(requestedUser, filters, GetBadgeMaxDays(authorizedUser, requestedUser))
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+
+	return v1.NewStreakBadgeDataFrom(streak, capped), nil, http.StatusOK
+}
+
+// LoadRankBadgeData looks up the requested user's rank on the public leaderboard, either in total or for the language given in the request path
+func LoadRankBadgeData(ls services.ILeaderboardService, reqPath string, authorizedUser, requestedUser *models.User) (*v1.BadgeData, error, int) {
+	if ls == nil {
+		return nil, errors.New("leaderboard is disabled"), http.StatusNotFound
+	}
+
+	filters, err := GetBadgeFilters(reqPath, authorizedUser, requestedUser)
+	if err != nil {
+		return nil, err, http.StatusForbidden
+	}
+
+	var by *uint8
+	var language string
+	if ok, entity, keys := filters.One(); ok {
+		if entity != models.SummaryLanguage {
+			return nil, errors.New("rank is only available in total or by language"), http.StatusBadRequest
+		}
+		by, language = &entity, keys[0]
+	}
+
+	items, err := ls.GetAggregatedByIntervalAndUser(ls.GetDefaultScope(), requestedUser.ID, by, false)
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+
+	item, _ := slice.FindBy(items, func(i int, item *models.LeaderboardItemRanked) bool {
+		return by == nil || (item.Key != nil && strings.EqualFold(*item.Key, language))
+	})
+	return v1.NewRankBadgeDataFrom(item), nil, http.StatusOK
+}
+
+// LoadGoalBadgeData computes the badge showing the progress towards one of the requested user's goals within the current period
+func LoadGoalBadgeData(gs services.IGoalService, goalId uint, authorizedUser, requestedUser *models.User) (*v1.BadgeData, error, int) {
+	goal, err := gs.GetById(goalId)
+	if err != nil || goal.UserID != requestedUser.ID {
+		return nil, errors.New("goal not found"), http.StatusNotFound
+	}
+
+	if err := CheckGoalBadgePermitted(goal, authorizedUser, requestedUser); err != nil {
+		return nil, err, http.StatusForbidden
+	}
+
+	progress, err := gs.GetProgress(goal, requestedUser, time.Now())
+	if err != nil {
+		return nil, err, http.StatusInternalServerError
+	}
+
+	return v1.NewGoalBadgeDataFrom(progress), nil, http.StatusOK
 }
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+// WriteAccountArchive streams an export of all the user's data as a file download in the given format (zip or ndjson)
+func WriteAccountArchive(w http.ResponseWriter, r *http.Request, exportService services.IExportService, user *models.User, format string) {
+	contentType := "application/zip"
+	switch format {
+	case models.AccountArchiveFormatZip:
+	case models.AccountArchiveFormatNdjson:
+		contentType = "application/x-ndjson"
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("unsupported format"))
+		return
+	}
+
+	filename := fmt.Sprintf("wakapi_%s_%s.%s", user.ID, time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.WriteHeader(http.StatusOK)
+
+	// headers are already sent at this point, so errors can only be logged
+	if err := exportService.Export(user, format, w); err != nil {
+		conf.Log().Request(r).Error("failed to export user data", "userID", user.ID, "error", err)
+	}
+}
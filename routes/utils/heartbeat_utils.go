@@ -9,17 +9,23 @@ import (
 )
 
 func ParseHeartbeats(r *http.Request) ([]*models.Heartbeat, error) {
+	heartbeats, _, err := ParseHeartbeatsBulk(r)
+	return heartbeats, err
+}
+
+// ParseHeartbeatsBulk parses either a single heartbeat or an array of heartbeats from the request body and tells which of both it was
+func ParseHeartbeatsBulk(r *http.Request) ([]*models.Heartbeat, bool, error) {
 	heartbeats, err := tryParseBulk(r)
 	if err == nil {
-		return heartbeats, err
+		return heartbeats, true, err
 	}
 
 	heartbeats, err = tryParseSingle(r)
 	if err == nil {
-		return heartbeats, err
+		return heartbeats, false, err
 	}
 
-	return []*models.Heartbeat{}, err
+	return []*models.Heartbeat{}, false, err
 }
 
 func tryParseBulk(r *http.Request) ([]*models.Heartbeat, error) {
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// WriteTimesheetCsv sends the timesheet as a csv file download
+func WriteTimesheetCsv(w http.ResponseWriter, r *http.Request, timesheet *models.Timesheet) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", timesheet.Filename(models.TimesheetFormatCsv)))
+	w.WriteHeader(http.StatusOK)
+
+	// headers are already sent at this point, so errors can only be logged
+	if err := timesheet.WriteCsv(w); err != nil {
+		conf.Log().Request(r).Error("failed to write timesheet", "error", err)
+	}
+}
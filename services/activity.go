@@ -5,6 +5,14 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+	"time"
+
 	svg "github.com/ajstarks/svgo/float"
 	"github.com/alitto/pond/v2"
 	"github.com/duke-git/lancet/v2/condition"
@@ -14,9 +22,9 @@ import (
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/utils"
 	"github.com/patrickmn/go-cache"
-	"math"
-	"sync"
-	"time"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 )
 
 const (
@@ -24,6 +32,9 @@ const (
 	cellWidth     = 20
 	cellHeight    = 20
 	cellSpacing   = 3
+	captionHeight = 25
+	labelHeight   = 15 // height of the hour labels above the heatmap
+	labelWidth    = 35 // width of the weekday labels left to the heatmap
 	colorMinDark  = "#242B3A"
 	colorMinLight = "#DCE3E1"
 	colorMaxDark  = "#047857"
@@ -33,47 +44,127 @@ const (
 )
 
 type ActivityService struct {
-	config         *config.Config
-	cache          *cache.Cache
-	summaryService ISummaryService
+	config          *config.Config
+	cache           *cache.Cache
+	summaryService  ISummaryService
+	durationService IDurationService
+}
+
+// activityChart is the format-independent layout of an activity chart
+type activityChart struct {
+	width     float64
+	height    float64
+	caption   string
+	textColor color.RGBA
+	cells     []*activityChartCell
+	labels    []*activityChartLabel
+}
+
+type activityChartCell struct {
+	x     float64
+	y     float64
+	fill  color.RGBA
+	title string
+}
+
+type activityChartLabel struct {
+	x    float64
+	y    float64
+	text string
 }
 
-func NewActivityService(summaryService ISummaryService) *ActivityService {
+func NewActivityService(summaryService ISummaryService, durationService IDurationService) *ActivityService {
 	return &ActivityService{
-		config:         config.Get(),
-		cache:          cache.New(6*time.Hour, 6*time.Hour),
-		summaryService: summaryService,
+		config:          config.Get(),
+		cache:           cache.New(6*time.Hour, 6*time.Hour),
+		summaryService:  summaryService,
+		durationService: durationService,
 	}
 }
 
-// GetChart generates an activity chart for a given user and the given time interval, similar to GitHub's contribution timeline. See https://github.com/muety/wakapi/issues/12.
-// Please note: currently, only yearly charts ("last_12_months") are supported. However, we could fairly easily restructure this to support dynamic intervals.
-func (s *ActivityService) GetChart(user *models.User, interval *models.IntervalKey, darkTheme, hideAttribution, skipCache bool) (string, error) {
-	cacheKey := fmt.Sprintf("chart_%s_%s_%v_%v", user.ID, (*interval)[0], darkTheme, hideAttribution)
+// GetChart generates an activity chart for a given user and the given time range, similar to GitHub's contribution timeline, either as svg or png image. See https://github.com/muety/wakapi/issues/12.
+func (s *ActivityService) GetChart(user *models.User, params *models.ActivityChartParams, skipCache bool) ([]byte, error) {
+	if !params.IsValid() || params.Format == models.ActivityChartFormatJson {
+		return nil, errors.New("invalid chart parameters")
+	}
+
+	cacheKey := fmt.Sprintf("chart_%s_%s", user.ID, params.Hash())
 	if result, found := s.cache.Get(cacheKey); found && !skipCache {
-		return result.(string), nil
+		return result.([]byte), nil
 	}
 
-	switch interval {
-	case models.IntervalPast12Months:
-		chart, err := s.getChartPastYear(user, darkTheme, hideAttribution)
-		if err == nil {
-			s.cache.SetDefault(cacheKey, chart) // TODO: cache compressed?
-		}
-		return chart, err
-	default:
-		return "", errors.New("unsupported interval")
+	summaries, err := s.getDailySummaries(user, params)
+	if err != nil {
+		return nil, err
+	}
+
+	chart, err := s.render(s.layout(summaries, params), params)
+	if err == nil {
+		s.cache.SetDefault(cacheKey, chart) // TODO: cache compressed?
 	}
+	return chart, err
 }
 
-func (s *ActivityService) getChartPastYear(user *models.User, darkTheme, hideAttribution bool) (string, error) {
-	err, from, to := helpers.ResolveIntervalTZ(models.IntervalPast12Months, user.TZ())
-	from = datetime.BeginOfWeek(from, time.Monday)
+// GetHeatmap bins the user's coding activity within the given time range by day of week and hour of day (in the user's timezone)
+func (s *ActivityService) GetHeatmap(user *models.User, params *models.ActivityChartParams, skipCache bool) (*models.Heatmap, error) {
+	if !params.IsValid() {
+		return nil, errors.New("invalid heatmap parameters")
+	}
+
+	cacheKey := fmt.Sprintf("heatmap_%s_%s_%s_%s", user.ID, params.From.Format(time.DateOnly), params.To.Format(time.DateOnly), params.Filters.Hash())
+	if result, found := s.cache.Get(cacheKey); found && !skipCache {
+		return result.(*models.Heatmap), nil
+	}
+
+	filters := params.Filters
+	if filters != nil && filters.IsEmpty() {
+		filters = nil
+	}
+
+	durations, err := s.durationService.Get(params.From, params.To, user, s.summaryService.ResolveFilters(user, filters), nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := models.NewHeatmap(params.From, params.To, user.TZ())
+	for _, d := range durations {
+		heatmap.Add(d.Time.T(), d.Duration)
+	}
+
+	s.cache.SetDefault(cacheKey, heatmap)
+	return heatmap, nil
+}
+
+// GetHeatmapChart renders the user's hour of day / day of week heatmap as svg or png image
+func (s *ActivityService) GetHeatmapChart(user *models.User, params *models.ActivityChartParams, skipCache bool) ([]byte, error) {
+	if params.Format == models.ActivityChartFormatJson {
+		return nil, errors.New("invalid heatmap parameters")
+	}
+
+	heatmap, err := s.GetHeatmap(user, params, skipCache)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return s.render(s.layoutHeatmap(heatmap, params), params)
+}
+
+func (s *ActivityService) getDailySummaries(user *models.User, params *models.ActivityChartParams) ([]*models.Summary, error) {
+	from := datetime.BeginOfWeek(params.From.In(user.TZ()), time.Monday)
+	to := params.To.In(user.TZ())
+
+	filters := params.Filters
+	if filters != nil && filters.IsEmpty() {
+		filters = nil
+	}
+	if filters != nil {
+		filters = filters.WithSelectFilteredOnly() // only total time is relevant, allows for using pre-generated summaries if filtering by a single entity type
 	}
 
 	intervals := utils.SplitRangeByDays(from, to)
+	if len(intervals) == 0 {
+		return nil, errors.New("empty range")
+	}
 	summaries := make([]*models.Summary, len(intervals))
 
 	wp := pond.NewPool(utils.HalfCPUs())
@@ -85,12 +176,18 @@ func (s *ActivityService) getChartPastYear(user *models.User, darkTheme, hideAtt
 		interval := interval
 
 		wp.Submit(func() {
-			summary, err := s.summaryService.Retrieve(interval[0], interval[1], user, nil, nil)
+			var summary *models.Summary
+			var err error
+			if filters != nil {
+				summary, err = s.summaryService.Aliased(interval[0], interval[1], user, s.summaryService.Retrieve, filters, nil, false)
+			} else {
+				summary, err = s.summaryService.Retrieve(interval[0], interval[1], user, nil, nil)
+			}
 			if err != nil {
-				config.Log().Warn("failed to retrieve summary for activity chart", "userID", user.ID, "from", from, "to", to)
+				config.Log().Warn("failed to retrieve summary for activity chart", "userID", user.ID, "from", interval[0], "to", interval[1])
 				summary = models.NewEmptySummary()
-				summary.FromTime = models.CustomTime(from)
-				summary.ToTime = models.CustomTime(to)
+				summary.FromTime = models.CustomTime(interval[0])
+				summary.ToTime = models.CustomTime(interval[1])
 				summary.UserID = user.ID
 				summary.User = user
 			}
@@ -103,48 +200,173 @@ func (s *ActivityService) getChartPastYear(user *models.User, darkTheme, hideAtt
 
 	wp.StopAndWait()
 
+	return summaries, nil
+}
+
+func (s *ActivityService) layout(summaries []*models.Summary, params *models.ActivityChartParams) *activityChart {
+	colorScale := s.colorScale(params)
 	maxTotal := models.Summaries(summaries).MaxTotalTime()
+	gridCols := math.Ceil(float64(len(summaries)) / float64(gridRows))
 
-	var (
-		colorRGBAMin         = utils.HexToRGBA(condition.TernaryOperator[bool, string](darkTheme, colorMinDark, colorMinLight))
-		colorRGBAMax         = utils.HexToRGBA(condition.TernaryOperator[bool, string](darkTheme, colorMaxDark, colorMaxLight))
-		colorText            = condition.TernaryOperator[bool, string](darkTheme, textDark, textLight)
-		gridCols             = math.Ceil(float64(len(summaries)) / float64(gridRows))
-		w            float64 = gridCols*cellWidth + gridCols*cellSpacing
-		h            float64 = gridRows*cellHeight + 25 + 24 + 5 + 5 + gridRows*cellSpacing
-	)
+	chart := &activityChart{
+		width:     gridCols*cellWidth + gridCols*cellSpacing,
+		height:    gridRows*cellHeight + captionHeight + 24 + 5 + 5 + gridRows*cellSpacing,
+		caption:   fmt.Sprintf("%s to %s", helpers.FormatDateHuman(summaries[0].FromTime.T()), helpers.FormatDateHuman(summaries[len(summaries)-1].ToTime.T())),
+		textColor: utils.HexToRGBA(condition.TernaryOperator[bool, string](params.DarkTheme, textDark, textLight)),
+		cells:     make([]*activityChartCell, len(summaries)),
+	}
 
-	// regenerate svg
+	for i, s := range summaries {
+		total := s.TotalTime()
+		var ratio float64
+		if maxTotal > 0 {
+			ratio = float64(total) / float64(maxTotal)
+		}
+
+		chart.cells[i] = &activityChartCell{
+			x:     float64(i/gridRows) * (cellWidth + cellSpacing),
+			y:     captionHeight + float64((i%gridRows)*(cellHeight+cellSpacing)),
+			fill:  utils.FadeColorScale(colorScale, ratio),
+			title: fmt.Sprintf("%s on %s", helpers.FmtWakatimeDuration(total), helpers.FormatDateHuman(s.FromTime.T())),
+		}
+	}
+
+	return chart
+}
+
+func (s *ActivityService) layoutHeatmap(heatmap *models.Heatmap, params *models.ActivityChartParams) *activityChart {
+	colorScale := s.colorScale(params)
+	maxTotal := heatmap.Max()
+	gridTop := float64(captionHeight + labelHeight)
+
+	chart := &activityChart{
+		width:     labelWidth + 24*(cellWidth+cellSpacing),
+		height:    gridTop + gridRows*(cellHeight+cellSpacing) + 24 + 5 + 5,
+		caption:   fmt.Sprintf("%s to %s (%s)", helpers.FormatDateHuman(heatmap.From.T()), helpers.FormatDateHuman(heatmap.To.T()), heatmap.Timezone),
+		textColor: utils.HexToRGBA(condition.TernaryOperator[bool, string](params.DarkTheme, textDark, textLight)),
+		cells:     make([]*activityChartCell, 0, len(models.HeatmapWeekdays)*24),
+		labels:    make([]*activityChartLabel, 0, len(models.HeatmapWeekdays)+8),
+	}
+
+	for hour := 0; hour < 24; hour += 3 {
+		chart.labels = append(chart.labels, &activityChartLabel{x: labelWidth + float64(hour*(cellWidth+cellSpacing)), y: gridTop - 4, text: fmt.Sprintf("%02d", hour)})
+	}
+
+	for day, weekday := range models.HeatmapWeekdays {
+		y := gridTop + float64(day*(cellHeight+cellSpacing))
+		chart.labels = append(chart.labels, &activityChartLabel{x: 0, y: y + cellHeight - 5, text: weekday.String()[:3]})
+
+		for hour, total := range heatmap.Data[day] {
+			var ratio float64
+			if maxTotal > 0 {
+				ratio = total / maxTotal
+			}
+
+			chart.cells = append(chart.cells, &activityChartCell{
+				x:     labelWidth + float64(hour*(cellWidth+cellSpacing)),
+				y:     y,
+				fill:  utils.FadeColorScale(colorScale, ratio),
+				title: fmt.Sprintf("%s on %ss at %02d:00", helpers.FmtWakatimeDuration(time.Duration(total)*time.Second), weekday.String(), hour),
+			})
+		}
+	}
+
+	return chart
+}
+
+func (s *ActivityService) colorScale(params *models.ActivityChartParams) []color.RGBA {
+	if len(params.Colors) == 0 {
+		return []color.RGBA{
+			utils.HexToRGBA(condition.TernaryOperator[bool, string](params.DarkTheme, colorMinDark, colorMinLight)),
+			utils.HexToRGBA(condition.TernaryOperator[bool, string](params.DarkTheme, colorMaxDark, colorMaxLight)),
+		}
+	}
+	colorScale := make([]color.RGBA, 0, len(params.Colors))
+	for _, c := range params.Colors {
+		colorScale = append(colorScale, utils.HexToRGBA(c))
+	}
+	return colorScale
+}
+
+func (s *ActivityService) render(chart *activityChart, params *models.ActivityChartParams) ([]byte, error) {
+	switch params.Format {
+	case models.ActivityChartFormatPng:
+		return s.renderPng(chart, params)
+	default:
+		return s.renderSvg(chart, params)
+	}
+}
+
+func (s *ActivityService) renderSvg(chart *activityChart, params *models.ActivityChartParams) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
 	canvas := svg.New(buf)
-	canvas.Start(w, h)
+	canvas.Start(chart.width, chart.height)
 	canvas.Style("text/css",
-		fmt.Sprintf("text { font-family: 'Source Sans 3', Roboto, Helvetica, Arial, sans-serif; font-size: 0.9rem; font-weight: 500; fill: %s; }", colorText),
+		fmt.Sprintf("text { font-family: 'Source Sans 3', Roboto, Helvetica, Arial, sans-serif; font-size: 0.9rem; font-weight: 500; fill: %s; }", utils.RGBAToHex(chart.textColor)),
 		fmt.Sprintf("rect { fill-opacity: 1; rx: 3px; ry: 3px; }"),
 		fmt.Sprintf("rect:hover { filter: brightness(0.9) }"),
+		fmt.Sprintf("text.label { font-size: 0.7rem; }"),
 	)
 
-	canvas.Text(0, 15, fmt.Sprintf("%s to %s", helpers.FormatDateHuman(summaries[0].FromTime.T()), helpers.FormatDateHuman(summaries[len(summaries)-1].ToTime.T())))
+	canvas.Text(0, 15, chart.caption)
 
-	for i, s := range summaries {
-		total := s.TotalTime()
-		fillColor := utils.RGBAToHex(utils.FadeColors(colorRGBAMin, colorRGBAMax, float64(total)/float64(maxTotal)))
+	for _, label := range chart.labels {
+		canvas.Text(label.x, label.y, label.text, `class="label"`)
+	}
 
+	for _, cell := range chart.cells {
 		canvas.Group()
-		canvas.Title(fmt.Sprintf("%s on %s", helpers.FmtWakatimeDuration(total), helpers.FormatDateHuman(s.FromTime.T())))
-		canvas.Rect(float64(i/gridRows)*(cellWidth+cellSpacing), 25+float64((i%gridRows)*(cellHeight+cellSpacing)), cellWidth, cellHeight, fmt.Sprintf("fill: %s", fillColor))
+		canvas.Title(cell.title)
+		canvas.Rect(cell.x, cell.y, cellWidth, cellHeight, fmt.Sprintf("fill: %s", utils.RGBAToHex(cell.fill)))
 		canvas.Gend()
 	}
 
-	if !hideAttribution {
+	if !params.HideAttribution {
 		canvas.Group()
 		canvas.Title("Wakapi.dev")
-		canvas.Image(w-60, h-24, 60, 24, "https://wakapi.dev/assets/images/logo-gh.svg")
+		canvas.Image(chart.width-60, chart.height-24, 60, 24, "https://wakapi.dev/assets/images/logo-gh.svg")
 		canvas.Gend()
 	}
 
 	canvas.End()
 
-	return buf.String(), nil
+	return buf.Bytes(), nil
+}
+
+// renderPng draws the chart as a raster image (with transparent background) for places that can't embed svg. Unlike the svg version, it has no tooltips and uses a plain text attribution.
+func (s *ActivityService) renderPng(chart *activityChart, params *models.ActivityChartParams) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(chart.width)), int(math.Ceil(chart.height))))
+
+	for _, cell := range chart.cells {
+		rect := image.Rect(int(cell.x), int(cell.y), int(cell.x)+cellWidth, int(cell.y)+cellHeight)
+		draw.Draw(img, rect, &image.Uniform{C: cell.fill}, image.Point{}, draw.Src)
+	}
+
+	drawText := func(text string, x, y int) {
+		d := &font.Drawer{
+			Dst:  img,
+			Src:  &image.Uniform{C: chart.textColor},
+			Face: basicfont.Face7x13,
+			Dot:  fixed.P(x, y),
+		}
+		d.DrawString(text)
+	}
+
+	drawText(chart.caption, 0, 15)
+
+	for _, label := range chart.labels {
+		drawText(label.text, int(label.x), int(label.y))
+	}
+
+	if !params.HideAttribution {
+		attribution := "wakapi.dev"
+		drawText(attribution, img.Bounds().Dx()-len(attribution)*basicfont.Face7x13.Advance, img.Bounds().Dy()-8)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
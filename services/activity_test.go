@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ActivityServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	SummaryService  *mocks.SummaryServiceMock
+	DurationService *mocks.DurationServiceMock
+}
+
+func (suite *ActivityServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01", Location: "UTC"}
+}
+
+func (suite *ActivityServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.DurationService = new(mocks.DurationServiceMock)
+}
+
+func TestActivityServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ActivityServiceTestSuite))
+}
+
+func (suite *ActivityServiceTestSuite) TestActivityService_GetChart_Svg() {
+	sut := NewActivityService(suite.SummaryService, suite.DurationService)
+
+	suite.SummaryService.On("Retrieve", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(activityTestSummary(1*time.Hour), nil)
+
+	params := &models.ActivityChartParams{
+		From:   time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), // wednesday
+		To:     time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		Format: models.ActivityChartFormatSvg,
+	}
+
+	result, err := sut.GetChart(suite.TestUser, params, true)
+	assert.Nil(suite.T(), err)
+
+	chart := string(result)
+	assert.True(suite.T(), strings.HasPrefix(chart, "<?xml"))
+	assert.Equal(suite.T(), 14, strings.Count(chart, "<rect")) // padded to start of week
+	assert.Contains(suite.T(), chart, "fill: #047857")
+	assert.Contains(suite.T(), chart, "wakapi.dev")
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Retrieve", 14)
+	suite.SummaryService.AssertNotCalled(suite.T(), "Aliased", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ActivityServiceTestSuite) TestActivityService_GetChart_PngWithFilters() {
+	sut := NewActivityService(suite.SummaryService, suite.DurationService)
+
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything, mock.Anything, false).Return(activityTestSummary(30*time.Minute), nil)
+
+	params := &models.ActivityChartParams{
+		From:            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:              time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Format:          models.ActivityChartFormatPng,
+		Filters:         models.NewFiltersWith(models.SummaryProject, "wakapi"),
+		HideAttribution: true,
+		Colors:          []string{"#ffffff", "#ff0000", "#0000ff"},
+	}
+
+	result, err := sut.GetChart(suite.TestUser, params, true)
+	assert.Nil(suite.T(), err)
+
+	img, err := png.Decode(bytes.NewReader(result))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 5*(cellWidth+cellSpacing), img.Bounds().Dx())
+	assert.Equal(suite.T(), color.RGBA{R: 0, G: 0, B: 255, A: 255}, color.RGBAModel.Convert(img.At(cellWidth/2, captionHeight+cellHeight/2)))
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 31)
+
+	filters := suite.SummaryService.Calls[0].Arguments.Get(4).(*models.Filters)
+	assert.Equal(suite.T(), []string{"wakapi"}, []string(filters.Project))
+	assert.True(suite.T(), filters.SelectFilteredOnly)
+}
+
+func (suite *ActivityServiceTestSuite) TestActivityService_GetChart_Invalid() {
+	sut := NewActivityService(suite.SummaryService, suite.DurationService)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invalid := []*models.ActivityChartParams{
+		{From: from, To: from.AddDate(0, 1, 0), Format: "gif"},
+		{From: from, To: from, Format: models.ActivityChartFormatSvg},
+		{From: from, To: from.AddDate(3, 0, 0), Format: models.ActivityChartFormatSvg},
+		{From: from, To: from.AddDate(0, 1, 0), Format: models.ActivityChartFormatSvg, Colors: []string{"#ffffff"}},
+		{From: from, To: from.AddDate(0, 1, 0), Format: models.ActivityChartFormatSvg, Colors: []string{"#ffffff", "red"}},
+	}
+
+	for _, params := range invalid {
+		_, err := sut.GetChart(suite.TestUser, params, true)
+		assert.Error(suite.T(), err)
+	}
+	suite.SummaryService.AssertNotCalled(suite.T(), "Retrieve", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ActivityServiceTestSuite) TestActivityService_GetHeatmap() {
+	sut := NewActivityService(suite.SummaryService, suite.DurationService)
+
+	tz, _ := time.LoadLocation("Asia/Kolkata") // utc+5:30
+	user := &models.User{ID: "testuser02", Location: tz.String()}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, tz) // monday
+	to := time.Date(2024, 1, 8, 0, 0, 0, 0, tz)
+	filters := models.NewFiltersWith(models.SummaryLabel, "work")
+	resolved := models.NewFiltersWith(models.SummaryLabel, "work").With(models.SummaryProject, "wakapi")
+
+	durations := models.Durations{
+		{Time: models.CustomTime(from.Add(-30 * time.Minute)), Duration: 1 * time.Hour},                              // sunday before, partially outside range
+		{Time: models.CustomTime(from.Add(9*time.Hour + 45*time.Minute)), Duration: 30 * time.Minute},                // monday, 09:45 - 10:15
+		{Time: models.CustomTime(from.AddDate(0, 0, 6).Add(23*time.Hour + 30*time.Minute)), Duration: 1 * time.Hour}, // sunday, partially outside range
+	}
+
+	suite.SummaryService.On("ResolveFilters", user, filters).Return(resolved)
+	suite.DurationService.On("Get", from, to, user, resolved, mock.Anything, false).Return(durations, nil)
+
+	params := &models.ActivityChartParams{From: from, To: to, Filters: filters, Format: models.ActivityChartFormatJson}
+
+	heatmap, err := sut.GetHeatmap(user, params, true)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Asia/Kolkata", heatmap.Timezone)
+	assert.Equal(suite.T(), float64(30*60), heatmap.Data[0][0])
+	assert.Equal(suite.T(), float64(15*60), heatmap.Data[0][9])
+	assert.Equal(suite.T(), float64(15*60), heatmap.Data[0][10])
+	assert.Equal(suite.T(), float64(30*60), heatmap.Data[6][23])
+	assert.Equal(suite.T(), 90*time.Minute, heatmap.Total())
+
+	params.Format = models.ActivityChartFormatSvg
+	chart, err := sut.GetHeatmapChart(user, params, false)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 7*24, strings.Count(string(chart), "<rect"))
+	assert.Contains(suite.T(), string(chart), ">Mon</text>")
+	assert.Contains(suite.T(), string(chart), "30 mins on Sundays at 23:00")
+	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 1) // cached
+}
+
+func activityTestSummary(total time.Duration) *models.Summary {
+	summary := models.NewEmptySummary()
+	summary.Projects = []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: total / time.Second}}
+	return summary
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"errors"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/config"
 	"log/slog"
@@ -20,6 +22,7 @@ var aggregationLock = sync.Mutex{}
 
 type AggregationService struct {
 	config           *config.Config
+	eventBus         *hub.Hub
 	userService      IUserService
 	summaryService   ISummaryService
 	heartbeatService IHeartbeatService
@@ -32,6 +35,7 @@ type AggregationService struct {
 func NewAggregationService(userService IUserService, summaryService ISummaryService, heartbeatService IHeartbeatService, durationService IDurationService) *AggregationService {
 	return &AggregationService{
 		config:           config.Get(),
+		eventBus:         config.EventBus(),
 		userService:      userService,
 		summaryService:   summaryService,
 		heartbeatService: heartbeatService,
@@ -43,9 +47,10 @@ func NewAggregationService(userService IUserService, summaryService ISummaryServ
 }
 
 type AggregationJob struct {
-	User *models.User
-	From time.Time
-	To   time.Time
+	User   *models.User
+	From   time.Time
+	To     time.Time
+	Notify bool // whether to publish the created summary, only done for the nightly aggregation, but not for regenerating summaries
 }
 
 // Schedule a job to (re-)generate summaries every day shortly after midnight
@@ -53,7 +58,7 @@ func (srv *AggregationService) Schedule() {
 	slog.Info("scheduling summary aggregation")
 
 	if _, err := srv.queueDefault.DispatchCron(func() {
-		if err := srv.AggregateSummaries(datastructure.New[string]()); err != nil {
+		if err := srv.aggregateSummaries(datastructure.New[string](), true); err != nil {
 			config.Log().Error("failed to regenerate summaries", "error", err)
 		}
 	}, srv.config.App.GetAggregationTimeCron()); err != nil {
@@ -61,7 +66,12 @@ func (srv *AggregationService) Schedule() {
 	}
 }
 
+// AggregateSummaries generates all of the given users' (or all users', if none given) missing daily summaries in the background
 func (srv *AggregationService) AggregateSummaries(userIds datastructure.Set[string]) error {
+	return srv.aggregateSummaries(userIds, false)
+}
+
+func (srv *AggregationService) aggregateSummaries(userIds datastructure.Set[string], notify bool) error {
 	if err := srv.lockUsers(userIds); err != nil {
 		return err
 	}
@@ -121,12 +131,12 @@ func (srv *AggregationService) AggregateSummaries(userIds datastructure.Set[stri
 				// Case 1: User has aggregated summaries already
 				// -> Spawn jobs to create summaries from their latest aggregation to now
 				slog.Info("generating summary aggregation jobs for user", "user", u.ID, "from", e.Time.T())
-				jobs = append(jobs, generateUserJobs(&u, e.Time.T())...)
+				jobs = append(jobs, generateUserJobs(&u, e.Time.T(), notify)...)
 			} else if t := firstUserHeartbeatLookup[e.User]; t.Valid() {
 				// Case 2: User has no aggregated summaries, yet, but has heartbeats
 				// -> Spawn jobs to create summaries from their first heartbeat to now
 				slog.Info("generating summary aggregation jobs for user", "user", u.ID, "from", t.T())
-				jobs = append(jobs, generateUserJobs(&u, t.T())...)
+				jobs = append(jobs, generateUserJobs(&u, t.T(), notify)...)
 			} else {
 				// Case 3: User doesn't have heartbeats at all
 				// -> Nothing to do
@@ -177,6 +187,43 @@ func (srv *AggregationService) AggregateDurations(userIds datastructure.Set[stri
 	return nil
 }
 
+// RegenerateSummariesWithin synchronously replaces a user's daily summaries between the beginning of from's day and the end of to's day (but no later than yesterday) by newly computed ones.
+// Unlike AggregateSummaries, it only returns once all summaries were generated. Returns the number of summaries created.
+func (srv *AggregationService) RegenerateSummariesWithin(user *models.User, from, to time.Time) (int, error) {
+	userIds := datastructure.New(user.ID)
+	if err := srv.lockUsers(userIds); err != nil {
+		return 0, err
+	}
+	defer srv.unlockUsers(userIds)
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	to = time.Date(to.Year(), to.Month(), to.Day()+aggregateIntervalDays, 0, 0, 0, 0, to.Location())
+	if today := datetime.BeginOfDay(time.Now()); to.After(today) {
+		to = today
+	}
+	if !from.Before(to) {
+		return 0, nil
+	}
+
+	if err := srv.summaryService.DeleteByUserWithin(user.ID, from, to); err != nil {
+		return 0, err
+	}
+
+	var count int
+	for t := from; t.Before(to); t = t.AddDate(0, 0, aggregateIntervalDays) {
+		summary, err := srv.summaryService.Summarize(t, t.AddDate(0, 0, aggregateIntervalDays), user, nil, nil)
+		if err != nil {
+			return count, err
+		}
+		if err := srv.summaryService.Insert(summary); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
 func (srv *AggregationService) process(job AggregationJob) {
 	// process single summary interval for single user
 	slog.Info("regenerating actual user summaries as part of summary aggregation", "user", job.User.ID, "from", job.From, "to", job.To)
@@ -186,11 +233,20 @@ func (srv *AggregationService) process(job AggregationJob) {
 		slog.Info("successfully generated summary", "from", job.From, "to", job.To, "userID", job.User.ID)
 		if err := srv.summaryService.Insert(summary); err != nil {
 			config.Log().Error("failed to save summary", "userID", summary.UserID, "fromTime", summary.FromTime, "toTime", summary.ToTime, "error", err)
+		} else if job.Notify {
+			srv.notifyCreate(summary)
 		}
 	}
 }
 
-func generateUserJobs(user *models.User, from time.Time) (jobs []*AggregationJob) {
+func (srv *AggregationService) notifyCreate(summary *models.Summary) {
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventSummaryCreate,
+		Fields: map[string]interface{}{config.FieldPayload: summary, config.FieldUserId: summary.UserID},
+	})
+}
+
+func generateUserJobs(user *models.User, from time.Time, notify bool) (jobs []*AggregationJob) {
 	var to time.Time
 
 	// Go to next day of either user's first heartbeat or latest aggregation
@@ -213,7 +269,7 @@ func generateUserJobs(user *models.User, from time.Time) (jobs []*AggregationJob
 			0, 0, 0, 0,
 			from.Location(),
 		)
-		jobs = append(jobs, &AggregationJob{user, from, to})
+		jobs = append(jobs, &AggregationJob{user, from, to, notify})
 		from = to
 	}
 
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AggregationServiceTestSuite struct {
+	suite.Suite
+	TestUser         *models.User
+	UserService      *mocks.UserServiceMock
+	SummaryService   *mocks.SummaryServiceMock
+	HeartbeatService *mocks.HeartbeatServiceMock
+	DurationService  *mocks.DurationServiceMock
+}
+
+func (suite *AggregationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.DurationService = new(mocks.DurationServiceMock)
+}
+
+func TestAggregationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AggregationServiceTestSuite))
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_RegenerateSummariesWithin() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, suite.DurationService)
+
+	today := datetime.BeginOfDay(time.Now())
+	from := today.AddDate(0, 0, -3).Add(5 * time.Hour)
+	to := time.Now().Add(24 * time.Hour) // capped to the end of yesterday
+
+	suite.SummaryService.On("DeleteByUserWithin", TestUserId, today.AddDate(0, 0, -3), today).Return(nil)
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(&models.Summary{UserID: TestUserId}, nil)
+	suite.SummaryService.On("Insert", mock.Anything).Return(nil)
+
+	count, err := sut.RegenerateSummariesWithin(suite.TestUser, from, to)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3, count)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Summarize", 3)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Insert", 3)
+	suite.SummaryService.AssertCalled(suite.T(), "Summarize", today.AddDate(0, 0, -3), today.AddDate(0, 0, -2), suite.TestUser, mock.Anything, mock.Anything)
+	suite.SummaryService.AssertCalled(suite.T(), "Summarize", today.AddDate(0, 0, -1), today, suite.TestUser, mock.Anything, mock.Anything)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_RegenerateSummariesWithin_Empty() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, suite.DurationService)
+
+	count, err := sut.RegenerateSummariesWithin(suite.TestUser, time.Now(), time.Now())
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), count)
+	suite.SummaryService.AssertNotCalled(suite.T(), "DeleteByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Process_NotifiesOnlyIfRequested() {
+	sut := NewAggregationService(suite.UserService, suite.SummaryService, suite.HeartbeatService, suite.DurationService)
+
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(&models.Summary{UserID: TestUserId}, nil)
+	suite.SummaryService.On("Insert", mock.Anything).Return(nil)
+
+	sub := config.EventBus().Subscribe(1, config.EventSummaryCreate)
+	defer config.EventBus().Unsubscribe(sub)
+
+	from := datetime.BeginOfDay(time.Now()).AddDate(0, 0, -1)
+
+	sut.process(AggregationJob{User: suite.TestUser, From: from, To: from.AddDate(0, 0, 1)}) // e.g. regeneration
+	select {
+	case <-sub.Receiver:
+		suite.T().Fatal("unexpected summary event")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	sut.process(AggregationJob{User: suite.TestUser, From: from, To: from.AddDate(0, 0, 1), Notify: true}) // nightly aggregation
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(suite.T(), TestUserId, m.Fields[config.FieldUserId])
+	case <-time.After(1 * time.Second):
+		suite.T().Fatal("missing summary event")
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// last used timestamps are only persisted at this granularity to avoid a database write upon every single request
+const apiTokenLastUsedResolution = 5 * time.Minute
+
+type ApiTokenService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.IApiTokenRepository
+}
+
+func NewApiTokenService(apiTokenRepository repositories.IApiTokenRepository) *ApiTokenService {
+	return &ApiTokenService{
+		config:     config.Get(),
+		cache:      cache.New(1*time.Hour, 2*time.Hour),
+		repository: apiTokenRepository,
+	}
+}
+
+func (srv *ApiTokenService) GetByUser(userId string) ([]*models.ApiToken, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// GetByToken resolves a plain token to its (non-expired) api token object and updates its last used timestamp
+func (srv *ApiTokenService) GetByToken(token string) (*models.ApiToken, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, errors.New("token must not be empty")
+	}
+
+	tokenHash := models.HashApiToken(token)
+
+	var apiToken *models.ApiToken
+	if t, ok := srv.cache.Get(tokenHash); ok {
+		apiToken = t.(*models.ApiToken)
+	} else {
+		t, err := srv.repository.GetByHash(tokenHash)
+		if err != nil {
+			return nil, err
+		}
+		apiToken = t
+		srv.cache.SetDefault(tokenHash, apiToken)
+	}
+
+	if apiToken.IsExpired() {
+		return nil, errors.New("token expired")
+	}
+
+	if now := time.Now(); apiToken.LastUsedAt == nil || now.Sub(apiToken.LastUsedAt.T()) > apiTokenLastUsedResolution {
+		if err := srv.repository.UpdateLastUsed(apiToken, now); err != nil {
+			config.Log().Error("failed to update api token last used timestamp", "tokenID", apiToken.ID, "error", err)
+		} else {
+			lastUsed := models.CustomTime(now)
+			apiToken.LastUsedAt = &lastUsed
+		}
+	}
+
+	return apiToken, nil
+}
+
+// Create generates a new api token for the given user and returns it along with the plain token, which is not retrievable later on
+func (srv *ApiTokenService) Create(user *models.User, name string, scopes []string, expiresAt *time.Time) (*models.ApiToken, string, error) {
+	plainToken := uuid.Must(uuid.NewV4()).String() // uuid format, because wakatime clients expect that
+
+	apiToken := &models.ApiToken{
+		UserID:    user.ID,
+		Name:      strings.TrimSpace(name),
+		TokenHash: models.HashApiToken(plainToken),
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if expiresAt != nil {
+		t := models.CustomTime(*expiresAt)
+		apiToken.ExpiresAt = &t
+	}
+
+	apiToken, err := srv.repository.Insert(apiToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return apiToken, plainToken, nil
+}
+
+func (srv *ApiTokenService) Delete(apiToken *models.ApiToken) error {
+	if err := srv.repository.Delete(apiToken.ID); err != nil {
+		return err
+	}
+	srv.cache.Delete(apiToken.TokenHash)
+	return nil
+}
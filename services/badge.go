@@ -0,0 +1,288 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/alitto/pond/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/shields/v1"
+	"github.com/muety/wakapi/utils"
+	"github.com/narqo/go-badge"
+	"github.com/narqo/go-badge/fonts"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+const (
+	badgeMaxDays        = 366
+	badgeStreakMaxDays  = 365
+	badgeStreakChunk    = 30
+	badgeBarWidth       = 100
+	badgeSparklineWidth = 80
+	badgeLabelColor     = "#555"
+	badgeChartColor     = "#444"
+	badgeDefaultColor   = "#2F855A"
+)
+
+// badgeTheme describes the looks of a badge style, modeled after the respective shields.io styles
+type badgeTheme struct {
+	height        float64
+	fontSize      float64
+	padding       float64
+	letterSpacing float64
+	radius        float64
+	uppercase     bool
+	bold          bool
+	gradient      bool
+}
+
+var badgeThemes = map[string]*badgeTheme{
+	v1.BadgeStyleFlat:        {height: 20, fontSize: 11, padding: 6, radius: 3, gradient: true},
+	v1.BadgeStyleForTheBadge: {height: 28, fontSize: 10, padding: 12, letterSpacing: 1.25, uppercase: true, bold: true},
+}
+
+type BadgeService struct {
+	config         *config.Config
+	summaryService ISummaryService
+	fontFaces      map[float64]font.Face
+	fontLock       sync.Mutex
+}
+
+func NewBadgeService(summaryService ISummaryService) *BadgeService {
+	srv := &BadgeService{
+		config:         config.Get(),
+		summaryService: summaryService,
+		fontFaces:      map[float64]font.Face{},
+	}
+
+	ttf, err := opentype.Parse(fonts.VeraSans)
+	if err != nil {
+		config.Log().Fatal("failed to parse badge font", "error", err)
+	}
+	for _, theme := range badgeThemes {
+		face, err := opentype.NewFace(ttf, &opentype.FaceOptions{Size: theme.fontSize, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			config.Log().Fatal("failed to load badge font", "error", err)
+		}
+		srv.fontFaces[theme.fontSize] = face
+	}
+
+	return srv
+}
+
+// GetDailyTotals returns the user's total coding time for every day within the given range, optionally restricted to the given filters
+func (srv *BadgeService) GetDailyTotals(user *models.User, from, to time.Time, filters *models.Filters) ([]time.Duration, error) {
+	intervals := utils.SplitRangeByDays(from.In(user.TZ()), to.In(user.TZ()))
+	if len(intervals) == 0 {
+		return nil, errors.New("empty range")
+	}
+	if len(intervals) > badgeMaxDays {
+		return nil, errors.New("requested time range too broad")
+	}
+
+	if filters != nil && filters.IsEmpty() {
+		filters = nil
+	}
+	if filters != nil {
+		filters = filters.WithSelectFilteredOnly() // only total time is relevant, allows for using pre-generated summaries
+	}
+
+	totals := make([]time.Duration, len(intervals))
+	errs := make([]error, len(intervals))
+
+	wp := pond.NewPool(utils.HalfCPUs())
+	for i, interval := range intervals {
+		wp.Submit(func() {
+			var summary *models.Summary
+			var err error
+			if filters != nil {
+				summary, err = srv.summaryService.Aliased(interval[0], interval[1], user, srv.summaryService.Retrieve, filters, nil, false)
+			} else {
+				summary, err = srv.summaryService.Retrieve(interval[0], interval[1], user, nil, nil)
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			totals[i] = summary.TotalTime()
+		})
+	}
+	wp.StopAndWait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// GetStreak counts the consecutive days with coding activity up until today, looking back at most maxDays days (-1 for no limit other than one year).
+// Today doesn't break the streak if there hasn't been any activity yet. The second return value tells whether the streak reaches back until the limit.
+func (srv *BadgeService) GetStreak(user *models.User, filters *models.Filters, maxDays int) (int, bool, error) {
+	if maxDays < 0 || maxDays > badgeStreakMaxDays {
+		maxDays = badgeStreakMaxDays
+	}
+
+	today := utils.BeginOfToday(user.TZ())
+
+	var streak, checked int // checked is the number of days looked at so far, going back from today
+	for checked <= maxDays {
+		n := min(badgeStreakChunk, maxDays+1-checked)
+		from, to := today.AddDate(0, 0, 1-checked-n), today.AddDate(0, 0, 1-checked)
+
+		totals, err := srv.GetDailyTotals(user, from, to, filters)
+		if err != nil {
+			return 0, false, err
+		}
+
+		for i := len(totals) - 1; i >= 0; i-- {
+			if totals[i] > 0 {
+				streak++
+			} else if day := checked + len(totals) - 1 - i; day > 0 {
+				return streak, false, nil
+			}
+		}
+		checked += n
+	}
+
+	return streak, true, nil
+}
+
+// Render draws the given badge as svg in its style, including charts, if any
+func (srv *BadgeService) Render(data *v1.BadgeData) ([]byte, error) {
+	theme, ok := badgeThemes[data.Style]
+	if !ok {
+		theme = badgeThemes[v1.BadgeStyleFlat]
+	}
+
+	if theme == badgeThemes[v1.BadgeStyleFlat] && data.Segments == nil && data.Series == nil {
+		// plain badges look the same as they always did
+		return badge.RenderBytes(data.Label, data.Message, badge.Color(resolveBadgeColor(data.Color)))
+	}
+
+	label, message, caption := data.Label, data.Message, data.Caption
+	if theme.uppercase {
+		label, message, caption = strings.ToUpper(label), strings.ToUpper(message), strings.ToUpper(caption)
+	}
+
+	labelWidth := srv.measureText(label, theme) + 2*theme.padding
+	valueWidth := srv.measureText(message, theme) + 2*theme.padding
+	chartWidth := 0.0
+	if data.Segments != nil {
+		chartWidth = badgeBarWidth
+	} else if data.Series != nil {
+		chartWidth = badgeSparklineWidth
+	}
+	if chartWidth > 0 {
+		valueWidth = chartWidth + 2*theme.padding
+		if caption != "" {
+			valueWidth += srv.measureText(caption, theme) + theme.padding
+		}
+	}
+	width, height := labelWidth+valueWidth, theme.height
+
+	valueColor := resolveBadgeColor(data.Color)
+	if data.Segments != nil {
+		valueColor = badgeChartColor
+	}
+
+	buf := &bytes.Buffer{}
+	canvas := svg.New(buf)
+	canvas.Start(width, height)
+	canvas.Title(strings.TrimSpace(fmt.Sprintf("%s: %s", data.Label, data.Message)))
+
+	canvas.Def()
+	if theme.gradient {
+		canvas.LinearGradient("smooth", 0, 0, 0, 100, []svg.Offcolor{{Offset: 0, Color: "#bbb", Opacity: .1}, {Offset: 100, Color: "#000", Opacity: .1}})
+	}
+	canvas.ClipPath(`id="round"`)
+	canvas.Roundrect(0, 0, width, height, theme.radius, theme.radius, `fill="#fff"`)
+	canvas.ClipEnd()
+	canvas.DefEnd()
+
+	canvas.Group(`clip-path="url(#round)"`)
+	canvas.Rect(0, 0, labelWidth, height, fmt.Sprintf(`fill="%s"`, badgeLabelColor))
+	canvas.Rect(labelWidth, 0, valueWidth, height, fmt.Sprintf(`fill="%s"`, valueColor))
+	if theme.gradient {
+		canvas.Rect(0, 0, width, height, `fill="url(#smooth)"`)
+	}
+	canvas.Gend()
+
+	textStyle := fmt.Sprintf(`font-family="DejaVu Sans,Verdana,Geneva,sans-serif" font-size="%.0f" letter-spacing="%.2f"`, theme.fontSize, theme.letterSpacing)
+	if theme.bold {
+		textStyle += ` font-weight="bold"`
+	}
+	canvas.Group(`fill="#fff"`, `text-anchor="middle"`, textStyle)
+	srv.renderText(canvas, labelWidth/2, label, theme)
+	if chartWidth == 0 {
+		srv.renderText(canvas, labelWidth+valueWidth/2, message, theme)
+	} else if caption != "" {
+		captionStart := labelWidth + 2*theme.padding + chartWidth
+		srv.renderText(canvas, captionStart+(labelWidth+valueWidth-theme.padding-captionStart)/2, caption, theme)
+	}
+	canvas.Gend()
+
+	if data.Segments != nil {
+		x, barHeight := labelWidth+theme.padding, height/2
+		for _, segment := range data.Segments {
+			segmentWidth := segment.Share * badgeBarWidth
+			canvas.Group()
+			canvas.Title(segment.Title)
+			canvas.Rect(x, (height-barHeight)/2, segmentWidth, barHeight, fmt.Sprintf(`fill="%s"`, resolveBadgeColor(segment.Color)))
+			canvas.Gend()
+			x += segmentWidth
+		}
+	}
+
+	if len(data.Series) > 0 {
+		xs, ys := make([]float64, len(data.Series)), make([]float64, len(data.Series))
+		step := badgeSparklineWidth / float64(max(len(data.Series)-1, 1))
+		for i, v := range data.Series {
+			xs[i] = labelWidth + theme.padding + float64(i)*step
+			ys[i] = height - height/4 - v*height/2
+		}
+		canvas.Polyline(xs, ys, `fill="none"`, `stroke="#fff"`, `stroke-width="1.5"`, `stroke-linejoin="round"`)
+	}
+
+	canvas.End()
+	return buf.Bytes(), nil
+}
+
+func (srv *BadgeService) renderText(canvas *svg.SVG, x float64, text string, theme *badgeTheme) {
+	y := theme.height/2 + theme.fontSize/2 - 1.5
+	if theme.gradient {
+		canvas.Text(x, y+1, text, `fill="#010101"`, `fill-opacity=".3"`)
+	}
+	canvas.Text(x, y, text)
+}
+
+func (srv *BadgeService) measureText(text string, theme *badgeTheme) float64 {
+	srv.fontLock.Lock()
+	defer srv.fontLock.Unlock()
+
+	width := float64(font.MeasureString(srv.fontFaces[theme.fontSize], text).Ceil())
+	if theme.bold {
+		width *= 1.1
+	}
+	return width + theme.letterSpacing*float64(len([]rune(text)))
+}
+
+// resolveBadgeColor translates named colors to hex codes and falls back to the default color for anything that's not a valid color
+func resolveBadgeColor(c string) string {
+	if named, ok := badge.ColorScheme[c]; ok {
+		return named
+	}
+	if !strings.HasPrefix(c, "#") {
+		c = "#" + c
+	}
+	if !utils.IsHexColor(c) {
+		return badgeDefaultColor
+	}
+	return c
+}
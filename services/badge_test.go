@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/shields/v1"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type BadgeServiceTestSuite struct {
+	suite.Suite
+	TestUser       *models.User
+	SummaryService *mocks.SummaryServiceMock
+}
+
+func (suite *BadgeServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01", Location: "Europe/Berlin"}
+}
+
+func (suite *BadgeServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+}
+
+func TestBadgeServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(BadgeServiceTestSuite))
+}
+
+func (suite *BadgeServiceTestSuite) TestBadgeService_GetStreak() {
+	sut := NewBadgeService(suite.SummaryService)
+
+	today := utils.BeginOfToday(suite.TestUser.TZ())
+	gap := today.AddDate(0, 0, -40) // no activity on that day, but every other
+
+	isInactive := func(t time.Time) bool {
+		return t.Equal(today) || t.Equal(gap) // no activity today, yet
+	}
+	active := &models.Summary{Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: 3600}}}
+
+	suite.SummaryService.On("Retrieve", mock.MatchedBy(isInactive), mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(models.NewEmptySummary(), nil)
+	suite.SummaryService.On("Retrieve", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything).Return(active, nil)
+
+	streak, capped, err := sut.GetStreak(suite.TestUser, nil, -1)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 39, streak)
+	assert.False(suite.T(), capped)
+
+	streak, capped, err = sut.GetStreak(suite.TestUser, nil, 10)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 10, streak)
+	assert.True(suite.T(), capped)
+}
+
+func (suite *BadgeServiceTestSuite) TestBadgeService_Render() {
+	sut := NewBadgeService(suite.SummaryService)
+
+	data := v1.NewLanguagesBadgeDataFrom(&models.Summary{
+		Languages: []*models.SummaryItem{
+			{Type: models.SummaryLanguage, Key: "Go", Total: 2700},
+			{Type: models.SummaryLanguage, Key: "HTML", Total: 900},
+		},
+	}, map[string]string{"go": "#00ADD8"})
+	data.Style = v1.BadgeStyleForTheBadge
+
+	result, err := sut.Render(data)
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), string(result), "<title>Go 75%</title>")
+	assert.Contains(suite.T(), string(result), `fill="#00ADD8"`)
+	assert.Contains(suite.T(), string(result), "LANGUAGES")
+
+	data.Color = `red"/><script>alert(1)</script>`
+	data.Segments = nil
+	data.Style = ""
+	result, err = sut.Render(data)
+	assert.Nil(suite.T(), err)
+	assert.NotContains(suite.T(), string(result), "<script>")
+}
@@ -83,6 +83,7 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 		config.Log().Error("failed to get cached durations", "user", user.ID, "from", from, "to", to, "error", err)
 		cached = models.Durations{}
 	}
+	durations = cached
 
 	// fill missing
 	// for simplicity, we assume no missing durations before 'from' or between 'from' and 'to'
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+type ExportService struct {
+	config                 *config.Config
+	userService            IUserService
+	heartbeatService       IHeartbeatService
+	aliasService           IAliasService
+	projectLabelService    IProjectLabelService
+	languageMappingService ILanguageMappingService
+}
+
+func NewExportService(userService IUserService, heartbeatService IHeartbeatService, aliasService IAliasService, projectLabelService IProjectLabelService, languageMappingService ILanguageMappingService) *ExportService {
+	return &ExportService{
+		config:                 config.Get(),
+		userService:            userService,
+		heartbeatService:       heartbeatService,
+		aliasService:           aliasService,
+		projectLabelService:    projectLabelService,
+		languageMappingService: languageMappingService,
+	}
+}
+
+// Export writes an archive of all the user's data in the given format (zip or ndjson), streaming heartbeats as they are read from the database
+func (srv *ExportService) Export(user *models.User, format string, w io.Writer) error {
+	archive, err := srv.collect(user)
+	if err != nil {
+		return err
+	}
+
+	heartbeats, err := srv.heartbeatService.StreamAllWithin(time.Unix(0, 0), time.Now().AddDate(0, 0, 1), user)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for range heartbeats { // drain in case of early return to not block the producer
+		}
+	}()
+
+	switch format {
+	case models.AccountArchiveFormatZip:
+		return srv.writeZip(archive, heartbeats, w)
+	case models.AccountArchiveFormatNdjson:
+		return srv.writeNdjson(archive, heartbeats, w)
+	default:
+		return fmt.Errorf("unsupported archive format '%s'", format)
+	}
+}
+
+// Restore applies an archive's preferences, aliases, project labels and language mappings to the given user.
+// Entries that already exist are skipped. Heartbeats are restored separately through the regular import mechanism.
+func (srv *ExportService) Restore(user *models.User, archive *models.AccountArchive) error {
+	if archive.Manifest == nil || archive.Manifest.Version > models.AccountArchiveVersion {
+		return errors.New("unsupported archive version")
+	}
+
+	if archive.User != nil {
+		if _, err := srv.userService.Update(archive.User.ApplyTo(user)); err != nil {
+			return err
+		}
+	}
+
+	existingAliases, err := srv.aliasService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, a := range archive.Aliases {
+		if slice.ContainBy[*models.Alias](existingAliases, func(e *models.Alias) bool {
+			return e.Type == a.Type && e.Key == a.Key && e.Value == a.Value
+		}) {
+			continue
+		}
+		alias := &models.Alias{UserID: user.ID, Type: a.Type, Key: a.Key, Value: a.Value}
+		if !alias.IsValid() {
+			continue
+		}
+		if _, err := srv.aliasService.Create(alias); err != nil {
+			return err
+		}
+	}
+
+	existingLabels, err := srv.projectLabelService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, l := range archive.ProjectLabels {
+		if slice.ContainBy[*models.ProjectLabel](existingLabels, func(e *models.ProjectLabel) bool {
+			return e.ProjectKey == l.ProjectKey && e.Label == l.Label
+		}) {
+			continue
+		}
+		label := &models.ProjectLabel{UserID: user.ID, ProjectKey: l.ProjectKey, Label: l.Label}
+		if !label.IsValid() {
+			continue
+		}
+		if _, err := srv.projectLabelService.Create(label); err != nil {
+			return err
+		}
+	}
+
+	existingMappings, err := srv.languageMappingService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, m := range archive.LanguageMappings {
+		if slice.ContainBy[*models.LanguageMapping](existingMappings, func(e *models.LanguageMapping) bool {
+			return e.Extension == m.Extension
+		}) {
+			continue
+		}
+		mapping := &models.LanguageMapping{UserID: user.ID, Extension: m.Extension, Language: m.Language}
+		if !mapping.IsValid() {
+			continue
+		}
+		if _, err := srv.languageMappingService.Create(mapping); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("restored account data from archive", "userID", user.ID, "archiveUserID", archive.Manifest.UserID, "aliases", len(archive.Aliases), "labels", len(archive.ProjectLabels), "mappings", len(archive.LanguageMappings))
+	return nil
+}
+
+func (srv *ExportService) collect(user *models.User) (*models.AccountArchive, error) {
+	aliases, err := srv.aliasService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := srv.projectLabelService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := srv.languageMappingService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AccountArchive{
+		Manifest: &models.AccountArchiveManifest{
+			Version:    models.AccountArchiveVersion,
+			UserID:     user.ID,
+			ExportedAt: time.Now(),
+			AppVersion: srv.config.Version,
+		},
+		User:             models.NewAccountArchiveUser(user),
+		Aliases:          slice.Map[*models.Alias, *models.ArchivedAlias](aliases, func(i int, a *models.Alias) *models.ArchivedAlias { return models.NewArchivedAlias(a) }),
+		ProjectLabels:    labels,
+		LanguageMappings: mappings,
+	}, nil
+}
+
+func (srv *ExportService) writeZip(archive *models.AccountArchive, heartbeats <-chan *models.Heartbeat, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{models.AccountArchiveFileManifest, archive.Manifest},
+		{models.AccountArchiveFileUser, archive.User},
+		{models.AccountArchiveFileAliases, archive.Aliases},
+		{models.AccountArchiveFileProjectLabels, archive.ProjectLabels},
+		{models.AccountArchiveFileLanguageMappings, archive.LanguageMappings},
+	}
+
+	create := func(name string) (io.Writer, error) {
+		return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: archive.Manifest.ExportedAt})
+	}
+
+	for _, f := range files {
+		fw, err := create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(fw).Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	fw, err := create(models.AccountArchiveFileHeartbeats)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(fw)
+	for hb := range heartbeats {
+		if err := encoder.Encode(models.NewArchivedHeartbeat(hb)); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (srv *ExportService) writeNdjson(archive *models.AccountArchive, heartbeats <-chan *models.Heartbeat, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	write := func(recordType string, data interface{}) error {
+		return encoder.Encode(&models.AccountArchiveRecord{Type: recordType, Data: data})
+	}
+
+	if err := write(models.AccountArchiveRecordManifest, archive.Manifest); err != nil {
+		return err
+	}
+	if err := write(models.AccountArchiveRecordUser, archive.User); err != nil {
+		return err
+	}
+	for _, a := range archive.Aliases {
+		if err := write(models.AccountArchiveRecordAlias, a); err != nil {
+			return err
+		}
+	}
+	for _, l := range archive.ProjectLabels {
+		if err := write(models.AccountArchiveRecordProjectLabel, l); err != nil {
+			return err
+		}
+	}
+	for _, m := range archive.LanguageMappings {
+		if err := write(models.AccountArchiveRecordLanguageMapping, m); err != nil {
+			return err
+		}
+	}
+	for hb := range heartbeats {
+		if err := write(models.AccountArchiveRecordHeartbeat, models.NewArchivedHeartbeat(hb)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportServiceTestSuite struct {
+	suite.Suite
+	TestUser               *models.User
+	TestHeartbeats         []*models.Heartbeat
+	TestAliases            []*models.Alias
+	TestLabels             []*models.ProjectLabel
+	TestMappings           []*models.LanguageMapping
+	UserService            *mocks.UserServiceMock
+	HeartbeatService       *mocks.HeartbeatServiceMock
+	AliasService           *mocks.AliasServiceMock
+	ProjectLabelService    *mocks.ProjectLabelServiceMock
+	LanguageMappingService *mocks.LanguageMappingServiceMock
+}
+
+func (suite *ExportServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+
+	suite.TestUser = &models.User{ID: "testuser01", Location: "Europe/Berlin", ShareProjects: true, HeartbeatsTimeoutSec: 300}
+	suite.TestHeartbeats = []*models.Heartbeat{
+		(&models.Heartbeat{ID: 10, UserID: suite.TestUser.ID, Entity: "main.go", Project: "wakapi", Language: "Go", Time: models.CustomTime(time.UnixMilli(1700000000123)), Origin: "wakatime", OriginId: "abc"}).Hashed(),
+		(&models.Heartbeat{ID: 11, UserID: suite.TestUser.ID, Entity: "README.md", Project: "wakapi", Time: models.CustomTime(time.UnixMilli(1700000060456))}).Hashed(),
+	}
+	suite.TestAliases = []*models.Alias{{ID: 1, UserID: suite.TestUser.ID, Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-fork"}}
+	suite.TestLabels = []*models.ProjectLabel{{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi", Label: "oss"}}
+	suite.TestMappings = []*models.LanguageMapping{{ID: 1, UserID: suite.TestUser.ID, Extension: "tpl", Language: "HTML"}}
+}
+
+func (suite *ExportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.LanguageMappingService = new(mocks.LanguageMappingServiceMock)
+}
+
+func TestExportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportServiceTestSuite))
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_RoundTrip() {
+	sut := NewExportService(suite.UserService, suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService)
+
+	for _, format := range []string{models.AccountArchiveFormatZip, models.AccountArchiveFormatNdjson} {
+		suite.AliasService.On("GetByUser", suite.TestUser.ID).Return(suite.TestAliases, nil)
+		suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(suite.TestLabels, nil)
+		suite.LanguageMappingService.On("GetByUser", suite.TestUser.ID).Return(suite.TestMappings, nil)
+		suite.HeartbeatService.On("StreamAllWithin", mock.Anything, mock.Anything, suite.TestUser).Return(suite.streamHeartbeats(), nil).Once()
+
+		var buf bytes.Buffer
+		err := sut.Export(suite.TestUser, format, &buf)
+		assert.Nil(suite.T(), err)
+
+		importer, err := imports.NewWakapiArchiveImporter(buf.Bytes(), true)
+		assert.Nil(suite.T(), err)
+
+		archive := importer.Archive()
+		assert.Equal(suite.T(), suite.TestUser.ID, archive.Manifest.UserID)
+		assert.Equal(suite.T(), models.AccountArchiveVersion, archive.Manifest.Version)
+		assert.Equal(suite.T(), suite.TestUser.Location, archive.User.Location)
+		assert.True(suite.T(), archive.User.ShareProjects)
+		assert.Len(suite.T(), archive.Aliases, 1)
+		assert.Equal(suite.T(), "wakapi-fork", archive.Aliases[0].Value)
+		assert.Len(suite.T(), archive.ProjectLabels, 1)
+		assert.Len(suite.T(), archive.LanguageMappings, 1)
+
+		stream, err := importer.ImportAll(suite.TestUser)
+		assert.Nil(suite.T(), err)
+
+		restored := make([]*models.Heartbeat, 0)
+		for hb := range stream {
+			restored = append(restored, hb)
+		}
+		assert.Len(suite.T(), restored, 2)
+		for i, hb := range restored {
+			assert.Equal(suite.T(), suite.TestHeartbeats[i].ID, hb.ID)
+			assert.Equal(suite.T(), suite.TestHeartbeats[i].Hash, hb.Hash)
+			assert.Equal(suite.T(), suite.TestHeartbeats[i].Entity, hb.Entity)
+			assert.Equal(suite.T(), suite.TestHeartbeats[i].Origin, hb.Origin)
+			assert.True(suite.T(), suite.TestHeartbeats[i].Time.T().Equal(hb.Time.T()))
+		}
+	}
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Export_RestoreAsOtherUser() {
+	sut := NewExportService(suite.UserService, suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService)
+
+	suite.AliasService.On("GetByUser", suite.TestUser.ID).Return(suite.TestAliases, nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(suite.TestLabels, nil)
+	suite.LanguageMappingService.On("GetByUser", suite.TestUser.ID).Return(suite.TestMappings, nil)
+	suite.HeartbeatService.On("StreamAllWithin", mock.Anything, mock.Anything, suite.TestUser).Return(suite.streamHeartbeats(), nil)
+
+	var buf bytes.Buffer
+	assert.Nil(suite.T(), sut.Export(suite.TestUser, models.AccountArchiveFormatZip, &buf))
+
+	importer, err := imports.NewWakapiArchiveImporter(buf.Bytes(), false)
+	assert.Nil(suite.T(), err)
+
+	otherUser := &models.User{ID: "testuser02"}
+	stream, err := importer.ImportAll(otherUser)
+	assert.Nil(suite.T(), err)
+
+	i := 0
+	for hb := range stream {
+		expected := *suite.TestHeartbeats[i]
+		expected.UserID = otherUser.ID
+		expected.Hash = ""
+
+		assert.Equal(suite.T(), uint64(0), hb.ID)
+		assert.Equal(suite.T(), otherUser.ID, hb.UserID)
+		assert.Equal(suite.T(), expected.Hashed().Hash, hb.Hash)
+		assert.NotEqual(suite.T(), suite.TestHeartbeats[i].Hash, hb.Hash)
+		i++
+	}
+	assert.Equal(suite.T(), 2, i)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Restore_SkipsExisting() {
+	sut := NewExportService(suite.UserService, suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService)
+
+	user := &models.User{ID: "testuser02", Email: "existing@example.org"}
+	archive := &models.AccountArchive{
+		Manifest: &models.AccountArchiveManifest{Version: models.AccountArchiveVersion, UserID: suite.TestUser.ID},
+		User:     &models.AccountArchiveUser{Email: "other@example.org", Location: "Europe/Berlin", ShareLanguages: true},
+		Aliases: []*models.ArchivedAlias{
+			{Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-fork"},
+			{Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-fork-2"},
+		},
+		ProjectLabels:    []*models.ProjectLabel{{ID: 99, ProjectKey: "wakapi", Label: "oss"}},
+		LanguageMappings: []*models.LanguageMapping{{ID: 99, Extension: "tpl", Language: "HTML"}, {ID: 100, Extension: "vue", Language: "Vue"}},
+	}
+
+	suite.UserService.On("Update", user).Return(user, nil)
+	suite.AliasService.On("GetByUser", user.ID).Return([]*models.Alias{{Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-fork"}}, nil)
+	suite.AliasService.On("Create", mock.Anything).Return(&models.Alias{}, nil)
+	suite.ProjectLabelService.On("GetByUser", user.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+	suite.LanguageMappingService.On("GetByUser", user.ID).Return([]*models.LanguageMapping{{Extension: "tpl", Language: "Go Template"}}, nil)
+	suite.LanguageMappingService.On("Create", mock.Anything).Return(&models.LanguageMapping{}, nil)
+
+	err := sut.Restore(user, archive)
+	assert.Nil(suite.T(), err)
+
+	assert.Equal(suite.T(), "existing@example.org", user.Email)
+	assert.Equal(suite.T(), "Europe/Berlin", user.Location)
+	assert.True(suite.T(), user.ShareLanguages)
+
+	suite.AliasService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	assert.Equal(suite.T(), "wakapi-fork-2", suite.AliasService.Calls[1].Arguments.Get(0).(*models.Alias).Value)
+	assert.Equal(suite.T(), user.ID, suite.AliasService.Calls[1].Arguments.Get(0).(*models.Alias).UserID)
+
+	suite.ProjectLabelService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	assert.Equal(suite.T(), uint(0), suite.ProjectLabelService.Calls[1].Arguments.Get(0).(*models.ProjectLabel).ID)
+
+	suite.LanguageMappingService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	assert.Equal(suite.T(), "vue", suite.LanguageMappingService.Calls[1].Arguments.Get(0).(*models.LanguageMapping).Extension)
+}
+
+func (suite *ExportServiceTestSuite) TestExportService_Restore_UnsupportedVersion() {
+	sut := NewExportService(suite.UserService, suite.HeartbeatService, suite.AliasService, suite.ProjectLabelService, suite.LanguageMappingService)
+
+	err := sut.Restore(suite.TestUser, &models.AccountArchive{Manifest: &models.AccountArchiveManifest{Version: models.AccountArchiveVersion + 1}})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ExportServiceTestSuite) streamHeartbeats() chan *models.Heartbeat {
+	c := make(chan *models.Heartbeat)
+	go func() {
+		defer close(c)
+		for _, hb := range suite.TestHeartbeats {
+			c <- hb
+		}
+	}()
+	return c
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+// number of periods, including the current one, to report progress for by default
+const goalHistoryPeriods = 7
+
+type GoalService struct {
+	config         *config.Config
+	repository     repositories.IGoalRepository
+	summaryService ISummaryService
+	mailService    IMailService
+	queueDefault   *artifex.Dispatcher
+	queueWorkers   *artifex.Dispatcher
+}
+
+func NewGoalService(goalRepository repositories.IGoalRepository, summaryService ISummaryService, mailService IMailService) *GoalService {
+	return &GoalService{
+		config:         config.Get(),
+		repository:     goalRepository,
+		summaryService: summaryService,
+		mailService:    mailService,
+		queueDefault:   config.GetDefaultQueue(),
+		queueWorkers:   config.GetQueue(config.QueueReports),
+	}
+}
+
+func (srv *GoalService) Schedule() {
+	slog.Info("scheduling goal notifications")
+
+	if _, err := srv.queueDefault.DispatchCron(srv.runNotifications, srv.config.App.GetGoalNotificationCron()); err != nil {
+		config.Log().Error("failed to schedule goal notifications", "error", err)
+	}
+}
+
+func (srv *GoalService) GetById(id uint) (*models.Goal, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *GoalService) GetByUser(userId string) ([]*models.Goal, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *GoalService) Create(goal *models.Goal) (*models.Goal, error) {
+	return srv.repository.Insert(goal)
+}
+
+func (srv *GoalService) Delete(goal *models.Goal) error {
+	return srv.repository.Delete(goal.ID)
+}
+
+// GetProgress computes the coding time towards the goal within the period (in the user's time zone) that contains t
+func (srv *GoalService) GetProgress(goal *models.Goal, user *models.User, t time.Time) (*models.GoalProgress, error) {
+	from, to := goal.PeriodAt(t.In(user.TZ()))
+
+	// don't request summaries ending in the future, as these would be cached while still changing
+	end := to
+	if now := time.Now(); now.Before(end) {
+		end = now
+	}
+
+	summary, err := srv.summaryService.Aliased(from, end, user, srv.summaryService.Retrieve, goal.Filters(), nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.GoalProgress{Goal: goal, From: from, To: to, Actual: summary.TotalTime()}, nil
+}
+
+// GetProgressHistory computes the goal's progress for the current and the n-1 preceding periods, latest first
+func (srv *GoalService) GetProgressHistory(goal *models.Goal, user *models.User, n int) ([]*models.GoalProgress, error) {
+	if n <= 0 {
+		n = goalHistoryPeriods
+	}
+
+	now := time.Now().In(user.TZ())
+	history := make([]*models.GoalProgress, 0, n)
+
+	for i := 0; i < n; i++ {
+		t := now.AddDate(0, 0, -i)
+		if goal.Period == models.GoalPeriodWeek {
+			t = now.AddDate(0, 0, -7*i)
+		}
+		progress, err := srv.GetProgress(goal, user, t)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, progress)
+	}
+
+	return history, nil
+}
+
+func (srv *GoalService) runNotifications() {
+	goals, err := srv.repository.GetAllNotify()
+	if err != nil {
+		config.Log().Error("failed to get goals for notification", "error", err)
+		return
+	}
+
+	for _, goal := range goals {
+		user := goal.User
+		if user == nil || user.Email == "" {
+			continue
+		}
+
+		// notify about the period that has ended most recently, i.e. yesterday or last week, in the user's time zone
+		now := time.Now().In(user.TZ())
+		if goal.Period == models.GoalPeriodWeek && now.Weekday() != time.Monday {
+			continue
+		}
+		currentFrom, _ := goal.PeriodAt(now)
+		previous := currentFrom.Add(-time.Second)
+
+		if err := srv.queueWorkers.Dispatch(func() {
+			progress, err := srv.GetProgress(goal, user, previous)
+			if err != nil {
+				config.Log().Error("failed to compute goal progress", "goalID", goal.ID, "userID", user.ID, "error", err)
+				return
+			}
+			if err := srv.mailService.SendGoalNotification(user, progress); err != nil {
+				config.Log().Error("failed to send goal notification", "goalID", goal.ID, "userID", user.ID, "error", err)
+				return
+			}
+			slog.Info("sent goal notification", "goalID", goal.ID, "userID", user.ID, "reached", progress.Reached())
+		}); err != nil {
+			config.Log().Error("failed to dispatch goal notification job", "goalID", goal.ID, "userID", user.ID, "error", err)
+		}
+	}
+}
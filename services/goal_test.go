@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type GoalServiceTestSuite struct {
+	suite.Suite
+	TestUser       *models.User
+	GoalRepository *mocks.GoalRepositoryMock
+	SummaryService *mocks.SummaryServiceMock
+}
+
+func (suite *GoalServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01", Location: "Europe/Berlin"}
+}
+
+func (suite *GoalServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.GoalRepository = new(mocks.GoalRepositoryMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+}
+
+func TestGoalServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(GoalServiceTestSuite))
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_GetProgress() {
+	sut := NewGoalService(suite.GoalRepository, suite.SummaryService, nil)
+
+	goal := &models.Goal{ID: 1, UserID: suite.TestUser.ID, Title: "Go", Period: models.GoalPeriodDay, TargetSeconds: 3600, Languages: "Go"}
+	ts := time.Date(2024, 5, 16, 14, 30, 0, 0, suite.TestUser.TZ())
+	from, to := time.Date(2024, 5, 16, 0, 0, 0, 0, suite.TestUser.TZ()), time.Date(2024, 5, 17, 0, 0, 0, 0, suite.TestUser.TZ())
+
+	summary := &models.Summary{Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Go", Total: 45 * time.Minute / time.Second}}}
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, goal.Filters(), (*time.Duration)(nil), false).Return(summary, nil)
+
+	progress, err := sut.GetProgress(goal, suite.TestUser, ts)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), from, progress.From)
+	assert.Equal(suite.T(), to, progress.To)
+	assert.Equal(suite.T(), 45*time.Minute, progress.Actual)
+	assert.Equal(suite.T(), 75, progress.Percent())
+	assert.False(suite.T(), progress.Reached())
+	assert.Equal(suite.T(), models.GoalStatusFail, progress.Status(time.Now()))
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_GetProgress_CurrentPeriod() {
+	sut := NewGoalService(suite.GoalRepository, suite.SummaryService, nil)
+
+	goal := &models.Goal{ID: 1, UserID: suite.TestUser.ID, Title: "Weekly", Period: models.GoalPeriodWeek, TargetSeconds: 3600}
+	summary := &models.Summary{Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: 2 * time.Hour / time.Second}}}
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, (*models.Filters)(nil), (*time.Duration)(nil), false).Return(summary, nil)
+
+	progress, err := sut.GetProgress(goal, suite.TestUser, time.Now())
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), progress.Reached())
+	assert.Equal(suite.T(), 100, progress.Percent())
+	assert.True(suite.T(), progress.To.After(time.Now()))
+
+	// summaries must not be requested for the future, as these would get cached
+	end := suite.SummaryService.Calls[0].Arguments.Get(1).(time.Time)
+	assert.False(suite.T(), end.After(time.Now()))
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_GetProgressHistory() {
+	sut := NewGoalService(suite.GoalRepository, suite.SummaryService, nil)
+
+	goal := &models.Goal{ID: 1, UserID: suite.TestUser.ID, Title: "Weekly", Period: models.GoalPeriodWeek, TargetSeconds: 3600}
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything, mock.Anything, false).Return(&models.Summary{}, nil)
+
+	history, err := sut.GetProgressHistory(goal, suite.TestUser, 4)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), history, 4)
+	for i := 1; i < len(history); i++ {
+		assert.Equal(suite.T(), history[i].To, history[i-1].From)
+		assert.Equal(suite.T(), time.Monday, history[i].From.Weekday())
+	}
+
+	history, err = sut.GetProgressHistory(goal, suite.TestUser, 0)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), history, goalHistoryPeriods)
+}
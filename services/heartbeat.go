@@ -189,6 +189,10 @@ func (srv *HeartbeatService) GetLatestByFilters(user *models.User, filters *mode
 	return srv.repository.GetLatestByFilters(user, srv.filtersToColumnMap(filters))
 }
 
+func (srv *HeartbeatService) GetFirstByFilters(user *models.User, filters *models.Filters) (*models.Heartbeat, error) {
+	return srv.repository.GetFirstByFilters(user, srv.filtersToColumnMap(filters))
+}
+
 func (srv *HeartbeatService) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	return srv.repository.GetFirstByUsers()
 }
@@ -232,6 +236,10 @@ func (srv *HeartbeatService) DeleteByUserBefore(user *models.User, t time.Time)
 	return srv.repository.DeleteByUserBefore(user, t)
 }
 
+func (srv *HeartbeatService) SyncIdSequence() error {
+	return srv.repository.SyncIdSequence()
+}
+
 func (srv *HeartbeatService) GetUserProjectStats(user *models.User, from, to time.Time, pageParams *utils.PageParams, skipCache bool) ([]*models.ProjectStats, error) {
 	// for projects page, call this like: GetUserProjectStats(&models.User{ID: "n1try"}, time.Time{}, utils.BeginOfToday(time.Local), false)
 
@@ -266,6 +274,12 @@ func (srv *HeartbeatService) GetUserProjectStats(user *models.User, from, to tim
 	return results, err
 }
 
+// RenameProjects permanently assigns the user's heartbeats of the given projects to the target project. Summaries and durations are not updated.
+func (srv *HeartbeatService) RenameProjects(user *models.User, projects []string, target string) (int64, error) {
+	defer srv.invalidateUserProjectCaches(user.ID)
+	return srv.repository.RenameProjects(user, projects, target)
+}
+
 func (srv *HeartbeatService) augmented(heartbeats []*models.Heartbeat, userId string) ([]*models.Heartbeat, error) {
 	languageMapping, err := srv.languageMappingSrvc.ResolveByUser(userId)
 	if err != nil {
@@ -366,6 +380,16 @@ func (srv *HeartbeatService) populateUniqueUserProjects(userId string) {
 	}
 }
 
+func (srv *HeartbeatService) invalidateUserProjectCaches(userId string) {
+	for _, k := range maputil.Keys[string, cache.Item](srv.cache.Items()) {
+		if strings.HasPrefix(k, fmt.Sprintf("project_stats_%s_", userId)) {
+			srv.cache.Delete(k)
+		}
+	}
+	srv.cache.Delete(srv.getEntityUserCacheKey(models.SummaryProject, userId))
+	srv.cache.Delete(srv.getUserProjectsCacheKey(userId))
+}
+
 func (srv *HeartbeatService) checkInvalidateProjectStatsCache(newHeartbeat *models.Heartbeat) {
 	// checks the cache of unique projects and clears the user's project_stats_* cache items if the new heartbeat is for a new, unseen project
 	var invalidated bool
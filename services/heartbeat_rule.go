@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+type HeartbeatRuleService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.IHeartbeatRuleRepository
+}
+
+func NewHeartbeatRuleService(heartbeatRuleRepository repositories.IHeartbeatRuleRepository) *HeartbeatRuleService {
+	return &HeartbeatRuleService{
+		config:     config.Get(),
+		repository: heartbeatRuleRepository,
+		cache:      cache.New(24*time.Hour, 24*time.Hour),
+	}
+}
+
+func (srv *HeartbeatRuleService) GetById(id uint) (*models.HeartbeatRule, error) {
+	return srv.repository.GetById(id)
+}
+
+// GetByUser returns the user's rules in the order they're applied in, ready for being applied
+func (srv *HeartbeatRuleService) GetByUser(userId string) ([]*models.HeartbeatRule, error) {
+	if rules, found := srv.cache.Get(userId); found {
+		return rules.([]*models.HeartbeatRule), nil
+	}
+
+	rules, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if err := r.Compile(); err != nil {
+			config.Log().Warn("failed to compile heartbeat rule", "ruleID", r.ID, "userID", userId, "error", err)
+		}
+	}
+
+	srv.cache.Set(userId, rules, cache.DefaultExpiration)
+	return rules, nil
+}
+
+// Apply runs all of the user's rules on the given heartbeat in-place and returns whether it is to be dropped
+func (srv *HeartbeatRuleService) Apply(user *models.User, heartbeat *models.Heartbeat) (bool, error) {
+	rules, err := srv.GetByUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range rules {
+		if r.Apply(heartbeat) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (srv *HeartbeatRuleService) Create(rule *models.HeartbeatRule) (*models.HeartbeatRule, error) {
+	rule.Pattern = strings.TrimSpace(rule.Pattern)
+	rule.Value = strings.TrimSpace(rule.Value)
+
+	result, err := srv.repository.Insert(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.cache.Delete(result.UserID)
+	return result, nil
+}
+
+func (srv *HeartbeatRuleService) Delete(rule *models.HeartbeatRule) error {
+	if rule.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	err := srv.repository.Delete(rule.ID)
+	srv.cache.Delete(rule.UserID)
+	return err
+}
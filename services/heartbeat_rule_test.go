@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HeartbeatRuleServiceTestSuite struct {
+	suite.Suite
+	TestUser                *models.User
+	HeartbeatRuleRepository *mocks.HeartbeatRuleRepositoryMock
+}
+
+func (suite *HeartbeatRuleServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01"}
+}
+
+func (suite *HeartbeatRuleServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.HeartbeatRuleRepository = new(mocks.HeartbeatRuleRepositoryMock)
+}
+
+func TestHeartbeatRuleServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(HeartbeatRuleServiceTestSuite))
+}
+
+func (suite *HeartbeatRuleServiceTestSuite) TestHeartbeatRuleService_Apply() {
+	sut := NewHeartbeatRuleService(suite.HeartbeatRuleRepository)
+
+	rules := []*models.HeartbeatRule{
+		{ID: 1, UserID: suite.TestUser.ID, Field: models.HeartbeatRuleFieldEntity, MatchType: models.HeartbeatRuleMatchRegex, Pattern: `^/work/([^/]+)/`, Action: models.HeartbeatRuleActionSetProject, Value: "$1"},
+		{ID: 2, UserID: suite.TestUser.ID, Field: models.HeartbeatRuleFieldProject, MatchType: models.HeartbeatRuleMatchGlob, Pattern: "secret-*", Action: models.HeartbeatRuleActionDrop},
+		{ID: 3, UserID: suite.TestUser.ID, Field: models.HeartbeatRuleFieldEntity, MatchType: models.HeartbeatRuleMatchGlob, Pattern: "*", Action: models.HeartbeatRuleActionRedactEntity},
+	}
+	suite.HeartbeatRuleRepository.On("GetByUser", suite.TestUser.ID).Return(rules, nil)
+
+	hb1 := &models.Heartbeat{Entity: "/work/wakapi/main.go", Type: "file"}
+	drop, err := sut.Apply(suite.TestUser, hb1)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), drop)
+	assert.Equal(suite.T(), "wakapi", hb1.Project)
+	assert.NotContains(suite.T(), hb1.Entity, "wakapi") // later rules see the outcome of previous ones
+
+	hb2 := &models.Heartbeat{Entity: "/work/secret-project/main.go", Type: "file"}
+	drop, err = sut.Apply(suite.TestUser, hb2)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), drop)
+
+	suite.HeartbeatRuleRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 1)
+}
+
+func (suite *HeartbeatRuleServiceTestSuite) TestHeartbeatRuleService_Create_InvalidatesCache() {
+	sut := NewHeartbeatRuleService(suite.HeartbeatRuleRepository)
+
+	rule := &models.HeartbeatRule{UserID: suite.TestUser.ID, Field: models.HeartbeatRuleFieldMachine, MatchType: models.HeartbeatRuleMatchGlob, Pattern: " ci-* ", Action: models.HeartbeatRuleActionDrop}
+	suite.HeartbeatRuleRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.HeartbeatRule{}, nil)
+	suite.HeartbeatRuleRepository.On("Insert", rule).Return(rule, nil)
+
+	sut.GetByUser(suite.TestUser.ID)
+	result, err := sut.Create(rule)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "ci-*", result.Pattern)
+
+	sut.GetByUser(suite.TestUser.ID)
+	suite.HeartbeatRuleRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 2)
+}
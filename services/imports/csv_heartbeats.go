@@ -0,0 +1,241 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const (
+	CsvTimeFormatUnix      = "unix"
+	CsvTimeFormatUnixMilli = "unix_ms"
+	CsvTimeFormatRFC3339   = "rfc3339"
+)
+
+// heartbeat fields that can be populated from csv columns, named like their json counterparts
+var csvHeartbeatFields = []string{
+	"entity",
+	"type",
+	"category",
+	"project",
+	"branch",
+	"language",
+	"is_write",
+	"editor",
+	"operating_system",
+	"machine",
+	"user_agent",
+	"time",
+	"lines",
+}
+
+// CsvColumnMapping maps heartbeat fields (e.g. "entity", "time") to the names of the csv columns holding their values
+type CsvColumnMapping map[string]string
+
+// ParseCsvColumnMapping parses a mapping given as comma-separated field=column pairs, e.g. "time=timestamp,entity=file".
+// Fields not explicitly mapped are read from a column of the same name, if present.
+func ParseCsvColumnMapping(s string) (CsvColumnMapping, error) {
+	mapping := make(CsvColumnMapping)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid column mapping '%s'", pair)
+		}
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !slice.Contain(csvHeartbeatFields, field) {
+			return nil, fmt.Errorf("unknown heartbeat field '%s'", field)
+		}
+		mapping[field] = strings.TrimSpace(parts[1])
+	}
+	return mapping, nil
+}
+
+// CsvHeartbeatsImporter reads heartbeats from a csv file with a header row, one heartbeat per line.
+// Columns are mapped to heartbeat fields by a CsvColumnMapping and time values are parsed according to timeFormat,
+// which is either one of the predefined formats (unix, unix_ms, rfc3339) or a custom go time layout.
+type CsvHeartbeatsImporter struct {
+	reader     io.Reader
+	mapping    CsvColumnMapping
+	timeFormat string
+}
+
+func NewCsvHeartbeatsImporter(reader io.Reader, mapping CsvColumnMapping, timeFormat string) *CsvHeartbeatsImporter {
+	if mapping == nil {
+		mapping = make(CsvColumnMapping)
+	}
+	if timeFormat == "" {
+		timeFormat = CsvTimeFormatUnix
+	}
+	return &CsvHeartbeatsImporter{reader: reader, mapping: mapping, timeFormat: timeFormat}
+}
+
+func (c *CsvHeartbeatsImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	reader := csv.NewReader(c.reader)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns, err := c.resolveColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *models.Heartbeat)
+
+	go func() {
+		defer close(out)
+
+		var count, skipped int
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			count++
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					skipped++
+					continue
+				}
+				config.Log().Error("failed to read heartbeats csv file, aborting", "userID", user.ID, "line", count+1, "error", err)
+				return
+			}
+
+			hb, err := c.mapRecord(record, columns)
+			if err != nil {
+				skipped++
+				continue
+			}
+
+			result, err := prepareFileHeartbeat(hb, user, OriginCsvFile)
+			if err != nil {
+				skipped++
+				continue
+			}
+			if result.Time.T().Before(minFrom) || result.Time.T().After(maxTo) {
+				continue
+			}
+			out <- result
+		}
+
+		slog.Info("finished reading heartbeats from csv file", "userID", user.ID, "count", count, "skipped", skipped)
+	}()
+
+	return out, nil
+}
+
+func (c *CsvHeartbeatsImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return c.Import(user, time.Unix(0, 0), time.Now())
+}
+
+// resolveColumns returns the index of the csv column for every mapped heartbeat field
+func (c *CsvHeartbeatsImporter) resolveColumns(header []string) (map[string]int, error) {
+	indices := make(map[string]int, len(header))
+	for i, name := range header {
+		indices[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	columns := make(map[string]int)
+	for _, field := range csvHeartbeatFields {
+		column := field
+		if mapped, ok := c.mapping[field]; ok {
+			column = mapped
+		}
+		if i, ok := indices[strings.ToLower(column)]; ok {
+			columns[field] = i
+		} else if _, ok := c.mapping[field]; ok {
+			return nil, fmt.Errorf("column '%s' not found in csv header", column)
+		}
+	}
+
+	for _, required := range []string{"entity", "time"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required column for field '%s'", required)
+		}
+	}
+
+	return columns, nil
+}
+
+func (c *CsvHeartbeatsImporter) mapRecord(record []string, columns map[string]int) (*models.Heartbeat, error) {
+	value := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	t, err := c.parseTime(value("time"))
+	if err != nil {
+		return nil, err
+	}
+
+	hb := &models.Heartbeat{
+		Entity:          value("entity"),
+		Type:            value("type"),
+		Category:        value("category"),
+		Project:         value("project"),
+		Branch:          value("branch"),
+		Language:        value("language"),
+		Editor:          value("editor"),
+		OperatingSystem: value("operating_system"),
+		Machine:         value("machine"),
+		UserAgent:       value("user_agent"),
+		Time:            models.CustomTime(t),
+	}
+
+	if v := value("is_write"); v != "" {
+		if hb.IsWrite, err = strconv.ParseBool(v); err != nil {
+			return nil, err
+		}
+	}
+	if v := value("lines"); v != "" {
+		if hb.Lines, err = strconv.Atoi(v); err != nil {
+			return nil, err
+		}
+	}
+	return hb, nil
+}
+
+func (c *CsvHeartbeatsImporter) parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, errMissingTime
+	}
+
+	switch strings.ToLower(c.timeFormat) {
+	case CsvTimeFormatUnix:
+		ts, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(ts*1e9)), nil
+	case CsvTimeFormatUnixMilli:
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(ts), nil
+	case CsvTimeFormatRFC3339:
+		return time.Parse(time.RFC3339, v)
+	default:
+		return time.Parse(c.timeFormat, v)
+	}
+}
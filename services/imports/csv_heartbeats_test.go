@@ -0,0 +1,72 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCsvColumnMapping(t *testing.T) {
+	mapping, err := ParseCsvColumnMapping("time=timestamp, Entity=file ,")
+	assert.Nil(t, err)
+	assert.Equal(t, CsvColumnMapping{"time": "timestamp", "entity": "file"}, mapping)
+
+	_, err = ParseCsvColumnMapping("time")
+	assert.Error(t, err)
+
+	_, err = ParseCsvColumnMapping("foo=bar")
+	assert.Error(t, err)
+}
+
+func TestCsvHeartbeatsImporter_Import(t *testing.T) {
+	user := &models.User{ID: "user01"}
+	data := "file,Project,language,is_write,timestamp\n" +
+		"main.go,wakapi,Go,true,2023-11-14T22:13:20Z\n" +
+		"main.go,wakapi,Go,maybe,2023-11-14T22:13:21Z\n" +
+		"main.go,wakapi,Go,false,yesterday\n" +
+		"README.md,wakapi,Markdown,,2023-11-14T22:14:00Z\n"
+
+	mapping := CsvColumnMapping{"entity": "file", "time": "timestamp"}
+	stream, err := NewCsvHeartbeatsImporter(strings.NewReader(data), mapping, CsvTimeFormatRFC3339).ImportAll(user)
+	assert.Nil(t, err)
+
+	results := collect(stream)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "main.go", results[0].Entity)
+	assert.Equal(t, "wakapi", results[0].Project)
+	assert.Equal(t, "Go", results[0].Language)
+	assert.True(t, results[0].IsWrite)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), results[0].Time.T().UTC())
+	assert.Equal(t, OriginCsvFile, results[0].Origin)
+	assert.Equal(t, "README.md", results[1].Entity)
+	assert.False(t, results[1].IsWrite)
+}
+
+func TestCsvHeartbeatsImporter_Import_TimeFormats(t *testing.T) {
+	user := &models.User{ID: "user01"}
+
+	stream, err := NewCsvHeartbeatsImporter(strings.NewReader("entity,time\na.go,1700000000.5\n"), nil, "").ImportAll(user)
+	assert.Nil(t, err)
+	assert.Equal(t, time.UnixMilli(1700000000500), collect(stream)[0].Time.T())
+
+	stream, err = NewCsvHeartbeatsImporter(strings.NewReader("entity,time\na.go,1700000000250\n"), nil, CsvTimeFormatUnixMilli).ImportAll(user)
+	assert.Nil(t, err)
+	assert.Equal(t, time.UnixMilli(1700000000250), collect(stream)[0].Time.T())
+
+	stream, err = NewCsvHeartbeatsImporter(strings.NewReader("entity,time\na.go,14.11.2023 22:13\n"), nil, "02.01.2006 15:04").ImportAll(user)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 0, 0, time.UTC), collect(stream)[0].Time.T().UTC())
+}
+
+func TestCsvHeartbeatsImporter_Import_MissingColumns(t *testing.T) {
+	user := &models.User{ID: "user01"}
+
+	_, err := NewCsvHeartbeatsImporter(strings.NewReader("entity,project\na.go,wakapi\n"), nil, "").ImportAll(user)
+	assert.Error(t, err)
+
+	_, err = NewCsvHeartbeatsImporter(strings.NewReader("entity,time\na.go,1700000000\n"), CsvColumnMapping{"project": "repo"}, "").ImportAll(user)
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package imports
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	OriginJsonFile = "json_file"
+	OriginCsvFile  = "csv_file"
+)
+
+var errMissingEntity = errors.New("missing entity")
+var errMissingTime = errors.New("missing time")
+
+// prepareFileHeartbeat completes a heartbeat read from an uploaded file in the same way heartbeats are completed when sent through the api
+func prepareFileHeartbeat(hb *models.Heartbeat, user *models.User, origin string) (*models.Heartbeat, error) {
+	if strings.TrimSpace(hb.Entity) == "" {
+		return nil, errMissingEntity
+	}
+	if hb.Time.T().IsZero() || hb.Time.T().Unix() <= 0 {
+		return nil, errMissingTime
+	}
+
+	if hb.UserAgent != "" && (hb.Editor == "" || hb.OperatingSystem == "") {
+		if opSys, editor, err := utils.ParseUserAgent(hb.UserAgent); err == nil {
+			if hb.OperatingSystem == "" {
+				hb.OperatingSystem = opSys
+			}
+			if hb.Editor == "" {
+				hb.Editor = editor
+			}
+		}
+	}
+
+	hb.ClearPlaceholders()
+
+	hb.ID = 0
+	hb.User = user
+	hb.UserID = user.ID
+	hb.Origin = origin
+	return hb.Hashed(), nil
+}
@@ -0,0 +1,102 @@
+package imports
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// JsonHeartbeatsImporter reads heartbeats from either a json array or newline-delimited json (one object per line).
+// Objects are expected in the same format as accepted by the heartbeats api, i.e. with `time` given as a (fractional) unix timestamp in seconds.
+// Example: {"entity": "main.go", "type": "file", "category": "coding", "project": "wakapi", "language": "Go", "time": 1700000000.5}
+type JsonHeartbeatsImporter struct {
+	reader io.Reader
+}
+
+func NewJsonHeartbeatsImporter(reader io.Reader) *JsonHeartbeatsImporter {
+	return &JsonHeartbeatsImporter{reader: reader}
+}
+
+func (j *JsonHeartbeatsImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	reader := bufio.NewReader(j.reader)
+	isArray, err := startsWithArray(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(reader)
+	if isArray {
+		if _, err := decoder.Token(); err != nil { // consume opening bracket
+			return nil, err
+		}
+	}
+
+	out := make(chan *models.Heartbeat)
+
+	go func() {
+		defer close(out)
+
+		var count, skipped int
+		for decoder.More() {
+			count++
+
+			var hb models.Heartbeat
+			if err := decoder.Decode(&hb); err != nil {
+				// syntax errors leave the decoder in an unrecoverable state, whereas invalid values can simply be skipped
+				var syntaxErr *json.SyntaxError
+				if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+					config.Log().Error("failed to decode heartbeats file, aborting", "userID", user.ID, "item", count, "error", err)
+					return
+				}
+				skipped++
+				continue
+			}
+
+			result, err := prepareFileHeartbeat(&hb, user, OriginJsonFile)
+			if err != nil {
+				skipped++
+				continue
+			}
+			if result.Time.T().Before(minFrom) || result.Time.T().After(maxTo) {
+				continue
+			}
+			out <- result
+		}
+
+		slog.Info("finished reading heartbeats from json file", "userID", user.ID, "count", count, "skipped", skipped)
+	}()
+
+	return out, nil
+}
+
+func (j *JsonHeartbeatsImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return j.Import(user, time.Unix(0, 0), time.Now())
+}
+
+func startsWithArray(reader *bufio.Reader) (bool, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return false, errors.New("empty file")
+			}
+			return false, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.ReadByte()
+		case '[':
+			return true, nil
+		case '{':
+			return false, nil
+		default:
+			return false, errors.New("invalid json, expected array or object")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonHeartbeatsImporter_Import_Array(t *testing.T) {
+	user := &models.User{ID: "user01"}
+	data := `[
+		{"entity": "main.go", "type": "file", "project": "wakapi", "language": "Go", "time": 1700000000.5},
+		{"entity": "", "time": 1700000001},
+		{"entity": "README.md", "project": "wakapi", "time": "invalid"},
+		{"entity": "README.md", "project": "wakapi", "user_agent": "wakatime/v1.35.4 (linux-5.15.0) go1.20.3 vscode/1.78.2 vscode-wakatime/24.0.10", "time": 1700000002}
+	]`
+
+	stream, err := NewJsonHeartbeatsImporter(strings.NewReader(data)).ImportAll(user)
+	assert.Nil(t, err)
+
+	results := collect(stream)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "main.go", results[0].Entity)
+	assert.Equal(t, "wakapi", results[0].Project)
+	assert.Equal(t, user.ID, results[0].UserID)
+	assert.Equal(t, OriginJsonFile, results[0].Origin)
+	assert.Equal(t, time.UnixMilli(1700000000500), results[0].Time.T())
+	assert.NotEmpty(t, results[0].Hash)
+	assert.Equal(t, "vscode", results[1].Editor)
+	assert.Equal(t, "Linux", results[1].OperatingSystem)
+}
+
+func TestJsonHeartbeatsImporter_Import_Ndjson(t *testing.T) {
+	user := &models.User{ID: "user01"}
+	data := "{\"entity\": \"a.go\", \"time\": 1700000000}\n{\"entity\": \"b.go\", \"time\": 1700000100}\n{\"entity\": \"c.go\", \"time\": 1700000200}\n"
+
+	stream, err := NewJsonHeartbeatsImporter(strings.NewReader(data)).Import(user, time.Unix(1700000050, 0), time.Unix(1700000150, 0))
+	assert.Nil(t, err)
+
+	results := collect(stream)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "b.go", results[0].Entity)
+}
+
+func TestJsonHeartbeatsImporter_Import_Invalid(t *testing.T) {
+	user := &models.User{ID: "user01"}
+
+	_, err := NewJsonHeartbeatsImporter(strings.NewReader("   ")).ImportAll(user)
+	assert.Error(t, err)
+
+	_, err = NewJsonHeartbeatsImporter(strings.NewReader("entity,time")).ImportAll(user)
+	assert.Error(t, err)
+}
+
+func collect(stream <-chan *models.Heartbeat) []*models.Heartbeat {
+	results := make([]*models.Heartbeat, 0)
+	for hb := range stream {
+		results = append(results, hb)
+	}
+	return results
+}
@@ -0,0 +1,223 @@
+package imports
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+var zipMagic = []byte("PK\x03\x04")
+
+// WakapiArchiveImporter restores heartbeats from an account archive as created by the export service (either zip or ndjson).
+// Heartbeats get their hashes recomputed, which still detects duplicates, and, optionally, keep their ids.
+// Ids must only be kept when restoring into an instance without any heartbeats, which is only offered to admins via ctl.
+// Aliases, labels, language mappings and preferences are parsed eagerly and accessible via Archive().
+type WakapiArchiveImporter struct {
+	data    []byte
+	isZip   bool
+	keepIds bool
+	archive *models.AccountArchive
+}
+
+type archiveRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func NewWakapiArchiveImporter(data []byte, keepIds bool) (*WakapiArchiveImporter, error) {
+	importer := &WakapiArchiveImporter{
+		data:    data,
+		isZip:   bytes.HasPrefix(data, zipMagic),
+		keepIds: keepIds,
+		archive: &models.AccountArchive{},
+	}
+
+	var err error
+	if importer.isZip {
+		err = importer.readZipMeta()
+	} else {
+		err = importer.readNdjsonMeta()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if importer.archive.Manifest == nil {
+		return nil, errors.New("invalid archive, manifest missing")
+	}
+	if importer.archive.Manifest.Version > models.AccountArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", importer.archive.Manifest.Version)
+	}
+
+	return importer, nil
+}
+
+func (w *WakapiArchiveImporter) Archive() *models.AccountArchive {
+	return w.archive
+}
+
+func (w *WakapiArchiveImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	var reader io.ReadCloser
+	if w.isZip {
+		zr, err := zip.NewReader(bytes.NewReader(w.data), int64(len(w.data)))
+		if err != nil {
+			return nil, err
+		}
+		if reader, err = zr.Open(models.AccountArchiveFileHeartbeats); err != nil {
+			return nil, err
+		}
+	} else {
+		reader = io.NopCloser(bytes.NewReader(w.data))
+	}
+
+	out := make(chan *models.Heartbeat)
+
+	go func() {
+		defer close(out)
+		defer reader.Close()
+
+		var count, skipped int
+		decoder := json.NewDecoder(bufio.NewReader(reader))
+
+		for decoder.More() {
+			var archived models.ArchivedHeartbeat
+
+			if w.isZip {
+				if err := decoder.Decode(&archived); err != nil {
+					config.Log().Error("failed to decode archived heartbeat, aborting", "userID", user.ID, "error", err)
+					return
+				}
+			} else {
+				var record archiveRecord
+				if err := decoder.Decode(&record); err != nil {
+					config.Log().Error("failed to decode archive record, aborting", "userID", user.ID, "error", err)
+					return
+				}
+				if record.Type != models.AccountArchiveRecordHeartbeat {
+					continue
+				}
+				if err := json.Unmarshal(record.Data, &archived); err != nil {
+					skipped++
+					continue
+				}
+			}
+
+			count++
+			hb := w.mapHeartbeat(&archived, user)
+			if hb == nil {
+				skipped++
+				continue
+			}
+			if hb.Time.T().Before(minFrom) || hb.Time.T().After(maxTo) {
+				continue
+			}
+			out <- hb
+		}
+
+		slog.Info("finished reading heartbeats from archive", "userID", user.ID, "count", count, "skipped", skipped)
+	}()
+
+	return out, nil
+}
+
+func (w *WakapiArchiveImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return w.Import(user, time.Unix(0, 0), time.Now().AddDate(0, 0, 1))
+}
+
+func (w *WakapiArchiveImporter) mapHeartbeat(archived *models.ArchivedHeartbeat, user *models.User) *models.Heartbeat {
+	hb := archived.ToHeartbeat()
+	if hb.Entity == "" || hb.Time.T().IsZero() {
+		return nil
+	}
+
+	hb.User = user
+	hb.UserID = user.ID
+	if !w.keepIds || hb.ID > math.MaxInt64 { // ids beyond the range of signed 64 bit primary keys would break the id sequence
+		hb.ID = 0
+	}
+	// archived hashes can't be trusted, a forged one could block other heartbeats from being inserted
+	hb.Hash = ""
+	hb.Hashed()
+	return hb
+}
+
+func (w *WakapiArchiveImporter) readZipMeta() error {
+	zr, err := zip.NewReader(bytes.NewReader(w.data), int64(len(w.data)))
+	if err != nil {
+		return err
+	}
+
+	files := map[string]interface{}{
+		models.AccountArchiveFileManifest:         &w.archive.Manifest,
+		models.AccountArchiveFileUser:             &w.archive.User,
+		models.AccountArchiveFileAliases:          &w.archive.Aliases,
+		models.AccountArchiveFileProjectLabels:    &w.archive.ProjectLabels,
+		models.AccountArchiveFileLanguageMappings: &w.archive.LanguageMappings,
+	}
+
+	for name, target := range files {
+		f, err := zr.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // only the manifest is mandatory, which is checked afterwards
+		} else if err != nil {
+			return err
+		}
+		err = json.NewDecoder(f).Decode(target)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *WakapiArchiveImporter) readNdjsonMeta() error {
+	decoder := json.NewDecoder(bytes.NewReader(w.data))
+
+	for decoder.More() {
+		var record archiveRecord
+		if err := decoder.Decode(&record); err != nil {
+			return err
+		}
+
+		var err error
+		switch record.Type {
+		case models.AccountArchiveRecordManifest:
+			err = json.Unmarshal(record.Data, &w.archive.Manifest)
+		case models.AccountArchiveRecordUser:
+			err = json.Unmarshal(record.Data, &w.archive.User)
+		case models.AccountArchiveRecordAlias:
+			var alias models.ArchivedAlias
+			if err = json.Unmarshal(record.Data, &alias); err == nil {
+				w.archive.Aliases = append(w.archive.Aliases, &alias)
+			}
+		case models.AccountArchiveRecordProjectLabel:
+			var label models.ProjectLabel
+			if err = json.Unmarshal(record.Data, &label); err == nil {
+				w.archive.ProjectLabels = append(w.archive.ProjectLabels, &label)
+			}
+		case models.AccountArchiveRecordLanguageMapping:
+			var mapping models.LanguageMapping
+			if err = json.Unmarshal(record.Data, &mapping); err == nil {
+				w.archive.LanguageMappings = append(w.archive.LanguageMappings, &mapping)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode %s record: %w", record.Type, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package imports
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testArchive = `{"type": "manifest", "data": {"version": 1, "user_id": "user01"}}
+{"type": "heartbeat", "data": {"id": 500, "hash": "3f8a1c2b4d5e6f70", "entity": "main.go", "project": "wakapi", "time": "2023-11-14T22:13:20Z"}}
+{"type": "heartbeat", "data": {"id": 18446744073709551615, "hash": "3f8a1c2b4d5e6f71", "entity": "README.md", "project": "wakapi", "time": "2023-11-14T22:13:21Z"}}
+`
+
+func TestWakapiArchiveImporter_Import_KeepIds(t *testing.T) {
+	user := &models.User{ID: "user01"}
+
+	importer, err := NewWakapiArchiveImporter([]byte(testArchive), true)
+	assert.Nil(t, err)
+	stream, err := importer.ImportAll(user)
+	assert.Nil(t, err)
+
+	results := collect(stream)
+	assert.Len(t, results, 2)
+	assert.Equal(t, uint64(500), results[0].ID)
+	assert.Equal(t, uint64(0), results[1].ID) // out of range for the id sequence
+
+	importer, err = NewWakapiArchiveImporter([]byte(testArchive), false)
+	assert.Nil(t, err)
+	stream, err = importer.ImportAll(user)
+	assert.Nil(t, err)
+
+	results = collect(stream)
+	assert.Len(t, results, 2)
+	assert.Equal(t, uint64(0), results[0].ID)
+	assert.Equal(t, uint64(0), results[1].ID)
+}
+
+func TestWakapiArchiveImporter_Import_RecomputesHashes(t *testing.T) {
+	user := &models.User{ID: "user01"}
+
+	importer, err := NewWakapiArchiveImporter([]byte(testArchive), false)
+	assert.Nil(t, err)
+	stream, err := importer.ImportAll(user)
+	assert.Nil(t, err)
+
+	for _, hb := range collect(stream) {
+		assert.NotContains(t, []string{"3f8a1c2b4d5e6f70", "3f8a1c2b4d5e6f71"}, hb.Hash)
+
+		expected := *hb
+		expected.Hash = ""
+		assert.Equal(t, expected.Hashed().Hash, hb.Hash)
+	}
+}
@@ -24,12 +24,13 @@ type LeaderboardService struct {
 	repository     repositories.ILeaderboardRepository
 	summaryService ISummaryService
 	userService    IUserService
+	projectService IProjectService
 	queueDefault   *artifex.Dispatcher
 	queueWorkers   *artifex.Dispatcher
 	defaultScope   *models.IntervalKey
 }
 
-func NewLeaderboardService(leaderboardRepo repositories.ILeaderboardRepository, summaryService ISummaryService, userService IUserService) *LeaderboardService {
+func NewLeaderboardService(leaderboardRepo repositories.ILeaderboardRepository, summaryService ISummaryService, userService IUserService, projectService IProjectService) *LeaderboardService {
 	srv := &LeaderboardService{
 		config:         config.Get(),
 		cache:          cache.New(6*time.Hour, 6*time.Hour),
@@ -37,6 +38,7 @@ func NewLeaderboardService(leaderboardRepo repositories.ILeaderboardRepository,
 		repository:     leaderboardRepo,
 		summaryService: summaryService,
 		userService:    userService,
+		projectService: projectService,
 		queueDefault:   config.GetDefaultQueue(),
 		queueWorkers:   config.GetQueue(config.QueueProcessing),
 	}
@@ -106,12 +108,45 @@ func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval
 			config.Log().Error("failed to delete leaderboard items for user", "userID", user.ID, "interval", (*interval)[0], "error", err)
 			continue
 		}
+	}
+
+	srv.computeLeaderboard(users, interval, by, nil)
+
+	srv.cache.Flush()
+	slog.Info("finished leaderboard generation")
+	return nil
+}
+
+// ComputeTeamLeaderboard (re-)generates a team's private leaderboard, which is kept separately from the public one.
+// Other than for the public leaderboard, all team members are included, as long as they share their data for (at least) the entire interval, consistent with the team's summary.
+// Aggregations by a certain entity (e.g. languages) are only included if the member shares them.
+func (srv *LeaderboardService) ComputeTeamLeaderboard(teamId uint, users []*models.User, interval *models.IntervalKey, by []uint8) error {
+	slog.Info("generating team leaderboard", "teamID", teamId, "interval", (*interval)[0], "userCount", len(users), "aggregationCount", len(by))
+
+	if err := srv.repository.DeleteByTeamAndInterval(teamId, interval); err != nil {
+		config.Log().Error("failed to delete leaderboard items for team", "teamID", teamId, "interval", (*interval)[0], "error", err)
+		return err
+	}
+
+	srv.computeLeaderboard(users, interval, by, &teamId)
+
+	srv.cache.Flush()
+	slog.Info("finished team leaderboard generation", "teamID", teamId)
+	return nil
+}
+
+func (srv *LeaderboardService) computeLeaderboard(users []*models.User, interval *models.IntervalKey, by []uint8, teamId *uint) {
+	for _, user := range users {
+		if teamId != nil && !srv.sharesIntervalWithTeam(user, interval) {
+			continue
+		}
 
 		item, err := srv.GenerateByUser(user, interval)
 		if err != nil {
 			config.Log().Error("failed to regenerate general leaderboard for user", "userID", user.ID, "error", err)
 			continue
 		}
+		item.TeamID = teamId
 
 		if err := srv.repository.InsertBatch([]*models.LeaderboardItem{item}); err != nil {
 			config.Log().Error("failed to persist general leaderboard for user", "userID", user.ID, "error", err)
@@ -119,6 +154,10 @@ func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval
 		}
 
 		for _, by := range by {
+			if teamId != nil && !user.SharesSummaryType(by) {
+				continue
+			}
+
 			items, err := srv.GenerateAggregatedByUser(user, interval, by)
 			if err != nil {
 				config.Log().Error("failed to regenerate aggregated leaderboard for user", "aggregatedBy", models.GetEntityColumn(by), "userID", user.ID, "error", err)
@@ -129,16 +168,28 @@ func (srv *LeaderboardService) ComputeLeaderboard(users []*models.User, interval
 				continue
 			}
 
+			for _, item := range items {
+				item.TeamID = teamId
+			}
+
 			if err := srv.repository.InsertBatch(items); err != nil {
 				config.Log().Error("failed to persist aggregated leaderboard for user", "aggregatedBy", models.GetEntityColumn(by), "userID", user.ID, "error", err)
 				continue
 			}
 		}
 	}
+}
 
-	srv.cache.Flush()
-	slog.Info("finished leaderboard generation")
-	return nil
+// sharesIntervalWithTeam tells whether the user's data is shared for the entire interval, see TeamService.GetSummary
+func (srv *LeaderboardService) sharesIntervalWithTeam(user *models.User, interval *models.IntervalKey) bool {
+	if user.ShareDataMaxDays < 0 {
+		return true
+	}
+	err, from, to := helpers.ResolveIntervalTZ(interval, user.TZ())
+	if err != nil {
+		return false
+	}
+	return !from.Before(to.AddDate(0, 0, -user.ShareDataMaxDays))
 }
 
 func (srv *LeaderboardService) ExistsAnyByUser(userId string) (bool, error) {
@@ -224,6 +275,43 @@ func (srv *LeaderboardService) GetAggregatedByIntervalAndUser(interval *models.I
 	return items, nil
 }
 
+func (srv *LeaderboardService) GetAggregatedByTeamAndInterval(teamId uint, interval *models.IntervalKey, by *uint8, resolveUsers bool) (models.Leaderboard, error) {
+	// check cache
+	cacheKey := srv.getHash(interval, by, fmt.Sprintf("team_%d", teamId), nil)
+	if cacheResult, ok := srv.cache.Get(cacheKey); ok {
+		return cacheResult.([]*models.LeaderboardItemRanked), nil
+	}
+
+	items, err := srv.repository.GetAllAggregatedByTeamAndInterval(teamId, interval, by, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolveUsers {
+		users, err := srv.userService.GetManyMapped(models.Leaderboard(items).UserIDs())
+		if err != nil {
+			config.Log().Error("failed to resolve users for team leaderboard item", "error", err)
+		} else {
+			for _, item := range items {
+				if u, ok := users[item.UserID]; ok {
+					item.User = u
+				}
+			}
+		}
+	}
+
+	srv.cache.SetDefault(cacheKey, items)
+	return items, nil
+}
+
+func (srv *LeaderboardService) DeleteByTeam(teamId uint) error {
+	if err := srv.repository.DeleteByTeam(teamId); err != nil {
+		return err
+	}
+	srv.cache.Flush()
+	return nil
+}
+
 func (srv *LeaderboardService) GenerateByUser(user *models.User, interval *models.IntervalKey) (*models.LeaderboardItem, error) {
 	err, from, to := helpers.ResolveIntervalTZ(interval, user.TZ())
 	if err != nil {
@@ -238,6 +326,15 @@ func (srv *LeaderboardService) GenerateByUser(user *models.User, interval *model
 
 	// exclude unknown language (will also exclude browsing time by chrome-wakatime plugin)
 	total := summary.TotalTime() - summary.TotalTimeByKey(models.SummaryLanguage, models.UnknownSummaryKey)
+
+	archivedSummary, err := srv.getArchivedSummary(from, to, user, &timeout)
+	if err != nil {
+		return nil, err
+	}
+	if archivedSummary != nil {
+		total -= archivedSummary.TotalTime() - archivedSummary.TotalTimeByKey(models.SummaryLanguage, models.UnknownSummaryKey)
+	}
+
 	return &models.LeaderboardItem{
 		User:     user,
 		UserID:   user.ID,
@@ -257,6 +354,11 @@ func (srv *LeaderboardService) GenerateAggregatedByUser(user *models.User, inter
 		return nil, err
 	}
 
+	archivedSummary, err := srv.getArchivedSummary(from, to, user, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	summaryItems := *summary.GetByType(by)
 	items := make([]*models.LeaderboardItem, 0, summaryItems.Len())
 
@@ -266,12 +368,20 @@ func (srv *LeaderboardService) GenerateAggregatedByUser(user *models.User, inter
 			continue
 		}
 
+		total := summary.TotalTimeByKey(by, item.Key)
+		if archivedSummary != nil {
+			total -= archivedSummary.TotalTimeByKey(by, item.Key)
+		}
+		if total <= 0 {
+			continue
+		}
+
 		items = append(items, &models.LeaderboardItem{
 			User:     user,
 			UserID:   user.ID,
 			Interval: (*interval)[0],
 			By:       &by,
-			Total:    summary.TotalTimeByKey(by, item.Key),
+			Total:    total,
 			Key:      &item.Key,
 		})
 	}
@@ -279,6 +389,15 @@ func (srv *LeaderboardService) GenerateAggregatedByUser(user *models.User, inter
 	return items, nil
 }
 
+// getArchivedSummary returns a summary of only the user's archived projects, whose coding time is not counted towards leaderboards, or nil if there are none
+func (srv *LeaderboardService) getArchivedSummary(from, to time.Time, user *models.User, customTimeout *time.Duration) (*models.Summary, error) {
+	archived, err := srv.projectService.GetArchived(user.ID)
+	if err != nil || len(archived) == 0 {
+		return nil, err
+	}
+	return srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, models.NewFilterWithMultiple(models.SummaryProject, archived), customTimeout, false)
+}
+
 func (srv *LeaderboardService) getHash(interval *models.IntervalKey, by *uint8, user string, pageParams *utils.PageParams) string {
 	k := strings.Join(*interval, "__") + "__" + user
 	if by != nil && !reflect.ValueOf(by).IsNil() {
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+)
+
+// live statuses are recomputed from scratch after this interval, in case heartbeats were missed (e.g. inserted by an import)
+const liveStatusRecomputeInterval = 5 * time.Minute
+
+// number of status updates buffered per subscriber, before further updates are dropped for slow consumers
+const liveSubscriberBufferSize = 16
+
+// LiveService keeps track of what users are currently coding on and notifies subscribers as new heartbeats come in
+type LiveService struct {
+	config           *config.Config
+	eventBus         *hub.Hub
+	cache            *cache.Cache
+	heartbeatService IHeartbeatService
+	summaryService   ISummaryService
+	subscribers      map[string]map[chan *models.LiveStatus]bool
+	lock             sync.Mutex // guards subscribers
+	userLocks        sync.Map   // user id -> *sync.Mutex, serializes reading, updating and caching a user's status
+}
+
+func NewLiveService(heartbeatService IHeartbeatService, summaryService ISummaryService) *LiveService {
+	srv := &LiveService{
+		config:           config.Get(),
+		eventBus:         config.EventBus(),
+		cache:            cache.New(1*time.Hour, 1*time.Hour),
+		heartbeatService: heartbeatService,
+		summaryService:   summaryService,
+		subscribers:      make(map[string]map[chan *models.LiveStatus]bool),
+	}
+
+	sub1 := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			if heartbeat.User == nil {
+				continue
+			}
+			srv.onHeartbeat(heartbeat)
+		}
+	}(&sub1)
+
+	return srv
+}
+
+// GetStatus returns the user's current live status, including today's total coding time
+func (srv *LiveService) GetStatus(user *models.User) (*models.LiveStatus, error) {
+	now := time.Now().In(user.TZ())
+
+	unlock := srv.lockUser(user.ID)
+	defer unlock()
+
+	if cached, ok := srv.cache.Get(user.ID); ok && srv.isFresh(cached.(*models.LiveStatus), now) {
+		return cached.(*models.LiveStatus).At(now, user.HeartbeatsTimeout()), nil
+	}
+
+	status, err := srv.compute(user, now)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(user.ID, status)
+
+	return status.At(now, user.HeartbeatsTimeout()), nil
+}
+
+// Subscribe returns a channel that receives every update of the given users' live statuses, as well as a function to cancel the subscription with
+func (srv *LiveService) Subscribe(userIds ...string) (<-chan *models.LiveStatus, func()) {
+	ch := make(chan *models.LiveStatus, liveSubscriberBufferSize)
+
+	srv.lock.Lock()
+	for _, id := range userIds {
+		if _, ok := srv.subscribers[id]; !ok {
+			srv.subscribers[id] = make(map[chan *models.LiveStatus]bool)
+		}
+		srv.subscribers[id][ch] = true
+	}
+	srv.lock.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			srv.lock.Lock()
+			defer srv.lock.Unlock()
+			for _, id := range userIds {
+				delete(srv.subscribers[id], ch)
+				if len(srv.subscribers[id]) == 0 {
+					delete(srv.subscribers, id)
+				}
+			}
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+func (srv *LiveService) onHeartbeat(heartbeat *models.Heartbeat) {
+	user := heartbeat.User
+	now := time.Now().In(user.TZ())
+
+	// hold the user's lock until the updated status is cached, so that concurrent updates aren't lost
+	unlock := srv.lockUser(user.ID)
+	defer unlock()
+
+	srv.lock.Lock()
+	_, hasSubscribers := srv.subscribers[user.ID]
+	srv.lock.Unlock()
+	cached, isCached := srv.cache.Get(user.ID)
+
+	// avoid computing statuses for users nobody is interested in
+	if !hasSubscribers && !isCached {
+		return
+	}
+
+	var status *models.LiveStatus
+	if isCached && srv.isFresh(cached.(*models.LiveStatus), now) {
+		status = cached.(*models.LiveStatus)
+	} else {
+		var err error
+		if status, err = srv.compute(user, now); err != nil {
+			config.Log().Error("failed to compute live status", "userID", user.ID, "error", err)
+			return
+		}
+	}
+	status = status.WithHeartbeat(heartbeat, user.HeartbeatsTimeout())
+	srv.cache.SetDefault(user.ID, status)
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	current := status.At(now, user.HeartbeatsTimeout())
+	for ch := range srv.subscribers[user.ID] {
+		select {
+		case ch <- current:
+		default:
+			config.Log().Warn("dropping live status update for slow subscriber", "userID", user.ID)
+		}
+	}
+}
+
+// lockUser acquires the lock for the given user's status and returns the function to release it with
+func (srv *LiveService) lockUser(userId string) func() {
+	l, _ := srv.userLocks.LoadOrStore(userId, &sync.Mutex{})
+	l.(*sync.Mutex).Lock()
+	return l.(*sync.Mutex).Unlock
+}
+
+// isFresh tells whether the cached status can be updated incrementally, i.e. it was computed recently and on the same day (in the user's time zone)
+func (srv *LiveService) isFresh(status *models.LiveStatus, now time.Time) bool {
+	return now.Sub(status.ComputedAt) < liveStatusRecomputeInterval && datetime.BeginOfDay(status.ComputedAt.In(now.Location())).Equal(datetime.BeginOfDay(now))
+}
+
+func (srv *LiveService) compute(user *models.User, now time.Time) (*models.LiveStatus, error) {
+	summary, err := srv.summaryService.Aliased(datetime.BeginOfDay(now), now, user, srv.summaryService.Retrieve, nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.LiveStatus{
+		UserID:     user.ID,
+		TotalToday: summary.TotalTime(),
+		ComputedAt: now,
+	}
+
+	latest, err := srv.heartbeatService.GetLatestByUser(user)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && !latest.Time.T().IsZero() {
+		t := latest.Time.T()
+		status.Project = latest.Project
+		status.Language = latest.Language
+		status.Editor = latest.Editor
+		status.LastHeartbeatAt = &t
+	}
+
+	return status, nil
+}
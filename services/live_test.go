@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type LiveServiceTestSuite struct {
+	suite.Suite
+	TestUsers        []*models.User
+	HeartbeatService *mocks.HeartbeatServiceMock
+	SummaryService   *mocks.SummaryServiceMock
+}
+
+func (suite *LiveServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+
+	suite.TestUsers = []*models.User{
+		{ID: "testuser01", HeartbeatsTimeoutSec: 600},
+		{ID: "testuser02", HeartbeatsTimeoutSec: 600},
+	}
+}
+
+func (suite *LiveServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+}
+
+func TestLiveServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LiveServiceTestSuite))
+}
+
+func (suite *LiveServiceTestSuite) TestLiveService_GetStatus() {
+	sut := NewLiveService(suite.HeartbeatService, suite.SummaryService)
+
+	user := suite.TestUsers[0]
+	latest := time.Now().Add(-1 * time.Minute)
+	suite.HeartbeatService.On("GetLatestByUser", user).Return(&models.Heartbeat{Project: "wakapi", Language: "Go", Time: models.CustomTime(latest)}, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, user, mock.Anything, mock.Anything, mock.Anything, false).Return(&models.Summary{
+		Projects: []*models.SummaryItem{{Key: "wakapi", Total: 90 * time.Minute / time.Second}},
+	}, nil)
+
+	status, err := sut.GetStatus(user)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), status.Active)
+	assert.Equal(suite.T(), "wakapi", status.Project)
+	assert.Equal(suite.T(), "Go", status.Language)
+	assert.Equal(suite.T(), 90*time.Minute, status.TotalToday)
+
+	// served from cache
+	_, err = sut.GetStatus(user)
+	assert.Nil(suite.T(), err)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "GetLatestByUser", 1)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 1)
+}
+
+func (suite *LiveServiceTestSuite) TestLiveService_Subscribe() {
+	sut := NewLiveService(suite.HeartbeatService, suite.SummaryService)
+
+	user1, user2 := suite.TestUsers[0], suite.TestUsers[1]
+	latest := time.Now().Add(-1 * time.Minute)
+	suite.HeartbeatService.On("GetLatestByUser", user1).Return(&models.Heartbeat{Project: "wakapi", Time: models.CustomTime(latest)}, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, user1, mock.Anything, mock.Anything, mock.Anything, false).Return(&models.Summary{}, nil)
+
+	updates, cancel := sut.Subscribe(user1.ID)
+
+	// heartbeats of users without subscribers are ignored
+	sut.onHeartbeat(&models.Heartbeat{User: user2, UserID: user2.ID, Project: "anchr", Time: models.CustomTime(time.Now())})
+	suite.SummaryService.AssertNotCalled(suite.T(), "Aliased", mock.Anything, mock.Anything, user2, mock.Anything, mock.Anything, mock.Anything, false)
+
+	sut.onHeartbeat(&models.Heartbeat{User: user1, UserID: user1.ID, Project: "anchr", Time: models.CustomTime(time.Now())})
+
+	select {
+	case status := <-updates:
+		assert.Equal(suite.T(), user1.ID, status.UserID)
+		assert.Equal(suite.T(), "anchr", status.Project)
+		assert.True(suite.T(), status.Active)
+	case <-time.After(time.Second):
+		suite.Fail("no status update received")
+	}
+
+	cancel()
+	_, ok := <-updates
+	assert.False(suite.T(), ok)
+	assert.Empty(suite.T(), sut.subscribers)
+}
+
+func (suite *LiveServiceTestSuite) TestLiveService_OnHeartbeat_Concurrent() {
+	sut := NewLiveService(suite.HeartbeatService, suite.SummaryService)
+
+	user := suite.TestUsers[0]
+	t0 := time.Now().Add(-10 * time.Minute)
+	suite.HeartbeatService.On("GetLatestByUser", user).Return(&models.Heartbeat{Project: "wakapi", Time: models.CustomTime(t0)}, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, user, mock.Anything, mock.Anything, mock.Anything, false).After(50*time.Millisecond).Return(&models.Summary{
+		Projects: []*models.SummaryItem{{Key: "wakapi", Total: 1 * time.Hour / time.Second}},
+	}, nil)
+
+	_, cancel := sut.Subscribe(user.ID)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(t time.Time) {
+			defer wg.Done()
+			sut.onHeartbeat(&models.Heartbeat{User: user, UserID: user.ID, Project: "wakapi", Time: models.CustomTime(t)})
+		}(t0.Add(time.Duration(i) * time.Second))
+	}
+	wg.Wait()
+
+	// status is computed once, then updated by every heartbeat in turn, so time up until the latest one is counted exactly once
+	status, err := sut.GetStatus(user)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1*time.Hour+10*time.Second, status.TotalToday)
+	assert.Equal(suite.T(), t0.Add(10*time.Second).Unix(), status.LastHeartbeatAt.Unix())
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 1)
+}
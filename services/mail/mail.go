@@ -3,6 +3,7 @@ package mail
 import (
 	"bytes"
 	"fmt"
+	"github.com/duke-git/lancet/v2/strutil"
 	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/routes"
@@ -20,11 +21,16 @@ const (
 	tplNameWakatimeFailureNotification = "wakatime_connection_failure"
 	tplNameReport                      = "report"
 	tplNameSubscriptionNotification    = "subscription_expiring"
+	tplNameGoalNotification            = "goal_notification"
 	subjectPasswordReset               = "Wakapi - Password Reset"
 	subjectImportNotification          = "Wakapi - Data Import Finished"
 	subjectWakatimeFailureNotification = "Wakapi - WakaTime Connection Failure"
+	subjectRelayFailureNotification    = "Wakapi - Relay Connection Failure: %s"
 	subjectReport                      = "Wakapi - Report from %s"
+	subjectReportSubscription          = "Wakapi - %s report from %s"
 	subjectSubscriptionNotification    = "Wakapi - Subscription expiring / expired"
+	subjectGoalReached                 = "Wakapi - Goal reached: %s"
+	subjectGoalMissed                  = "Wakapi - Goal missed: %s"
 )
 
 type SendingService interface {
@@ -90,6 +96,24 @@ func (m *MailService) SendWakatimeFailureNotification(recipient *models.User, nu
 	return m.sendingService.Send(mail)
 }
 
+func (m *MailService) SendRelayFailureNotification(recipient *models.User, target *models.RelayTarget, numFailures int) error {
+	tpl, err := m.getWakatimeFailureNotificationTemplate(WakatimeFailureNotificationNotificationTplData{
+		PublicUrl:   m.config.Server.PublicUrl,
+		NumFailures: numFailures,
+		TargetName:  target.Name,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: fmt.Sprintf(subjectRelayFailureNotification, target.Name),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
 func (m *MailService) SendImportNotification(recipient *models.User, duration time.Duration, numHeartbeats int) error {
 	tpl, err := m.getImportNotificationTemplate(ImportNotificationTplData{
 		PublicUrl:     m.config.Server.PublicUrl,
@@ -113,10 +137,14 @@ func (m *MailService) SendReport(recipient *models.User, report *models.Report)
 	if err != nil {
 		return err
 	}
+	subject := fmt.Sprintf(subjectReport, helpers.FormatDateHuman(time.Now().In(recipient.TZ())))
+	if report.Subscription != nil {
+		subject = fmt.Sprintf(subjectReportSubscription, strutil.Capitalize(report.Subscription.Cadence), helpers.FormatDateHuman(time.Now().In(recipient.TZ())))
+	}
 	mail := &models.Mail{
 		From:    models.MailAddress(m.config.Mail.Sender),
-		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
-		Subject: fmt.Sprintf(subjectReport, helpers.FormatDateHuman(time.Now().In(recipient.TZ()))),
+		To:      report.Recipients(),
+		Subject: subject,
 	}
 	mail.WithHTML(tpl.String())
 	return m.sendingService.Send(mail)
@@ -140,6 +168,39 @@ func (m *MailService) SendSubscriptionNotification(recipient *models.User, hasEx
 	return m.sendingService.Send(mail)
 }
 
+func (m *MailService) SendGoalNotification(recipient *models.User, progress *models.GoalProgress) error {
+	periodText := "yesterday"
+	if progress.Goal.Period == models.GoalPeriodWeek {
+		periodText = "last week"
+	}
+
+	tpl, err := m.getGoalNotificationTemplate(GoalNotificationTplData{
+		PublicUrl:  m.config.Server.PublicUrl,
+		Title:      progress.Goal.Title,
+		PeriodText: periodText,
+		Reached:    progress.Reached(),
+		Actual:     helpers.FmtWakatimeDuration(progress.Actual),
+		Target:     helpers.FmtWakatimeDuration(progress.Target()),
+		Percent:    progress.Percent(),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject := subjectGoalMissed
+	if progress.Reached() {
+		subject = subjectGoalReached
+	}
+
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: fmt.Sprintf(subject, progress.Goal.Title),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
 func (m *MailService) getPasswordResetTemplate(data PasswordResetTplData) (*bytes.Buffer, error) {
 	var rendered bytes.Buffer
 	if err := m.templates[m.fmtName(tplNamePasswordReset)].Execute(&rendered, data); err != nil {
@@ -180,6 +241,14 @@ func (m *MailService) getSubscriptionNotificationTemplate(data SubscriptionNotif
 	return &rendered, nil
 }
 
+func (m *MailService) getGoalNotificationTemplate(data GoalNotificationTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameGoalNotification)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) fmtName(name string) string {
 	return fmt.Sprintf("%s.tpl.html", name)
 }
@@ -15,6 +15,7 @@ type ImportNotificationTplData struct {
 type WakatimeFailureNotificationNotificationTplData struct {
 	PublicUrl   string
 	NumFailures int
+	TargetName  string // name of the relay target, empty for the legacy wakatime connection
 }
 
 type ReportTplData struct {
@@ -26,3 +27,13 @@ type SubscriptionNotificationTplData struct {
 	HasExpired          bool
 	DataRetentionMonths int
 }
+
+type GoalNotificationTplData struct {
+	PublicUrl  string
+	Title      string
+	PeriodText string
+	Reached    bool
+	Actual     string
+	Target     string
+	Percent    int
+}
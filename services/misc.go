@@ -30,28 +30,28 @@ var countLock = sync.Mutex{}
 var firstDataLock = sync.Mutex{}
 
 type MiscService struct {
-	config           *config.Config
-	userService      IUserService
-	heartbeatService IHeartbeatService
-	summaryService   ISummaryService
-	keyValueService  IKeyValueService
-	mailService      IMailService
-	queueDefault     *artifex.Dispatcher
-	queueWorkers     *artifex.Dispatcher
-	queueMails       *artifex.Dispatcher
+	config              *config.Config
+	userService         IUserService
+	heartbeatService    IHeartbeatService
+	summaryService      ISummaryService
+	keyValueService     IKeyValueService
+	notificationService INotificationService
+	queueDefault        *artifex.Dispatcher
+	queueWorkers        *artifex.Dispatcher
+	queueMails          *artifex.Dispatcher
 }
 
-func NewMiscService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, keyValueService IKeyValueService, mailService IMailService) *MiscService {
+func NewMiscService(userService IUserService, heartbeatService IHeartbeatService, summaryService ISummaryService, keyValueService IKeyValueService, notificationService INotificationService) *MiscService {
 	return &MiscService{
-		config:           config.Get(),
-		userService:      userService,
-		heartbeatService: heartbeatService,
-		summaryService:   summaryService,
-		keyValueService:  keyValueService,
-		mailService:      mailService,
-		queueDefault:     config.GetDefaultQueue(),
-		queueWorkers:     config.GetQueue(config.QueueProcessing),
-		queueMails:       config.GetQueue(config.QueueMails),
+		config:              config.Get(),
+		userService:         userService,
+		heartbeatService:    heartbeatService,
+		summaryService:      summaryService,
+		keyValueService:     keyValueService,
+		notificationService: notificationService,
+		queueDefault:        config.GetDefaultQueue(),
+		queueWorkers:        config.GetQueue(config.QueueProcessing),
+		queueMails:          config.GetQueue(config.QueueMails),
 	}
 }
 
@@ -223,11 +223,14 @@ func (srv *MiscService) NotifyExpiringSubscription() {
 			}
 		}
 
-		// skip users without e-mail address
 		// skip users who already received a notification before
 		// skip users who either never had a subscription before or intentionally deleted it
 		// skip users who have upcoming auto-renewal (everyone except users who chose to cancel subscription at later date)
-		if alreadySent || u.Email == "" || u.SubscribedUntil == nil || (u.SubscriptionRenewal != nil && u.SubscriptionRenewal.T().After(now)) {
+		// skip users without e-mail address or notification channel
+		if alreadySent || u.SubscribedUntil == nil || (u.SubscriptionRenewal != nil && u.SubscriptionRenewal.T().After(now)) {
+			continue
+		}
+		if u.Email == "" && !srv.notificationService.HasChannels(u, models.NotificationTypeSubscription) {
 			continue
 		}
 
@@ -253,7 +256,7 @@ func (srv *MiscService) sendSubscriptionNotificationScheduled(user *models.User,
 		slog.Info("sending subscription expiry notification mail", "userID", u.ID, "expired", hasExpired)
 		defer time.Sleep(10 * time.Second)
 
-		if err := srv.mailService.SendSubscriptionNotification(&u, hasExpired); err != nil {
+		if err := srv.notificationService.SendSubscriptionNotification(&u, hasExpired); err != nil {
 			config.Log().Error("failed to send subscription notification mail to user", "userID", u.ID, "error", err)
 			return
 		}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/strutil"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/services/notification"
+	"github.com/muety/wakapi/utils"
+)
+
+// number of top projects and languages to list in report notifications
+const notificationReportTopItems = 5
+
+// NotificationService sends user-facing notifications via e-mail and, in addition, to all of the user's notification channels subscribed to the respective notification type
+type NotificationService struct {
+	config       *config.Config
+	eventBus     *hub.Hub
+	repository   repositories.INotificationChannelRepository
+	mailService  IMailService
+	sender       *notification.Sender
+	queueWorkers *artifex.Dispatcher
+}
+
+func NewNotificationService(notificationChannelRepository repositories.INotificationChannelRepository, mailService IMailService) *NotificationService {
+	cfg := config.Get()
+	srv := &NotificationService{
+		config:       cfg,
+		eventBus:     config.EventBus(),
+		repository:   notificationChannelRepository,
+		mailService:  mailService,
+		sender:       notification.NewSender(utils.NewPublicOnlyHttpClient(10*time.Second, cfg.Security.GetAllowedPrivateHosts()), fmt.Sprintf("wakapi/%s", cfg.Version)),
+		queueWorkers: config.GetQueue(config.QueueNotification),
+	}
+
+	// e-mails about connection failures are sent by the user service already
+	sub1 := srv.eventBus.Subscribe(0, config.EventWakatimeFailure)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			user := m.Fields[config.FieldUser].(*models.User)
+			n := m.Fields[config.FieldPayload].(int)
+			target, _ := m.Fields[config.FieldRelayTarget].(*models.RelayTarget)
+			srv.dispatch(user, srv.buildWakatimeFailureNotification(target, n))
+		}
+	}(&sub1)
+
+	return srv
+}
+
+func (srv *NotificationService) GetById(id uint) (*models.NotificationChannel, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *NotificationService) GetByUser(userId string) ([]*models.NotificationChannel, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *NotificationService) Create(channel *models.NotificationChannel) (*models.NotificationChannel, error) {
+	channel.Url = strings.TrimSpace(channel.Url)
+	channel.Token = strings.TrimSpace(channel.Token)
+	channel.Target = strings.TrimSpace(channel.Target)
+	return srv.repository.Insert(channel)
+}
+
+func (srv *NotificationService) Delete(channel *models.NotificationChannel) error {
+	return srv.repository.Delete(channel.ID)
+}
+
+// HasChannels tells whether the user has at least one channel to receive the given type of notification on
+func (srv *NotificationService) HasChannels(user *models.User, notificationType string) bool {
+	channels, err := srv.getSubscribed(user, notificationType)
+	return err == nil && len(channels) > 0
+}
+
+// Test synchronously sends a test notification to the given channel
+func (srv *NotificationService) Test(channel *models.NotificationChannel) error {
+	return srv.sender.Send(channel, &models.Notification{
+		Type:    models.NotificationTypeTest,
+		Title:   "Wakapi - Test Notification",
+		Message: "This channel is set up correctly to receive notifications from Wakapi.",
+		Url:     srv.config.Server.GetPublicUrl(),
+	})
+}
+
+func (srv *NotificationService) SendReport(user *models.User, report *models.Report) error {
+	srv.dispatch(user, srv.buildReportNotification(report))
+	if len(report.Recipients()) == 0 {
+		return nil
+	}
+	return srv.mailService.SendReport(user, report)
+}
+
+func (srv *NotificationService) SendImportNotification(user *models.User, duration time.Duration, numHeartbeats int) error {
+	srv.dispatch(user, &models.Notification{
+		Type:    models.NotificationTypeImport,
+		Title:   "Wakapi - Data Import Finished",
+		Message: fmt.Sprintf("Your data import has finished after %.0f seconds (%d new heartbeats imported).", duration.Seconds(), numHeartbeats),
+		Url:     srv.config.Server.GetPublicUrl() + "/summary",
+	})
+	if user.Email == "" {
+		return nil
+	}
+	return srv.mailService.SendImportNotification(user, duration, numHeartbeats)
+}
+
+func (srv *NotificationService) SendSubscriptionNotification(user *models.User, hasExpired bool) error {
+	message := "Your Wakapi subscription is about to expire. Once expired, data older than %d months will be deleted, unless you renew your subscription."
+	if hasExpired {
+		message = "Your Wakapi subscription has expired. Data older than %d months will be deleted, unless you renew your subscription."
+	}
+	srv.dispatch(user, &models.Notification{
+		Type:    models.NotificationTypeSubscription,
+		Title:   "Wakapi - Subscription expiring / expired",
+		Message: fmt.Sprintf(message, srv.config.App.DataRetentionMonths),
+		Url:     srv.config.Server.GetPublicUrl() + "/settings#subscription",
+	})
+	if user.Email == "" {
+		return nil
+	}
+	return srv.mailService.SendSubscriptionNotification(user, hasExpired)
+}
+
+// dispatch asynchronously sends the notification to all of the user's channels subscribed to its type
+func (srv *NotificationService) dispatch(user *models.User, n *models.Notification) {
+	channels, err := srv.getSubscribed(user, n.Type)
+	if err != nil {
+		config.Log().Error("failed to get notification channels", "userID", user.ID, "error", err)
+		return
+	}
+
+	for _, c := range channels {
+		channel := c
+		if err := srv.queueWorkers.Dispatch(func() {
+			if err := srv.sender.Send(channel, n); err != nil {
+				config.Log().Error("failed to send notification", "userID", user.ID, "channelID", channel.ID, "type", n.Type, "error", err)
+				return
+			}
+			slog.Info("sent notification", "userID", user.ID, "channelID", channel.ID, "type", n.Type)
+		}); err != nil {
+			config.Log().Error("failed to dispatch notification job", "userID", user.ID, "channelID", channel.ID, "error", err)
+		}
+	}
+}
+
+func (srv *NotificationService) getSubscribed(user *models.User, notificationType string) ([]*models.NotificationChannel, error) {
+	channels, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed := make([]*models.NotificationChannel, 0, len(channels))
+	for _, c := range channels {
+		if c.Subscribes(notificationType) {
+			subscribed = append(subscribed, c)
+		}
+	}
+	return subscribed, nil
+}
+
+func (srv *NotificationService) buildReportNotification(report *models.Report) *models.Notification {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("You have coded a total of %s between %s and %s.", helpers.FmtWakatimeDuration(report.Summary.TotalTime()), helpers.FormatDateHuman(report.From), helpers.FormatDateHuman(report.To)))
+	if report.Subscription != nil {
+		if projects := report.Subscription.ProjectsList(); len(projects) > 0 {
+			sb.WriteString(fmt.Sprintf(" Only covers the projects %s.", strings.Join(projects, ", ")))
+		}
+		if labels := report.Subscription.LabelsList(); len(labels) > 0 {
+			sb.WriteString(fmt.Sprintf(" Only covers projects labeled %s.", strings.Join(labels, ", ")))
+		}
+	}
+
+	for _, section := range []struct {
+		key   string
+		title string
+		items models.SummaryItems
+	}{
+		{models.ReportSectionProjects, "Projects", report.Summary.Projects},
+		{models.ReportSectionLanguages, "Languages", report.Summary.Languages},
+	} {
+		if !report.ShowSection(section.key) || len(section.items) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n\n%s:", section.title))
+		items := append(models.SummaryItems{}, section.items...) // copy, as the mail lists items in their original order
+		sort.Sort(sort.Reverse(items))
+		for i, item := range items {
+			if i >= notificationReportTopItems {
+				break
+			}
+			sb.WriteString(fmt.Sprintf("\n- %s: %s", item.Key, helpers.FmtWakatimeDuration(item.TotalFixed())))
+		}
+	}
+
+	title := fmt.Sprintf("Wakapi - Report from %s", helpers.FormatDateHuman(report.To))
+	if report.Subscription != nil {
+		title = fmt.Sprintf("Wakapi - %s report from %s", strutil.Capitalize(report.Subscription.Cadence), helpers.FormatDateHuman(report.To))
+	}
+
+	query := url.Values{}
+	query.Set("from", helpers.FormatDate(report.From))
+	query.Set("to", helpers.FormatDate(report.To))
+
+	return &models.Notification{
+		Type:    models.NotificationTypeReport,
+		Title:   title,
+		Message: sb.String(),
+		Url:     fmt.Sprintf("%s/summary?%s", srv.config.Server.GetPublicUrl(), query.Encode()),
+	}
+}
+
+func (srv *NotificationService) buildWakatimeFailureNotification(target *models.RelayTarget, numFailures int) *models.Notification {
+	if target != nil {
+		return &models.Notification{
+			Type:    models.NotificationTypeWakatimeFailure,
+			Title:   fmt.Sprintf("Wakapi - Relay Connection Failure: %s", target.Name),
+			Message: fmt.Sprintf("Relaying your heartbeats to %s failed %d times in a row, so the relay target was paused. Please check its settings and enable it again.", target.Name, numFailures),
+			Url:     srv.config.Server.GetPublicUrl() + "/settings#integrations",
+		}
+	}
+	return &models.Notification{
+		Type:    models.NotificationTypeWakatimeFailure,
+		Title:   "Wakapi - WakaTime Connection Failure",
+		Message: fmt.Sprintf("Relaying your heartbeats to WakaTime failed %d times in a row, most likely due to an invalid API key. The WakaTime connection is paused until you re-enter your API key.", numFailures),
+		Url:     srv.config.Server.GetPublicUrl() + "/settings#integrations",
+	}
+}
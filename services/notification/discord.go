@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/muety/wakapi/models"
+)
+
+// maximum length of a discord message's content
+const discordMaxContentLength = 2000
+
+// see https://discord.com/developers/docs/resources/webhook#execute-webhook
+func buildDiscordRequest(channel *models.NotificationChannel, notification *models.Notification) (*http.Request, error) {
+	content := []rune(fmt.Sprintf("**%s**\n%s", notification.Title, notification.Text()))
+	if len(content) > discordMaxContentLength {
+		content = content[:discordMaxContentLength]
+	}
+	return newJsonRequest(http.MethodPost, channel.Url, map[string]interface{}{
+		"username": "Wakapi",
+		"content":  string(content),
+	})
+}
@@ -0,0 +1,29 @@
+package notification
+
+import (
+	"net/http"
+
+	"github.com/muety/wakapi/models"
+)
+
+const gotifyPriority = 5
+
+// see https://gotify.net/docs/pushmsg
+func buildGotifyRequest(channel *models.NotificationChannel, notification *models.Notification) (*http.Request, error) {
+	payload := map[string]interface{}{
+		"title":    notification.Title,
+		"message":  notification.Message,
+		"priority": gotifyPriority,
+	}
+	if notification.Url != "" {
+		payload["extras"] = map[string]interface{}{
+			"client::notification": map[string]interface{}{"click": map[string]string{"url": notification.Url}},
+		}
+	}
+	req, err := newJsonRequest(http.MethodPost, baseUrl(channel)+"/message", payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Gotify-Key", channel.Token)
+	return req, nil
+}
@@ -0,0 +1,28 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/models"
+)
+
+// see https://spec.matrix.org/latest/client-server-api/#put_matrixclientv3roomsroomidsendeventtypetxnid
+func buildMatrixRequest(channel *models.NotificationChannel, notification *models.Notification) (*http.Request, error) {
+	txnId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", baseUrl(channel), url.PathEscape(channel.Target), txnId.String())
+	req, err := newJsonRequest(http.MethodPut, endpoint, map[string]interface{}{
+		"msgtype": "m.text",
+		"body":    fmt.Sprintf("%s\n\n%s", notification.Title, notification.Text()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	setBearerToken(req, channel.Token)
+	return req, nil
+}
@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/muety/wakapi/models"
+)
+
+// see https://docs.ntfy.sh/publish, the channel's url is expected to include the topic
+func buildNtfyRequest(channel *models.NotificationChannel, notification *models.Notification) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, channel.Url, strings.NewReader(notification.Message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Title", notification.Title)
+	req.Header.Set("Tags", "wakapi")
+	if notification.Url != "" {
+		req.Header.Set("Click", notification.Url)
+	}
+	setBearerToken(req, channel.Token)
+	return req, nil
+}
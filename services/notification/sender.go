@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/muety/wakapi/models"
+)
+
+// requestBuilder translates a notification into an http request in the respective service's format
+type requestBuilder func(*models.NotificationChannel, *models.Notification) (*http.Request, error)
+
+var requestBuilders = map[string]requestBuilder{
+	models.NotificationChannelSlack:   buildSlackRequest,
+	models.NotificationChannelDiscord: buildDiscordRequest,
+	models.NotificationChannelMatrix:  buildMatrixRequest,
+	models.NotificationChannelNtfy:    buildNtfyRequest,
+	models.NotificationChannelGotify:  buildGotifyRequest,
+	models.NotificationChannelWebhook: buildWebhookRequest,
+}
+
+// Sender delivers notifications to chat and push services
+type Sender struct {
+	httpClient *http.Client
+	userAgent  string
+}
+
+func NewSender(httpClient *http.Client, userAgent string) *Sender {
+	return &Sender{httpClient: httpClient, userAgent: userAgent}
+}
+
+func (s *Sender) Send(channel *models.NotificationChannel, notification *models.Notification) error {
+	build, ok := requestBuilders[channel.Type]
+	if !ok {
+		return fmt.Errorf("unsupported notification channel type '%s'", channel.Type)
+	}
+
+	req, err := build(channel, notification)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("got unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+func newJsonRequest(method, url string, payload interface{}) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func setBearerToken(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func baseUrl(channel *models.NotificationChannel) string {
+	return strings.TrimSuffix(channel.Url, "/")
+}
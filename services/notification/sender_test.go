@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type receivedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+func TestSender_Send(t *testing.T) {
+	var received *receivedRequest
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = &receivedRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header, Body: body}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sut := NewSender(&http.Client{Timeout: 5 * time.Second}, "wakapi/test")
+	notification := &models.Notification{Type: models.NotificationTypeImport, Title: "Import finished", Message: "Imported 42 heartbeats.", Url: "https://wakapi.dev/summary"}
+
+	t.Run("when sending to slack", func(t *testing.T) {
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelSlack, Url: server.URL + "/services/T0/B0/x"}, notification)
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "/services/T0/B0/x", received.Path)
+
+		var payload map[string]string
+		assert.Nil(t, json.Unmarshal(received.Body, &payload))
+		assert.Equal(t, "*Import finished*\nImported 42 heartbeats.\n<https://wakapi.dev/summary|View in Wakapi>", payload["text"])
+	})
+
+	t.Run("when sending to discord", func(t *testing.T) {
+		long := &models.Notification{Title: "Report", Message: strings.Repeat("x", 3000)}
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelDiscord, Url: server.URL + "/api/webhooks/1/abc"}, long)
+		assert.Nil(t, err)
+
+		var payload map[string]string
+		assert.Nil(t, json.Unmarshal(received.Body, &payload))
+		assert.Equal(t, "Wakapi", payload["username"])
+		assert.Len(t, payload["content"], discordMaxContentLength)
+	})
+
+	t.Run("when sending to matrix", func(t *testing.T) {
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelMatrix, Url: server.URL + "/", Token: "secret", Target: "!room:example.org"}, notification)
+		assert.Nil(t, err)
+		assert.Equal(t, http.MethodPut, received.Method)
+		assert.True(t, strings.HasPrefix(received.Path, "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/"))
+		assert.Equal(t, "Bearer secret", received.Header.Get("Authorization"))
+
+		var payload map[string]string
+		assert.Nil(t, json.Unmarshal(received.Body, &payload))
+		assert.Equal(t, "m.text", payload["msgtype"])
+		assert.Contains(t, payload["body"], "Imported 42 heartbeats.")
+	})
+
+	t.Run("when sending to ntfy", func(t *testing.T) {
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelNtfy, Url: server.URL + "/wakapi-alerts"}, notification)
+		assert.Nil(t, err)
+		assert.Equal(t, "/wakapi-alerts", received.Path)
+		assert.Equal(t, "Import finished", received.Header.Get("Title"))
+		assert.Equal(t, "https://wakapi.dev/summary", received.Header.Get("Click"))
+		assert.Empty(t, received.Header.Get("Authorization"))
+		assert.Equal(t, "Imported 42 heartbeats.", string(received.Body))
+	})
+
+	t.Run("when sending to gotify", func(t *testing.T) {
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelGotify, Url: server.URL, Token: "app-token"}, notification)
+		assert.Nil(t, err)
+		assert.Equal(t, "/message", received.Path)
+		assert.Equal(t, "app-token", received.Header.Get("X-Gotify-Key"))
+
+		var payload map[string]interface{}
+		assert.Nil(t, json.Unmarshal(received.Body, &payload))
+		assert.Equal(t, "Import finished", payload["title"])
+		assert.Equal(t, float64(gotifyPriority), payload["priority"])
+	})
+
+	t.Run("when sending to a generic webhook", func(t *testing.T) {
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelWebhook, Url: server.URL + "/hook", Token: "secret"}, notification)
+		assert.Nil(t, err)
+		assert.Equal(t, "Bearer secret", received.Header.Get("Authorization"))
+		assert.Equal(t, "wakapi/test", received.Header.Get("User-Agent"))
+
+		var payload map[string]interface{}
+		assert.Nil(t, json.Unmarshal(received.Body, &payload))
+		assert.Equal(t, models.NotificationTypeImport, payload["type"])
+		assert.Equal(t, "Imported 42 heartbeats.", payload["message"])
+		assert.NotEmpty(t, payload["timestamp"])
+	})
+
+	t.Run("when the service responds with an error", func(t *testing.T) {
+		status = http.StatusForbidden
+		defer func() { status = http.StatusOK }()
+
+		err := sut.Send(&models.NotificationChannel{Type: models.NotificationChannelWebhook, Url: server.URL}, notification)
+		assert.ErrorContains(t, err, "403")
+	})
+
+	t.Run("when the channel type is unknown", func(t *testing.T) {
+		err := sut.Send(&models.NotificationChannel{Type: "pager", Url: server.URL}, notification)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,17 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/muety/wakapi/models"
+)
+
+// see https://api.slack.com/messaging/webhooks
+func buildSlackRequest(channel *models.NotificationChannel, notification *models.Notification) (*http.Request, error) {
+	text := fmt.Sprintf("*%s*\n%s", notification.Title, notification.Message)
+	if notification.Url != "" {
+		text += fmt.Sprintf("\n<%s|View in Wakapi>", notification.Url)
+	}
+	return newJsonRequest(http.MethodPost, channel.Url, map[string]interface{}{"text": text})
+}
@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+type webhookPayload struct {
+	*models.Notification
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// buildWebhookRequest posts the notification as json to an arbitrary endpoint
+func buildWebhookRequest(channel *models.NotificationChannel, notification *models.Notification) (*http.Request, error) {
+	req, err := newJsonRequest(http.MethodPost, channel.Url, &webhookPayload{Notification: notification, Timestamp: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	setBearerToken(req, channel.Token)
+	return req, nil
+}
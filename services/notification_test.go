@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationServiceTestSuite struct {
+	suite.Suite
+	TestUsers                     []*models.User
+	NotificationChannelRepository *mocks.NotificationChannelRepositoryMock
+	MailService                   *mocks.MailServiceMock
+	Server                        *httptest.Server
+	Received                      chan *models.Notification
+}
+
+func (suite *NotificationServiceTestSuite) SetupSuite() {
+	cfg := config.Empty()
+	cfg.App.DateFormat = config.SimpleDateFormat
+	cfg.Security.AllowedPrivateHosts = "127.0.0.1" // test server listens on loopback
+	config.Set(cfg)
+
+	suite.TestUsers = []*models.User{
+		{ID: "testuser01", Email: "testuser01@example.org"},
+		{ID: "testuser02"},
+	}
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var notification models.Notification
+		json.Unmarshal(body, &notification)
+		suite.Received <- &notification
+	}))
+}
+
+func (suite *NotificationServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *NotificationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.NotificationChannelRepository = new(mocks.NotificationChannelRepositoryMock)
+	suite.MailService = new(mocks.MailServiceMock)
+	suite.Received = make(chan *models.Notification, 10)
+}
+
+func TestNotificationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(NotificationServiceTestSuite))
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_SendImportNotification() {
+	sut := NewNotificationService(suite.NotificationChannelRepository, suite.MailService)
+
+	channels := []*models.NotificationChannel{
+		{ID: 1, UserID: suite.TestUsers[0].ID, Type: models.NotificationChannelWebhook, Url: suite.Server.URL, Events: models.NotificationTypeImport},
+		{ID: 2, UserID: suite.TestUsers[0].ID, Type: models.NotificationChannelWebhook, Url: suite.Server.URL, Events: models.NotificationTypeReport},
+	}
+	suite.NotificationChannelRepository.On("GetByUser", suite.TestUsers[0].ID).Return(channels, nil)
+	suite.MailService.On("SendImportNotification", suite.TestUsers[0], 10*time.Second, 42).Return(nil)
+	suite.mockFallbacks()
+
+	err := sut.SendImportNotification(suite.TestUsers[0], 10*time.Second, 42)
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendImportNotification", 1)
+
+	received := suite.awaitReceived(1)
+	assert.Len(suite.T(), received, 1)
+	assert.Equal(suite.T(), models.NotificationTypeImport, received[0].Type)
+	assert.Contains(suite.T(), received[0].Message, "42 new heartbeats")
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_SendImportNotification_WithoutEmail() {
+	sut := NewNotificationService(suite.NotificationChannelRepository, suite.MailService)
+
+	channels := []*models.NotificationChannel{
+		{ID: 3, UserID: suite.TestUsers[1].ID, Type: models.NotificationChannelWebhook, Url: suite.Server.URL, Events: models.NotificationTypeImport},
+	}
+	suite.NotificationChannelRepository.On("GetByUser", suite.TestUsers[1].ID).Return(channels, nil)
+	suite.mockFallbacks()
+
+	err := sut.SendImportNotification(suite.TestUsers[1], 10*time.Second, 42)
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNotCalled(suite.T(), "SendImportNotification")
+	assert.Len(suite.T(), suite.awaitReceived(1), 1)
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_SendReport() {
+	sut := NewNotificationService(suite.NotificationChannelRepository, suite.MailService)
+
+	channels := []*models.NotificationChannel{
+		{ID: 1, UserID: suite.TestUsers[0].ID, Type: models.NotificationChannelWebhook, Url: suite.Server.URL, Events: models.NotificationTypeReport},
+	}
+	suite.NotificationChannelRepository.On("GetByUser", suite.TestUsers[0].ID).Return(channels, nil)
+
+	report := &models.Report{
+		From: time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 5, 5, 23, 59, 59, 0, time.UTC),
+		User: suite.TestUsers[0],
+		Summary: &models.Summary{
+			Projects: []*models.SummaryItem{
+				{Type: models.SummaryProject, Key: "anchr", Total: 1 * time.Hour / time.Second},
+				{Type: models.SummaryProject, Key: "wakapi", Total: 3 * time.Hour / time.Second},
+			},
+			Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Go", Total: 4 * time.Hour / time.Second}},
+		},
+		Subscription: &models.ReportSubscription{Cadence: models.ReportCadenceWeekly, Sections: models.ReportSectionProjects, Recipients: "boss@example.org"},
+	}
+	suite.MailService.On("SendReport", suite.TestUsers[0], report).Return(nil)
+	suite.mockFallbacks()
+
+	err := sut.SendReport(suite.TestUsers[0], report)
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendReport", 1)
+
+	received := suite.awaitReceived(1)
+	assert.Len(suite.T(), received, 1)
+	assert.Equal(suite.T(), "Wakapi - Weekly report from 2024-05-05", received[0].Title)
+	assert.Contains(suite.T(), received[0].Message, "Projects:\n- wakapi: 3 hrs 0 mins\n- anchr: 1 hrs 0 mins")
+	assert.NotContains(suite.T(), received[0].Message, "Languages")
+	assert.Equal(suite.T(), "wakapi", report.Summary.Projects[1].Key) // original order is retained
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_HasChannels() {
+	sut := NewNotificationService(suite.NotificationChannelRepository, suite.MailService)
+
+	channels := []*models.NotificationChannel{
+		{ID: 1, UserID: suite.TestUsers[0].ID, Type: models.NotificationChannelNtfy, Url: suite.Server.URL, Events: models.NotificationTypeReport + "," + models.NotificationTypeImport},
+	}
+	suite.NotificationChannelRepository.On("GetByUser", suite.TestUsers[0].ID).Return(channels, nil)
+	suite.NotificationChannelRepository.On("GetByUser", suite.TestUsers[1].ID).Return([]*models.NotificationChannel{}, nil)
+	suite.mockFallbacks()
+
+	assert.True(suite.T(), sut.HasChannels(suite.TestUsers[0], models.NotificationTypeReport))
+	assert.False(suite.T(), sut.HasChannels(suite.TestUsers[0], models.NotificationTypeSubscription))
+	assert.False(suite.T(), sut.HasChannels(suite.TestUsers[1], models.NotificationTypeReport))
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_Test_RefusesPrivateAddresses() {
+	config.Get().Security.AllowedPrivateHosts = ""
+	defer func() { config.Get().Security.AllowedPrivateHosts = "127.0.0.1" }()
+
+	sut := NewNotificationService(suite.NotificationChannelRepository, suite.MailService)
+	suite.mockFallbacks()
+
+	channel := &models.NotificationChannel{ID: 1, UserID: suite.TestUsers[0].ID, Type: models.NotificationChannelNtfy, Url: suite.Server.URL, Events: models.NotificationTypeReport}
+	assert.Error(suite.T(), sut.Test(channel))
+	assert.Empty(suite.T(), suite.Received)
+}
+
+// mockFallbacks makes sure that notification services of other tests, which are still subscribed to the event bus, don't fail on unexpected calls
+func (suite *NotificationServiceTestSuite) mockFallbacks() {
+	suite.NotificationChannelRepository.On("GetByUser", mock.Anything).Return([]*models.NotificationChannel{}, nil)
+}
+
+func (suite *NotificationServiceTestSuite) awaitReceived(n int) []*models.Notification {
+	received := make([]*models.Notification, 0, n)
+	timeout := time.After(3 * time.Second)
+	for len(received) < n {
+		select {
+		case r := <-suite.Received:
+			received = append(received, r)
+		case <-timeout:
+			return received
+		}
+	}
+	// make sure no unexpected notifications arrive
+	select {
+	case r := <-suite.Received:
+		received = append(received, r)
+	case <-time.After(200 * time.Millisecond):
+	}
+	return received
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	oidcCacheKeyMetadata = "metadata"
+	oidcCacheKeyVerifier = "verifier"
+	oidcClockSkew        = 1 * time.Minute
+	oidcMaxUsernameLen   = 64
+)
+
+var (
+	ErrOidcUnknownUser    = errors.New("no wakapi account linked to this identity")
+	ErrOidcAlreadyLinked  = errors.New("identity is already linked to another account")
+	oidcUsernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+	oidcSigningAlgs       = []string{oidc.RS256, oidc.RS384, oidc.RS512, oidc.ES256, oidc.ES384, oidc.ES512, oidc.PS256, oidc.PS384, oidc.PS512, oidc.EdDSA}
+)
+
+type OidcService struct {
+	config      *config.Config
+	cache       *cache.Cache
+	repository  repositories.IOidcIdentityRepository
+	userService IUserService
+	httpClient  *http.Client
+}
+
+func NewOidcService(oidcIdentityRepository repositories.IOidcIdentityRepository, userService IUserService) *OidcService {
+	return &OidcService{
+		config:      config.Get(),
+		cache:       cache.New(1*time.Hour, 1*time.Hour),
+		repository:  oidcIdentityRepository,
+		userService: userService,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (srv *OidcService) RedirectUri() string {
+	return fmt.Sprintf("%s/login/oidc/callback", srv.config.Server.GetPublicUrl())
+}
+
+// NewAuthState generates fresh, random state, nonce and pkce verifier for a single authorization request
+func (srv *OidcService) NewAuthState(linkUserId string) *models.OidcAuthState {
+	return &models.OidcAuthState{
+		State:        utils.RandomUrlToken(24),
+		Nonce:        utils.RandomUrlToken(24),
+		CodeVerifier: utils.RandomUrlToken(48),
+		LinkUserId:   linkUserId,
+	}
+}
+
+// AuthCodeUrl builds the provider's authorization url to redirect the user to (authorization code flow with pkce)
+func (srv *OidcService) AuthCodeUrl(state *models.OidcAuthState) (string, error) {
+	metadata, err := srv.getMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	authUrl, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	q := authUrl.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", srv.config.Security.Oidc.ClientId)
+	q.Set("redirect_uri", srv.RedirectUri())
+	q.Set("scope", strings.Join(srv.config.Security.Oidc.GetScopes(), " "))
+	q.Set("state", state.State)
+	q.Set("nonce", state.Nonce)
+	q.Set("code_challenge", utils.PkceChallenge(state.CodeVerifier))
+	q.Set("code_challenge_method", "S256")
+	authUrl.RawQuery = q.Encode()
+
+	return authUrl.String(), nil
+}
+
+// Exchange redeems an authorization code at the provider's token endpoint and returns the claims of the verified id token
+func (srv *OidcService) Exchange(code string, state *models.OidcAuthState) (*models.OidcClaims, error) {
+	metadata, err := srv.getMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", srv.RedirectUri())
+	form.Set("code_verifier", state.CodeVerifier)
+	form.Set("client_id", srv.config.Security.Oidc.ClientId)
+
+	req, _ := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if secret := srv.config.Security.Oidc.ClientSecret; secret != "" {
+		req.SetBasicAuth(url.QueryEscape(srv.config.Security.Oidc.ClientId), url.QueryEscape(secret))
+	}
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResponse models.OidcTokenResponse
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse token response (status %d): %v", res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK || tokenResponse.Error != "" {
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", res.StatusCode, tokenResponse.Error, tokenResponse.ErrorDesc)
+	}
+	if tokenResponse.IdToken == "" {
+		return nil, errors.New("token response did not contain an id token")
+	}
+
+	return srv.verifyIdToken(tokenResponse.IdToken, state.Nonce, metadata)
+}
+
+// ResolveUser finds the user an identity is linked to. For unknown identities, it optionally links them to an existing
+// user with the same verified e-mail address or creates a new user, depending on configuration.
+func (srv *OidcService) ResolveUser(claims *models.OidcClaims) (*models.User, bool, error) {
+	if identity, err := srv.repository.GetBySubject(claims.Issuer, claims.Subject); err == nil {
+		user, err := srv.userService.GetUserById(identity.UserID)
+		return user, false, err
+	}
+
+	cfg := srv.config.Security.Oidc
+
+	if cfg.LinkByEmail && claims.EmailVerified && claims.Email != "" {
+		if user, err := srv.userService.GetUserByEmail(claims.Email); err == nil && user != nil {
+			if _, err := srv.Link(user, claims); err != nil {
+				return nil, false, err
+			}
+			return user, false, nil
+		}
+	}
+
+	if cfg.AutoProvision && srv.config.Security.AllowSignup {
+		user, err := srv.provisionUser(claims)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, err := srv.Link(user, claims); err != nil {
+			return nil, false, err
+		}
+		return user, true, nil
+	}
+
+	return nil, false, ErrOidcUnknownUser
+}
+
+// Link connects an identity to the given user, unless it is already linked to a different one
+func (srv *OidcService) Link(user *models.User, claims *models.OidcClaims) (*models.OidcIdentity, error) {
+	if existing, err := srv.repository.GetBySubject(claims.Issuer, claims.Subject); err == nil {
+		if existing.UserID != user.ID {
+			return nil, ErrOidcAlreadyLinked
+		}
+		return existing, nil
+	}
+
+	return srv.repository.Insert(&models.OidcIdentity{
+		UserID:  user.ID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	})
+}
+
+func (srv *OidcService) GetById(id uint) (*models.OidcIdentity, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *OidcService) GetByUser(userId string) ([]*models.OidcIdentity, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *OidcService) Delete(identity *models.OidcIdentity) error {
+	return srv.repository.Delete(identity.ID)
+}
+
+func (srv *OidcService) verifyIdToken(idToken, nonce string, metadata *models.OidcProviderMetadata) (*models.OidcClaims, error) {
+	// checks signature, issuer, audience and expiry
+	token, err := srv.getVerifier(metadata).Verify(srv.clientContext(), idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims models.OidcClaims
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("malformed id token claims: %v", err)
+	}
+	if err := token.Claims(&claims.Raw); err != nil {
+		return nil, fmt.Errorf("malformed id token claims: %v", err)
+	}
+
+	clientId := srv.config.Security.Oidc.ClientId
+	switch {
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != "" && claims.AuthorizedParty != clientId:
+		return nil, errors.New("id token authorized party mismatch")
+	case token.Nonce != nonce:
+		return nil, errors.New("id token nonce mismatch")
+	case token.Subject == "":
+		return nil, errors.New("id token is missing subject")
+	}
+
+	return &claims, nil
+}
+
+func (srv *OidcService) getMetadata() (*models.OidcProviderMetadata, error) {
+	if cached, ok := srv.cache.Get(oidcCacheKeyMetadata); ok {
+		return cached.(*models.OidcProviderMetadata), nil
+	}
+
+	issuer := srv.config.Security.Oidc.Issuer
+	var metadata models.OidcProviderMetadata
+	if err := srv.getJson(strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc provider metadata: %v", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("oidc provider metadata issuer '%s' does not match configured issuer '%s'", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksUri == "" {
+		return nil, errors.New("oidc provider metadata is incomplete")
+	}
+	if len(metadata.CodeChallengeMethods) > 0 && !slice.Contain(metadata.CodeChallengeMethods, "S256") {
+		return nil, errors.New("oidc provider does not support pkce with S256")
+	}
+
+	srv.cache.SetDefault(oidcCacheKeyMetadata, &metadata)
+	return &metadata, nil
+}
+
+// getVerifier returns an id token verifier for the provider, whose key set is fetched lazily and re-fetched when encountering unknown keys, e.g. after key rotation
+func (srv *OidcService) getVerifier(metadata *models.OidcProviderMetadata) *oidc.IDTokenVerifier {
+	if cached, ok := srv.cache.Get(oidcCacheKeyVerifier); ok {
+		return cached.(*oidc.IDTokenVerifier)
+	}
+
+	keySet := oidc.NewRemoteKeySet(srv.clientContext(), metadata.JwksUri)
+	verifier := oidc.NewVerifier(metadata.Issuer, keySet, &oidc.Config{
+		ClientID:             srv.config.Security.Oidc.ClientId,
+		SupportedSigningAlgs: oidcSigningAlgs,
+		Now:                  func() time.Time { return time.Now().Add(-oidcClockSkew) },
+	})
+
+	srv.cache.SetDefault(oidcCacheKeyVerifier, verifier)
+	return verifier
+}
+
+// clientContext makes the oidc library use the service's own http client
+func (srv *OidcService) clientContext() context.Context {
+	return oidc.ClientContext(context.Background(), srv.httpClient)
+}
+
+func (srv *OidcService) getJson(url string, target interface{}) error {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept", "application/json")
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %d from %s", res.StatusCode, url)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(target)
+}
+
+func (srv *OidcService) provisionUser(claims *models.OidcClaims) (*models.User, error) {
+	base := srv.deriveUsername(claims)
+
+	var email string
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+	var location string
+	if models.ValidateTimezone(claims.ZoneInfo) {
+		location = claims.ZoneInfo
+	}
+
+	numUsers, _ := srv.userService.Count()
+
+	// append a numeric suffix in case the username is already taken
+	for i := 1; i <= 10; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		signup := &models.Signup{
+			Username: username,
+			Email:    email,
+			Location: location,
+			Password: uuid.Must(uuid.NewV4()).String(), // not known to anyone, but can be reset via e-mail
+		}
+		user, created, err := srv.userService.CreateOrGet(signup, numUsers == 0)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find an available username for '%s'", base)
+}
+
+func (srv *OidcService) deriveUsername(claims *models.OidcClaims) string {
+	candidates := []string{
+		claims.ClaimString(srv.config.Security.Oidc.UsernameClaim),
+		claims.PreferredUsername,
+		strings.Split(claims.Email, "@")[0],
+	}
+	for _, c := range candidates {
+		username := strings.Trim(oidcUsernameSanitizer.ReplaceAllString(c, "_"), "_")
+		if len(username) > oidcMaxUsernameLen {
+			username = username[:oidcMaxUsernameLen]
+		}
+		if username != "" && models.ValidateUsername(username) {
+			return username
+		}
+	}
+	return "user-" + utils.RandomUrlToken(6)
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testOidcClientId = "wakapi"
+	testOidcCode     = "c0de"
+)
+
+// mockOidcIssuer is a minimal openid provider, serving discovery, keys and a token endpoint that issues id tokens with configurable claims
+type mockOidcIssuer struct {
+	Server       *httptest.Server
+	Key          *rsa.PrivateKey
+	Claims       map[string]interface{}
+	LastTokenReq url.Values
+}
+
+func newMockOidcIssuer() *mockOidcIssuer {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	issuer := &mockOidcIssuer{Key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                           issuer.Server.URL,
+			"authorization_endpoint":           issuer.Server.URL + "/authorize",
+			"token_endpoint":                   issuer.Server.URL + "/token",
+			"jwks_uri":                         issuer.Server.URL + "/jwks",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "key1",
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issuer.LastTokenReq = r.PostForm
+		if r.PostForm.Get("code") != testOidcCode {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": issuer.sign(issuer.Claims)})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (i *mockOidcIssuer) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "key1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, i.Key, crypto.SHA256, digest[:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+type OidcServiceTestSuite struct {
+	suite.Suite
+	Issuer                 *mockOidcIssuer
+	TestUser               *models.User
+	OidcIdentityRepository *mocks.OidcIdentityRepositoryMock
+	UserService            *mocks.UserServiceMock
+}
+
+func (suite *OidcServiceTestSuite) SetupSuite() {
+	suite.Issuer = newMockOidcIssuer()
+	suite.TestUser = &models.User{ID: "testuser01", Email: "alice@example.org"}
+}
+
+func (suite *OidcServiceTestSuite) TearDownSuite() {
+	suite.Issuer.Server.Close()
+}
+
+func (suite *OidcServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := config.Empty()
+	cfg.Server.PublicUrl = "http://localhost:3000"
+	cfg.Security.AllowSignup = true
+	cfg.Security.Oidc.Enabled = true
+	cfg.Security.Oidc.Issuer = suite.Issuer.Server.URL
+	cfg.Security.Oidc.ClientId = testOidcClientId
+	cfg.Security.Oidc.Scopes = "profile email"
+	cfg.Security.Oidc.UsernameClaim = "preferred_username"
+	config.Set(cfg)
+
+	suite.OidcIdentityRepository = new(mocks.OidcIdentityRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.Issuer.Claims = map[string]interface{}{
+		"iss":                suite.Issuer.Server.URL,
+		"sub":                "alice-sub",
+		"aud":                testOidcClientId,
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"email":              "alice@example.org",
+		"email_verified":     true,
+		"preferred_username": "Alice Smith",
+	}
+}
+
+func TestOidcServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(OidcServiceTestSuite))
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_AuthCodeUrl() {
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	state := sut.NewAuthState("")
+
+	authUrl, err := sut.AuthCodeUrl(state)
+	assert.Nil(suite.T(), err)
+
+	parsed, _ := url.Parse(authUrl)
+	q := parsed.Query()
+	assert.Equal(suite.T(), suite.Issuer.Server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(suite.T(), "code", q.Get("response_type"))
+	assert.Equal(suite.T(), testOidcClientId, q.Get("client_id"))
+	assert.Equal(suite.T(), "http://localhost:3000/login/oidc/callback", q.Get("redirect_uri"))
+	assert.Equal(suite.T(), "openid profile email", q.Get("scope"))
+	assert.Equal(suite.T(), state.State, q.Get("state"))
+	assert.Equal(suite.T(), state.Nonce, q.Get("nonce"))
+	assert.Equal(suite.T(), utils.PkceChallenge(state.CodeVerifier), q.Get("code_challenge"))
+	assert.Equal(suite.T(), "S256", q.Get("code_challenge_method"))
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Exchange() {
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	state := sut.NewAuthState("")
+	suite.Issuer.Claims["nonce"] = state.Nonce
+
+	claims, err := sut.Exchange(testOidcCode, state)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "alice-sub", claims.Subject)
+	assert.Equal(suite.T(), "alice@example.org", claims.Email)
+	assert.True(suite.T(), claims.EmailVerified)
+	assert.Equal(suite.T(), state.CodeVerifier, suite.Issuer.LastTokenReq.Get("code_verifier"))
+	assert.Equal(suite.T(), "authorization_code", suite.Issuer.LastTokenReq.Get("grant_type"))
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Exchange_Invalid() {
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	state := sut.NewAuthState("")
+
+	// wrong nonce
+	suite.Issuer.Claims["nonce"] = "foo"
+	_, err := sut.Exchange(testOidcCode, state)
+	assert.ErrorContains(suite.T(), err, "nonce")
+
+	// wrong audience
+	suite.Issuer.Claims["nonce"] = state.Nonce
+	suite.Issuer.Claims["aud"] = []string{"another-client"}
+	_, err = sut.Exchange(testOidcCode, state)
+	assert.ErrorContains(suite.T(), err, "audience")
+
+	// expired
+	suite.Issuer.Claims["aud"] = testOidcClientId
+	suite.Issuer.Claims["exp"] = time.Now().Add(-1 * time.Hour).Unix()
+	_, err = sut.Exchange(testOidcCode, state)
+	assert.ErrorContains(suite.T(), err, "expired")
+
+	// invalid code
+	suite.Issuer.Claims["exp"] = time.Now().Add(5 * time.Minute).Unix()
+	_, err = sut.Exchange("invalid", state)
+	assert.ErrorContains(suite.T(), err, "invalid_grant")
+
+	// signed by another key
+	otherIssuer := newMockOidcIssuer()
+	defer otherIssuer.Server.Close()
+	defer func(key *rsa.PrivateKey) { suite.Issuer.Key = key }(suite.Issuer.Key)
+	suite.Issuer.Key = otherIssuer.Key
+	_, err = sut.Exchange(testOidcCode, state)
+	assert.ErrorContains(suite.T(), err, "signature")
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_ResolveUser_Linked() {
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	claims := &models.OidcClaims{Issuer: suite.Issuer.Server.URL, Subject: "alice-sub"}
+
+	suite.OidcIdentityRepository.On("GetBySubject", claims.Issuer, claims.Subject).Return(&models.OidcIdentity{ID: 1, UserID: suite.TestUser.ID}, nil)
+	suite.UserService.On("GetUserById", suite.TestUser.ID).Return(suite.TestUser, nil)
+
+	user, created, err := sut.ResolveUser(claims)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), created)
+	assert.Equal(suite.T(), suite.TestUser, user)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_ResolveUser_Unknown() {
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	claims := &models.OidcClaims{Issuer: suite.Issuer.Server.URL, Subject: "alice-sub", Email: "alice@example.org", EmailVerified: true}
+
+	suite.OidcIdentityRepository.On("GetBySubject", claims.Issuer, claims.Subject).Return((*models.OidcIdentity)(nil), errors.New("not found"))
+
+	_, _, err := sut.ResolveUser(claims)
+	assert.ErrorIs(suite.T(), err, ErrOidcUnknownUser)
+	suite.UserService.AssertNotCalled(suite.T(), "GetUserByEmail", mock.Anything)
+	suite.UserService.AssertNotCalled(suite.T(), "CreateOrGet", mock.Anything, mock.Anything)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_ResolveUser_LinkByEmail() {
+	config.Get().Security.Oidc.LinkByEmail = true
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	claims := &models.OidcClaims{Issuer: suite.Issuer.Server.URL, Subject: "alice-sub", Email: "alice@example.org", EmailVerified: true}
+
+	suite.OidcIdentityRepository.On("GetBySubject", claims.Issuer, claims.Subject).Return((*models.OidcIdentity)(nil), errors.New("not found"))
+	suite.OidcIdentityRepository.On("Insert", mock.Anything).Return(&models.OidcIdentity{}, nil)
+	suite.UserService.On("GetUserByEmail", "alice@example.org").Return(suite.TestUser, nil)
+
+	user, created, err := sut.ResolveUser(claims)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), created)
+	assert.Equal(suite.T(), suite.TestUser, user)
+
+	suite.OidcIdentityRepository.AssertCalled(suite.T(), "Insert", mock.MatchedBy(func(i *models.OidcIdentity) bool {
+		return i.UserID == suite.TestUser.ID && i.Subject == "alice-sub" && i.Issuer == claims.Issuer
+	}))
+
+	// unverified e-mail addresses must not be linked
+	claims.EmailVerified = false
+	_, _, err = sut.ResolveUser(claims)
+	assert.ErrorIs(suite.T(), err, ErrOidcUnknownUser)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_ResolveUser_AutoProvision() {
+	config.Get().Security.Oidc.AutoProvision = true
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	claims := &models.OidcClaims{Issuer: suite.Issuer.Server.URL, Subject: "alice-sub", Email: "alice@example.org", EmailVerified: true, PreferredUsername: "Alice Smith", Raw: map[string]interface{}{"preferred_username": "Alice Smith"}}
+
+	suite.OidcIdentityRepository.On("GetBySubject", claims.Issuer, claims.Subject).Return((*models.OidcIdentity)(nil), errors.New("not found"))
+	suite.OidcIdentityRepository.On("Insert", mock.Anything).Return(&models.OidcIdentity{}, nil)
+	suite.UserService.On("Count").Return(1, nil)
+	suite.UserService.On("CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool { return s.Username == "Alice_Smith" }), false).Return(&models.User{ID: "Alice_Smith"}, false, nil)
+	suite.UserService.On("CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool { return s.Username == "Alice_Smith-2" }), false).Return(&models.User{ID: "Alice_Smith-2"}, true, nil)
+
+	user, created, err := sut.ResolveUser(claims)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), created)
+	assert.Equal(suite.T(), "Alice_Smith-2", user.ID)
+
+	suite.UserService.AssertCalled(suite.T(), "CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool {
+		return s.Username == "Alice_Smith-2" && s.Email == "alice@example.org" && s.Password != ""
+	}), false)
+
+	// signups disabled
+	config.Get().Security.AllowSignup = false
+	_, _, err = sut.ResolveUser(claims)
+	assert.ErrorIs(suite.T(), err, ErrOidcUnknownUser)
+}
+
+func (suite *OidcServiceTestSuite) TestOidcService_Link_AlreadyLinked() {
+	sut := NewOidcService(suite.OidcIdentityRepository, suite.UserService)
+	claims := &models.OidcClaims{Issuer: suite.Issuer.Server.URL, Subject: "alice-sub"}
+
+	suite.OidcIdentityRepository.On("GetBySubject", claims.Issuer, claims.Subject).Return(&models.OidcIdentity{ID: 1, UserID: "someone-else"}, nil)
+
+	_, err := sut.Link(suite.TestUser, claims)
+	assert.ErrorIs(suite.T(), err, ErrOidcAlreadyLinked)
+	suite.OidcIdentityRepository.AssertNotCalled(suite.T(), "Insert", mock.Anything)
+}
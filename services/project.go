@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const maxProjectNameLength = 255
+
+type ProjectService struct {
+	config              *config.Config
+	cache               *cache.Cache
+	queue               *artifex.Dispatcher
+	repository          repositories.IArchivedProjectRepository
+	heartbeatService    IHeartbeatService
+	durationService     IDurationService
+	aggregationService  IAggregationService
+	projectLabelService IProjectLabelService
+	rewriting           map[string]bool // users with a rename or merge job in progress
+	lock                sync.Mutex
+}
+
+func NewProjectService(archivedProjectRepository repositories.IArchivedProjectRepository, heartbeatService IHeartbeatService, durationService IDurationService, aggregationService IAggregationService, projectLabelService IProjectLabelService) *ProjectService {
+	return &ProjectService{
+		config:              config.Get(),
+		cache:               cache.New(24*time.Hour, 24*time.Hour),
+		queue:               config.GetQueue(config.QueueProcessing),
+		repository:          archivedProjectRepository,
+		heartbeatService:    heartbeatService,
+		durationService:     durationService,
+		aggregationService:  aggregationService,
+		projectLabelService: projectLabelService,
+		rewriting:           map[string]bool{},
+	}
+}
+
+// GetArchived returns the names of the user's archived projects
+func (srv *ProjectService) GetArchived(userId string) ([]string, error) {
+	archived, err := srv.getArchivedByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Map[*models.ArchivedProject, string](archived, func(i int, p *models.ArchivedProject) string {
+		return p.ProjectKey
+	}), nil
+}
+
+func (srv *ProjectService) Archive(user *models.User, project string) error {
+	archived, err := srv.GetArchived(user.ID)
+	if err != nil {
+		return err
+	}
+	if slice.Contain(archived, project) {
+		return nil
+	}
+
+	defer srv.cache.Delete(user.ID)
+	_, err = srv.repository.Insert(&models.ArchivedProject{UserID: user.ID, ProjectKey: project})
+	return err
+}
+
+func (srv *ProjectService) Unarchive(user *models.User, project string) error {
+	return srv.unarchive(user, []string{project})
+}
+
+// IsRewriting tells whether a rename or merge job is currently running for the given user
+func (srv *ProjectService) IsRewriting(userId string) bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return srv.rewriting[userId]
+}
+
+// Rename permanently renames the project in all of the user's heartbeats in the background, see Merge
+func (srv *ProjectService) Rename(user *models.User, project, newName string) error {
+	return srv.Merge(user, []string{project}, newName)
+}
+
+// Merge permanently assigns all of the user's heartbeats of the given projects to the target project, which may or may not exist already.
+// Project labels are carried over to the target and summaries and durations are regenerated. All of this is done in the background.
+func (srv *ProjectService) Merge(user *models.User, projects []string, target string) error {
+	target = strings.TrimSpace(target)
+	if target == "" || len(target) > maxProjectNameLength {
+		return errors.New("invalid project name")
+	}
+
+	projects = slice.Filter[string](slice.Unique(projects), func(i int, p string) bool {
+		return p != "" && p != target
+	})
+	if len(projects) == 0 {
+		return errors.New("no projects to rename")
+	}
+
+	srv.lock.Lock()
+	if srv.rewriting[user.ID] {
+		srv.lock.Unlock()
+		return errors.New("another project is being renamed or merged, please wait")
+	}
+	srv.rewriting[user.ID] = true
+	srv.lock.Unlock()
+
+	if err := srv.queue.Dispatch(func() {
+		defer srv.unlockRewriting(user.ID)
+		if err := srv.rewrite(user, projects, target); err != nil {
+			config.Log().Error("failed to rename projects", "userID", user.ID, "projects", projects, "target", target, "error", err)
+		}
+	}); err != nil {
+		srv.unlockRewriting(user.ID)
+		return err
+	}
+
+	return nil
+}
+
+func (srv *ProjectService) rewrite(user *models.User, projects []string, target string) error {
+	slog.Info("renaming projects", "userID", user.ID, "projects", projects, "target", target)
+
+	// determine the range of summaries to regenerate
+	filters := models.NewFilterWithMultiple(models.SummaryProject, projects)
+	first, err := srv.heartbeatService.GetFirstByFilters(user, filters)
+	if err != nil {
+		return err
+	}
+	latest, err := srv.heartbeatService.GetLatestByFilters(user, filters)
+	if err != nil {
+		return err
+	}
+	if first == nil || latest == nil {
+		return errors.New("no heartbeats found for projects")
+	}
+
+	count, err := srv.heartbeatService.RenameProjects(user, projects, target)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.moveLabels(user, projects, target); err != nil {
+		return err
+	}
+
+	// the target keeps its own archived state
+	if err := srv.unarchive(user, projects); err != nil {
+		return err
+	}
+
+	srv.durationService.Regenerate(user, true)
+
+	summaries, err := srv.aggregationService.RegenerateSummariesWithin(user, first.Time.T().Local(), latest.Time.T().Local())
+	if err != nil {
+		return err
+	}
+
+	slog.Info("finished renaming projects", "userID", user.ID, "target", target, "heartbeats", count, "summaries", summaries)
+	return nil
+}
+
+// moveLabels assigns the labels of the given projects to the target project, unless it has them already
+func (srv *ProjectService) moveLabels(user *models.User, projects []string, target string) error {
+	labels, err := srv.projectLabelService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	targetLabels := make(map[string]bool)
+	for _, l := range labels {
+		if l.ProjectKey == target {
+			targetLabels[l.Label] = true
+		}
+	}
+
+	for _, l := range labels {
+		if !slice.Contain(projects, l.ProjectKey) {
+			continue
+		}
+		if !targetLabels[l.Label] {
+			if _, err := srv.projectLabelService.Create(&models.ProjectLabel{UserID: user.ID, ProjectKey: target, Label: l.Label}); err != nil {
+				return err
+			}
+			targetLabels[l.Label] = true
+		}
+		if err := srv.projectLabelService.Delete(l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (srv *ProjectService) unarchive(user *models.User, projects []string) error {
+	archived, err := srv.getArchivedByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	defer srv.cache.Delete(user.ID)
+	for _, p := range archived {
+		if slice.Contain(projects, p.ProjectKey) {
+			if err := srv.repository.Delete(p.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (srv *ProjectService) getArchivedByUser(userId string) ([]*models.ArchivedProject, error) {
+	if archived, found := srv.cache.Get(userId); found {
+		return archived.([]*models.ArchivedProject), nil
+	}
+
+	archived, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, archived, cache.DefaultExpiration)
+	return archived, nil
+}
+
+func (srv *ProjectService) unlockRewriting(userId string) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	delete(srv.rewriting, userId)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProjectServiceTestSuite struct {
+	suite.Suite
+	TestUser                  *models.User
+	ArchivedProjectRepository *mocks.ArchivedProjectRepositoryMock
+	HeartbeatService          *mocks.HeartbeatServiceMock
+	DurationService           *mocks.DurationServiceMock
+	AggregationService        *mocks.AggregationServiceMock
+	ProjectLabelService       *mocks.ProjectLabelServiceMock
+}
+
+func (suite *ProjectServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01"}
+}
+
+func (suite *ProjectServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.ArchivedProjectRepository = new(mocks.ArchivedProjectRepositoryMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+}
+
+func TestProjectServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectServiceTestSuite))
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Archive() {
+	sut := NewProjectService(suite.ArchivedProjectRepository, suite.HeartbeatService, suite.DurationService, suite.AggregationService, suite.ProjectLabelService)
+
+	existing := []*models.ArchivedProject{{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "old-project"}}
+	suite.ArchivedProjectRepository.On("GetByUser", suite.TestUser.ID).Return(existing, nil)
+	suite.ArchivedProjectRepository.On("Insert", mock.Anything).Return(&models.ArchivedProject{}, nil)
+	suite.ArchivedProjectRepository.On("Delete", uint(1)).Return(nil)
+
+	assert.Nil(suite.T(), sut.Archive(suite.TestUser, "old-project")) // already archived
+	assert.Nil(suite.T(), sut.Archive(suite.TestUser, "wakapi"))
+	assert.Nil(suite.T(), sut.Unarchive(suite.TestUser, "old-project"))
+
+	suite.ArchivedProjectRepository.AssertNumberOfCalls(suite.T(), "Insert", 1)
+	suite.ArchivedProjectRepository.AssertCalled(suite.T(), "Insert", &models.ArchivedProject{UserID: suite.TestUser.ID, ProjectKey: "wakapi"})
+	suite.ArchivedProjectRepository.AssertNumberOfCalls(suite.T(), "Delete", 1)
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Merge_Invalid() {
+	sut := NewProjectService(suite.ArchivedProjectRepository, suite.HeartbeatService, suite.DurationService, suite.AggregationService, suite.ProjectLabelService)
+
+	assert.Error(suite.T(), sut.Merge(suite.TestUser, []string{"wakapi"}, "  "))
+	assert.Error(suite.T(), sut.Merge(suite.TestUser, []string{"wakapi"}, "wakapi"))
+	assert.Error(suite.T(), sut.Rename(suite.TestUser, "wakapi", " wakapi "))
+	assert.False(suite.T(), sut.IsRewriting(suite.TestUser.ID))
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Rewrite() {
+	sut := NewProjectService(suite.ArchivedProjectRepository, suite.HeartbeatService, suite.DurationService, suite.AggregationService, suite.ProjectLabelService)
+
+	projects := []string{"wakapi-old", "wakapi-fork"}
+	filters := models.NewFilterWithMultiple(models.SummaryProject, projects)
+	first := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	latest := time.Date(2024, 3, 20, 18, 0, 0, 0, time.UTC)
+
+	labels := []*models.ProjectLabel{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi-old", Label: "oss"},
+		{ID: 2, UserID: suite.TestUser.ID, ProjectKey: "wakapi-fork", Label: "work"},
+		{ID: 3, UserID: suite.TestUser.ID, ProjectKey: "wakapi", Label: "oss"},
+	}
+	archived := []*models.ArchivedProject{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi-fork"},
+		{ID: 2, UserID: suite.TestUser.ID, ProjectKey: "other"},
+	}
+
+	suite.HeartbeatService.On("GetFirstByFilters", suite.TestUser, filters).Return(&models.Heartbeat{Time: models.CustomTime(first)}, nil)
+	suite.HeartbeatService.On("GetLatestByFilters", suite.TestUser, filters).Return(&models.Heartbeat{Time: models.CustomTime(latest)}, nil)
+	suite.HeartbeatService.On("RenameProjects", suite.TestUser, projects, "wakapi").Return(int64(42), nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(labels, nil)
+	suite.ProjectLabelService.On("Create", mock.Anything).Return(&models.ProjectLabel{}, nil)
+	suite.ProjectLabelService.On("Delete", mock.Anything).Return(nil)
+	suite.ArchivedProjectRepository.On("GetByUser", suite.TestUser.ID).Return(archived, nil)
+	suite.ArchivedProjectRepository.On("Delete", uint(1)).Return(nil)
+	suite.DurationService.On("Regenerate", suite.TestUser, true).Return()
+	suite.AggregationService.On("RegenerateSummariesWithin", suite.TestUser, first.Local(), latest.Local()).Return(71, nil)
+
+	err := sut.rewrite(suite.TestUser, projects, "wakapi")
+	assert.Nil(suite.T(), err)
+
+	// "oss" label exists for target already, "work" label is carried over
+	suite.ProjectLabelService.AssertNumberOfCalls(suite.T(), "Create", 1)
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Create", &models.ProjectLabel{UserID: suite.TestUser.ID, ProjectKey: "wakapi", Label: "work"})
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Delete", labels[0])
+	suite.ProjectLabelService.AssertCalled(suite.T(), "Delete", labels[1])
+	suite.ProjectLabelService.AssertNotCalled(suite.T(), "Delete", labels[2])
+
+	suite.ArchivedProjectRepository.AssertNumberOfCalls(suite.T(), "Delete", 1)
+	suite.DurationService.AssertCalled(suite.T(), "Regenerate", suite.TestUser, true)
+	suite.AggregationService.AssertCalled(suite.T(), "RegenerateSummariesWithin", suite.TestUser, first.Local(), latest.Local())
+}
+
+func (suite *ProjectServiceTestSuite) TestProjectService_Rewrite_NoHeartbeats() {
+	sut := NewProjectService(suite.ArchivedProjectRepository, suite.HeartbeatService, suite.DurationService, suite.AggregationService, suite.ProjectLabelService)
+
+	filters := models.NewFilterWithMultiple(models.SummaryProject, []string{"unknown"})
+	suite.HeartbeatService.On("GetFirstByFilters", suite.TestUser, filters).Return((*models.Heartbeat)(nil), nil)
+	suite.HeartbeatService.On("GetLatestByFilters", suite.TestUser, filters).Return((*models.Heartbeat)(nil), nil)
+
+	assert.Error(suite.T(), sut.rewrite(suite.TestUser, []string{"unknown"}, "wakapi"))
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "RenameProjects", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+// delays between consecutive attempts to forward a batch of heartbeats, after the first attempt failed, the last one is repeated until the batch expires
+var relayRetryDelays = []time.Duration{1 * time.Minute, 5 * time.Minute, 30 * time.Minute, 1 * time.Hour}
+
+const (
+	relayMaxFailures    = 100                // consecutive failures after which a target is paused and its owner is notified
+	relayQueueRetention = 7 * 24 * time.Hour // queued heartbeats older than this are dropped
+	relayQueueBatchSize = 100
+)
+
+type RelayService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	eventBus     *hub.Hub
+	repository   repositories.IRelayRepository
+	httpClient   *http.Client // refuses to connect to local or private addresses, except for the ones allowed by config
+	queueDefault *artifex.Dispatcher
+	queueWorkers *artifex.Dispatcher
+	queueLock    sync.Mutex
+}
+
+func NewRelayService(relayRepository repositories.IRelayRepository) *RelayService {
+	return &RelayService{
+		config:       config.Get(),
+		cache:        cache.New(1*time.Hour, 2*time.Hour),
+		eventBus:     config.EventBus(),
+		repository:   relayRepository,
+		httpClient:   utils.NewPublicOnlyHttpClient(10*time.Second, config.Get().Security.GetAllowedPrivateHosts()),
+		queueDefault: config.GetDefaultQueue(),
+		queueWorkers: config.GetQueue(config.QueueRelay),
+	}
+}
+
+func (srv *RelayService) Schedule() {
+	slog.Info("scheduling relay retry queue processing")
+
+	if _, err := srv.queueDefault.DispatchCron(func() {
+		if err := srv.queueWorkers.Dispatch(srv.ProcessQueue); err != nil {
+			config.Log().Error("failed to dispatch relay queue processing", "error", err)
+		}
+	}, "30 * * * * *"); err != nil {
+		config.Log().Error("failed to schedule relay queue processing", "error", err)
+	}
+
+	if _, err := srv.queueDefault.DispatchCron(func() {
+		if n, err := srv.repository.DeleteQueueItemsBefore(time.Now().Add(-relayQueueRetention)); err != nil {
+			config.Log().Error("failed to delete expired relay queue items", "error", err)
+		} else if n > 0 {
+			slog.Warn("dropped expired heartbeats from relay queue", "count", n)
+		}
+	}, "0 40 4 * * *"); err != nil {
+		config.Log().Error("failed to schedule relay queue cleanup", "error", err)
+	}
+}
+
+func (srv *RelayService) GetById(id uint) (*models.RelayTarget, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *RelayService) GetByUser(userId string) ([]*models.RelayTarget, error) {
+	if targets, ok := srv.cache.Get(userId); ok {
+		return targets.([]*models.RelayTarget), nil
+	}
+	targets, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(userId, targets)
+	return targets, nil
+}
+
+func (srv *RelayService) Create(target *models.RelayTarget) (*models.RelayTarget, error) {
+	target.Name = strings.TrimSpace(target.Name)
+	target.ApiUrl = strings.TrimSuffix(strings.TrimSpace(target.ApiUrl), "/")
+	target.Enabled = true
+
+	result, err := srv.repository.Insert(target)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(target.UserID)
+	return result, nil
+}
+
+func (srv *RelayService) Update(target *models.RelayTarget) (*models.RelayTarget, error) {
+	srv.cache.Delete(target.UserID)
+	return srv.repository.Update(target)
+}
+
+func (srv *RelayService) Delete(target *models.RelayTarget) error {
+	srv.cache.Delete(target.UserID)
+	return srv.repository.Delete(target.ID)
+}
+
+// CountQueued returns the number of heartbeat batches waiting to be retried for the given target
+func (srv *RelayService) CountQueued(target *models.RelayTarget) (int64, error) {
+	return srv.repository.CountQueueItems(target.ID)
+}
+
+// Forward asynchronously sends the given heartbeats to all of the user's enabled relay targets, whose project filters they pass.
+// Heartbeats are forwarded in their raw json representation as originally sent by the client (rawHeartbeats), while the parsed counterparts at the same indices are only used for filtering.
+// Batches, that can't be delivered, are persisted to be retried later.
+func (srv *RelayService) Forward(user *models.User, heartbeats []*models.Heartbeat, rawHeartbeats []interface{}, header http.Header) {
+	targets, err := srv.GetByUser(user.ID)
+	if err != nil {
+		config.Log().Error("failed to get relay targets for user", "userID", user.ID, "error", err)
+		return
+	}
+
+	// prevent cycles
+	originInstance := header.Get(models.RelayHeaderOriginInstance)
+	if originInstance == "" {
+		originInstance = srv.config.InstanceId
+	}
+
+	for _, target := range targets {
+		if !target.Enabled {
+			continue
+		}
+
+		data := make([]interface{}, 0, len(heartbeats))
+		for i, hb := range heartbeats {
+			if target.Matches(hb.Project) {
+				data = append(data, rawHeartbeats[i])
+			}
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		payload, err := json.Marshal(data)
+		if err != nil {
+			config.Log().Error("failed to serialize heartbeats to relay", "targetID", target.ID, "error", err)
+			continue
+		}
+
+		item := &models.RelayQueueItem{
+			TargetID:       target.ID,
+			Payload:        string(payload),
+			UserAgent:      header.Get("User-Agent"),
+			MachineName:    header.Get("X-Machine-Name"),
+			OriginInstance: originInstance,
+		}
+
+		job := func(target *models.RelayTarget, item *models.RelayQueueItem) func() {
+			return func() {
+				if err := srv.deliver(target, item); err != nil {
+					slog.Warn("failed to relay heartbeats, queuing for retry", "targetID", target.ID, "userID", user.ID, "error", err)
+					srv.enqueue(item)
+					srv.registerFailure(target, user, err)
+				} else if target.FailureCount > 0 {
+					srv.registerSuccess(target)
+				}
+			}
+		}(target, item)
+
+		if err := srv.queueWorkers.Dispatch(job); err != nil {
+			config.Log().Error("failed to dispatch relay job", "targetID", target.ID, "error", err)
+		}
+	}
+}
+
+// ProcessQueue retries forwarding all queued heartbeat batches, which are due
+func (srv *RelayService) ProcessQueue() {
+	if !srv.queueLock.TryLock() {
+		return // previous run still in progress
+	}
+	defer srv.queueLock.Unlock()
+
+	items, err := srv.repository.GetQueueItemsDue(time.Now(), relayQueueBatchSize)
+	if err != nil {
+		config.Log().Error("failed to fetch relay queue items", "error", err)
+		return
+	}
+
+	failedTargets := make(map[uint]bool)
+
+	for _, item := range items {
+		target := item.Target
+		if failedTargets[target.ID] {
+			srv.reschedule(item, false)
+			continue
+		}
+
+		if err := srv.deliver(target, item); err != nil {
+			failedTargets[target.ID] = true // don't hammer the target with the remaining batches in this run
+			srv.reschedule(item, true)
+			srv.registerFailure(target, target.User, err)
+			continue
+		}
+
+		if err := srv.repository.DeleteQueueItem(item.ID); err != nil {
+			config.Log().Error("failed to delete relay queue item", "itemID", item.ID, "error", err)
+		}
+		if target.FailureCount > 0 {
+			srv.registerSuccess(target)
+			target.FailureCount = 0
+		}
+	}
+}
+
+// deliver performs a single attempt to post the batch of heartbeats to the target
+func (srv *RelayService) deliver(target *models.RelayTarget, item *models.RelayQueueItem) error {
+	req, err := http.NewRequest(http.MethodPost, target.HeartbeatsUrl(), bytes.NewReader([]byte(item.Payload)))
+	if err != nil {
+		return err
+	}
+
+	userAgent := item.UserAgent
+	if userAgent == "" {
+		userAgent = fmt.Sprintf("wakapi/%s", srv.config.Version)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(target.ApiKey))))
+	req.Header.Set(models.RelayHeaderOrigin, fmt.Sprintf("wakapi v%s", srv.config.Version))
+	req.Header.Set(models.RelayHeaderOriginInstance, item.OriginInstance)
+	if item.MachineName != "" {
+		req.Header.Set("X-Machine-Name", item.MachineName)
+	}
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.New(res.Status)
+	}
+	return nil
+}
+
+func (srv *RelayService) enqueue(item *models.RelayQueueItem) {
+	item.Attempts = 1
+	item.NextAttemptAt = models.CustomTime(time.Now().Add(relayRetryDelays[0]))
+	if _, err := srv.repository.InsertQueueItem(item); err != nil {
+		config.Log().Error("failed to queue heartbeats for relay retry", "targetID", item.TargetID, "error", err)
+	}
+}
+
+// reschedule postpones the item's next attempt, with increasing delays if the attempt was actually made
+func (srv *RelayService) reschedule(item *models.RelayQueueItem, attempted bool) {
+	if attempted {
+		item.Attempts++
+	}
+	delay := relayRetryDelays[min(item.Attempts, len(relayRetryDelays))-1]
+	item.NextAttemptAt = models.CustomTime(time.Now().Add(delay))
+	if _, err := srv.repository.UpdateQueueItem(item); err != nil {
+		config.Log().Error("failed to update relay queue item", "itemID", item.ID, "error", err)
+	}
+}
+
+func (srv *RelayService) registerSuccess(target *models.RelayTarget) {
+	if err := srv.repository.ResetFailures(target.ID); err != nil {
+		config.Log().Error("failed to reset relay target failures", "targetID", target.ID, "error", err)
+	}
+	srv.cache.Delete(target.UserID)
+}
+
+// registerFailure counts a failed attempt for the target and, once there were too many of them in a row, pauses the target and notifies its owner
+func (srv *RelayService) registerFailure(target *models.RelayTarget, user *models.User, cause error) {
+	defer srv.cache.Delete(target.UserID)
+
+	n, err := srv.repository.IncrementFailures(target.ID, cause.Error())
+	if err != nil {
+		config.Log().Error("failed to count relay target failure", "targetID", target.ID, "error", err)
+		return
+	}
+
+	if n < relayMaxFailures {
+		if n%10 == 0 {
+			slog.Warn("failed heartbeat relaying attempts for target", "failedCount", n, "maxFailures", relayMaxFailures, "targetID", target.ID, "userID", target.UserID)
+		}
+		return
+	}
+	if n > relayMaxFailures {
+		return // already paused and notified
+	}
+
+	slog.Warn("pausing relay target due to too many failures", "targetID", target.ID, "userID", target.UserID, "failureCount", n)
+
+	paused := *target
+	paused.Enabled = false
+	paused.FailureCount = n
+	paused.LastError = cause.Error()
+	if _, err := srv.repository.Update(&paused); err != nil {
+		config.Log().Error("failed to pause relay target", "targetID", target.ID, "error", err)
+	}
+
+	if user != nil {
+		srv.eventBus.Publish(hub.Message{
+			Name:   config.EventWakatimeFailure,
+			Fields: map[string]interface{}{config.FieldUser: user, config.FieldPayload: n, config.FieldRelayTarget: &paused},
+		})
+	}
+}
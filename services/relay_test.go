@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type receivedRelay struct {
+	Header     http.Header
+	Heartbeats []map[string]interface{}
+}
+
+type RelayServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	RelayRepository *mocks.RelayRepositoryMock
+	Server          *httptest.Server
+	ServerStatus    int
+	Received        chan *receivedRelay
+}
+
+func (suite *RelayServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	config.Get().InstanceId = "instance01"
+	config.Get().Security.AllowedPrivateHosts = "127.0.0.1" // test server listens on loopback
+
+	suite.TestUser = &models.User{ID: "testuser01", Email: "testuser01@example.org"}
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var heartbeats []map[string]interface{}
+		json.Unmarshal(body, &heartbeats)
+		if strings.HasPrefix(r.URL.Path, "/broken") {
+			w.WriteHeader(http.StatusBadGateway)
+		} else {
+			w.WriteHeader(suite.ServerStatus)
+		}
+		suite.Received <- &receivedRelay{Header: r.Header, Heartbeats: heartbeats}
+	}))
+}
+
+func (suite *RelayServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *RelayServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.RelayRepository = new(mocks.RelayRepositoryMock)
+	suite.ServerStatus = http.StatusCreated
+	suite.Received = make(chan *receivedRelay, 10)
+}
+
+func TestRelayServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RelayServiceTestSuite))
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_Forward_FiltersProjects() {
+	sut := NewRelayService(suite.RelayRepository)
+
+	targets := []*models.RelayTarget{
+		{ID: 1, UserID: suite.TestUser.ID, Name: "WakaTime", ApiUrl: suite.Server.URL, ApiKey: "key1", ExcludeProjects: "secret-*", Enabled: true},
+		{ID: 2, UserID: suite.TestUser.ID, Name: "Company", ApiUrl: suite.Server.URL + "/", ApiKey: "key2", IncludeProjects: "wakapi, anchr", Enabled: true},
+		{ID: 3, UserID: suite.TestUser.ID, Name: "Paused", ApiUrl: suite.Server.URL, ApiKey: "key3", Enabled: false},
+	}
+	suite.RelayRepository.On("GetByUser", suite.TestUser.ID).Return(targets, nil)
+
+	heartbeats, raw := relayTestHeartbeats("wakapi", "secret-project", "other")
+	header := http.Header{}
+	header.Set("User-Agent", "wakatime/v1.90.0 (linux) go1.21 vscode/1.85.0")
+
+	sut.Forward(suite.TestUser, heartbeats, raw, header)
+
+	received := suite.awaitReceived(2)
+	assert.Len(suite.T(), received, 2)
+
+	projectsByKey := make(map[string][]string)
+	for _, r := range received {
+		projects := make([]string, 0)
+		for _, hb := range r.Heartbeats {
+			projects = append(projects, hb["project"].(string))
+		}
+		projectsByKey[r.Header.Get("Authorization")] = projects
+
+		assert.Equal(suite.T(), header.Get("User-Agent"), r.Header.Get("User-Agent"))
+		assert.Equal(suite.T(), "instance01", r.Header.Get(models.RelayHeaderOriginInstance))
+	}
+	assert.ElementsMatch(suite.T(), []string{"wakapi", "other"}, projectsByKey[relayTestAuth("key1")])
+	assert.ElementsMatch(suite.T(), []string{"wakapi"}, projectsByKey[relayTestAuth("key2")])
+	assert.NotContains(suite.T(), projectsByKey, relayTestAuth("key3"))
+
+	suite.RelayRepository.AssertNotCalled(suite.T(), "InsertQueueItem", mock.Anything)
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_Forward_KeepsOrigin() {
+	sut := NewRelayService(suite.RelayRepository)
+
+	targets := []*models.RelayTarget{
+		{ID: 1, UserID: suite.TestUser.ID, Name: "Company", ApiUrl: suite.Server.URL, ApiKey: "key1", Enabled: true},
+	}
+	suite.RelayRepository.On("GetByUser", suite.TestUser.ID).Return(targets, nil)
+
+	heartbeats, raw := relayTestHeartbeats("wakapi")
+	header := http.Header{}
+	header.Set(models.RelayHeaderOriginInstance, "instance02")
+
+	sut.Forward(suite.TestUser, heartbeats, raw, header)
+
+	received := suite.awaitReceived(1)
+	assert.Len(suite.T(), received, 1)
+	assert.Equal(suite.T(), "instance02", received[0].Header.Get(models.RelayHeaderOriginInstance))
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_Forward_QueuesOnFailure() {
+	sut := NewRelayService(suite.RelayRepository)
+	suite.ServerStatus = http.StatusServiceUnavailable
+
+	target := &models.RelayTarget{ID: 1, UserID: suite.TestUser.ID, Name: "Company", ApiUrl: suite.Server.URL, ApiKey: "key1", Enabled: true}
+	suite.RelayRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayTarget{target}, nil)
+	suite.RelayRepository.On("InsertQueueItem", mock.Anything).Return(&models.RelayQueueItem{}, nil)
+	suite.RelayRepository.On("IncrementFailures", target.ID, mock.Anything).Return(relayMaxFailures, nil)
+	suite.RelayRepository.On("Update", mock.Anything).Return(target, nil)
+
+	sub := config.EventBus().Subscribe(1, config.EventWakatimeFailure)
+	defer config.EventBus().Unsubscribe(sub)
+
+	heartbeats, raw := relayTestHeartbeats("wakapi")
+	sut.Forward(suite.TestUser, heartbeats, raw, http.Header{})
+
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(suite.T(), suite.TestUser, m.Fields[config.FieldUser])
+		assert.Equal(suite.T(), relayMaxFailures, m.Fields[config.FieldPayload])
+		paused := m.Fields[config.FieldRelayTarget].(*models.RelayTarget)
+		assert.False(suite.T(), paused.Enabled)
+		assert.Equal(suite.T(), target.ID, paused.ID)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("no failure event published")
+	}
+
+	suite.RelayRepository.AssertNumberOfCalls(suite.T(), "InsertQueueItem", 1)
+	var item *models.RelayQueueItem
+	for _, c := range suite.RelayRepository.Calls {
+		if c.Method == "InsertQueueItem" {
+			item = c.Arguments.Get(0).(*models.RelayQueueItem)
+		}
+	}
+	assert.Equal(suite.T(), target.ID, item.TargetID)
+	assert.Equal(suite.T(), 1, item.Attempts)
+	assert.True(suite.T(), item.NextAttemptAt.T().After(time.Now()))
+	assert.Equal(suite.T(), "instance01", item.OriginInstance)
+	assert.JSONEq(suite.T(), `[{"project": "wakapi", "entity": "main.go", "time": 1700000000.5}]`, item.Payload)
+	assert.True(suite.T(), target.Enabled) // cached instance is left untouched
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_Forward_RefusesPrivateAddresses() {
+	config.Get().Security.AllowedPrivateHosts = ""
+	defer func() { config.Get().Security.AllowedPrivateHosts = "127.0.0.1" }()
+
+	sut := NewRelayService(suite.RelayRepository)
+
+	target := &models.RelayTarget{ID: 1, UserID: suite.TestUser.ID, Name: "Local", ApiUrl: suite.Server.URL, ApiKey: "key1", Enabled: true}
+	suite.RelayRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayTarget{target}, nil)
+	suite.RelayRepository.On("InsertQueueItem", mock.Anything).Return(&models.RelayQueueItem{}, nil)
+	suite.RelayRepository.On("IncrementFailures", target.ID, mock.Anything).Return(relayMaxFailures, nil)
+	suite.RelayRepository.On("Update", mock.Anything).Return(target, nil)
+
+	sub := config.EventBus().Subscribe(1, config.EventWakatimeFailure)
+	defer config.EventBus().Unsubscribe(sub)
+
+	heartbeats, raw := relayTestHeartbeats("wakapi")
+	sut.Forward(suite.TestUser, heartbeats, raw, http.Header{})
+
+	select {
+	case <-sub.Receiver:
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("no failure event published")
+	}
+	assert.Empty(suite.T(), suite.Received)
+}
+
+func (suite *RelayServiceTestSuite) TestRelayService_ProcessQueue() {
+	sut := NewRelayService(suite.RelayRepository)
+
+	healthy := &models.RelayTarget{ID: 1, UserID: suite.TestUser.ID, User: suite.TestUser, Name: "Healthy", ApiUrl: suite.Server.URL, ApiKey: "key1", Enabled: true, FailureCount: 3}
+	broken := &models.RelayTarget{ID: 2, UserID: suite.TestUser.ID, User: suite.TestUser, Name: "Broken", ApiUrl: suite.Server.URL + "/broken", ApiKey: "key2", Enabled: true}
+
+	items := []*models.RelayQueueItem{
+		{ID: 1, TargetID: healthy.ID, Target: healthy, Payload: `[{"project": "wakapi"}]`, Attempts: 1},
+		{ID: 2, TargetID: broken.ID, Target: broken, Payload: `[{"project": "wakapi"}]`, Attempts: 2},
+		{ID: 3, TargetID: broken.ID, Target: broken, Payload: `[{"project": "anchr"}]`, Attempts: 1},
+	}
+
+	suite.RelayRepository.On("GetQueueItemsDue", mock.Anything, relayQueueBatchSize).Return(items, nil)
+	suite.RelayRepository.On("DeleteQueueItem", uint(1)).Return(nil)
+	suite.RelayRepository.On("ResetFailures", healthy.ID).Return(nil)
+	suite.RelayRepository.On("UpdateQueueItem", mock.Anything).Return(&models.RelayQueueItem{}, nil)
+	suite.RelayRepository.On("IncrementFailures", broken.ID, mock.Anything).Return(1, nil)
+
+	sut.ProcessQueue()
+
+	assert.Len(suite.T(), suite.awaitReceived(3), 2) // second item of broken target isn't attempted
+	suite.RelayRepository.AssertCalled(suite.T(), "DeleteQueueItem", uint(1))
+	suite.RelayRepository.AssertCalled(suite.T(), "ResetFailures", healthy.ID)
+	suite.RelayRepository.AssertNumberOfCalls(suite.T(), "IncrementFailures", 1)
+	suite.RelayRepository.AssertNumberOfCalls(suite.T(), "UpdateQueueItem", 2)
+	assert.Equal(suite.T(), 3, items[1].Attempts)
+	assert.Equal(suite.T(), 1, items[2].Attempts)
+	assert.True(suite.T(), items[1].NextAttemptAt.T().After(time.Now().Add(relayRetryDelays[1])))
+}
+
+func (suite *RelayServiceTestSuite) awaitReceived(n int) []*receivedRelay {
+	received := make([]*receivedRelay, 0, n)
+	timeout := time.After(2 * time.Second)
+	for len(received) < n {
+		select {
+		case r := <-suite.Received:
+			received = append(received, r)
+		case <-timeout:
+			return received
+		}
+	}
+	return received
+}
+
+func relayTestHeartbeats(projects ...string) ([]*models.Heartbeat, []interface{}) {
+	heartbeats := make([]*models.Heartbeat, len(projects))
+	raw := make([]interface{}, len(projects))
+	for i, p := range projects {
+		heartbeats[i] = &models.Heartbeat{Project: p, Entity: "main.go"}
+		raw[i] = map[string]interface{}{"project": p, "entity": "main.go", "time": 1700000000.5}
+	}
+	return heartbeats, raw
+}
+
+func relayTestAuth(apiKey string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(apiKey))
+}
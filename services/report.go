@@ -7,6 +7,7 @@ import (
 	"github.com/muety/artifex/v2"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/utils"
 	"log/slog"
 	"math/rand"
@@ -19,27 +20,32 @@ const reportDelay = 10 * time.Second
 // past time range to cover in the report
 const reportRange = 7 * 24 * time.Hour
 
+// cron expression to check for due report subscriptions, i.e. at the beginning of every hour
+const reportSubscriptionCron = "0 0 * * * *"
+
 type ReportService struct {
-	config         *config.Config
-	eventBus       *hub.Hub
-	summaryService ISummaryService
-	userService    IUserService
-	mailService    IMailService
-	rand           *rand.Rand
-	queueDefault   *artifex.Dispatcher
-	queueWorkers   *artifex.Dispatcher
+	config              *config.Config
+	eventBus            *hub.Hub
+	repository          repositories.IReportSubscriptionRepository
+	summaryService      ISummaryService
+	userService         IUserService
+	notificationService INotificationService
+	rand                *rand.Rand
+	queueDefault        *artifex.Dispatcher
+	queueWorkers        *artifex.Dispatcher
 }
 
-func NewReportService(summaryService ISummaryService, userService IUserService, mailService IMailService) *ReportService {
+func NewReportService(reportSubscriptionRepository repositories.IReportSubscriptionRepository, summaryService ISummaryService, userService IUserService, notificationService INotificationService) *ReportService {
 	srv := &ReportService{
-		config:         config.Get(),
-		eventBus:       config.EventBus(),
-		summaryService: summaryService,
-		userService:    userService,
-		mailService:    mailService,
-		rand:           rand.New(rand.NewSource(time.Now().Unix())),
-		queueDefault:   config.GetDefaultQueue(),
-		queueWorkers:   config.GetQueue(config.QueueReports),
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		repository:          reportSubscriptionRepository,
+		summaryService:      summaryService,
+		userService:         userService,
+		notificationService: notificationService,
+		rand:                rand.New(rand.NewSource(time.Now().Unix())),
+		queueDefault:        config.GetDefaultQueue(),
+		queueWorkers:        config.GetQueue(config.QueueReports),
 	}
 
 	return srv
@@ -74,9 +80,9 @@ func (srv *ReportService) Schedule() {
 			return
 		}
 
-		// filter users who have their email set
+		// filter users who have their email set or any other way to receive the report
 		users = slice.Filter[*models.User](users, func(i int, u *models.User) bool {
-			return u.Email != ""
+			return srv.isReachable(u)
 		})
 
 		// schedule jobs, throttled by one job per x seconds
@@ -89,11 +95,84 @@ func (srv *ReportService) Schedule() {
 	if err != nil {
 		config.Log().Error("failed to dispatch report generation jobs", "error", err)
 	}
+
+	_, err = srv.queueDefault.DispatchCron(func() {
+		srv.SendDueSubscriptions(time.Now())
+	}, reportSubscriptionCron)
+
+	if err != nil {
+		config.Log().Error("failed to dispatch report subscription jobs", "error", err)
+	}
+}
+
+// SendDueSubscriptions sends all custom reports, which are due at the given time in their respective user's time zone
+func (srv *ReportService) SendDueSubscriptions(t time.Time) {
+	subscriptions, err := srv.repository.GetAll()
+	if err != nil {
+		config.Log().Error("failed to get report subscriptions", "error", err)
+		return
+	}
+
+	subscriptions = slice.Filter[*models.ReportSubscription](subscriptions, func(i int, s *models.ReportSubscription) bool {
+		return s.User != nil && (s.Recipients != "" || srv.isReachable(s.User)) && s.IsDue(t.In(s.User.TZ()))
+	})
+
+	slog.Info("scheduling custom report generation", "subscriptionCount", len(subscriptions))
+	for _, s := range subscriptions {
+		subscription := s
+		if err := srv.queueWorkers.Dispatch(func() {
+			t0 := time.Now()
+
+			if err := srv.SendSubscription(subscription, t); err != nil {
+				config.Log().Error("failed to send custom report", "userID", subscription.UserID, "subscriptionID", subscription.ID, "error", err)
+			}
+
+			if diff := reportDelay - time.Now().Sub(t0); diff > 0 {
+				time.Sleep(diff)
+			}
+		}); err != nil {
+			config.Log().Error("failed to dispatch custom report generation job", "userID", subscription.UserID, "subscriptionID", subscription.ID, "error", err)
+		}
+	}
+}
+
+// SendSubscription generates and sends the subscription's report for the most recent complete period before the given time
+func (srv *ReportService) SendSubscription(subscription *models.ReportSubscription, t time.Time) error {
+	user := subscription.User
+	if user == nil || (subscription.Recipients == "" && !srv.isReachable(user)) {
+		slog.Warn("not generating custom report as neither an e-mail address nor a notification channel is set", "subscriptionID", subscription.ID)
+		return nil
+	}
+
+	slog.Info("generating custom report for user", "userID", user.ID, "subscriptionID", subscription.ID)
+
+	start, end := subscription.PeriodBefore(t.In(user.TZ()))
+
+	report, err := srv.generate(user, start, end, subscription.Filters, subscription.HasSection(models.ReportSectionDaily))
+	if err != nil {
+		config.Log().Error("failed to generate custom report", "userID", user.ID, "subscriptionID", subscription.ID, "error", err)
+		return err
+	}
+	report.To = end.Add(-1 * time.Second)
+	report.Subscription = subscription
+
+	if err := srv.notificationService.SendReport(user, report); err != nil {
+		config.Log().Error("failed to send custom report", "userID", user.ID, "subscriptionID", subscription.ID, "error", err)
+		return err
+	}
+
+	if err := srv.repository.UpdateLastSent(subscription.ID, t); err != nil {
+		config.Log().Error("failed to update custom report subscription", "subscriptionID", subscription.ID, "error", err)
+		return err
+	}
+
+	slog.Info("sent custom report to user", "userID", user.ID, "subscriptionID", subscription.ID)
+	return nil
 }
 
 func (srv *ReportService) SendReport(user *models.User, duration time.Duration) error {
-	if user.Email == "" {
-		slog.Warn("not generating report as no e-mail address is set", "userID", user.ID)
+	if !srv.isReachable(user) {
+		slog.Warn("not generating report as neither an e-mail address nor a notification channel is set", "userID", user.ID)
 		return nil
 	}
 
@@ -102,41 +181,74 @@ func (srv *ReportService) SendReport(user *models.User, duration time.Duration)
 	end := time.Now().In(user.TZ())
 	start := time.Now().Add(-1 * duration)
 
-	fullSummary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, nil, nil, false)
+	report, err := srv.generate(user, start, end, func() *models.Filters { return nil }, true)
 	if err != nil {
 		config.Log().Error("failed to regenerate report", "userID", user.ID, "error", err)
 		return err
 	}
 
-	// regenerate per-day summaries
-	dayIntervals := utils.SplitRangeByDays(start, end)
-	dailySummaries := make([]*models.Summary, len(dayIntervals))
+	if err := srv.notificationService.SendReport(user, report); err != nil {
+		config.Log().Error("failed to send report", "userID", user.ID, "error", err)
+		return err
+	}
 
-	for i, interval := range dayIntervals {
-		from, to := datetime.BeginOfDay(interval[0]), interval[1]
-		summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, nil, nil, false)
-		if err != nil {
-			config.Log().Error("failed to regenerate day summary for report", "from", from, "to", to, "userID", user.ID, "error", err)
-			break
+	slog.Info("sent report to user", "userID", user.ID)
+	return nil
+}
+
+func (srv *ReportService) GetSubscriptionById(id uint) (*models.ReportSubscription, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *ReportService) GetSubscriptionsByUser(user *models.User) ([]*models.ReportSubscription, error) {
+	return srv.repository.GetByUser(user.ID)
+}
+
+func (srv *ReportService) CreateSubscription(subscription *models.ReportSubscription) (*models.ReportSubscription, error) {
+	return srv.repository.Insert(subscription)
+}
+
+func (srv *ReportService) DeleteSubscription(subscription *models.ReportSubscription) error {
+	return srv.repository.Delete(subscription.ID)
+}
+
+// isReachable tells whether the user has an e-mail address set or a notification channel to receive reports on
+func (srv *ReportService) isReachable(user *models.User) bool {
+	return user.Email != "" || srv.notificationService.HasChannels(user, models.NotificationTypeReport)
+}
+
+// generate builds a report for the given time range, optionally including per-day summaries.
+// filters are obtained from a function, because summary generation modifies them in-place (alias resolution).
+func (srv *ReportService) generate(user *models.User, start, end time.Time, filters func() *models.Filters, withDaily bool) (*models.Report, error) {
+	fullSummary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, filters(), nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// regenerate per-day summaries
+	var dailySummaries []*models.Summary
+	if withDaily {
+		dayIntervals := utils.SplitRangeByDays(start, end)
+		dailySummaries = make([]*models.Summary, len(dayIntervals))
+
+		for i, interval := range dayIntervals {
+			from, to := datetime.BeginOfDay(interval[0]), interval[1]
+			summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, filters(), nil, false)
+			if err != nil {
+				config.Log().Error("failed to regenerate day summary for report", "from", from, "to", to, "userID", user.ID, "error", err)
+				break
+			}
+			summary.FromTime = models.CustomTime(from)
+			summary.ToTime = models.CustomTime(to.Add(-1 * time.Second))
+			dailySummaries[i] = summary
 		}
-		summary.FromTime = models.CustomTime(from)
-		summary.ToTime = models.CustomTime(to.Add(-1 * time.Second))
-		dailySummaries[i] = summary
 	}
 
-	report := &models.Report{
+	return &models.Report{
 		From:           start,
 		To:             end,
 		User:           user,
 		Summary:        fullSummary,
 		DailySummaries: dailySummaries,
-	}
-
-	if err := srv.mailService.SendReport(user, report); err != nil {
-		config.Log().Error("failed to send report", "userID", user.ID, "error", err)
-		return err
-	}
-
-	slog.Info("sent report to user", "userID", user.ID)
-	return nil
+	}, nil
 }
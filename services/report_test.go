@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReportServiceTestSuite struct {
+	suite.Suite
+	TestUser                     *models.User
+	ReportSubscriptionRepository *mocks.ReportSubscriptionRepositoryMock
+	SummaryService               *mocks.SummaryServiceMock
+	UserService                  *mocks.UserServiceMock
+	NotificationService          *mocks.NotificationServiceMock
+}
+
+func (suite *ReportServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01", Email: "testuser01@example.org", Location: "Europe/Berlin"}
+}
+
+func (suite *ReportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.ReportSubscriptionRepository = new(mocks.ReportSubscriptionRepositoryMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+}
+
+func TestReportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ReportServiceTestSuite))
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_SendSubscription() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.NotificationService)
+
+	tz := suite.TestUser.TZ()
+	ts := time.Date(2024, 5, 6, 8, 0, 0, 0, tz) // a monday
+	from, to := time.Date(2024, 4, 29, 0, 0, 0, 0, tz), time.Date(2024, 5, 6, 0, 0, 0, 0, tz)
+
+	subscription := &models.ReportSubscription{
+		ID:         1,
+		User:       suite.TestUser,
+		UserID:     suite.TestUser.ID,
+		Cadence:    models.ReportCadenceWeekly,
+		Hour:       8,
+		Projects:   "wakapi",
+		Recipients: "boss@example.org",
+		Sections:   "projects,languages",
+	}
+
+	summary := &models.Summary{Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: 2 * time.Hour / time.Second}}}
+	suite.SummaryService.On("Aliased", from, to, suite.TestUser, mock.Anything, subscription.Filters(), (*time.Duration)(nil), false).Return(summary, nil)
+	suite.NotificationService.On("SendReport", suite.TestUser, mock.Anything).Return(nil)
+	suite.ReportSubscriptionRepository.On("UpdateLastSent", uint(1), ts).Return(nil)
+
+	err := sut.SendSubscription(subscription, ts)
+	assert.Nil(suite.T(), err)
+
+	// no per-day summaries without the daily section
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 1)
+	suite.ReportSubscriptionRepository.AssertCalled(suite.T(), "UpdateLastSent", uint(1), ts)
+
+	report := suite.NotificationService.Calls[0].Arguments.Get(1).(*models.Report)
+	assert.Equal(suite.T(), from, report.From)
+	assert.Equal(suite.T(), to.Add(-1*time.Second), report.To)
+	assert.Same(suite.T(), summary, report.Summary)
+	assert.Empty(suite.T(), report.DailySummaries)
+	assert.Equal(suite.T(), models.MailAddresses{"testuser01@example.org", "boss@example.org"}, report.Recipients())
+}
+
+func (suite *ReportServiceTestSuite) TestReportService_SendSubscription_Daily() {
+	sut := NewReportService(suite.ReportSubscriptionRepository, suite.SummaryService, suite.UserService, suite.NotificationService)
+
+	ts := time.Date(2024, 5, 6, 8, 0, 0, 0, suite.TestUser.TZ())
+	subscription := &models.ReportSubscription{ID: 1, User: suite.TestUser, UserID: suite.TestUser.ID, Cadence: models.ReportCadenceWeekly, Hour: 8, Sections: "daily"}
+
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, (*models.Filters)(nil), (*time.Duration)(nil), false).Return(&models.Summary{}, nil)
+	suite.NotificationService.On("SendReport", suite.TestUser, mock.Anything).Return(nil)
+	suite.ReportSubscriptionRepository.On("UpdateLastSent", uint(1), ts).Return(nil)
+
+	assert.Nil(suite.T(), sut.SendSubscription(subscription, ts))
+
+	report := suite.NotificationService.Calls[0].Arguments.Get(1).(*models.Report)
+	assert.Len(suite.T(), report.DailySummaries, 7)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 8)
+}
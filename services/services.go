@@ -3,8 +3,11 @@ package services
 import (
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
 	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/shields/v1"
 	"github.com/muety/wakapi/models/types"
 	"github.com/muety/wakapi/utils"
+	"io"
+	"net/http"
 	"time"
 )
 
@@ -12,6 +15,7 @@ type IAggregationService interface {
 	Schedule()
 	AggregateSummaries(set datastructure.Set[string]) error
 	AggregateDurations(set datastructure.Set[string]) error
+	RegenerateSummariesWithin(*models.User, time.Time, time.Time) (int, error)
 }
 
 type IMiscService interface {
@@ -43,13 +47,16 @@ type IHeartbeatService interface {
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
 	GetLatestByFilters(*models.User, *models.Filters) (*models.Heartbeat, error)
+	GetFirstByFilters(*models.User, *models.Filters) (*models.Heartbeat, error)
 	GetEntitySetByUser(uint8, string) ([]string, error)
 	StreamAllWithin(time.Time, time.Time, *models.User) (chan *models.Heartbeat, error)
 	StreamAllWithinByFilters(time.Time, time.Time, *models.User, *models.Filters) (chan *models.Heartbeat, error)
 	DeleteBefore(time.Time) error
 	DeleteByUser(*models.User) error
 	DeleteByUserBefore(*models.User, time.Time) error
+	SyncIdSequence() error
 	GetUserProjectStats(*models.User, time.Time, time.Time, *utils.PageParams, bool) ([]*models.ProjectStats, error)
+	RenameProjects(*models.User, []string, string) (int64, error)
 }
 
 type IDiagnosticsService interface {
@@ -73,6 +80,14 @@ type ILanguageMappingService interface {
 	Delete(mapping *models.LanguageMapping) error
 }
 
+type IHeartbeatRuleService interface {
+	GetById(uint) (*models.HeartbeatRule, error)
+	GetByUser(string) ([]*models.HeartbeatRule, error)
+	Apply(*models.User, *models.Heartbeat) (bool, error)
+	Create(*models.HeartbeatRule) (*models.HeartbeatRule, error)
+	Delete(*models.HeartbeatRule) error
+}
+
 type IProjectLabelService interface {
 	GetById(uint) (*models.ProjectLabel, error)
 	GetByUser(string) ([]*models.ProjectLabel, error)
@@ -82,12 +97,87 @@ type IProjectLabelService interface {
 	Delete(*models.ProjectLabel) error
 }
 
+type IProjectService interface {
+	GetArchived(string) ([]string, error)
+	Archive(*models.User, string) error
+	Unarchive(*models.User, string) error
+	Rename(*models.User, string, string) error
+	Merge(*models.User, []string, string) error
+	IsRewriting(string) bool
+}
+
+type IExportService interface {
+	Export(*models.User, string, io.Writer) error
+	Restore(*models.User, *models.AccountArchive) error
+}
+
+type IApiTokenService interface {
+	GetByUser(string) ([]*models.ApiToken, error)
+	GetByToken(string) (*models.ApiToken, error)
+	Create(*models.User, string, []string, *time.Time) (*models.ApiToken, string, error)
+	Delete(*models.ApiToken) error
+}
+
+type IGoalService interface {
+	Schedule()
+	GetById(uint) (*models.Goal, error)
+	GetByUser(string) ([]*models.Goal, error)
+	Create(*models.Goal) (*models.Goal, error)
+	Delete(*models.Goal) error
+	GetProgress(*models.Goal, *models.User, time.Time) (*models.GoalProgress, error)
+	GetProgressHistory(*models.Goal, *models.User, int) ([]*models.GoalProgress, error)
+}
+
+type IWebhookService interface {
+	Schedule()
+	GetById(uint) (*models.Webhook, error)
+	GetByUser(string) ([]*models.Webhook, error)
+	GetGlobal() ([]*models.Webhook, error)
+	Create(*models.User, string, []string, bool) (*models.Webhook, error)
+	Delete(*models.Webhook) error
+	GetDeliveries(*models.Webhook, int) ([]*models.WebhookDelivery, error)
+	Ping(*models.Webhook, *models.User) (*models.WebhookDelivery, error)
+	Dispatch(string, string, interface{})
+}
+
+type IRelayService interface {
+	Schedule()
+	GetById(uint) (*models.RelayTarget, error)
+	GetByUser(string) ([]*models.RelayTarget, error)
+	Create(*models.RelayTarget) (*models.RelayTarget, error)
+	Update(*models.RelayTarget) (*models.RelayTarget, error)
+	Delete(*models.RelayTarget) error
+	CountQueued(*models.RelayTarget) (int64, error)
+	Forward(*models.User, []*models.Heartbeat, []interface{}, http.Header)
+	ProcessQueue()
+}
+
+type ITeamService interface {
+	Schedule()
+	GetById(uint) (*models.Team, error)
+	GetByUser(string) ([]*models.Team, error)
+	GetMembers(*models.Team) ([]*models.TeamMember, error)
+	GetMember(*models.Team, string) (*models.TeamMember, error)
+	Create(*models.Team, *models.User) (*models.Team, error)
+	Delete(*models.Team) error
+	AddMember(*models.Team, *models.User, string) (*models.TeamMember, error)
+	UpdateMemberRole(*models.Team, string, string) (*models.TeamMember, error)
+	RemoveMember(*models.Team, string) error
+	GenerateInvite(*models.Team, *models.User) (string, error)
+	GetInvite(string) (*models.Team, error)
+	RedeemInvite(string, *models.User) (*models.Team, error)
+	GetSummary(*models.Team, time.Time, time.Time, *models.Filters) (*models.TeamSummary, error)
+	ComputeLeaderboard(*models.Team) error
+}
+
 type IMailService interface {
 	SendPasswordReset(*models.User, string) error
 	SendWakatimeFailureNotification(*models.User, int) error
+	SendRelayFailureNotification(*models.User, *models.RelayTarget, int) error
 	SendImportNotification(*models.User, time.Duration, int) error
 	SendReport(*models.User, *models.Report) error
 	SendSubscriptionNotification(*models.User, bool) error
+	SendGoalNotification(*models.User, *models.GoalProgress) error
 }
 
 type IDurationService interface {
@@ -99,20 +189,52 @@ type IDurationService interface {
 type ISummaryService interface {
 	Aliased(time.Time, time.Time, *models.User, types.SummaryRetriever, *models.Filters, *time.Duration, bool) (*models.Summary, error)
 	Retrieve(time.Time, time.Time, *models.User, *models.Filters, *time.Duration) (*models.Summary, error)
+	ResolveFilters(*models.User, *models.Filters) *models.Filters
 	Summarize(time.Time, time.Time, *models.User, *models.Filters, *time.Duration) (*models.Summary, error)
 	GetLatestByUser() ([]*models.TimeByUser, error)
 	DeleteByUser(string) error
 	DeleteByUserBefore(string, time.Time) error
+	DeleteByUserWithin(string, time.Time, time.Time) error
 	Insert(*models.Summary) error
 }
 
+type ITimesheetService interface {
+	Generate(*models.User, *models.TimesheetParams) (*models.Timesheet, error)
+}
+
 type IActivityService interface {
-	GetChart(*models.User, *models.IntervalKey, bool, bool, bool) (string, error)
+	GetChart(*models.User, *models.ActivityChartParams, bool) ([]byte, error)
+	GetHeatmap(*models.User, *models.ActivityChartParams, bool) (*models.Heatmap, error)
+	GetHeatmapChart(*models.User, *models.ActivityChartParams, bool) ([]byte, error)
+}
+
+type IBadgeService interface {
+	GetDailyTotals(*models.User, time.Time, time.Time, *models.Filters) ([]time.Duration, error)
+	GetStreak(*models.User, *models.Filters, int) (int, bool, error)
+	Render(*v1.BadgeData) ([]byte, error)
+}
+
+type INotificationService interface {
+	GetById(uint) (*models.NotificationChannel, error)
+	GetByUser(string) ([]*models.NotificationChannel, error)
+	Create(*models.NotificationChannel) (*models.NotificationChannel, error)
+	Delete(*models.NotificationChannel) error
+	HasChannels(*models.User, string) bool
+	Test(*models.NotificationChannel) error
+	SendReport(*models.User, *models.Report) error
+	SendImportNotification(*models.User, time.Duration, int) error
+	SendSubscriptionNotification(*models.User, bool) error
 }
 
 type IReportService interface {
 	Schedule()
 	SendReport(*models.User, time.Duration) error
+	SendDueSubscriptions(time.Time)
+	SendSubscription(*models.ReportSubscription, time.Time) error
+	GetSubscriptionById(uint) (*models.ReportSubscription, error)
+	GetSubscriptionsByUser(*models.User) ([]*models.ReportSubscription, error)
+	CreateSubscription(*models.ReportSubscription) (*models.ReportSubscription, error)
+	DeleteSubscription(*models.ReportSubscription) error
 }
 
 type IHousekeepingService interface {
@@ -124,12 +246,15 @@ type ILeaderboardService interface {
 	GetDefaultScope() *models.IntervalKey
 	Schedule()
 	ComputeLeaderboard([]*models.User, *models.IntervalKey, []uint8) error
+	ComputeTeamLeaderboard(uint, []*models.User, *models.IntervalKey, []uint8) error
 	ExistsAnyByUser(string) (bool, error)
 	CountUsers(bool) (int64, error)
 	GetByInterval(*models.IntervalKey, *utils.PageParams, bool) (models.Leaderboard, error)
 	GetByIntervalAndUser(*models.IntervalKey, string, bool) (models.Leaderboard, error)
 	GetAggregatedByInterval(*models.IntervalKey, *uint8, *utils.PageParams, bool) (models.Leaderboard, error)
 	GetAggregatedByIntervalAndUser(*models.IntervalKey, string, *uint8, bool) (models.Leaderboard, error)
+	GetAggregatedByTeamAndInterval(uint, *models.IntervalKey, *uint8, bool) (models.Leaderboard, error)
+	DeleteByTeam(uint) error
 	GenerateByUser(*models.User, *models.IntervalKey) (*models.LeaderboardItem, error)
 	GenerateAggregatedByUser(*models.User, *models.IntervalKey, uint8) ([]*models.LeaderboardItem, error)
 }
@@ -137,6 +262,7 @@ type ILeaderboardService interface {
 type IUserService interface {
 	GetUserById(string) (*models.User, error)
 	GetUserByKey(string) (*models.User, error)
+	GetUserByToken(string) (*models.User, *models.ApiToken, error)
 	GetUserByEmail(string) (*models.User, error)
 	GetUserByResetToken(string) (*models.User, error)
 	GetUserByStripeCustomerId(string) (*models.User, error)
@@ -158,3 +284,31 @@ type IUserService interface {
 	FlushCache()
 	FlushUserCache(string)
 }
+
+type IOidcService interface {
+	RedirectUri() string
+	NewAuthState(string) *models.OidcAuthState
+	AuthCodeUrl(*models.OidcAuthState) (string, error)
+	Exchange(string, *models.OidcAuthState) (*models.OidcClaims, error)
+	ResolveUser(*models.OidcClaims) (*models.User, bool, error)
+	Link(*models.User, *models.OidcClaims) (*models.OidcIdentity, error)
+	GetById(uint) (*models.OidcIdentity, error)
+	GetByUser(string) ([]*models.OidcIdentity, error)
+	Delete(*models.OidcIdentity) error
+}
+
+type ITotpService interface {
+	Setup(*models.User) (*models.User, error)
+	Enable(*models.User, string) ([]string, error)
+	Disable(*models.User) (*models.User, error)
+	Verify(*models.User, string) error
+	RegenerateRecoveryCodes(*models.User) ([]string, error)
+	KeyUri(*models.User) string
+	QrCode(*models.User) ([]byte, error)
+	IsRequired(*models.User) bool
+}
+
+type ILiveService interface {
+	GetStatus(*models.User) (*models.LiveStatus, error)
+	Subscribe(...string) (<-chan *models.LiveStatus, func())
+}
@@ -67,14 +67,9 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f type
 
 	// Resolver functions
 	resolveAliases := srv.getAliasResolver(user)
-	resolveAliasesReverse := srv.getAliasReverseResolver(user)
-	resolveProjectLabelsReverse := srv.getProjectLabelsReverseResolver(user)
 
 	// Post-process filters
-	if filters != nil {
-		filters = filters.WithAliases(resolveAliasesReverse)
-		filters = filters.WithProjectLabels(resolveProjectLabelsReverse)
-	}
+	filters = srv.ResolveFilters(user, filters)
 
 	// Initialize alias resolver service
 	if err := srv.aliasService.InitializeUser(user.ID); err != nil {
@@ -102,6 +97,16 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f type
 	return summary.Sorted().InTZ(user.TZ()), nil
 }
 
+// ResolveFilters expands the given filters by the user's aliases and project labels, so they can be matched against raw heartbeats or durations
+func (srv *SummaryService) ResolveFilters(user *models.User, filters *models.Filters) *models.Filters {
+	if filters == nil {
+		return nil
+	}
+	filters = filters.WithAliases(srv.getAliasReverseResolver(user))
+	filters = filters.WithProjectLabels(srv.getProjectLabelsReverseResolver(user))
+	return filters
+}
+
 func (srv *SummaryService) Retrieve(from, to time.Time, user *models.User, filters *models.Filters, customTimeout *time.Duration) (*models.Summary, error) {
 	summaries := make([]*models.Summary, 0)
 	requestedTimeout := getEffectiveTimeout(user, customTimeout)
@@ -243,6 +248,11 @@ func (srv *SummaryService) DeleteByUserBefore(userId string, t time.Time) error
 	return srv.repository.DeleteByUserBefore(userId, t)
 }
 
+func (srv *SummaryService) DeleteByUserWithin(userId string, from, to time.Time) error {
+	srv.invalidateUserCache(userId)
+	return srv.repository.DeleteByUserWithin(userId, from, to)
+}
+
 func (srv *SummaryService) Insert(summary *models.Summary) error {
 	srv.invalidateUserCache(summary.UserID)
 	return srv.repository.Insert(summary)
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+const teamInviteValidity = 24 * time.Hour
+
+type TeamService struct {
+	config             *config.Config
+	repository         repositories.ITeamRepository
+	summaryService     ISummaryService
+	keyValueService    IKeyValueService
+	leaderboardService ILeaderboardService // may be nil, if leaderboards are disabled
+	queueDefault       *artifex.Dispatcher
+}
+
+func NewTeamService(teamRepository repositories.ITeamRepository, summaryService ISummaryService, keyValueService IKeyValueService, leaderboardService ILeaderboardService) *TeamService {
+	return &TeamService{
+		config:             config.Get(),
+		repository:         teamRepository,
+		summaryService:     summaryService,
+		keyValueService:    keyValueService,
+		leaderboardService: leaderboardService,
+		queueDefault:       config.GetDefaultQueue(),
+	}
+}
+
+func (srv *TeamService) Schedule() {
+	if srv.leaderboardService == nil {
+		return
+	}
+
+	slog.Info("scheduling team leaderboard generation")
+
+	generate := func() {
+		teams, err := srv.repository.GetAll()
+		if err != nil {
+			config.Log().Error("failed to get teams for leaderboard generation", "error", err)
+			return
+		}
+		for _, team := range teams {
+			if err := srv.ComputeLeaderboard(team); err != nil {
+				config.Log().Error("failed to generate team leaderboard", "teamID", team.ID, "error", err)
+			}
+		}
+	}
+
+	for _, cronExp := range srv.config.App.GetLeaderboardGenerationTimeCron() {
+		if _, err := srv.queueDefault.DispatchCron(generate, cronExp); err != nil {
+			config.Log().Error("failed to schedule team leaderboard generation", "cronExpression", cronExp, "error", err)
+		}
+	}
+}
+
+func (srv *TeamService) GetById(id uint) (*models.Team, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *TeamService) GetByUser(userId string) ([]*models.Team, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *TeamService) GetMembers(team *models.Team) ([]*models.TeamMember, error) {
+	return srv.repository.GetMembers(team.ID)
+}
+
+func (srv *TeamService) GetMember(team *models.Team, userId string) (*models.TeamMember, error) {
+	return srv.repository.GetMember(team.ID, userId)
+}
+
+// Create persists a new team and makes the given user its owner
+func (srv *TeamService) Create(team *models.Team, owner *models.User) (*models.Team, error) {
+	team.Name = strings.TrimSpace(team.Name)
+	team, err := srv.repository.Insert(team)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := srv.repository.InsertMember(&models.TeamMember{
+		TeamID: team.ID,
+		UserID: owner.ID,
+		Role:   models.TeamRoleOwner,
+	}); err != nil {
+		srv.repository.Delete(team.ID)
+		return nil, err
+	}
+	srv.onMembersChanged(team)
+	return team, nil
+}
+
+func (srv *TeamService) Delete(team *models.Team) error {
+	if err := srv.repository.Delete(team.ID); err != nil {
+		return err
+	}
+	if srv.leaderboardService != nil {
+		return srv.leaderboardService.DeleteByTeam(team.ID)
+	}
+	return nil
+}
+
+func (srv *TeamService) AddMember(team *models.Team, user *models.User, role string) (*models.TeamMember, error) {
+	if existing, _ := srv.repository.GetMember(team.ID, user.ID); existing != nil {
+		return nil, errors.New("user is already a member of this team")
+	}
+	member, err := srv.repository.InsertMember(&models.TeamMember{
+		TeamID: team.ID,
+		UserID: user.ID,
+		Role:   role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	srv.onMembersChanged(team)
+	return member, nil
+}
+
+func (srv *TeamService) UpdateMemberRole(team *models.Team, userId string, role string) (*models.TeamMember, error) {
+	member, err := srv.repository.GetMember(team.ID, userId)
+	if err != nil {
+		return nil, err
+	}
+	if member.IsOwner() && role != models.TeamRoleOwner {
+		if err := srv.ensureOtherOwner(team, userId); err != nil {
+			return nil, err
+		}
+	}
+	member.Role = role
+	return srv.repository.UpdateMember(member)
+}
+
+func (srv *TeamService) RemoveMember(team *models.Team, userId string) error {
+	member, err := srv.repository.GetMember(team.ID, userId)
+	if err != nil {
+		return err
+	}
+	if member.IsOwner() {
+		if err := srv.ensureOtherOwner(team, userId); err != nil {
+			return err
+		}
+	}
+	if err := srv.repository.DeleteMember(team.ID, userId); err != nil {
+		return err
+	}
+	srv.onMembersChanged(team)
+	return nil
+}
+
+// GenerateInvite creates a single-use invite code for the given team, analogous to user invite codes, that expires after 24 hours
+func (srv *TeamService) GenerateInvite(team *models.Team, inviter *models.User) (string, error) {
+	inviteCode := uuid.Must(uuid.NewV4()).String()[0:8]
+
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{
+		Key:   srv.inviteCodeKey(inviteCode),
+		Value: fmt.Sprintf("%d,%s,%s", team.ID, inviter.ID, time.Now().Format(time.RFC3339)),
+	}); err != nil {
+		return "", err
+	}
+	return inviteCode, nil
+}
+
+// GetInvite resolves the given invite code to the team it refers to, without redeeming it
+func (srv *TeamService) GetInvite(inviteCode string) (*models.Team, error) {
+	kv, err := srv.keyValueService.GetString(srv.inviteCodeKey(inviteCode))
+	if err != nil || kv == nil || kv.Value == "" {
+		return nil, errors.New("invite code invalid or expired")
+	}
+
+	parts := strings.Split(kv.Value, ",")
+	if len(parts) != 3 {
+		return nil, errors.New("invite code invalid or expired")
+	}
+	teamId, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, errors.New("invite code invalid or expired")
+	}
+	if invitedDate, err := time.Parse(time.RFC3339, parts[2]); err != nil || time.Since(invitedDate) > teamInviteValidity {
+		return nil, errors.New("invite code invalid or expired")
+	}
+
+	team, err := srv.repository.GetById(uint(teamId))
+	if err != nil {
+		return nil, errors.New("team not found")
+	}
+	return team, nil
+}
+
+// RedeemInvite adds the user to the team referred to by the given invite code as a regular member and revokes the code
+func (srv *TeamService) RedeemInvite(inviteCode string, user *models.User) (*models.Team, error) {
+	team, err := srv.GetInvite(inviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := srv.AddMember(team, user, models.TeamRoleMember); err != nil {
+		return nil, err
+	}
+
+	if err := srv.keyValueService.DeleteString(srv.inviteCodeKey(inviteCode)); err != nil {
+		config.Log().Error("failed to revoke team invite code", "inviteCode", inviteCode, "error", err)
+	}
+
+	return team, nil
+}
+
+// GetSummary sums up the summaries of all team members for the given interval.
+// Every member's summary is redacted according to their sharing settings: members who don't share any data at all are left out,
+// the interval is cut to the number of days shared and summary items of a non-shared type (e.g. projects) are collapsed into "unknown".
+// If filters are given, members who don't share all types of data filtered by are left out, too.
+func (srv *TeamService) GetSummary(team *models.Team, from, to time.Time, filters *models.Filters) (*models.TeamSummary, error) {
+	members, err := srv.repository.GetMembers(team.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	teamSummary := &models.TeamSummary{
+		Summary: models.NewEmptySummary(),
+		Members: make([]*models.TeamMemberTotal, 0, len(members)),
+	}
+	teamSummary.FromTime = models.CustomTime(from)
+	teamSummary.ToTime = models.CustomTime(to)
+
+	for _, m := range members {
+		if m.User == nil {
+			continue
+		}
+
+		memberTotal := &models.TeamMemberTotal{UserID: m.UserID}
+		teamSummary.Members = append(teamSummary.Members, memberTotal)
+
+		memberFrom := from
+		if m.User.ShareDataMaxDays == 0 {
+			continue
+		} else if minStart := to.AddDate(0, 0, -m.User.ShareDataMaxDays); m.User.ShareDataMaxDays > 0 && memberFrom.Before(minStart) {
+			memberFrom = minStart
+		}
+		if !memberFrom.Before(to) {
+			continue
+		}
+		if !sharesFilteredTypes(m.User, filters) {
+			continue // filtered totals would reveal data of the type the member doesn't share
+		}
+
+		summary, err := srv.summaryService.Aliased(memberFrom, to, m.User, srv.summaryService.Retrieve, filters, nil, false)
+		if err != nil {
+			config.Log().Error("failed to retrieve summary for team member", "teamID", team.ID, "userID", m.UserID, "error", err)
+			continue
+		}
+
+		memberTotal.Shared = true
+		memberTotal.Total = summary.TotalTime()
+
+		for _, t := range models.SummaryTypes() {
+			items := *summary.GetByType(t)
+			if !m.User.SharesSummaryType(t) {
+				items = redactSummaryItems(items, t)
+			}
+			merged := mergeTeamSummaryItems(*teamSummary.GetByType(t), items)
+			teamSummary.SetByType(t, &merged)
+		}
+		teamSummary.NumHeartbeats += summary.NumHeartbeats
+	}
+
+	sort.Slice(teamSummary.Members, func(i, j int) bool {
+		return teamSummary.Members[i].Total > teamSummary.Members[j].Total
+	})
+
+	return teamSummary, nil
+}
+
+// ComputeLeaderboard (re-)generates the team's private leaderboard, if leaderboards are enabled
+func (srv *TeamService) ComputeLeaderboard(team *models.Team) error {
+	if srv.leaderboardService == nil {
+		return nil
+	}
+
+	members, err := srv.repository.GetMembers(team.ID)
+	if err != nil {
+		return err
+	}
+
+	users := make([]*models.User, 0, len(members))
+	for _, m := range members {
+		if m.User != nil {
+			users = append(users, m.User)
+		}
+	}
+
+	return srv.leaderboardService.ComputeTeamLeaderboard(team.ID, users, srv.leaderboardService.GetDefaultScope(), []uint8{models.SummaryLanguage})
+}
+
+func (srv *TeamService) onMembersChanged(team *models.Team) {
+	if srv.leaderboardService == nil {
+		return
+	}
+	if err := srv.queueDefault.Dispatch(func() {
+		if err := srv.ComputeLeaderboard(team); err != nil {
+			config.Log().Error("failed to regenerate team leaderboard", "teamID", team.ID, "error", err)
+		}
+	}); err != nil {
+		config.Log().Error("failed to dispatch team leaderboard generation", "teamID", team.ID, "error", err)
+	}
+}
+
+func (srv *TeamService) ensureOtherOwner(team *models.Team, userId string) error {
+	members, err := srv.repository.GetMembers(team.ID)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.IsOwner() && m.UserID != userId {
+			return nil
+		}
+	}
+	return errors.New("a team must have at least one owner")
+}
+
+func sharesFilteredTypes(user *models.User, filters *models.Filters) bool {
+	if filters == nil {
+		return true
+	}
+	for _, t := range models.SummaryTypes() {
+		if filters.CountByType(t) > 0 && !user.SharesSummaryType(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func redactSummaryItems(items models.SummaryItems, summaryType uint8) models.SummaryItems {
+	var total time.Duration
+	for _, item := range items {
+		total += item.Total
+	}
+	if total == 0 {
+		return models.SummaryItems{}
+	}
+	return models.SummaryItems{{Type: summaryType, Key: models.UnknownSummaryKey, Total: total}}
+}
+
+func mergeTeamSummaryItems(existing models.SummaryItems, new models.SummaryItems) models.SummaryItems {
+	mapped := make(map[string]*models.SummaryItem, len(existing))
+	itemList := make(models.SummaryItems, 0, len(existing)+len(new))
+
+	for _, items := range []models.SummaryItems{existing, new} {
+		for _, item := range items {
+			if it, ok := mapped[item.Key]; ok {
+				it.Total += item.Total
+				continue
+			}
+			copied := &models.SummaryItem{Type: item.Type, Key: item.Key, Total: item.Total}
+			mapped[item.Key] = copied
+			itemList = append(itemList, copied)
+		}
+	}
+
+	sort.Slice(itemList, func(i, j int) bool {
+		return itemList[i].Total > itemList[j].Total
+	})
+
+	return itemList
+}
+
+func (srv *TeamService) inviteCodeKey(inviteCode string) string {
+	return fmt.Sprintf("%s_%s", config.KeyTeamInvite, inviteCode)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type TeamServiceTestSuite struct {
+	suite.Suite
+	TestTeam        *models.Team
+	TestUsers       []*models.User
+	TeamRepository  *mocks.TeamRepositoryMock
+	SummaryService  *mocks.SummaryServiceMock
+	KeyValueService *mocks.KeyValueServiceMock
+}
+
+func (suite *TeamServiceTestSuite) SetupSuite() {
+	suite.TestTeam = &models.Team{ID: 1, Name: "Test Team"}
+	suite.TestUsers = []*models.User{
+		{ID: "testuser01", ShareDataMaxDays: -1, ShareProjects: true, ShareLanguages: true},
+		{ID: "testuser02", ShareDataMaxDays: -1, ShareProjects: false, ShareLanguages: true},
+		{ID: "testuser03", ShareDataMaxDays: 0, ShareProjects: true, ShareLanguages: true},
+	}
+}
+
+func (suite *TeamServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TeamRepository = new(mocks.TeamRepositoryMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestTeamServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TeamServiceTestSuite))
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_GetSummary_RespectsSharing() {
+	sut := NewTeamService(suite.TeamRepository, suite.SummaryService, suite.KeyValueService, nil)
+
+	from, to := time.Now().Add(-24*time.Hour), time.Now()
+
+	suite.TeamRepository.On("GetMembers", suite.TestTeam.ID).Return([]*models.TeamMember{
+		{TeamID: suite.TestTeam.ID, UserID: suite.TestUsers[0].ID, User: suite.TestUsers[0], Role: models.TeamRoleOwner},
+		{TeamID: suite.TestTeam.ID, UserID: suite.TestUsers[1].ID, User: suite.TestUsers[1], Role: models.TeamRoleMember},
+		{TeamID: suite.TestTeam.ID, UserID: suite.TestUsers[2].ID, User: suite.TestUsers[2], Role: models.TeamRoleMember},
+	}, nil)
+
+	summary1 := models.NewEmptySummary()
+	summary1.Projects = models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: 60}}
+	summary1.Languages = models.SummaryItems{{Type: models.SummaryLanguage, Key: "Go", Total: 60}}
+
+	summary2 := models.NewEmptySummary()
+	summary2.Projects = models.SummaryItems{{Type: models.SummaryProject, Key: "secret-project", Total: 120}}
+	summary2.Languages = models.SummaryItems{{Type: models.SummaryLanguage, Key: "Go", Total: 120}}
+
+	suite.SummaryService.On("Aliased", from, to, suite.TestUsers[0], mock.Anything, mock.Anything, mock.Anything, false).Return(summary1, nil)
+	suite.SummaryService.On("Aliased", from, to, suite.TestUsers[1], mock.Anything, mock.Anything, mock.Anything, false).Return(summary2, nil)
+
+	result, err := sut.GetSummary(suite.TestTeam, from, to, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3*time.Minute, result.TotalTime())
+	assert.Equal(suite.T(), 2*time.Minute, result.TotalTimeByKey(models.SummaryProject, models.UnknownSummaryKey))
+	assert.Zero(suite.T(), result.TotalTimeByKey(models.SummaryProject, "secret-project"))
+	assert.Equal(suite.T(), 3*time.Minute, result.TotalTimeByKey(models.SummaryLanguage, "Go"))
+	assert.Len(suite.T(), result.Members, 3)
+	assert.False(suite.T(), result.Members[2].Shared)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 2)
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_GetSummary_Filtered() {
+	sut := NewTeamService(suite.TeamRepository, suite.SummaryService, suite.KeyValueService, nil)
+
+	from, to := time.Now().Add(-24*time.Hour), time.Now()
+
+	suite.TeamRepository.On("GetMembers", suite.TestTeam.ID).Return([]*models.TeamMember{
+		{TeamID: suite.TestTeam.ID, UserID: suite.TestUsers[0].ID, User: suite.TestUsers[0], Role: models.TeamRoleOwner},
+		{TeamID: suite.TestTeam.ID, UserID: suite.TestUsers[1].ID, User: suite.TestUsers[1], Role: models.TeamRoleMember},
+	}, nil)
+
+	summary1 := models.NewEmptySummary()
+	summary1.Projects = models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: 60}}
+	summary1.Languages = models.SummaryItems{{Type: models.SummaryLanguage, Key: "Go", Total: 60}}
+
+	summary2 := models.NewEmptySummary()
+	summary2.Projects = models.SummaryItems{{Type: models.SummaryProject, Key: "wakapi", Total: 120}}
+	summary2.Languages = models.SummaryItems{{Type: models.SummaryLanguage, Key: "Go", Total: 120}}
+
+	suite.SummaryService.On("Aliased", from, to, suite.TestUsers[0], mock.Anything, mock.Anything, mock.Anything, false).Return(summary1, nil)
+	suite.SummaryService.On("Aliased", from, to, suite.TestUsers[1], mock.Anything, mock.Anything, mock.Anything, false).Return(summary2, nil)
+
+	// second user doesn't share projects, so mustn't be included when filtering by one
+	result, err := sut.GetSummary(suite.TestTeam, from, to, models.NewFiltersWith(models.SummaryProject, "wakapi"))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1*time.Minute, result.TotalTime())
+	assert.Len(suite.T(), result.Members, 2)
+	assert.True(suite.T(), result.Members[0].Shared)
+	assert.Equal(suite.T(), 1*time.Minute, result.Members[0].Total)
+	assert.False(suite.T(), result.Members[1].Shared)
+	assert.Zero(suite.T(), result.Members[1].Total)
+	suite.SummaryService.AssertNotCalled(suite.T(), "Aliased", from, to, suite.TestUsers[1], mock.Anything, mock.Anything, mock.Anything, false)
+
+	// languages are shared by both
+	result, err = sut.GetSummary(suite.TestTeam, from, to, models.NewFiltersWith(models.SummaryLanguage, "Go"))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 3*time.Minute, result.TotalTime())
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_RedeemInvite() {
+	sut := NewTeamService(suite.TeamRepository, suite.SummaryService, suite.KeyValueService, nil)
+
+	validKey := fmt.Sprintf("%s_%s", config.KeyTeamInvite, "valid")
+	expiredKey := fmt.Sprintf("%s_%s", config.KeyTeamInvite, "expired")
+
+	suite.KeyValueService.On("GetString", validKey).Return(&models.KeyStringValue{Key: validKey, Value: fmt.Sprintf("1,testuser01,%s", time.Now().Format(time.RFC3339))}, nil)
+	suite.KeyValueService.On("GetString", expiredKey).Return(&models.KeyStringValue{Key: expiredKey, Value: fmt.Sprintf("1,testuser01,%s", time.Now().Add(-25*time.Hour).Format(time.RFC3339))}, nil)
+	suite.KeyValueService.On("DeleteString", validKey).Return(nil)
+	suite.TeamRepository.On("GetById", suite.TestTeam.ID).Return(suite.TestTeam, nil)
+	suite.TeamRepository.On("GetMember", suite.TestTeam.ID, suite.TestUsers[1].ID).Return((*models.TeamMember)(nil), errors.New("not found"))
+	suite.TeamRepository.On("InsertMember", mock.Anything).Return(&models.TeamMember{TeamID: suite.TestTeam.ID, UserID: suite.TestUsers[1].ID, Role: models.TeamRoleMember}, nil)
+
+	_, err := sut.RedeemInvite("expired", suite.TestUsers[1])
+	assert.Error(suite.T(), err)
+
+	team, err := sut.RedeemInvite("valid", suite.TestUsers[1])
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.TestTeam, team)
+	suite.TeamRepository.AssertCalled(suite.T(), "InsertMember", mock.MatchedBy(func(m *models.TeamMember) bool {
+		return m.TeamID == suite.TestTeam.ID && m.UserID == suite.TestUsers[1].ID && m.Role == models.TeamRoleMember
+	}))
+	suite.KeyValueService.AssertCalled(suite.T(), "DeleteString", validKey)
+}
+
+func (suite *TeamServiceTestSuite) TestTeamService_GetInvite() {
+	sut := NewTeamService(suite.TeamRepository, suite.SummaryService, suite.KeyValueService, nil)
+
+	validKey := fmt.Sprintf("%s_%s", config.KeyTeamInvite, "valid")
+	invalidKey := fmt.Sprintf("%s_%s", config.KeyTeamInvite, "invalid")
+
+	suite.KeyValueService.On("GetString", validKey).Return(&models.KeyStringValue{Key: validKey, Value: fmt.Sprintf("1,testuser01,%s", time.Now().Format(time.RFC3339))}, nil)
+	suite.KeyValueService.On("GetString", invalidKey).Return((*models.KeyStringValue)(nil), errors.New("not found"))
+	suite.TeamRepository.On("GetById", suite.TestTeam.ID).Return(suite.TestTeam, nil)
+
+	_, err := sut.GetInvite("invalid")
+	assert.Error(suite.T(), err)
+
+	team, err := sut.GetInvite("valid")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.TestTeam, team)
+
+	// invite is neither redeemed nor revoked
+	suite.TeamRepository.AssertNotCalled(suite.T(), "InsertMember", mock.Anything)
+	suite.KeyValueService.AssertNotCalled(suite.T(), "DeleteString", mock.Anything)
+}
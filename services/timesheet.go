@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+type TimesheetService struct {
+	config              *config.Config
+	summaryService      ISummaryService
+	projectLabelService IProjectLabelService
+}
+
+func NewTimesheetService(summaryService ISummaryService, projectLabelService IProjectLabelService) *TimesheetService {
+	return &TimesheetService{
+		config:              config.Get(),
+		summaryService:      summaryService,
+		projectLabelService: projectLabelService,
+	}
+}
+
+// Generate computes the time spent on every requested project per day or week, rounded and priced according to the given params
+func (srv *TimesheetService) Generate(user *models.User, params *models.TimesheetParams) (*models.Timesheet, error) {
+	if !params.IsValid() {
+		return nil, errors.New("invalid timesheet params")
+	}
+
+	from, to := params.From.In(user.TZ()), params.To.In(user.TZ())
+	timesheet := &models.Timesheet{
+		From:     from,
+		To:       to,
+		GroupBy:  params.GroupBy,
+		Currency: params.Currency,
+		Entries:  []*models.TimesheetEntry{},
+	}
+
+	labelsByProject, err := srv.projectLabelService.GetByUserGrouped(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := params.Projects
+	if params.Label != "" {
+		projects = []string{}
+		for project, labels := range labelsByProject {
+			if slice.ContainBy[*models.ProjectLabel](labels, func(l *models.ProjectLabel) bool { return l.Label == params.Label }) {
+				projects = append(projects, project)
+			}
+		}
+		if len(projects) == 0 {
+			return timesheet, nil
+		}
+	}
+
+	var intervals [][]time.Time
+	if params.GroupBy == models.TimesheetGroupWeek {
+		intervals = utils.SplitRangeByWeeks(from, to)
+	} else {
+		intervals = utils.SplitRangeByDays(from, to)
+	}
+
+	for _, interval := range intervals {
+		var filters *models.Filters
+		if len(projects) > 0 {
+			// filters are modified while being resolved, so they can't be shared across intervals
+			filters = models.NewFilterWithMultiple(models.SummaryProject, projects).WithSelectFilteredOnly()
+		}
+
+		summary, err := srv.summaryService.Aliased(interval[0], interval[1], user, srv.summaryService.Retrieve, filters, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]*models.TimesheetEntry, 0, len(summary.Projects))
+		for _, p := range summary.Projects {
+			if len(projects) > 0 && !slice.Contain(projects, p.Key) {
+				continue
+			}
+
+			duration := summary.TotalTimeByKey(models.SummaryProject, p.Key)
+			if duration == 0 {
+				continue
+			}
+
+			labels := slice.Map[*models.ProjectLabel, string](labelsByProject[p.Key], func(i int, l *models.ProjectLabel) string {
+				return l.Label
+			})
+			if params.Label != "" {
+				labels = []string{params.Label} // bill at the selected label's rate
+			}
+			label, rate := params.Rate(labels)
+
+			entries = append(entries, &models.TimesheetEntry{
+				From:     interval[0],
+				To:       interval[1],
+				Project:  p.Key,
+				Label:    label,
+				Duration: duration,
+				Billed:   params.Round(duration),
+				Rate:     rate,
+			})
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Project < entries[j].Project
+		})
+		timesheet.Entries = append(timesheet.Entries, entries...)
+	}
+
+	return timesheet, nil
+}
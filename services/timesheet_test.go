@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimesheetServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	SummaryService      *mocks.SummaryServiceMock
+	ProjectLabelService *mocks.ProjectLabelServiceMock
+}
+
+func (suite *TimesheetServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01", Location: "Europe/Berlin"}
+}
+
+func (suite *TimesheetServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.ProjectLabelService.On("GetByUserGrouped", suite.TestUser.ID).Return(map[string][]*models.ProjectLabel{
+		"wakapi": {{ProjectKey: "wakapi", Label: "oss"}, {ProjectKey: "wakapi", Label: "work"}},
+		"anchr":  {{ProjectKey: "anchr", Label: "work"}},
+	}, nil)
+}
+
+func TestTimesheetServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TimesheetServiceTestSuite))
+}
+
+func (suite *TimesheetServiceTestSuite) TestTimesheetService_Generate() {
+	sut := NewTimesheetService(suite.SummaryService, suite.ProjectLabelService)
+
+	tz := suite.TestUser.TZ()
+	from, to := time.Date(2024, 3, 4, 0, 0, 0, 0, tz), time.Date(2024, 3, 6, 0, 0, 0, 0, tz)
+
+	summary := &models.Summary{Projects: []*models.SummaryItem{
+		{Type: models.SummaryProject, Key: "wakapi", Total: 50 * time.Minute / time.Second},
+		{Type: models.SummaryProject, Key: "anchr", Total: 10 * time.Minute / time.Second},
+		{Type: models.SummaryProject, Key: "dotfiles", Total: 5 * time.Minute / time.Second},
+	}}
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, (*models.Filters)(nil), (*time.Duration)(nil), false).Return(summary, nil)
+
+	params := &models.TimesheetParams{
+		From:         from,
+		To:           to,
+		GroupBy:      models.TimesheetGroupDay,
+		Rounding:     15 * time.Minute,
+		RoundingMode: models.TimesheetRoundingUp,
+		Rates:        map[string]float64{"work": 100},
+		DefaultRate:  50,
+		Currency:     "EUR",
+	}
+
+	timesheet, err := sut.Generate(suite.TestUser, params)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), timesheet.Entries, 6)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Aliased", 2)
+
+	entries := timesheet.Entries[:3]
+	assert.Equal(suite.T(), "anchr", entries[0].Project)
+	assert.Equal(suite.T(), 15*time.Minute, entries[0].Billed)
+	assert.Equal(suite.T(), 25.0, entries[0].Amount())
+	assert.Equal(suite.T(), "dotfiles", entries[1].Project)
+	assert.Equal(suite.T(), "", entries[1].Label)
+	assert.Equal(suite.T(), 50.0, entries[1].Rate)
+	assert.Equal(suite.T(), "wakapi", entries[2].Project)
+	assert.Equal(suite.T(), "work", entries[2].Label)
+	assert.Equal(suite.T(), 60*time.Minute, entries[2].Billed)
+	assert.Equal(suite.T(), 2*65*time.Minute, timesheet.TotalDuration())
+	assert.Equal(suite.T(), 2*90*time.Minute, timesheet.TotalBilled())
+	assert.Equal(suite.T(), 2*137.5, timesheet.TotalAmount())
+
+	var buf bytes.Buffer
+	assert.Nil(suite.T(), timesheet.WriteCsv(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(suite.T(), lines, 7)
+	assert.Equal(suite.T(), "2024-03-04,2024-03-04,anchr,work,0.17,0.25,100.00,25.00,EUR", lines[1])
+}
+
+func (suite *TimesheetServiceTestSuite) TestTimesheetService_Generate_ByLabelAndWeek() {
+	sut := NewTimesheetService(suite.SummaryService, suite.ProjectLabelService)
+
+	tz := suite.TestUser.TZ()
+	from, to := time.Date(2024, 3, 6, 0, 0, 0, 0, tz), time.Date(2024, 3, 20, 0, 0, 0, 0, tz) // wednesday to wednesday
+
+	summary := &models.Summary{Projects: []*models.SummaryItem{
+		{Type: models.SummaryProject, Key: "wakapi", Total: 100 * time.Minute / time.Second},
+	}}
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything, (*time.Duration)(nil), false).Return(summary, nil)
+
+	params := &models.TimesheetParams{
+		From:         from,
+		To:           to,
+		Label:        "oss",
+		GroupBy:      models.TimesheetGroupWeek,
+		RoundingMode: models.TimesheetRoundingNearest,
+		Rates:        map[string]float64{"work": 100},
+	}
+
+	timesheet, err := sut.Generate(suite.TestUser, params)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), timesheet.Entries, 3)
+	assert.Equal(suite.T(), "oss", timesheet.Entries[0].Label)
+	assert.Equal(suite.T(), 0.0, timesheet.Entries[0].Rate)
+	assert.Equal(suite.T(), time.Date(2024, 3, 11, 0, 0, 0, 0, tz), timesheet.Entries[0].To)
+
+	filters := suite.SummaryService.Calls[0].Arguments.Get(4).(*models.Filters)
+	assert.Equal(suite.T(), []string{"wakapi"}, []string(filters.Project))
+	assert.True(suite.T(), filters.SelectFilteredOnly)
+}
+
+func (suite *TimesheetServiceTestSuite) TestTimesheetService_Generate_Invalid() {
+	sut := NewTimesheetService(suite.SummaryService, suite.ProjectLabelService)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invalid := []*models.TimesheetParams{
+		{From: from, To: from, GroupBy: models.TimesheetGroupDay, RoundingMode: models.TimesheetRoundingUp},
+		{From: from, To: from.AddDate(2, 0, 0), GroupBy: models.TimesheetGroupDay, RoundingMode: models.TimesheetRoundingUp},
+		{From: from, To: from.AddDate(0, 1, 0), GroupBy: "month", RoundingMode: models.TimesheetRoundingUp},
+		{From: from, To: from.AddDate(0, 1, 0), GroupBy: models.TimesheetGroupDay, RoundingMode: "sideways"},
+		{From: from, To: from.AddDate(0, 1, 0), GroupBy: models.TimesheetGroupDay, RoundingMode: models.TimesheetRoundingUp, DefaultRate: -1},
+	}
+
+	for _, params := range invalid {
+		_, err := sut.Generate(suite.TestUser, params)
+		assert.Error(suite.T(), err)
+	}
+	suite.SummaryService.AssertNotCalled(suite.T(), "Aliased", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	totpIssuer             = "Wakapi"
+	totpSkew               = 1 // accept codes of the previous and next time step to account for clock drift
+	totpRecoveryCodesCount = 10
+	totpQrCodeSize         = 256
+)
+
+var (
+	ErrTotpInvalidCode    = errors.New("invalid two-factor authentication code")
+	ErrTotpNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTotpAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTotpRequired       = errors.New("two-factor authentication is mandatory on this server")
+)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding) // no ambiguous characters
+
+type TotpService struct {
+	config      *config.Config
+	userService IUserService
+	lock        sync.Mutex
+}
+
+func NewTotpService(userService IUserService) *TotpService {
+	return &TotpService{
+		config:      config.Get(),
+		userService: userService,
+	}
+}
+
+// Setup generates a new secret for the user to add to their authenticator app. Two-factor authentication only becomes active once confirmed via Enable.
+func (srv *TotpService) Setup(user *models.User) (*models.User, error) {
+	if user.HasTotp() {
+		return nil, ErrTotpAlreadyEnabled
+	}
+	user.TotpSecret = utils.GenerateTotpSecret()
+	user.TotpEnabled = false
+	user.TotpRecoveryCodes = ""
+	user.TotpLastCounter = 0
+	return srv.userService.Update(user)
+}
+
+// Enable completes the enrollment, given a valid code for the previously set up secret, and returns a fresh set of recovery codes
+func (srv *TotpService) Enable(user *models.User, code string) ([]string, error) {
+	if user.HasTotp() {
+		return nil, ErrTotpAlreadyEnabled
+	}
+	if user.TotpSecret == "" {
+		return nil, ErrTotpNotEnabled
+	}
+
+	counter, ok := utils.ValidateTotp(user.TotpSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrTotpInvalidCode
+	}
+
+	recoveryCodes, hashes := generateRecoveryCodes()
+	user.TotpEnabled = true
+	user.TotpLastCounter = counter
+	user.TotpRecoveryCodes = strings.Join(hashes, ",")
+	if _, err := srv.userService.Update(user); err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// Disable turns off two-factor authentication or cancels a pending setup
+func (srv *TotpService) Disable(user *models.User) (*models.User, error) {
+	if user.HasTotp() && srv.IsRequired(user) {
+		return nil, ErrTotpRequired
+	}
+	user.TotpSecret = ""
+	user.TotpEnabled = false
+	user.TotpRecoveryCodes = ""
+	user.TotpLastCounter = 0
+	return srv.userService.Update(user)
+}
+
+// Verify checks either a one-time code or one of the user's recovery codes. Each of them is only accepted once.
+func (srv *TotpService) Verify(user *models.User, code string) error {
+	if !user.HasTotp() {
+		return ErrTotpNotEnabled
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	// re-fetch to not miss a concurrent login's update of last used counter or recovery codes
+	current, err := srv.userService.GetUserById(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if counter, ok := utils.ValidateTotp(current.TotpSecret, code, time.Now(), totpSkew); ok {
+		if counter <= current.TotpLastCounter {
+			return ErrTotpInvalidCode
+		}
+		current.TotpLastCounter = counter
+		user.TotpLastCounter = counter
+		_, err := srv.userService.Update(current)
+		return err
+	}
+
+	if remaining, ok := consumeRecoveryCode(current.TotpRecoveryCodes, code); ok {
+		current.TotpRecoveryCodes = remaining
+		user.TotpRecoveryCodes = remaining
+		_, err := srv.userService.Update(current)
+		return err
+	}
+
+	return ErrTotpInvalidCode
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes by new ones
+func (srv *TotpService) RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	if !user.HasTotp() {
+		return nil, ErrTotpNotEnabled
+	}
+	recoveryCodes, hashes := generateRecoveryCodes()
+	user.TotpRecoveryCodes = strings.Join(hashes, ",")
+	if _, err := srv.userService.Update(user); err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// KeyUri returns the otpauth:// uri for the user's (pending) secret
+func (srv *TotpService) KeyUri(user *models.User) string {
+	return utils.TotpUri(totpIssuer, user.ID, user.TotpSecret)
+}
+
+// QrCode renders the user's key uri as a png image
+func (srv *TotpService) QrCode(user *models.User) ([]byte, error) {
+	return qrcode.Encode(srv.KeyUri(user), qrcode.Medium, totpQrCodeSize)
+}
+
+// IsRequired returns whether the user must have two-factor authentication set up, as configured by the server admin
+func (srv *TotpService) IsRequired(user *models.User) bool {
+	return srv.config.Security.Requires2fa(user.IsAdmin)
+}
+
+func generateRecoveryCodes() ([]string, []string) {
+	codes, hashes := make([]string, totpRecoveryCodesCount), make([]string, totpRecoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		code := recoveryCodeEncoding.EncodeToString(b) // 8 characters
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	h := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(h[:])
+}
+
+// consumeRecoveryCode checks the code against the given comma-separated list of hashes and returns the list without the matching one
+func consumeRecoveryCode(hashes, code string) (string, bool) {
+	if hashes == "" || strings.TrimSpace(code) == "" {
+		return hashes, false
+	}
+
+	hash := hashRecoveryCode(code)
+	remaining := make([]string, 0)
+	var found bool
+	for _, h := range strings.Split(hashes, ",") {
+		if !found && subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			found = true
+			continue
+		}
+		remaining = append(remaining, h)
+	}
+	return strings.Join(remaining, ","), found
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type TotpServiceTestSuite struct {
+	suite.Suite
+	TestUser    *models.User
+	UserService *mocks.UserServiceMock
+}
+
+func (suite *TotpServiceTestSuite) BeforeTest(suiteName, testName string) {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01"}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.UserService.On("Update", mock.Anything).Return(suite.TestUser, nil)
+	suite.UserService.On("GetUserById", suite.TestUser.ID).Return(suite.TestUser, nil)
+}
+
+func TestTotpServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TotpServiceTestSuite))
+}
+
+func (suite *TotpServiceTestSuite) TestTotpService_Enable() {
+	sut := NewTotpService(suite.UserService)
+
+	_, err := sut.Enable(suite.TestUser, "123456")
+	assert.ErrorIs(suite.T(), err, ErrTotpNotEnabled)
+
+	_, err = sut.Setup(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), suite.TestUser.TotpSecret)
+	assert.False(suite.T(), suite.TestUser.HasTotp())
+	assert.Contains(suite.T(), sut.KeyUri(suite.TestUser), "secret="+suite.TestUser.TotpSecret)
+
+	_, err = sut.Enable(suite.TestUser, invalidCode(suite.TestUser.TotpSecret))
+	assert.ErrorIs(suite.T(), err, ErrTotpInvalidCode)
+	assert.False(suite.T(), suite.TestUser.HasTotp())
+
+	recoveryCodes, err := sut.Enable(suite.TestUser, currentCode(suite.TestUser.TotpSecret))
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.TestUser.HasTotp())
+	assert.Len(suite.T(), recoveryCodes, totpRecoveryCodesCount)
+	assert.Equal(suite.T(), totpRecoveryCodesCount, suite.TestUser.RecoveryCodesLeft())
+	assert.NotContains(suite.T(), suite.TestUser.TotpRecoveryCodes, recoveryCodes[0]) // only hashes are stored
+
+	_, err = sut.Setup(suite.TestUser)
+	assert.ErrorIs(suite.T(), err, ErrTotpAlreadyEnabled)
+}
+
+func (suite *TotpServiceTestSuite) TestTotpService_Verify() {
+	sut := NewTotpService(suite.UserService)
+	sut.Setup(suite.TestUser)
+	suite.TestUser.TotpLastCounter = 0
+	suite.TestUser.TotpEnabled = true
+
+	code := currentCode(suite.TestUser.TotpSecret)
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, code))
+	assert.Equal(suite.T(), utils.TotpCounter(time.Now()), suite.TestUser.TotpLastCounter)
+
+	// replay
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, code), ErrTotpInvalidCode)
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, invalidCode(suite.TestUser.TotpSecret)), ErrTotpInvalidCode)
+}
+
+func (suite *TotpServiceTestSuite) TestTotpService_Verify_RecoveryCode() {
+	sut := NewTotpService(suite.UserService)
+	sut.Setup(suite.TestUser)
+	recoveryCodes, err := sut.Enable(suite.TestUser, currentCode(suite.TestUser.TotpSecret))
+	assert.Nil(suite.T(), err)
+
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, " "+strings.ToUpper(recoveryCodes[3])))
+	assert.Equal(suite.T(), totpRecoveryCodesCount-1, suite.TestUser.RecoveryCodesLeft())
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, recoveryCodes[3]), ErrTotpInvalidCode)
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, strings.ReplaceAll(recoveryCodes[4], "-", "")))
+
+	newRecoveryCodes, err := sut.RegenerateRecoveryCodes(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), totpRecoveryCodesCount, suite.TestUser.RecoveryCodesLeft())
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, recoveryCodes[5]), ErrTotpInvalidCode)
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, newRecoveryCodes[5]))
+}
+
+func (suite *TotpServiceTestSuite) TestTotpService_Disable() {
+	config.Get().Security.Enforce2fa = config.Enforce2faAdmins
+	sut := NewTotpService(suite.UserService)
+	sut.Setup(suite.TestUser)
+	suite.TestUser.TotpEnabled = true
+
+	suite.TestUser.IsAdmin = true
+	assert.True(suite.T(), sut.IsRequired(suite.TestUser))
+	_, err := sut.Disable(suite.TestUser)
+	assert.ErrorIs(suite.T(), err, ErrTotpRequired)
+	assert.True(suite.T(), suite.TestUser.HasTotp())
+
+	suite.TestUser.IsAdmin = false
+	assert.False(suite.T(), sut.IsRequired(suite.TestUser))
+	_, err = sut.Disable(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), suite.TestUser.HasTotp())
+	assert.Empty(suite.T(), suite.TestUser.TotpSecret)
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, "123456"), ErrTotpNotEnabled)
+}
+
+func currentCode(secret string) string {
+	code, _ := utils.TotpCode(secret, utils.TotpCounter(time.Now()))
+	return code
+}
+
+// invalidCode returns a code that is not valid for any time step within the accepted skew
+func invalidCode(secret string) string {
+	valid := make(map[string]bool)
+	for i := -totpSkew; i <= totpSkew; i++ {
+		code, _ := utils.TotpCode(secret, utils.TotpCounter(time.Now())+int64(i))
+		valid[code] = true
+	}
+	for _, code := range []string{"000000", "111111", "222222", "333333"} {
+		if !valid[code] {
+			return code
+		}
+	}
+	return ""
+}
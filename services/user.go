@@ -23,16 +23,18 @@ type UserService struct {
 	eventBus        *hub.Hub
 	keyValueService IKeyValueService
 	mailService     IMailService
+	apiTokenService IApiTokenService
 	repository      repositories.IUserRepository
 }
 
-func NewUserService(keyValueService IKeyValueService, mailService IMailService, userRepo repositories.IUserRepository) *UserService {
+func NewUserService(keyValueService IKeyValueService, mailService IMailService, apiTokenService IApiTokenService, userRepo repositories.IUserRepository) *UserService {
 	srv := &UserService{
 		config:          config.Get(),
 		eventBus:        config.EventBus(),
 		cache:           cache.New(1*time.Hour, 2*time.Hour),
 		keyValueService: keyValueService,
 		mailService:     mailService,
+		apiTokenService: apiTokenService,
 		repository:      userRepo,
 	}
 
@@ -42,6 +44,18 @@ func NewUserService(keyValueService IKeyValueService, mailService IMailService,
 			user := m.Fields[config.FieldUser].(*models.User)
 			n := m.Fields[config.FieldPayload].(int)
 
+			// failures of one of the user's relay targets, which was already paused by the relay service
+			if target, ok := m.Fields[config.FieldRelayTarget].(*models.RelayTarget); ok {
+				if user.Email != "" {
+					if err := mailService.SendRelayFailureNotification(user, target, n); err != nil {
+						config.Log().Error("failed to send relay failure notification mail to user", "userID", user.ID, "targetID", target.ID)
+					} else {
+						slog.Info("sent relay connection failure mail", "userID", user.ID, "targetID", target.ID)
+					}
+				}
+				continue
+			}
+
 			slog.Warn("resetting wakatime api key for user due to too many failures", "userID", user.ID, "failureCount", n)
 
 			if _, err := srv.SetWakatimeApiCredentials(user, "", ""); err != nil {
@@ -97,6 +111,20 @@ func (srv *UserService) GetUserByKey(key string) (*models.User, error) {
 	return u, nil
 }
 
+// GetUserByToken resolves a user by one of their scoped api tokens, see models.ApiToken
+func (srv *UserService) GetUserByToken(token string) (*models.User, *models.ApiToken, error) {
+	apiToken, err := srv.apiTokenService.GetByToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := srv.GetUserById(apiToken.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, apiToken, nil
+}
+
 func (srv *UserService) GetUserByEmail(email string) (*models.User, error) {
 	if email == "" {
 		return nil, errors.New("email must not be empty")
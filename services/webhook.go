@@ -0,0 +1,326 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/artifex/v2"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+// delays between consecutive attempts to deliver an event, after the first attempt failed
+var webhookRetryDelays = []time.Duration{30 * time.Second, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// delivery log entries older than this are deleted
+const webhookDeliveryRetention = 30 * 24 * time.Hour
+
+const webhookCacheKeyGlobal = "--global"
+
+type WebhookService struct {
+	config         *config.Config
+	cache          *cache.Cache
+	eventBus       *hub.Hub
+	repository     repositories.IWebhookRepository
+	httpClient     *http.Client // for global webhooks, which only admins can create
+	httpClientUser *http.Client // for webhooks of individual users, refuses to connect to local or private addresses
+	queueDefault   *artifex.Dispatcher
+	queueWorkers   *artifex.Dispatcher
+}
+
+func NewWebhookService(webhookRepository repositories.IWebhookRepository) *WebhookService {
+	srv := &WebhookService{
+		config:         config.Get(),
+		cache:          cache.New(1*time.Hour, 2*time.Hour),
+		eventBus:       config.EventBus(),
+		repository:     webhookRepository,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		httpClientUser: utils.NewPublicOnlyHttpClient(10*time.Second, nil),
+		queueDefault:   config.GetDefaultQueue(),
+		queueWorkers:   config.GetQueue(config.QueueWebhooks),
+	}
+
+	sub1 := srv.eventBus.Subscribe(0, models.WebhookEvents()...)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.handleEvent(m)
+		}
+	}(&sub1)
+
+	return srv
+}
+
+func (srv *WebhookService) Schedule() {
+	slog.Info("scheduling webhook delivery log cleanup")
+
+	if _, err := srv.queueDefault.DispatchCron(func() {
+		if err := srv.repository.DeleteDeliveriesBefore(time.Now().Add(-webhookDeliveryRetention)); err != nil {
+			config.Log().Error("failed to delete old webhook deliveries", "error", err)
+		}
+	}, "0 30 4 * * *"); err != nil {
+		config.Log().Error("failed to schedule webhook delivery log cleanup", "error", err)
+	}
+}
+
+func (srv *WebhookService) GetById(id uint) (*models.Webhook, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *WebhookService) GetByUser(userId string) ([]*models.Webhook, error) {
+	if webhooks, ok := srv.cache.Get(userId); ok {
+		return webhooks.([]*models.Webhook), nil
+	}
+	webhooks, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(userId, webhooks)
+	return webhooks, nil
+}
+
+// GetGlobal returns all webhooks not bound to a specific user, which receive events of all users
+func (srv *WebhookService) GetGlobal() ([]*models.Webhook, error) {
+	if webhooks, ok := srv.cache.Get(webhookCacheKeyGlobal); ok {
+		return webhooks.([]*models.Webhook), nil
+	}
+	webhooks, err := srv.repository.GetGlobal()
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(webhookCacheKeyGlobal, webhooks)
+	return webhooks, nil
+}
+
+// Create registers a new webhook for the given user (or for all users, if global is set, which requires admin privileges) and generates its signing secret
+func (srv *WebhookService) Create(user *models.User, url string, events []string, global bool) (*models.Webhook, error) {
+	if global && !user.IsAdmin {
+		return nil, errors.New("only admins can create webhooks for all users")
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		Url:     strings.TrimSpace(url),
+		Secret:  hex.EncodeToString(secret),
+		Events:  strings.Join(events, ","),
+		Enabled: true,
+	}
+	if !global {
+		webhook.UserID = &user.ID
+	}
+
+	result, err := srv.repository.Insert(webhook)
+	if err != nil {
+		return nil, err
+	}
+	srv.invalidateCache(webhook)
+	return result, nil
+}
+
+func (srv *WebhookService) Delete(webhook *models.Webhook) error {
+	srv.invalidateCache(webhook)
+	return srv.repository.Delete(webhook.ID)
+}
+
+func (srv *WebhookService) GetDeliveries(webhook *models.Webhook, limit int) ([]*models.WebhookDelivery, error) {
+	return srv.repository.GetDeliveries(webhook.ID, limit)
+}
+
+// Ping synchronously sends a test event to the given webhook, without retrying, and returns the logged delivery
+func (srv *WebhookService) Ping(webhook *models.Webhook, user *models.User) (*models.WebhookDelivery, error) {
+	payload, err := srv.buildPayload(models.WebhookEventPing, user.ID, map[string]interface{}{"webhook_id": webhook.ID})
+	if err != nil {
+		return nil, err
+	}
+	return srv.deliver(webhook, payload, 1)
+}
+
+// Dispatch asynchronously sends an event with the given data to all matching webhooks of the user, as well as to global webhooks
+func (srv *WebhookService) Dispatch(event string, userId string, data interface{}) {
+	webhooks, err := srv.getSubscribed(event, userId)
+	if err != nil {
+		config.Log().Error("failed to get webhooks for event", "event", event, "userID", userId, "error", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	for _, webhook := range webhooks {
+		// every webhook gets its own delivery id, so that receivers can use it for deduplication across retries
+		payload, err := srv.buildPayload(event, userId, data)
+		if err != nil {
+			config.Log().Error("failed to build webhook payload", "webhookID", webhook.ID, "event", event, "userID", userId, "error", err)
+			continue
+		}
+		srv.schedule(webhook, payload, 1, 0)
+	}
+}
+
+func (srv *WebhookService) handleEvent(m hub.Message) {
+	var userId string
+	var data interface{}
+
+	switch m.Name {
+	case config.EventHeartbeatCreate:
+		hb := m.Fields[config.FieldPayload].(*models.Heartbeat)
+		userId, data = hb.UserID, hb
+	case config.EventUserUpdate, config.EventUserDelete:
+		user := m.Fields[config.FieldPayload].(*models.User)
+		userId, data = user.ID, models.NewWebhookUserData(user)
+	case config.EventWakatimeFailure:
+		user := m.Fields[config.FieldUser].(*models.User)
+		failureData := map[string]interface{}{"failures": m.Fields[config.FieldPayload]}
+		if target, ok := m.Fields[config.FieldRelayTarget].(*models.RelayTarget); ok {
+			failureData["relay_target"] = target.Name
+		}
+		userId, data = user.ID, failureData
+	default:
+		userId, _ = m.Fields[config.FieldUserId].(string)
+		data = m.Fields[config.FieldPayload]
+	}
+
+	srv.Dispatch(m.Name, userId, data)
+
+	if m.Name == config.EventUserDelete {
+		srv.cache.Delete(userId) // user's webhooks are gone along with the user
+	}
+}
+
+func (srv *WebhookService) getSubscribed(event, userId string) ([]*models.Webhook, error) {
+	userWebhooks, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	globalWebhooks, err := srv.GetGlobal()
+	if err != nil {
+		return nil, err
+	}
+
+	subscribed := make([]*models.Webhook, 0)
+	for _, webhooks := range [][]*models.Webhook{userWebhooks, globalWebhooks} {
+		for _, w := range webhooks {
+			if w.Enabled && w.Subscribes(event) {
+				subscribed = append(subscribed, w)
+			}
+		}
+	}
+	return subscribed, nil
+}
+
+func (srv *WebhookService) buildPayload(event, userId string, data interface{}) (*models.WebhookPayload, error) {
+	deliveryId, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebhookPayload{
+		ID:        deliveryId.String(),
+		Event:     event,
+		UserID:    userId,
+		Timestamp: time.Now(),
+		Data:      data,
+	}, nil
+}
+
+func (srv *WebhookService) schedule(webhook *models.Webhook, payload *models.WebhookPayload, attempt int, delay time.Duration) {
+	job := func() {
+		delivery, err := srv.deliver(webhook, payload, attempt)
+		if err != nil {
+			config.Log().Error("failed to deliver webhook", "webhookID", webhook.ID, "event", payload.Event, "error", err)
+			return
+		}
+		if delivery.Success {
+			return
+		}
+		if attempt > len(webhookRetryDelays) {
+			slog.Warn("giving up webhook delivery", "webhookID", webhook.ID, "event", payload.Event, "deliveryID", payload.ID, "attempts", attempt)
+			return
+		}
+		srv.schedule(webhook, payload, attempt+1, webhookRetryDelays[attempt-1])
+	}
+
+	var err error
+	if delay > 0 {
+		err = srv.queueWorkers.DispatchIn(job, delay)
+	} else {
+		err = srv.queueWorkers.Dispatch(job)
+	}
+	if err != nil {
+		config.Log().Error("failed to dispatch webhook delivery job", "webhookID", webhook.ID, "event", payload.Event, "error", err)
+	}
+}
+
+// deliver performs a single attempt to post the payload to the webhook and logs its outcome. Errors are only returned if the attempt couldn't even be made.
+func (srv *WebhookService) deliver(webhook *models.Webhook, payload *models.WebhookPayload, attempt int) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("wakapi/%s", srv.config.Version))
+	req.Header.Set(models.WebhookHeaderEvent, payload.Event)
+	req.Header.Set(models.WebhookHeaderDelivery, payload.ID)
+	req.Header.Set(models.WebhookHeaderSignature, "sha256="+webhook.Sign(body))
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:  webhook.ID,
+		DeliveryID: payload.ID,
+		Event:      payload.Event,
+		Attempt:    attempt,
+	}
+
+	client := srv.httpClient
+	if !webhook.IsGlobal() {
+		client = srv.httpClientUser
+	}
+
+	t0 := time.Now()
+	res, err := client.Do(req)
+	delivery.DurationMs = time.Since(t0).Milliseconds()
+
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		res.Body.Close()
+		delivery.StatusCode = res.StatusCode
+		delivery.Success = res.StatusCode >= 200 && res.StatusCode < 300
+		if !delivery.Success {
+			delivery.Error = res.Status
+		}
+	}
+
+	if _, err := srv.repository.InsertDelivery(delivery); err != nil {
+		config.Log().Error("failed to log webhook delivery", "webhookID", webhook.ID, "error", err)
+	}
+
+	return delivery, nil
+}
+
+func (srv *WebhookService) invalidateCache(webhook *models.Webhook) {
+	if webhook.IsGlobal() {
+		srv.cache.Delete(webhookCacheKeyGlobal)
+	} else {
+		srv.cache.Delete(*webhook.UserID)
+	}
+}
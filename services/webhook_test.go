@@ -0,0 +1,238 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type receivedWebhook struct {
+	Header  http.Header
+	Body    []byte
+	Payload *models.WebhookPayload
+}
+
+type WebhookServiceTestSuite struct {
+	suite.Suite
+	TestUsers         []*models.User
+	WebhookRepository *mocks.WebhookRepositoryMock
+	Server            *httptest.Server
+	ServerStatus      int
+	Received          chan *receivedWebhook
+}
+
+func (suite *WebhookServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+
+	suite.TestUsers = []*models.User{
+		{ID: "testuser01"},
+		{ID: "testuser02"},
+		{ID: "admin", IsAdmin: true},
+	}
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload models.WebhookPayload
+		json.Unmarshal(body, &payload)
+		w.WriteHeader(suite.ServerStatus)
+		suite.Received <- &receivedWebhook{Header: r.Header, Body: body, Payload: &payload}
+	}))
+}
+
+func (suite *WebhookServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *WebhookServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.WebhookRepository = new(mocks.WebhookRepositoryMock)
+	suite.ServerStatus = http.StatusOK
+	suite.Received = make(chan *receivedWebhook, 10)
+}
+
+func TestWebhookServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookServiceTestSuite))
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Ping_Signed() {
+	sut := suite.newService()
+	suite.mockFallbacks()
+
+	webhook := &models.Webhook{ID: 1, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s3cr3t", Events: config.EventSummaryCreate, Enabled: true}
+	suite.WebhookRepository.On("InsertDelivery", mock.Anything).Return(&models.WebhookDelivery{}, nil)
+
+	delivery, err := sut.Ping(webhook, suite.TestUsers[0])
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), delivery.Success)
+	assert.Equal(suite.T(), http.StatusOK, delivery.StatusCode)
+	assert.Equal(suite.T(), 1, delivery.Attempt)
+
+	received := <-suite.Received
+	assert.Equal(suite.T(), models.WebhookEventPing, received.Header.Get(models.WebhookHeaderEvent))
+	assert.Equal(suite.T(), "sha256="+webhook.Sign(received.Body), received.Header.Get(models.WebhookHeaderSignature))
+	assert.NotEqual(suite.T(), "sha256="+(&models.Webhook{Secret: "other"}).Sign(received.Body), received.Header.Get(models.WebhookHeaderSignature))
+	assert.Equal(suite.T(), received.Payload.ID, received.Header.Get(models.WebhookHeaderDelivery))
+	assert.Equal(suite.T(), suite.TestUsers[0].ID, received.Payload.UserID)
+	assert.Equal(suite.T(), delivery.DeliveryID, received.Payload.ID)
+
+	suite.WebhookRepository.AssertNumberOfCalls(suite.T(), "InsertDelivery", 1)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Ping_Failure() {
+	sut := suite.newService()
+	suite.mockFallbacks()
+	suite.ServerStatus = http.StatusInternalServerError
+
+	webhook := &models.Webhook{ID: 1, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s3cr3t", Events: config.EventSummaryCreate, Enabled: true}
+	suite.WebhookRepository.On("InsertDelivery", mock.Anything).Return(&models.WebhookDelivery{}, nil)
+
+	delivery, err := sut.Ping(webhook, suite.TestUsers[0])
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), delivery.Success)
+	assert.Equal(suite.T(), http.StatusInternalServerError, delivery.StatusCode)
+	assert.NotEmpty(suite.T(), delivery.Error)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Dispatch_MatchesSubscriptions() {
+	sut := suite.newService()
+
+	userWebhooks := []*models.Webhook{
+		{ID: 1, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s1", Events: config.EventSummaryCreate + "," + config.EventUserUpdate, Enabled: true},
+		{ID: 2, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s2", Events: config.EventHeartbeatCreate, Enabled: true},
+		{ID: 3, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s3", Events: config.EventSummaryCreate, Enabled: false},
+	}
+	globalWebhooks := []*models.Webhook{
+		{ID: 4, Url: suite.Server.URL, Secret: "s4", Events: config.EventSummaryCreate, Enabled: true},
+	}
+
+	suite.WebhookRepository.On("GetByUser", suite.TestUsers[0].ID).Return(userWebhooks, nil)
+	suite.WebhookRepository.On("GetGlobal").Return(globalWebhooks, nil)
+	suite.WebhookRepository.On("InsertDelivery", mock.Anything).Return(&models.WebhookDelivery{}, nil)
+	suite.mockFallbacks()
+
+	sut.Dispatch(config.EventSummaryCreate, suite.TestUsers[0].ID, &models.Summary{UserID: suite.TestUsers[0].ID})
+
+	received := suite.awaitReceived(2)
+	assert.Len(suite.T(), received, 2)
+	signedBy := make([]uint, 0, len(received))
+	for _, r := range received {
+		assert.Equal(suite.T(), config.EventSummaryCreate, r.Payload.Event)
+		assert.Equal(suite.T(), suite.TestUsers[0].ID, r.Payload.UserID)
+		for _, w := range []*models.Webhook{userWebhooks[0], globalWebhooks[0]} {
+			if r.Header.Get(models.WebhookHeaderSignature) == "sha256="+w.Sign(r.Body) {
+				signedBy = append(signedBy, w.ID)
+			}
+		}
+	}
+	assert.ElementsMatch(suite.T(), []uint{1, 4}, signedBy)
+	assert.NotEqual(suite.T(), received[0].Payload.ID, received[1].Payload.ID)
+
+	// other users' events only reach global webhooks
+	sut.Dispatch(config.EventSummaryCreate, suite.TestUsers[1].ID, &models.Summary{UserID: suite.TestUsers[1].ID})
+	received = suite.awaitReceived(1)
+	assert.Len(suite.T(), received, 1)
+	assert.Equal(suite.T(), "sha256="+globalWebhooks[0].Sign(received[0].Body), received[0].Header.Get(models.WebhookHeaderSignature))
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Dispatch_UserDataWithoutSecrets() {
+	sut := suite.newService()
+
+	webhooks := []*models.Webhook{
+		{ID: 1, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s1", Events: config.EventUserUpdate, Enabled: true},
+	}
+	suite.WebhookRepository.On("GetByUser", suite.TestUsers[0].ID).Return(webhooks, nil)
+	suite.WebhookRepository.On("InsertDelivery", mock.Anything).Return(&models.WebhookDelivery{}, nil)
+	suite.mockFallbacks()
+
+	sut.handleEvent(hub.Message{
+		Name:   config.EventUserUpdate,
+		Fields: map[string]interface{}{config.FieldPayload: &models.User{ID: suite.TestUsers[0].ID, ApiKey: "secret-api-key", Password: "secret-password"}},
+	})
+
+	received := suite.awaitReceived(1)
+	assert.Len(suite.T(), received, 1)
+	assert.NotContains(suite.T(), string(received[0].Body), "secret-api-key")
+	assert.NotContains(suite.T(), string(received[0].Body), "secret-password")
+	assert.Contains(suite.T(), string(received[0].Body), suite.TestUsers[0].ID)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Create_GlobalRequiresAdmin() {
+	sut := NewWebhookService(suite.WebhookRepository)
+	suite.mockFallbacks()
+
+	suite.WebhookRepository.On("Insert", mock.Anything).Return(&models.Webhook{}, nil)
+
+	_, err := sut.Create(suite.TestUsers[0], suite.Server.URL, []string{config.EventSummaryCreate}, true)
+	assert.Error(suite.T(), err)
+
+	_, err = sut.Create(suite.TestUsers[2], suite.Server.URL, []string{config.EventSummaryCreate}, true)
+	assert.Nil(suite.T(), err)
+
+	_, err = sut.Create(suite.TestUsers[0], suite.Server.URL, []string{config.EventSummaryCreate}, false)
+	assert.Nil(suite.T(), err)
+
+	suite.WebhookRepository.AssertNumberOfCalls(suite.T(), "Insert", 2)
+	created := suite.WebhookRepository.Calls[len(suite.WebhookRepository.Calls)-2].Arguments.Get(0).(*models.Webhook)
+	assert.Nil(suite.T(), created.UserID)
+	assert.Len(suite.T(), created.Secret, 48)
+	created = suite.WebhookRepository.Calls[len(suite.WebhookRepository.Calls)-1].Arguments.Get(0).(*models.Webhook)
+	assert.Equal(suite.T(), suite.TestUsers[0].ID, *created.UserID)
+}
+
+func (suite *WebhookServiceTestSuite) TestWebhookService_Ping_DeniesNonPublicAddresses() {
+	sut := NewWebhookService(suite.WebhookRepository)
+	suite.mockFallbacks()
+
+	suite.WebhookRepository.On("InsertDelivery", mock.Anything).Return(&models.WebhookDelivery{}, nil)
+
+	// test server listens on loopback interface
+	userWebhook := &models.Webhook{ID: 1, UserID: &suite.TestUsers[0].ID, Url: suite.Server.URL, Secret: "s1", Events: config.EventSummaryCreate, Enabled: true}
+	delivery, err := sut.Ping(userWebhook, suite.TestUsers[0])
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), delivery.Success)
+	assert.Contains(suite.T(), delivery.Error, "non-public address")
+	assert.Len(suite.T(), suite.Received, 0)
+
+	globalWebhook := &models.Webhook{ID: 2, Url: suite.Server.URL, Secret: "s2", Events: config.EventSummaryCreate, Enabled: true}
+	delivery, err = sut.Ping(globalWebhook, suite.TestUsers[2])
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), delivery.Success)
+	assert.Len(suite.T(), suite.awaitReceived(1), 1)
+}
+
+// newService creates the service under test with user webhooks being allowed to reach the local test server
+func (suite *WebhookServiceTestSuite) newService() *WebhookService {
+	sut := NewWebhookService(suite.WebhookRepository)
+	sut.httpClientUser = sut.httpClient
+	return sut
+}
+
+// mockFallbacks makes the repository answer lookups caused by events published from elsewhere during the test run
+func (suite *WebhookServiceTestSuite) mockFallbacks() {
+	suite.WebhookRepository.On("GetByUser", mock.Anything).Return([]*models.Webhook{}, nil)
+	suite.WebhookRepository.On("GetGlobal").Return([]*models.Webhook{}, nil)
+}
+
+func (suite *WebhookServiceTestSuite) awaitReceived(n int) []*receivedWebhook {
+	received := make([]*receivedWebhook, 0, n)
+	timeout := time.After(5 * time.Second)
+	for len(received) < n {
+		select {
+		case r := <-suite.Received:
+			received = append(received, r)
+		case <-timeout:
+			return received
+		}
+	}
+	return received
+}
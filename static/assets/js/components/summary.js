@@ -1,15 +1,33 @@
 PetiteVue.createApp({
     $delimiters: ['${', '}'],
     activityChartSvg: '',
+    heatmapSvg: '',
     get currentInterval() {
         const urlParams = new URLSearchParams(window.location.search)
         if (urlParams.has('interval')) return urlParams.get('interval')
         if (!urlParams.has('from') && !urlParams.has('to')) return 'today'
         return null
     },
+    heatmapUrl(userId, format) {
+        // heatmap covers the same range and filters as the summary
+        const urlParams = new URLSearchParams(window.location.search)
+        const params = new URLSearchParams()
+        for (const key of ['from', 'to', 'project', 'language', 'editor', 'operating_system', 'machine', 'label']) {
+            if (urlParams.has(key)) params.set(key, urlParams.get(key))
+        }
+        if (this.currentInterval) params.set('interval', this.currentInterval)
+        if (format === 'svg') {
+            params.set('dark', 'true')
+            params.set('noattr', 'true')
+        }
+        return `api/activity/heatmap/${userId}.${format}?${params.toString()}`
+    },
     mounted({userId}) {
         fetch(`api/activity/chart/${userId}.svg?dark&noattr`)
             .then(res => res.text())
             .then(data => this.activityChartSvg = data)
+        fetch(this.heatmapUrl(userId, 'svg'))
+            .then(res => res.ok ? res.text() : '')
+            .then(data => this.heatmapSvg = data)
     }
 }).mount('#summary-page')
\ No newline at end of file
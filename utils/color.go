@@ -3,8 +3,16 @@ package utils
 import (
 	"fmt"
 	"image/color"
+	"math"
+	"regexp"
 )
 
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func IsHexColor(s string) bool {
+	return hexColorPattern.MatchString(s)
+}
+
 func HexToRGBA(s string) (c color.RGBA) {
 	// https://stackoverflow.com/questions/54197913/parse-hex-string-to-image-color
 	c.A = 0xff
@@ -38,3 +46,14 @@ func FadeColors(color1, color2 color.RGBA, ratio float64) color.RGBA {
 
 	return color.RGBA{R: r, G: g, B: b, A: a}
 }
+
+// FadeColorScale interpolates along a scale of two or more colors, where a ratio of 0 corresponds to the first and 1 to the last one
+func FadeColorScale(colors []color.RGBA, ratio float64) color.RGBA {
+	if len(colors) == 1 {
+		return colors[0]
+	}
+	ratio = math.Max(0, math.Min(1, ratio))
+	pos := ratio * float64(len(colors)-1)
+	i := int(math.Min(math.Floor(pos), float64(len(colors)-2)))
+	return FadeColors(colors[i], colors[i+1], pos-float64(i))
+}
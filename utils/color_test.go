@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColor_IsHexColor(t *testing.T) {
+	assert.True(t, IsHexColor("#047857"))
+	assert.True(t, IsHexColor("#fFf"))
+	assert.False(t, IsHexColor("047857"))
+	assert.False(t, IsHexColor("#04785"))
+	assert.False(t, IsHexColor("#04785g"))
+	assert.False(t, IsHexColor("red"))
+}
+
+func TestColor_FadeColorScale(t *testing.T) {
+	scale := []color.RGBA{
+		{R: 0, G: 0, B: 0, A: 255},
+		{R: 200, G: 0, B: 0, A: 255},
+		{R: 200, G: 200, B: 0, A: 255},
+	}
+
+	assert.Equal(t, scale[0], FadeColorScale(scale, 0))
+	assert.Equal(t, color.RGBA{R: 100, G: 0, B: 0, A: 255}, FadeColorScale(scale, 0.25))
+	assert.Equal(t, scale[1], FadeColorScale(scale, 0.5))
+	assert.Equal(t, color.RGBA{R: 200, G: 100, B: 0, A: 255}, FadeColorScale(scale, 0.75))
+	assert.Equal(t, scale[2], FadeColorScale(scale, 1))
+	assert.Equal(t, scale[2], FadeColorScale(scale, 1.5))
+	assert.Equal(t, scale[0], FadeColorScale(scale[:1], 0.5))
+}
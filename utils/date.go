@@ -52,6 +52,22 @@ func SplitRangeByDays(from time.Time, to time.Time) [][]time.Time {
 	return intervals
 }
 
+// SplitRangeByWeeks creates a slice of intervals between from and to, each of which is at max of one week length and has its split at midnight between sunday and monday
+func SplitRangeByWeeks(from time.Time, to time.Time) [][]time.Time {
+	intervals := make([][]time.Time, 0)
+
+	for t1 := from; t1.Before(to); {
+		t2 := datetime.BeginOfWeek(t1, time.Monday).AddDate(0, 0, 7)
+		if t2.After(to) {
+			t2 = to
+		}
+		intervals = append(intervals, []time.Time{t1, t2})
+		t1 = t2
+	}
+
+	return intervals
+}
+
 // LocalTZOffset returns the time difference between server local time and UTC
 func LocalTZOffset() time.Duration {
 	_, offset := time.Now().Zone()
@@ -57,3 +57,20 @@ func TestDate_SplitRangeByDays(t *testing.T) {
 
 	assert.Len(t, result4, 0)
 }
+
+func TestDate_SplitRangeByWeeks(t *testing.T) {
+	df1 := time.Date(2024, 3, 6, 12, 0, 0, 0, tzCet) // a wednesday
+	dt1 := time.Date(2024, 3, 20, 0, 0, 0, 0, tzCet)
+
+	result1 := SplitRangeByWeeks(df1, dt1)
+	result2 := SplitRangeByWeeks(df1, df1)
+
+	assert.Len(t, result1, 3)
+	assert.Equal(t, df1, result1[0][0])
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, tzCet), result1[0][1])
+	assert.Equal(t, time.Date(2024, 3, 18, 0, 0, 0, 0, tzCet), result1[1][1])
+	assert.Equal(t, dt1, result1[2][1])
+	assert.Equal(t, result1[1][0], result1[0][1])
+
+	assert.Len(t, result2, 0)
+}
@@ -1,10 +1,18 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"net/http"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// shared address space (carrier-grade nat), not covered by net.IP.IsPrivate()
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
 // CheckEmailMX takes an e-mail address and verifies that an MX DNS record exists for its domain
 func CheckEmailMX(email string) bool {
 	parts := strings.Split(email, "@")
@@ -14,3 +22,123 @@ func CheckEmailMX(email string) bool {
 	records, err := net.LookupMX(parts[1])
 	return len(records) > 0 && err == nil
 }
+
+// IsPublicIP checks whether the given ip is publicly routable, i.e. not a loopback, private, link-local, unspecified or multicast address
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// IsPublicHost checks whether the given host name or ip literal may refer to a public address. Host names are not resolved, so their addresses need to be checked again when connecting (see DenyNonPublicAddresses).
+func IsPublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPublicIP(ip)
+	}
+	return true
+}
+
+// HostAllowList holds host names and ip ranges of the local network, which outgoing requests on behalf of users (e.g. to relay targets) may go to, despite them not being public
+type HostAllowList struct {
+	hosts []string
+	nets  []*net.IPNet
+}
+
+// ParseHostAllowList parses a comma-separated list of host names, ip addresses or cidr ranges
+func ParseHostAllowList(s string) *HostAllowList {
+	list := &HostAllowList{hosts: []string{}, nets: []*net.IPNet{}}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.Trim(strings.TrimSpace(entry), "[]"))
+		if entry == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			list.nets = append(list.nets, ipNet)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			bits := len(ip) * 8
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), net.IPv4len*8
+			}
+			list.nets = append(list.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		} else {
+			list.hosts = append(list.hosts, strings.TrimSuffix(entry, "."))
+		}
+	}
+	return list
+}
+
+// Contains checks whether the given host name or ip literal is explicitly allowed
+func (l *HostAllowList) Contains(host string) bool {
+	if l == nil {
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		return l.ContainsIP(ip)
+	}
+	for _, h := range l.hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *HostAllowList) ContainsIP(ip net.IP) bool {
+	if l == nil {
+		return false
+	}
+	for _, n := range l.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permits checks whether requests may be sent to the given host, because it is either public or explicitly allowed
+func (l *HostAllowList) Permits(host string) bool {
+	return IsPublicHost(host) || l.Contains(host)
+}
+
+// NewPublicOnlyHttpClient creates a client that checks the resolved address of every connection (including those after redirects), so that users can't make the server send requests to hosts in its local network, except for the explicitly allowed ones
+func NewPublicOnlyHttpClient(timeout time.Duration, allowed *HostAllowList) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	restrictedDialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: func(network, address string, c syscall.RawConn) error {
+		if host, _, err := net.SplitHostPort(address); err == nil && allowed.Contains(host) {
+			return nil
+		}
+		return DenyNonPublicAddresses(network, address, c)
+	}}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // would otherwise bypass the address check
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(address); err == nil && allowed.Contains(host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return restrictedDialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// DenyNonPublicAddresses is meant to be used as a net.Dialer's control function to refuse connections to addresses that aren't publicly routable, after host names were resolved
+func DenyNonPublicAddresses(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("connecting to non-public address %s is not allowed", host)
+	}
+	return nil
+}
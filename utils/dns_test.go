@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"1.1.1.1", "81.169.145.105", "2a01:238:20a:202:1105::"} {
+		assert.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.178.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1"} {
+		assert.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestIsPublicHost(t *testing.T) {
+	assert.True(t, IsPublicHost("example.org"))
+	assert.True(t, IsPublicHost("1.1.1.1"))
+	assert.False(t, IsPublicHost("localhost"))
+	assert.False(t, IsPublicHost("LOCALHOST."))
+	assert.False(t, IsPublicHost("api.localhost"))
+	assert.False(t, IsPublicHost("127.0.0.1"))
+	assert.False(t, IsPublicHost("::1"))
+	assert.False(t, IsPublicHost("169.254.169.254"))
+	assert.False(t, IsPublicHost(""))
+}
+
+func TestHostAllowList_Permits(t *testing.T) {
+	allowed := ParseHostAllowList(" wakapi.internal , 10.0.0.0/24,192.168.178.10, [fd00::1]")
+	assert.True(t, allowed.Permits("example.org"))
+	assert.True(t, allowed.Permits("WAKAPI.internal."))
+	assert.True(t, allowed.Permits("10.0.0.42"))
+	assert.True(t, allowed.Permits("192.168.178.10"))
+	assert.True(t, allowed.Permits("fd00::1"))
+	assert.False(t, allowed.Permits("10.0.1.42"))
+	assert.False(t, allowed.Permits("192.168.178.11"))
+	assert.True(t, allowed.Contains("wakapi.internal"))
+	assert.False(t, allowed.Contains("other.internal")) // still permitted, but only dialed if it resolves to a public address
+	assert.False(t, allowed.Permits("localhost"))
+
+	var empty *HostAllowList
+	assert.True(t, empty.Permits("example.org"))
+	assert.False(t, empty.Permits("127.0.0.1"))
+	assert.False(t, ParseHostAllowList("").Permits("127.0.0.1"))
+}
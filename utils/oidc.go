@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// RandomUrlToken returns a cryptographically secure, url-safe random string of n bytes of entropy, e.g. for oauth state parameters
+func RandomUrlToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// PkceChallenge derives the S256 code challenge from a pkce code verifier (https://datatracker.ietf.org/doc/html/rfc7636#section-4.2)
+func PkceChallenge(verifier string) string {
+	h := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
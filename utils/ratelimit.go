@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// RateLimiter is a keyed token bucket rate limiter. Each key's bucket holds up to limit tokens and is refilled at a rate of limit tokens per window.
+type RateLimiter struct {
+	limit       float64
+	window      time.Duration
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	rejected    atomic.Int64
+	now         func() time.Time
+	lock        sync.Mutex
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:       float64(limit),
+		window:      window,
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+		now:         time.Now,
+	}
+}
+
+// Allow takes a token from the given key's bucket. If the bucket is empty, it returns false along with the time to wait until the next token becomes available.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.limit, updatedAt: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.limit, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*l.ratePerSecond())
+	bucket.updatedAt = now
+
+	if bucket.tokens < 1 {
+		l.rejected.Add(1)
+		return false, time.Duration((1 - bucket.tokens) / l.ratePerSecond() * float64(time.Second))
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+// Rejected returns the total number of requests rejected by this limiter
+func (l *RateLimiter) Rejected() int64 {
+	return l.rejected.Load()
+}
+
+func (l *RateLimiter) ratePerSecond() float64 {
+	return l.limit / l.window.Seconds()
+}
+
+// cleanup drops buckets that have been refilled entirely, as they're equivalent to fresh ones
+func (l *RateLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.window {
+		return
+	}
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updatedAt) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	sut := NewRateLimiter(2, time.Minute)
+	sut.now = func() time.Time { return now }
+
+	ok, _ := sut.Allow("key1")
+	assert.True(t, ok)
+	ok, _ = sut.Allow("key1")
+	assert.True(t, ok)
+
+	ok, retryAfter := sut.Allow("key1")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// other keys have their own bucket
+	ok, _ = sut.Allow("key2")
+	assert.True(t, ok)
+
+	// refilled at a rate of one token per 30 seconds
+	now = now.Add(30 * time.Second)
+	ok, _ = sut.Allow("key1")
+	assert.True(t, ok)
+	ok, retryAfter = sut.Allow("key1")
+	assert.False(t, ok)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	assert.Equal(t, int64(2), sut.Rejected())
+}
+
+func TestRateLimiter_Cleanup(t *testing.T) {
+	now := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	sut := NewRateLimiter(2, time.Minute)
+	sut.now = func() time.Time { return now }
+	sut.lastCleanup = now
+
+	sut.Allow("key1")
+	now = now.Add(30 * time.Second)
+	sut.Allow("key2")
+	assert.Len(t, sut.buckets, 2)
+
+	now = now.Add(40 * time.Second)
+	sut.Allow("key3")
+	assert.Len(t, sut.buckets, 2)
+	assert.NotContains(t, sut.buckets, "key1")
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// test vectors from https://datatracker.ietf.org/doc/html/rfc6238#appendix-B (sha1, last 6 digits)
+var totpTestSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTotp_TotpCode(t *testing.T) {
+	testCases := []struct {
+		ts       int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := TotpCode(totpTestSecret, TotpCounter(time.Unix(tc.ts, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, code)
+	}
+
+	_, err := TotpCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestTotp_ValidateTotp(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	counter, ok := ValidateTotp(totpTestSecret, "050471", now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, TotpCounter(now), counter)
+
+	// previous time step still accepted within skew
+	counter, ok = ValidateTotp(totpTestSecret, " 050 471 ", now.Add(TotpPeriod*time.Second), 1)
+	assert.True(t, ok)
+	assert.Equal(t, TotpCounter(now), counter)
+
+	_, ok = ValidateTotp(totpTestSecret, "050471", now.Add(2*TotpPeriod*time.Second), 1)
+	assert.False(t, ok)
+	_, ok = ValidateTotp(totpTestSecret, "123456", now, 1)
+	assert.False(t, ok)
+	_, ok = ValidateTotp(totpTestSecret, "", now, 1)
+	assert.False(t, ok)
+}
+
+func TestTotp_TotpUri(t *testing.T) {
+	uri, err := url.Parse(TotpUri("Wakapi", "john doe", "JBSWY3DPEHPK3PXP"))
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Wakapi:john doe", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "Wakapi", uri.Query().Get("issuer"))
+}